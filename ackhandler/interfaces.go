@@ -13,12 +13,15 @@ type SentPacketHandler interface {
 	ReceivedAck(ackFrame *frames.AckFrame, withPacketNumber protocol.PacketNumber, recvTime time.Time) error
 
 	SendingAllowed() bool
+	TimeUntilSend() time.Time
 	GetStopWaitingFrame(force bool) *frames.StopWaitingFrame
 	DequeuePacketForRetransmission() (packet *Packet)
 	GetLeastUnacked() protocol.PacketNumber
 
 	GetAlarmTimeout() time.Time
 	OnAlarm()
+
+	GetStatistics() SentPacketStatistics
 }
 
 // ReceivedPacketHandler handles ACKs needed to send for incoming packets
@@ -27,4 +30,28 @@ type ReceivedPacketHandler interface {
 	ReceivedStopWaiting(*frames.StopWaitingFrame) error
 
 	GetAckFrame() *frames.AckFrame
+
+	GetStatistics() ReceivedPacketStatistics
+}
+
+// SentPacketStatistics are statistics about the packets sent, and the state of the congestion controller
+type SentPacketStatistics struct {
+	PacketsSent          uint64
+	PacketsLost          uint64
+	PacketsRetransmitted uint64
+
+	BytesInFlight    protocol.ByteCount
+	CongestionWindow protocol.ByteCount
+	// SlowStartThreshold is 0 if the congestion controller doesn't use a slow start threshold
+	SlowStartThreshold protocol.ByteCount
+}
+
+// ReceivedPacketStatistics are statistics about the packets received
+type ReceivedPacketStatistics struct {
+	PacketsReceived uint64
+}
+
+// a slowStartThresholder is a congestion controller with a slow start threshold, e.g. Cubic
+type slowStartThresholder interface {
+	GetSlowStartThreshold() protocol.ByteCount
 }
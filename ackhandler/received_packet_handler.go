@@ -32,6 +32,8 @@ type receivedPacketHandler struct {
 	ackAlarm                                   time.Time
 	ackAlarmResetCallback                      func(time.Time)
 	lastAck                                    *frames.AckFrame
+
+	packetsReceived uint64
 }
 
 // NewReceivedPacketHandler creates a new receivedPacketHandler
@@ -71,6 +73,7 @@ func (h *receivedPacketHandler) ReceivedPacket(packetNumber protocol.PacketNumbe
 		h.largestObserved = packetNumber
 		h.largestObservedReceivedTime = time.Now()
 	}
+	h.packetsReceived++
 
 	h.maybeQueueAck(packetNumber, shouldInstigateAck)
 	return nil
@@ -164,3 +167,7 @@ func (h *receivedPacketHandler) GetAckFrame() *frames.AckFrame {
 
 	return ack
 }
+
+func (h *receivedPacketHandler) GetStatistics() ReceivedPacketStatistics {
+	return ReceivedPacketStatistics{PacketsReceived: h.packetsReceived}
+}
@@ -49,6 +49,16 @@ var _ = Describe("receivedPacketHandler", func() {
 			Expect(err).To(MatchError(ErrDuplicatePacket))
 		})
 
+		It("counts the packets received", func() {
+			for i := 1; i < 5; i++ {
+				err := handler.ReceivedPacket(protocol.PacketNumber(i), true)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			err := handler.ReceivedPacket(4, true)
+			Expect(err).To(MatchError(ErrDuplicatePacket))
+			Expect(handler.GetStatistics().PacketsReceived).To(BeEquivalentTo(4))
+		})
+
 		It("ignores a packet with PacketNumber less than the LeastUnacked of a previously received StopWaiting", func() {
 			err := handler.ReceivedPacket(5, true)
 			Expect(err).ToNot(HaveOccurred())
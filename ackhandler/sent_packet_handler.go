@@ -62,23 +62,27 @@ type sentPacketHandler struct {
 
 	// The alarm timeout
 	alarm time.Time
+
+	packetsSent          uint64
+	packetsLost          uint64
+	packetsRetransmitted uint64
+
+	onPacketLost func(*Packet)
+
+	logger utils.Logger
 }
 
 // NewSentPacketHandler creates a new sentPacketHandler
-func NewSentPacketHandler(rttStats *congestion.RTTStats) SentPacketHandler {
-	congestion := congestion.NewCubicSender(
-		congestion.DefaultClock{},
-		rttStats,
-		false, /* don't use reno since chromium doesn't (why?) */
-		protocol.InitialCongestionWindow,
-		protocol.DefaultMaxCongestionWindow,
-	)
-
+// The congestion controller has to use the same RTTStats.
+// onPacketLost is called for every packet that is declared lost, before it is queued for retransmission.
+func NewSentPacketHandler(rttStats *congestion.RTTStats, congestion congestion.SendAlgorithm, onPacketLost func(*Packet), logger utils.Logger) SentPacketHandler {
 	return &sentPacketHandler{
 		packetHistory:      NewPacketList(),
 		stopWaitingManager: stopWaitingManager{},
 		rttStats:           rttStats,
 		congestion:         congestion,
+		onPacketLost:       onPacketLost,
+		logger:             logger,
 	}
 }
 
@@ -115,6 +119,7 @@ func (h *sentPacketHandler) SentPacket(packet *Packet) error {
 
 	h.lastSentPacketNumber = packet.PacketNumber
 	h.packetHistory.PushBack(*packet)
+	h.packetsSent++
 
 	h.congestion.OnPacketSent(
 		now,
@@ -314,6 +319,7 @@ func (h *sentPacketHandler) DequeuePacketForRetransmission() *Packet {
 	// packets are usually NACKed in descending order. So use the slice as a stack
 	packet := h.retransmissionQueue[queueLen-1]
 	h.retransmissionQueue = h.retransmissionQueue[:queueLen-1]
+	h.packetsRetransmitted++
 	return packet
 }
 
@@ -329,13 +335,38 @@ func (h *sentPacketHandler) SendingAllowed() bool {
 	congestionLimited := h.bytesInFlight > h.congestion.GetCongestionWindow()
 	maxTrackedLimited := protocol.PacketNumber(len(h.retransmissionQueue)+h.packetHistory.Len()) >= protocol.MaxTrackedSentPackets
 	if congestionLimited {
-		utils.Debugf("Congestion limited: bytes in flight %d, window %d",
+		h.logger.Debugf("Congestion limited: bytes in flight %d, window %d",
 			h.bytesInFlight,
 			h.congestion.GetCongestionWindow())
 	}
 	return !(congestionLimited || maxTrackedLimited)
 }
 
+// TimeUntilSend returns the time when the next packet may be sent, as determined by the pacer of the congestion controller
+// Whether the congestion window allows sending is checked by SendingAllowed.
+func (h *sentPacketHandler) TimeUntilSend() time.Time {
+	now := time.Now()
+	delay := h.congestion.TimeUntilSend(now, h.bytesInFlight)
+	if delay == utils.InfDuration {
+		return now
+	}
+	return now.Add(delay)
+}
+
+func (h *sentPacketHandler) GetStatistics() SentPacketStatistics {
+	stats := SentPacketStatistics{
+		PacketsSent:          h.packetsSent,
+		PacketsLost:          h.packetsLost,
+		PacketsRetransmitted: h.packetsRetransmitted,
+		BytesInFlight:        h.bytesInFlight,
+		CongestionWindow:     h.congestion.GetCongestionWindow(),
+	}
+	if s, ok := h.congestion.(slowStartThresholder); ok {
+		stats.SlowStartThreshold = s.GetSlowStartThreshold()
+	}
+	return stats
+}
+
 func (h *sentPacketHandler) retransmitOldestTwoPackets() {
 	if p := h.packetHistory.Front(); p != nil {
 		h.queueRTO(p)
@@ -347,7 +378,7 @@ func (h *sentPacketHandler) retransmitOldestTwoPackets() {
 
 func (h *sentPacketHandler) queueRTO(el *PacketElement) {
 	packet := &el.Value
-	utils.Debugf(
+	h.logger.Debugf(
 		"\tQueueing packet 0x%x for retransmission (RTO), %d outstanding",
 		packet.PacketNumber,
 		h.packetHistory.Len(),
@@ -359,9 +390,11 @@ func (h *sentPacketHandler) queueRTO(el *PacketElement) {
 
 func (h *sentPacketHandler) queuePacketForRetransmission(packetElement *PacketElement) {
 	packet := &packetElement.Value
+	h.onPacketLost(packet)
 	h.bytesInFlight -= packet.Length
 	h.retransmissionQueue = append(h.retransmissionQueue, packet)
 	h.packetHistory.Remove(packetElement)
+	h.packetsLost++
 	h.stopWaitingManager.QueuedRetransmissionForPacketNumber(packet.PacketNumber)
 }
 
@@ -6,6 +6,7 @@ import (
 	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/frames"
 	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -17,10 +18,11 @@ type mockCongestion struct {
 	getCongestionWindow     bool
 	packetsAcked            [][]interface{}
 	packetsLost             [][]interface{}
+	timeUntilSend           time.Duration
 }
 
 func (m *mockCongestion) TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration {
-	panic("not implemented")
+	return m.timeUntilSend
 }
 
 func (m *mockCongestion) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
@@ -61,11 +63,14 @@ var _ = Describe("SentPacketHandler", func() {
 	var (
 		handler     *sentPacketHandler
 		streamFrame frames.StreamFrame
+		lostPackets []protocol.PacketNumber
 	)
 
 	BeforeEach(func() {
 		rttStats := &congestion.RTTStats{}
-		handler = NewSentPacketHandler(rttStats).(*sentPacketHandler)
+		lostPackets = nil
+		onPacketLost := func(p *Packet) { lostPackets = append(lostPackets, p.PacketNumber) }
+		handler = NewSentPacketHandler(rttStats, congestion.CubicFactory(congestion.DefaultClock{}, rttStats), onPacketLost, utils.DefaultLogger).(*sentPacketHandler)
 		streamFrame = frames.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -566,6 +571,18 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.DequeuePacketForRetransmission()).To(BeNil())
 		})
 
+		It("counts lost and retransmitted packets", func() {
+			getPacketElement(1).Value.SendTime = time.Now().Add(-time.Hour)
+			handler.OnAlarm()
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			stats := handler.GetStatistics()
+			Expect(stats.PacketsSent).To(BeEquivalentTo(7))
+			Expect(stats.PacketsLost).To(BeEquivalentTo(1))
+			Expect(stats.PacketsRetransmitted).To(BeZero())
+			handler.DequeuePacketForRetransmission()
+			Expect(handler.GetStatistics().PacketsRetransmitted).To(BeEquivalentTo(1))
+		})
+
 		Context("StopWaitings", func() {
 			It("gets a StopWaitingFrame", func() {
 				ack := frames.AckFrame{LargestAcked: 5, LowestAcked: 5}
@@ -581,6 +598,11 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	It("gets the slow start threshold of the congestion controller", func() {
+		Expect(handler.GetStatistics().SlowStartThreshold).To(Equal(handler.congestion.(slowStartThresholder).GetSlowStartThreshold()))
+		Expect(handler.GetStatistics().SlowStartThreshold).ToNot(BeZero())
+	})
+
 	It("calculates bytes in flight", func() {
 		packet1 := Packet{PacketNumber: 1, Frames: []frames.Frame{&streamFrame}, Length: 1}
 		packet2 := Packet{PacketNumber: 2, Frames: []frames.Frame{&streamFrame}, Length: 2}
@@ -678,6 +700,27 @@ var _ = Describe("SentPacketHandler", func() {
 			handler.retransmissionQueue = make([]*Packet, protocol.MaxTrackedSentPackets)
 			Expect(handler.SendingAllowed()).To(BeFalse())
 		})
+
+		It("gets the time when the next packet may be sent from the pacer", func() {
+			cong.timeUntilSend = 100 * time.Millisecond
+			Expect(handler.TimeUntilSend()).To(BeTemporally("~", time.Now().Add(100*time.Millisecond), 10*time.Millisecond))
+			cong.timeUntilSend = 0
+			Expect(handler.TimeUntilSend()).To(BeTemporally("~", time.Now(), 10*time.Millisecond))
+		})
+
+		It("leaves checking the congestion window to SendingAllowed", func() {
+			cong.timeUntilSend = utils.InfDuration
+			Expect(handler.TimeUntilSend()).To(BeTemporally("~", time.Now(), 10*time.Millisecond))
+		})
+
+		It("gets the congestion statistics", func() {
+			err := handler.SentPacket(&Packet{PacketNumber: 1, Frames: []frames.Frame{}, Length: 42})
+			Expect(err).NotTo(HaveOccurred())
+			stats := handler.GetStatistics()
+			Expect(stats.BytesInFlight).To(Equal(protocol.ByteCount(42)))
+			Expect(stats.CongestionWindow).To(Equal(protocol.DefaultTCPMSS))
+			Expect(stats.SlowStartThreshold).To(BeZero())
+		})
 	})
 
 	Context("calculating RTO", func() {
@@ -773,6 +816,7 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.DequeuePacketForRetransmission()).ToNot(BeNil())
 
 			Expect(handler.rtoCount).To(BeEquivalentTo(1))
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2}))
 		})
 	})
 })
@@ -12,16 +12,15 @@ import (
 	"github.com/lucas-clemente/quic-go/protocol"
 	"github.com/lucas-clemente/quic-go/qerr"
 	"github.com/lucas-clemente/quic-go/utils"
-   "github.com/mami-project/plus-lib"
 )
 
 type client struct {
 	mutex     sync.Mutex
 	listenErr error
 
-	conn     connection
-    plusConnManager *PLUS.ConnectionManager
- 
+	transport PacketTransport
+	conn      TransportConnection
+
 	hostname string
 
 	errorChan     chan struct{}
@@ -38,53 +37,162 @@ type client struct {
 
 var (
 	errCloseSessionForNewVersion = errors.New("closing session in order to recreate it with a new version")
+	errPacketConnClosed          = errors.New("the net.PacketConn was closed")
 )
 
 // DialAddr establishes a new QUIC connection to a server.
 // The hostname for SNI is taken from the given address.
 func DialAddr(addr string, config *Config) (Session, error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", addr)
-	if err != nil {
-		return nil, err
-	}
-	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
-	if err != nil {
-		return nil, err
-	}
-	return Dial(udpConn, udpAddr, addr, config)
+	return dialAddr(addr, &net.UDPAddr{IP: net.IPv4zero, Port: 0}, config, Dial)
 }
 
-func DialAddrFunc(laddr *net.UDPAddr) func (string, *Config) (Session, error) {
+func DialAddrFunc(laddr *net.UDPAddr) func(string, *Config) (Session, error) {
 	return func(addr string, config *Config) (Session, error) {
-		udpAddr, err := net.ResolveUDPAddr("udp", addr)
-		if err != nil {
-			return nil, err
-		}
-		udpConn, err := net.ListenUDP("udp", laddr)
-		if err != nil {
-			return nil, err
-		}
-		return Dial(udpConn, udpAddr, addr, config)
+		return dialAddr(addr, laddr, config, Dial)
 	}
 }
 
 // DialAddrNonFWSecure establishes a new QUIC connection to a server.
 // The hostname for SNI is taken from the given address.
 func DialAddrNonFWSecure(addr string, config *Config) (NonFWSession, error) {
+	sess, err := dialAddr(addr, &net.UDPAddr{IP: net.IPv4zero, Port: 0}, config, func(pconn net.PacketConn, remoteAddr net.Addr, host string, config *Config) (Session, error) {
+		return DialNonFWSecure(pconn, remoteAddr, host, config)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sess.(NonFWSession), nil
+}
+
+type dialFunc func(pconn net.PacketConn, remoteAddr net.Addr, host string, config *Config) (Session, error)
+
+func dialAddr(addr string, laddr *net.UDPAddr, config *Config, dial dialFunc) (Session, error) {
 	udpAddr, err := net.ResolveUDPAddr("udp", addr)
 	if err != nil {
 		return nil, err
 	}
-	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	listen := func() (net.PacketConn, error) {
+		return net.ListenUDP("udp", laddr)
+	}
+	if config.FallbackTransport != nil {
+		return dialWithFallback(listen, udpAddr, addr, config, dial)
+	}
+	udpConn, err := listen()
 	if err != nil {
 		return nil, err
 	}
-	return DialNonFWSecure(udpConn, udpAddr, addr, config)
+	return dial(udpConn, udpAddr, addr, config)
+}
+
+type dialResult struct {
+	sess     Session
+	err      error
+	fallback bool
+}
+
+// dialWithFallback dials using the config.Transport
+// After the config.FallbackDelay, or as soon as this fails, it starts a second handshake using the config.FallbackTransport.
+// The first session that is established is returned.
+// The net.PacketConn of the other handshake is closed right away, which closes its session and aborts that handshake.
+func dialWithFallback(listen func() (net.PacketConn, error), remoteAddr net.Addr, host string, config *Config, dial dialFunc) (Session, error) {
+	primaryConfig := *config
+	primaryConfig.FallbackTransport = nil
+	fallbackConfig := primaryConfig
+	fallbackConfig.Transport = config.FallbackTransport
+
+	logger := populateLogger(config)
+	results := make(chan dialResult, 2)
+	pconns := make(map[bool]net.PacketConn, 2) // indexed by fallback
+	start := func(conf *Config, fallback bool) error {
+		pconn, err := listen()
+		if err != nil {
+			return err
+		}
+		pconns[fallback] = pconn
+		go func() {
+			sess, err := dial(pconn, remoteAddr, host, conf)
+			if err != nil {
+				pconn.Close()
+			}
+			results <- dialResult{sess: sess, err: err, fallback: fallback}
+		}()
+		return nil
+	}
+
+	if err := start(&primaryConfig, false); err != nil {
+		return nil, err
+	}
+	pending := 1
+
+	fallbackDelay := config.FallbackDelay
+	if fallbackDelay == 0 {
+		fallbackDelay = protocol.DefaultFallbackDelay
+	}
+	fallbackTimer := time.NewTimer(fallbackDelay)
+	defer fallbackTimer.Stop()
+	fallbackStarted := false
+	startFallback := func() error {
+		fallbackStarted = true
+		logger.Infof("Starting a second handshake using the fallback transport")
+		if err := start(&fallbackConfig, true); err != nil {
+			return err
+		}
+		pending++
+		return nil
+	}
+
+	var firstErr error
+	for {
+		select {
+		case <-fallbackTimer.C:
+			if fallbackStarted {
+				continue
+			}
+			if err := startFallback(); err != nil {
+				logger.Errorf("Error starting the fallback handshake: %s", err.Error())
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if r.fallback {
+					logger.Infof("Established the connection using the fallback transport")
+				}
+				if pending > 0 {
+					// abort the other handshake
+					pconns[!r.fallback].Close()
+				}
+				// close the other session, if it was established in the meantime
+				go func(pending int) {
+					for i := 0; i < pending; i++ {
+						if r := <-results; r.err == nil {
+							r.sess.Close(nil)
+						}
+					}
+				}(pending)
+				return r.sess, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if !fallbackStarted {
+				if err := startFallback(); err != nil {
+					return nil, firstErr
+				}
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
 }
 
 // DialNonFWSecure establishes a new non-forward-secure QUIC connection to a server using a net.PacketConn.
 // The host parameter is used for SNI.
+// The config.FallbackTransport is not used, since a second handshake would need its own net.PacketConn.
 func DialNonFWSecure(pconn net.PacketConn, remoteAddr net.Addr, host string, config *Config) (NonFWSession, error) {
+	if err := validateConfig(config, protocol.PerspectiveClient); err != nil {
+		return nil, err
+	}
 	connID, err := utils.GenerateConnectionID()
 	if err != nil {
 		return nil, err
@@ -96,48 +204,34 @@ func DialNonFWSecure(pconn net.PacketConn, remoteAddr net.Addr, host string, con
 	}
 
 	clientConfig := populateClientConfig(config)
-    
-    var c *client
-    
-    var connection *PLUS.Connection
-    
-    if !config.UsePLUS {
-        c = &client{
-            conn:         &conn{pconn: pconn, currentAddr: remoteAddr},
-            connectionID: connID,
-            hostname:     hostname,
-            config:       clientConfig,
-            version:      clientConfig.Versions[0],
-            errorChan:    make(chan struct{}),
-        }
-    } else {
-        var plusConnManager *PLUS.ConnectionManager
-		plusConnManager, connection = PLUS.NewConnectionManagerClient(pconn, uint64(connID), remoteAddr)    
-
-        c = &client{
-            conn:         nil,
-            connectionID: connID,
-            hostname:     hostname,
-            config:       clientConfig,
-            version:      clientConfig.Versions[0],
-            errorChan:    make(chan struct{}),
-            plusConnManager: plusConnManager,
-        }
-    }
-
-	err = c.createNewSession(nil, connection)
+	transport, tconn, err := clientConfig.Transport.Dial(pconn, remoteAddr, connID)
 	if err != nil {
 		return nil, err
 	}
 
-    
-	utils.Infof("Starting new connection to %s (%s), connectionID %x, version %d", hostname, remoteAddr.String(), c.connectionID, c.version)
+	c := &client{
+		transport:    transport,
+		conn:         tconn,
+		connectionID: connID,
+		hostname:     hostname,
+		config:       clientConfig,
+		version:      clientConfig.Versions[0],
+		errorChan:    make(chan struct{}),
+	}
+
+	err = c.createNewSession(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig.Logger.Infof("Starting new connection to %s (%s), connectionID %x, version %d", hostname, remoteAddr.String(), c.connectionID, c.version)
 
 	return c.session.(NonFWSession), c.establishSecureConnection()
 }
 
 // Dial establishes a new QUIC connection to a server using a net.PacketConn.
 // The host parameter is used for SNI.
+// The config.FallbackTransport is not used, since a second handshake would need its own net.PacketConn.
 func Dial(pconn net.PacketConn, remoteAddr net.Addr, host string, config *Config) (Session, error) {
 	sess, err := DialNonFWSecure(pconn, remoteAddr, host, config)
 	if err != nil {
@@ -157,10 +251,25 @@ func populateClientConfig(config *Config) *Config {
 	}
 
 	return &Config{
-		TLSConfig:                     config.TLSConfig,
-		Versions:                      versions,
-		RequestConnectionIDTruncation: config.RequestConnectionIDTruncation,
-        UsePLUS:                       config.UsePLUS,
+		TLSConfig:                             config.TLSConfig,
+		Versions:                              versions,
+		RequestConnectionIDTruncation:         config.RequestConnectionIDTruncation,
+		UsePLUS:                               config.UsePLUS,
+		Transport:                             populateTransport(config),
+		FallbackTransport:                     config.FallbackTransport,
+		FallbackDelay:                         config.FallbackDelay,
+		HandshakeTimeout:                      populateHandshakeTimeout(config),
+		IdleTimeout:                           config.IdleTimeout,
+		ReceiveStreamFlowControlWindow:        config.ReceiveStreamFlowControlWindow,
+		ReceiveConnectionFlowControlWindow:    config.ReceiveConnectionFlowControlWindow,
+		MaxReceiveStreamFlowControlWindow:     config.MaxReceiveStreamFlowControlWindow,
+		MaxReceiveConnectionFlowControlWindow: config.MaxReceiveConnectionFlowControlWindow,
+		MaxIncomingStreams:                    config.MaxIncomingStreams,
+		StreamScheduling:                      config.StreamScheduling,
+		CongestionControl:                     populateCongestionControl(config),
+		Tracer:                                config.Tracer,
+		Logger:                                populateLogger(config),
+		KeyLogWriter:                          config.KeyLogWriter,
 	}
 }
 
@@ -182,89 +291,39 @@ func (c *client) establishSecureConnection() error {
 	}
 }
 
-func (c *client) listenPLUS() {
-    utils.Infof("listenPLUS")
-
-	for {
-		connection, plusPacket, remoteAddr, feedbackData_, err := c.plusConnManager.ReadAndProcessPacket()
-        
-        
-        
-		if err != nil {
-			if !strings.HasSuffix(err.Error(), "use of closed network connection") {
-				c.session.Close(err)
-			}
-			break
-		}
-
-		data := getPacketBuffer()
-		data = data[:protocol.MaxReceivePacketSize]
-
-		payload := plusPacket.Payload()
-		  
-      copy(data, payload)
-		//fmt.Printf("len(data) := %d, len(payload) := %d, cap(data) := %d\n", len(data), len(payload), cap(data))
-		data = data[:len(payload)]
-
-		var feedbackData []byte = nil
-
-		if feedbackData_ != nil {
-			feedbackData = getPacketBuffer()
-			copy(feedbackData, feedbackData_)
-			feedbackData = feedbackData[:len(feedbackData_)] 
-		}
-
-		c.plusConnManager.ReturnPacketAndBuffer(plusPacket)
-        
-
-		err = c.handlePacketPLUS(remoteAddr, data, connection, feedbackData)
-		if err != nil {
-			utils.Errorf("error handling PLUS packet: %s", err.Error())
-			c.session.Close(err)
-			break
-		}
-	}
-}
-
 // Listen listens
 func (c *client) listen() {
-    if c.config.UsePLUS {
-        c.listenPLUS()
-        return
-    }
-
 	var err error
 
 	for {
 		var n int
 		var addr net.Addr
+		var feedbackData []byte
 		data := getPacketBuffer()
 		data = data[:protocol.MaxReceivePacketSize]
 		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
 		// If it does, we only read a truncated packet, which will then end up undecryptable
-		n, addr, err = c.conn.Read(data)
+		n, addr, _, feedbackData, err = c.transport.ReadPacket(data)
 		if err != nil {
-			if !strings.HasSuffix(err.Error(), "use of closed network connection") {
-				c.session.Close(err)
+			if strings.HasSuffix(err.Error(), "use of closed network connection") {
+				// this aborts the handshake, if the net.PacketConn is closed before the session is established
+				err = errPacketConnClosed
 			}
+			c.session.Close(err)
 			break
 		}
 		data = data[:n]
 
-		err = c.handlePacket(addr, data)
+		err = c.handlePacket(addr, data, feedbackData)
 		if err != nil {
-			utils.Errorf("error handling packet: %s", err.Error())
+			c.config.Logger.Errorf("error handling packet: %s", err.Error())
 			c.session.Close(err)
 			break
 		}
 	}
 }
 
-func (c *client) handlePacket(remoteAddr net.Addr, packet []byte) error {
-    return c.handlePacketPLUS(remoteAddr, packet, nil, nil)
-}
-
-func (c *client) handlePacketPLUS(remoteAddr net.Addr, packet []byte, connection *PLUS.Connection, feedbackData []byte) error {
+func (c *client) handlePacket(remoteAddr net.Addr, packet []byte, feedbackData []byte) error {
 	rcvTime := time.Now()
 
 	r := bytes.NewReader(packet)
@@ -290,7 +349,7 @@ func (c *client) handlePacketPLUS(remoteAddr net.Addr, packet []byte, connection
 
 	if hdr.VersionFlag {
 		// version negotiation packets have no payload
-		return c.handlePacketWithVersionFlag(hdr, connection)
+		return c.handlePacketWithVersionFlag(hdr)
 	}
 
 	c.session.handlePacket(&receivedPacket{
@@ -298,11 +357,12 @@ func (c *client) handlePacketPLUS(remoteAddr net.Addr, packet []byte, connection
 		publicHeader: hdr,
 		data:         packet[len(packet)-r.Len():],
 		rcvTime:      rcvTime,
+		feedbackData: feedbackData,
 	})
 	return nil
 }
 
-func (c *client) handlePacketWithVersionFlag(hdr *PublicHeader, connection *PLUS.Connection) error {
+func (c *client) handlePacketWithVersionFlag(hdr *PublicHeader) error {
 	for _, v := range hdr.SupportedVersions {
 		if v == c.version {
 			// the version negotiation packet contains the version that we offered
@@ -325,13 +385,13 @@ func (c *client) handlePacketWithVersionFlag(hdr *PublicHeader, connection *PLUS
 	if err != nil {
 		return err
 	}
-	utils.Infof("Switching to QUIC version %d. New connection ID: %x", newVersion, c.connectionID)
+	c.config.Logger.Infof("Switching to QUIC version %d. New connection ID: %x", newVersion, c.connectionID)
 
 	c.session.Close(errCloseSessionForNewVersion)
-	return c.createNewSession(hdr.SupportedVersions, connection)
+	return c.createNewSession(hdr.SupportedVersions)
 }
 
-func (c *client) createNewSession(negotiatedVersions []protocol.VersionNumber, connection *PLUS.Connection) error {
+func (c *client) createNewSession(negotiatedVersions []protocol.VersionNumber) error {
 	var err error
 	c.session, c.handshakeChan, err = newClientSession(
 		c.conn,
@@ -340,7 +400,6 @@ func (c *client) createNewSession(negotiatedVersions []protocol.VersionNumber, c
 		c.connectionID,
 		c.config,
 		negotiatedVersions,
-        connection,
 	)
 	if err != nil {
 		return err
@@ -355,13 +414,13 @@ func (c *client) createNewSession(negotiatedVersions []protocol.VersionNumber, c
 		c.listenErr = err
 		close(c.errorChan)
 
-		utils.Infof("Connection %x closed.", c.connectionID)
-        
-        if !c.config.UsePLUS {
-            c.conn.Close()
-        } else {
-            c.plusConnManager.Close()
-        }
+		c.config.Logger.Infof("Connection %x closed.", c.connectionID)
+		if sess, ok := c.session.(stopPacketSender); ok && sess.sentStopPacket() {
+			// keep receiving packets for a while, such that the session can repeat the PLUS stop packet
+			time.AfterFunc(protocol.PLUSStopRepeatTimeout, func() { c.transport.Close() })
+			return
+		}
+		c.transport.Close()
 	}()
 	return nil
 }
@@ -4,16 +4,28 @@ import (
 	"bytes"
 	"errors"
 	"net"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/protocol"
 	"github.com/lucas-clemente/quic-go/qerr"
+	"github.com/lucas-clemente/quic-go/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-
-	"github.com/mami-project/plus-lib"
 )
 
+type failingTransport struct {
+	err error
+}
+
+func (t *failingTransport) Listen(net.PacketConn) (PacketTransport, error) {
+	return nil, t.err
+}
+
+func (t *failingTransport) Dial(net.PacketConn, net.Addr, protocol.ConnectionID) (PacketTransport, TransportConnection, error) {
+	return nil, nil, t.err
+}
+
 var _ = Describe("Client", func() {
 	var (
 		cl         *client
@@ -22,17 +34,18 @@ var _ = Describe("Client", func() {
 		packetConn *mockPacketConn
 		addr       net.Addr
 
-		originalClientSessConstructor func(conn connection, hostname string, v protocol.VersionNumber, connectionID protocol.ConnectionID, config *Config, negotiatedVersions []protocol.VersionNumber, plusConnection *PLUS.Connection) (packetHandler, <-chan handshakeEvent, error)
+		originalClientSessConstructor func(conn TransportConnection, hostname string, v protocol.VersionNumber, connectionID protocol.ConnectionID, config *Config, negotiatedVersions []protocol.VersionNumber) (packetHandler, <-chan handshakeEvent, error)
 	)
 
 	BeforeEach(func() {
 		originalClientSessConstructor = newClientSession
 		Eventually(areSessionsRunning).Should(BeFalse())
-		msess, _, _ := newMockSession(nil, 0, 0, nil, nil, nil)
+		msess, _, _ := newMockSession(nil, 0, 0, nil, nil)
 		sess = msess.(*mockSession)
 		packetConn = &mockPacketConn{}
 		config = &Config{
 			Versions: []protocol.VersionNumber{protocol.SupportedVersions[0], 77, 78},
+			Logger:   utils.DefaultLogger,
 		}
 		addr = &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
 		cl = &client{
@@ -40,6 +53,7 @@ var _ = Describe("Client", func() {
 			connectionID: 0x1337,
 			session:      sess,
 			version:      protocol.SupportedVersions[0],
+			transport:    &udpPacketTransport{pconn: packetConn},
 			conn:         &conn{pconn: packetConn, currentAddr: addr},
 			errorChan:    make(chan struct{}),
 		}
@@ -59,13 +73,12 @@ var _ = Describe("Client", func() {
 	Context("Dialing", func() {
 		BeforeEach(func() {
 			newClientSession = func(
-				_ connection,
+				_ TransportConnection,
 				_ string,
 				_ protocol.VersionNumber,
 				_ protocol.ConnectionID,
 				_ *Config,
 				_ []protocol.VersionNumber,
-				_ *PLUS.Connection,
 			) (packetHandler, <-chan handshakeEvent, error) {
 				return sess, sess.handshakeChan, nil
 			}
@@ -115,21 +128,20 @@ var _ = Describe("Client", func() {
 		})
 
 		It("resolves the address", func(done Done) {
-			var cconn connection
+			var cconn TransportConnection
 			newClientSession = func(
-				conn connection,
+				conn TransportConnection,
 				_ string,
 				_ protocol.VersionNumber,
 				_ protocol.ConnectionID,
 				_ *Config,
 				_ []protocol.VersionNumber,
-				_ *PLUS.Connection,
 			) (packetHandler, <-chan handshakeEvent, error) {
 				cconn = conn
 				return sess, nil, nil
 			}
 			go DialAddr("localhost:17890", &Config{})
-			Eventually(func() connection { return cconn }).ShouldNot(BeNil())
+			Eventually(func() TransportConnection { return cconn }).ShouldNot(BeNil())
 			Expect(cconn.RemoteAddr().String()).To(Equal("127.0.0.1:17890"))
 			close(done)
 		})
@@ -162,6 +174,39 @@ var _ = Describe("Client", func() {
 			Expect(c.Versions).To(Equal(protocol.SupportedVersions))
 		})
 
+		It("uses the transport selected in the quic.Config", func() {
+			Expect(populateClientConfig(&Config{}).Transport).To(Equal(UDPTransport))
+			Expect(populateClientConfig(&Config{UsePLUS: true}).Transport).To(Equal(PLUSTransport))
+		})
+
+		It("copies the transport parameters from the quic.Config", func() {
+			c := populateClientConfig(&Config{
+				HandshakeTimeout:                      time.Second,
+				IdleTimeout:                           42 * time.Second,
+				ReceiveStreamFlowControlWindow:        1 << 16,
+				ReceiveConnectionFlowControlWindow:    1 << 17,
+				MaxReceiveStreamFlowControlWindow:     1 << 20,
+				MaxReceiveConnectionFlowControlWindow: 1 << 21,
+				MaxIncomingStreams:                    1000,
+				StreamScheduling:                      StreamSchedulingWeightedFair,
+			})
+			Expect(c.HandshakeTimeout).To(Equal(time.Second))
+			Expect(c.IdleTimeout).To(Equal(42 * time.Second))
+			Expect(c.ReceiveStreamFlowControlWindow).To(Equal(protocol.ByteCount(1 << 16)))
+			Expect(c.ReceiveConnectionFlowControlWindow).To(Equal(protocol.ByteCount(1 << 17)))
+			Expect(c.MaxReceiveStreamFlowControlWindow).To(Equal(protocol.ByteCount(1 << 20)))
+			Expect(c.MaxReceiveConnectionFlowControlWindow).To(Equal(protocol.ByteCount(1 << 21)))
+			Expect(c.MaxIncomingStreams).To(Equal(uint32(1000)))
+			Expect(c.StreamScheduling).To(Equal(StreamSchedulingWeightedFair))
+			Expect(populateClientConfig(&Config{}).HandshakeTimeout).To(Equal(protocol.MaxTimeForCryptoHandshake))
+		})
+
+		It("rejects invalid configs", func() {
+			config.ReceiveStreamFlowControlWindow = 1000
+			_, err := Dial(packetConn, addr, "quic.clemente.io:1337", config)
+			Expect(err).To(MatchError("stream-level flow control window too small: 1000 (minimum 16384)"))
+		})
+
 		It("errors when receiving an invalid first packet from the server", func(done Done) {
 			packetConn.dataToRead = []byte{0xff}
 			_, err := Dial(packetConn, addr, "quic.clemente.io:1337", config)
@@ -180,13 +225,12 @@ var _ = Describe("Client", func() {
 		It("errors if it can't create a session", func() {
 			testErr := errors.New("error creating session")
 			newClientSession = func(
-				_ connection,
+				_ TransportConnection,
 				_ string,
 				_ protocol.VersionNumber,
 				_ protocol.ConnectionID,
 				_ *Config,
 				_ []protocol.VersionNumber,
-				_ *PLUS.Connection,
 			) (packetHandler, <-chan handshakeEvent, error) {
 				return nil, nil, testErr
 			}
@@ -194,6 +238,225 @@ var _ = Describe("Client", func() {
 			Expect(err).To(MatchError(testErr))
 		})
 
+		It("dials using PLUS, if UsePLUS is set", func(done Done) {
+			originalPLUSTransport := PLUSTransport
+			defer func() { PLUSTransport = originalPLUSTransport }()
+			fakeTransport, rec := newFakePLUSTransport()
+			PLUSTransport = fakeTransport
+			clientConn, serverConn := newMemPacketConnPair()
+			defer clientConn.Close()
+			defer serverConn.Close()
+			var cconn TransportConnection
+			var connID protocol.ConnectionID
+			newClientSession = func(
+				connP TransportConnection,
+				_ string,
+				_ protocol.VersionNumber,
+				connIDP protocol.ConnectionID,
+				_ *Config,
+				_ []protocol.VersionNumber,
+			) (packetHandler, <-chan handshakeEvent, error) {
+				cconn = connP
+				connID = connIDP
+				return sess, sess.handshakeChan, nil
+			}
+			config.UsePLUS = true
+			var dialedSess Session
+			go func() {
+				defer GinkgoRecover()
+				var err error
+				dialedSess, err = DialNonFWSecure(clientConn, serverConn.LocalAddr(), "quic.clemente.io:1337", config)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			sess.handshakeChan <- handshakeEvent{encLevel: protocol.EncryptionSecure}
+			Eventually(func() Session { return dialedSess }).Should(Equal(sess))
+			Expect(cconn).To(BeAssignableToTypeOf(&plusConn{}))
+			err := cconn.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.sentPackets()).To(HaveLen(1))
+			Expect(rec.sentPackets()[0].cat).To(Equal(uint64(connID)))
+			Expect(rec.sentPackets()[0].payload).To(Equal([]byte("foobar")))
+			b := make([]byte, protocol.MaxReceivePacketSize)
+			n, _, err := serverConn.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			p, err := parseFakePLUSPacket(b[:n])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.cat).To(Equal(uint64(connID)))
+			close(done)
+		})
+
+		It("uses the fallback transport when dialing an address", func(done Done) {
+			var cconn TransportConnection
+			newClientSession = func(
+				conn TransportConnection,
+				_ string,
+				_ protocol.VersionNumber,
+				_ protocol.ConnectionID,
+				_ *Config,
+				_ []protocol.VersionNumber,
+			) (packetHandler, <-chan handshakeEvent, error) {
+				cconn = conn
+				return sess, sess.handshakeChan, nil
+			}
+			config.Transport = &failingTransport{err: errors.New("PLUS not available")}
+			config.FallbackTransport = UDPTransport
+			var dialedSess Session
+			go func() {
+				defer GinkgoRecover()
+				var err error
+				dialedSess, err = DialAddrNonFWSecure("localhost:18902", config)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			sess.handshakeChan <- handshakeEvent{encLevel: protocol.EncryptionSecure}
+			Eventually(func() Session { return dialedSess }).Should(Equal(sess))
+			Expect(cconn).To(BeAssignableToTypeOf(&conn{}))
+			close(done)
+		})
+
+		Context("falling back", func() {
+			var (
+				attempts             chan *Config
+				plusResult           chan dialResult
+				udpResult            chan dialResult
+				dial                 dialFunc
+				listen               func() (net.PacketConn, error)
+				plusSess, udpSess    *mockSession
+				dialedSess           Session
+				dialErr              error
+				dialReturned         chan struct{}
+				dialWithFallbackFunc func()
+			)
+
+			BeforeEach(func() {
+				attempts = make(chan *Config, 2)
+				plusResult = make(chan dialResult, 1)
+				udpResult = make(chan dialResult, 1)
+				dial = func(_ net.PacketConn, _ net.Addr, _ string, conf *Config) (Session, error) {
+					attempts <- conf
+					result := plusResult
+					if conf.Transport == UDPTransport {
+						result = udpResult
+					}
+					r := <-result
+					return r.sess, r.err
+				}
+				listen = func() (net.PacketConn, error) {
+					return &mockPacketConn{}, nil
+				}
+				s, _, _ := newMockSession(nil, 0, 0, nil, nil)
+				plusSess = s.(*mockSession)
+				s, _, _ = newMockSession(nil, 0, 0, nil, nil)
+				udpSess = s.(*mockSession)
+				config.Transport = PLUSTransport
+				config.FallbackTransport = UDPTransport
+				config.FallbackDelay = 50 * time.Millisecond
+				dialReturned = make(chan struct{})
+				dialWithFallbackFunc = func() {
+					go func() {
+						dialedSess, dialErr = dialWithFallback(listen, addr, "quic.clemente.io:1337", config, dial)
+						close(dialReturned)
+					}()
+				}
+			})
+
+			It("uses the Transport if the handshake completes before the FallbackDelay", func() {
+				dialWithFallbackFunc()
+				var conf *Config
+				Eventually(attempts).Should(Receive(&conf))
+				Expect(conf.Transport).To(Equal(PLUSTransport))
+				Expect(conf.FallbackTransport).To(BeNil())
+				plusResult <- dialResult{sess: plusSess}
+				Eventually(dialReturned).Should(BeClosed())
+				Expect(dialErr).ToNot(HaveOccurred())
+				Expect(dialedSess).To(Equal(plusSess))
+				Consistently(attempts, 100*time.Millisecond).ShouldNot(Receive())
+			})
+
+			It("starts a second handshake after the FallbackDelay", func() {
+				dialWithFallbackFunc()
+				Eventually(attempts).Should(Receive())
+				var conf *Config
+				Consistently(attempts, 25*time.Millisecond).ShouldNot(Receive())
+				Eventually(attempts).Should(Receive(&conf))
+				Expect(conf.Transport).To(Equal(UDPTransport))
+				udpResult <- dialResult{sess: udpSess}
+				Eventually(dialReturned).Should(BeClosed())
+				Expect(dialErr).ToNot(HaveOccurred())
+				Expect(dialedSess).To(Equal(udpSess))
+				// the session established over PLUS is closed
+				plusResult <- dialResult{sess: plusSess}
+				Eventually(func() bool { return plusSess.closed }).Should(BeTrue())
+				Expect(udpSess.closed).To(BeFalse())
+			})
+
+			It("aborts the other handshake as soon as one handshake completes", func() {
+				var udpConns []net.PacketConn
+				listen = func() (net.PacketConn, error) {
+					c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+					udpConns = append(udpConns, c)
+					return c, err
+				}
+				fallbackDialErr := make(chan error, 1)
+				dial = func(pconn net.PacketConn, remoteAddr net.Addr, host string, conf *Config) (Session, error) {
+					attempts <- conf
+					if conf.Transport == UDPTransport {
+						// run the fallback handshake, it never completes
+						_, err := Dial(pconn, remoteAddr, host, conf)
+						fallbackDialErr <- err
+						return nil, err
+					}
+					r := <-plusResult
+					return r.sess, r.err
+				}
+				dialWithFallbackFunc()
+				Eventually(attempts).Should(Receive())
+				Eventually(attempts).Should(Receive())
+				plusResult <- dialResult{sess: plusSess}
+				Eventually(dialReturned).Should(BeClosed())
+				Expect(dialedSess).To(Equal(plusSess))
+				Eventually(fallbackDialErr).Should(Receive(MatchError(errPacketConnClosed)))
+				Expect(sess.closed).To(BeTrue())
+				Expect(plusSess.closed).To(BeFalse())
+				Expect(udpConns).To(HaveLen(2))
+				Expect(udpConns[0].Close()).To(Succeed())
+			})
+
+			It("falls back as soon as the handshake fails", func() {
+				config.FallbackDelay = time.Hour
+				dialWithFallbackFunc()
+				Eventually(attempts).Should(Receive())
+				plusResult <- dialResult{err: errors.New("handshake failed")}
+				var conf *Config
+				Eventually(attempts).Should(Receive(&conf))
+				Expect(conf.Transport).To(Equal(UDPTransport))
+				udpResult <- dialResult{sess: udpSess}
+				Eventually(dialReturned).Should(BeClosed())
+				Expect(dialErr).ToNot(HaveOccurred())
+				Expect(dialedSess).To(Equal(udpSess))
+			})
+
+			It("returns the first error if both handshakes fail", func() {
+				testErr := errors.New("handshake failed")
+				dialWithFallbackFunc()
+				Eventually(attempts).Should(Receive())
+				Eventually(attempts).Should(Receive())
+				plusResult <- dialResult{err: testErr}
+				Eventually(plusResult).Should(BeEmpty())
+				Consistently(dialReturned).ShouldNot(BeClosed())
+				udpResult <- dialResult{err: errors.New("another error")}
+				Eventually(dialReturned).Should(BeClosed())
+				Expect(dialErr).To(MatchError(testErr))
+			})
+
+			It("errors if it can't listen", func() {
+				testErr := errors.New("listen error")
+				listen = func() (net.PacketConn, error) { return nil, testErr }
+				dialWithFallbackFunc()
+				Eventually(dialReturned).Should(BeClosed())
+				Expect(dialErr).To(MatchError(testErr))
+			})
+		})
+
 		Context("version negotiation", func() {
 			It("recognizes that a packet without VersionFlag means that the server accepted the suggested version", func() {
 				ph := PublicHeader{
@@ -204,7 +467,7 @@ var _ = Describe("Client", func() {
 				b := &bytes.Buffer{}
 				err := ph.Write(b, protocol.VersionWhatever, protocol.PerspectiveServer)
 				Expect(err).ToNot(HaveOccurred())
-				err = cl.handlePacket(nil, b.Bytes())
+				err = cl.handlePacket(nil, b.Bytes(), nil)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cl.versionNegotiated).To(BeTrue())
 			})
@@ -212,13 +475,12 @@ var _ = Describe("Client", func() {
 			It("changes the version after receiving a version negotiation packet", func() {
 				var negotiatedVersions []protocol.VersionNumber
 				newClientSession = func(
-					_ connection,
+					_ TransportConnection,
 					_ string,
 					_ protocol.VersionNumber,
 					connectionID protocol.ConnectionID,
 					_ *Config,
 					negotiatedVersionsP []protocol.VersionNumber,
-					_ *PLUS.Connection,
 				) (packetHandler, <-chan handshakeEvent, error) {
 					negotiatedVersions = negotiatedVersionsP
 					return &mockSession{
@@ -231,7 +493,7 @@ var _ = Describe("Client", func() {
 				Expect(newVersion).ToNot(Equal(cl.version))
 				Expect(sess.packetCount).To(BeZero())
 				cl.connectionID = 0x1337
-				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{newVersion}))
+				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{newVersion}, utils.DefaultLogger), nil)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cl.version).To(Equal(newVersion))
 				Expect(cl.versionNegotiated).To(BeTrue())
@@ -245,7 +507,7 @@ var _ = Describe("Client", func() {
 			})
 
 			It("errors if no matching version is found", func() {
-				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{1}))
+				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{1}, utils.DefaultLogger), nil)
 				Expect(err).To(MatchError(qerr.InvalidVersion))
 			})
 
@@ -253,12 +515,12 @@ var _ = Describe("Client", func() {
 				v := protocol.SupportedVersions[1]
 				Expect(v).ToNot(Equal(cl.version))
 				Expect(config.Versions).ToNot(ContainElement(v))
-				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{v}))
+				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{v}, utils.DefaultLogger), nil)
 				Expect(err).To(MatchError(qerr.InvalidVersion))
 			})
 
 			It("changes to the version preferred by the quic.Config", func() {
-				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{config.Versions[2], config.Versions[1]}))
+				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{config.Versions[2], config.Versions[1]}, utils.DefaultLogger), nil)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cl.version).To(Equal(config.Versions[1]))
 			})
@@ -267,7 +529,7 @@ var _ = Describe("Client", func() {
 				// if the version was not yet negotiated, handlePacket would return a VersionNegotiationMismatch error, see above test
 				cl.versionNegotiated = true
 				Expect(sess.packetCount).To(BeZero())
-				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{1}))
+				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{1}, utils.DefaultLogger), nil)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cl.versionNegotiated).To(BeTrue())
 				Expect(sess.packetCount).To(BeZero())
@@ -275,7 +537,7 @@ var _ = Describe("Client", func() {
 
 			It("drops version negotiation packets that contain the offered version", func() {
 				ver := cl.version
-				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{ver}))
+				err := cl.handlePacket(nil, composeVersionNegotiation(0x1337, []protocol.VersionNumber{ver}, utils.DefaultLogger), nil)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(cl.version).To(Equal(ver))
 			})
@@ -283,24 +545,23 @@ var _ = Describe("Client", func() {
 	})
 
 	It("errors on invalid public header", func() {
-		err := cl.handlePacket(nil, nil)
+		err := cl.handlePacket(nil, nil, nil)
 		Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.InvalidPacketHeader))
 	})
 
 	It("creates new sessions with the right parameters", func(done Done) {
 		c := make(chan struct{})
-		var cconn connection
+		var cconn TransportConnection
 		var hostname string
 		var version protocol.VersionNumber
 		var conf *Config
 		newClientSession = func(
-			connP connection,
+			connP TransportConnection,
 			hostnameP string,
 			versionP protocol.VersionNumber,
 			_ protocol.ConnectionID,
 			configP *Config,
 			_ []protocol.VersionNumber,
-			_ *PLUS.Connection,
 		) (packetHandler, <-chan handshakeEvent, error) {
 			cconn = connP
 			hostname = hostnameP
@@ -317,7 +578,8 @@ var _ = Describe("Client", func() {
 		Expect(cconn.(*conn).pconn).To(Equal(packetConn))
 		Expect(hostname).To(Equal("quic.clemente.io"))
 		Expect(version).To(Equal(cl.version))
-		Expect(conf).To(Equal(config))
+		Expect(conf.Versions).To(Equal(config.Versions))
+		Expect(conf.Transport).To(Equal(UDPTransport))
 		close(done)
 	})
 
@@ -346,6 +608,22 @@ var _ = Describe("Client", func() {
 			Consistently(func() bool { return stoppedListening }).Should(BeFalse())
 		})
 
+		It("passes PLUS feedback to the session", func() {
+			ph := PublicHeader{
+				PacketNumber:    1,
+				PacketNumberLen: protocol.PacketNumberLen2,
+				ConnectionID:    0x1337,
+			}
+			b := &bytes.Buffer{}
+			err := ph.Write(b, protocol.Version36, protocol.PerspectiveServer)
+			Expect(err).ToNot(HaveOccurred())
+			err = cl.handlePacket(addr, b.Bytes(), []byte("feedback"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.packetCount).To(Equal(1))
+			Expect(sess.lastPacket.remoteAddr).To(Equal(addr))
+			Expect(sess.lastPacket.feedbackData).To(Equal([]byte("feedback")))
+		})
+
 		It("closes the session when encountering an error while handling a packet", func() {
 			Expect(sess.closeReason).ToNot(HaveOccurred())
 			packetConn.dataToRead = bytes.Repeat([]byte{0xff}, 100)
@@ -354,6 +632,13 @@ var _ = Describe("Client", func() {
 			Expect(sess.closeReason).To(HaveOccurred())
 		})
 
+		It("closes the session when the connection is closed", func() {
+			packetConn.readErr = errors.New("read udp 127.0.0.1:1337: use of closed network connection")
+			cl.listen()
+			Expect(sess.closed).To(BeTrue())
+			Expect(sess.closeReason).To(MatchError(errPacketConnClosed))
+		})
+
 		It("closes the session when encountering an error while reading from the connection", func() {
 			testErr := errors.New("test error")
 			packetConn.readErr = testErr
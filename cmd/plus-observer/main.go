@@ -0,0 +1,81 @@
+// plus-observer reads packet captures of PLUS and gQUIC traffic, and prints the metrics an on-path observer can measure as JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/observer"
+)
+
+func main() {
+	interval := flag.Duration("interval", time.Second, "time between two samples of a flow, 0 samples every packet")
+	output := flag.String("o", "", "file to write the JSON output to (default stdout)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] capture.pcap...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	o := observer.NewObserver(*interval)
+	for _, filename := range flag.Args() {
+		if err := observeFile(o, filename); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(struct {
+		Flows []*observer.Flow `json:"flows"`
+	}{o.Flows()}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func observeFile(o *observer.Observer, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := observer.NewPcapReader(f)
+	if err != nil {
+		return err
+	}
+	for {
+		d, err := r.ReadDatagram()
+		if err == io.EOF {
+			return nil
+		}
+		// captures are often cut off while a packet is written, keep the flows observed until then
+		if err == io.ErrUnexpectedEOF {
+			fmt.Fprintf(os.Stderr, "%s: the last record is truncated\n", filename)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		o.Observe(d)
+	}
+}
@@ -0,0 +1,93 @@
+package quic
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/handshake"
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
+)
+
+// validateConfig checks that the transport parameters in the Config can be negotiated with the peer
+// The perspective is needed, since the default maximum flow control windows of the client and the server differ.
+func validateConfig(config *Config, pers protocol.Perspective) error {
+	if config.HandshakeTimeout < 0 {
+		return fmt.Errorf("invalid HandshakeTimeout: %s", config.HandshakeTimeout)
+	}
+	if config.IdleTimeout != 0 && config.IdleTimeout < protocol.MinIdleTimeout {
+		return fmt.Errorf("IdleTimeout too small: %s (minimum %s)", config.IdleTimeout, protocol.MinIdleTimeout)
+	}
+	if config.IdleTimeout/time.Second > math.MaxUint32 {
+		return fmt.Errorf("IdleTimeout too large: %s", config.IdleTimeout)
+	}
+	if config.MaxIncomingStreams > protocol.MaxIncomingStreamsLimit {
+		return fmt.Errorf("MaxIncomingStreams too large: %d (maximum %d)", config.MaxIncomingStreams, protocol.MaxIncomingStreamsLimit)
+	}
+	defaultMaxStreamWindow := protocol.MaxReceiveStreamFlowControlWindowServer
+	defaultMaxConnectionWindow := protocol.MaxReceiveConnectionFlowControlWindowServer
+	if pers == protocol.PerspectiveClient {
+		defaultMaxStreamWindow = protocol.MaxReceiveStreamFlowControlWindowClient
+		defaultMaxConnectionWindow = protocol.MaxReceiveConnectionFlowControlWindowClient
+	}
+	if err := validateFlowControlWindows("stream", config.ReceiveStreamFlowControlWindow, protocol.ReceiveStreamFlowControlWindow, config.MaxReceiveStreamFlowControlWindow, defaultMaxStreamWindow); err != nil {
+		return err
+	}
+	return validateFlowControlWindows("connection", config.ReceiveConnectionFlowControlWindow, protocol.ReceiveConnectionFlowControlWindow, config.MaxReceiveConnectionFlowControlWindow, defaultMaxConnectionWindow)
+}
+
+func validateFlowControlWindows(level string, window, defaultWindow, maxWindow, defaultMaxWindow protocol.ByteCount) error {
+	if window == 0 {
+		window = defaultWindow
+	}
+	if maxWindow == 0 {
+		maxWindow = defaultMaxWindow
+	}
+	if window < protocol.MinReceiveFlowControlWindow {
+		return fmt.Errorf("%s-level flow control window too small: %d (minimum %d)", level, window, protocol.MinReceiveFlowControlWindow)
+	}
+	// the flow control window is sent as a uint32 in the handshake
+	if window > math.MaxUint32 {
+		return fmt.Errorf("%s-level flow control window too large: %d", level, window)
+	}
+	if maxWindow < window {
+		return fmt.Errorf("maximum %s-level flow control window (%d) smaller than the flow control window (%d)", level, maxWindow, window)
+	}
+	return nil
+}
+
+func populateHandshakeTimeout(config *Config) time.Duration {
+	if config.HandshakeTimeout == 0 {
+		return protocol.MaxTimeForCryptoHandshake
+	}
+	return config.HandshakeTimeout
+}
+
+func populateCongestionControl(config *Config) congestion.SendAlgorithmFactory {
+	if config.CongestionControl == nil {
+		return congestion.CubicFactory
+	}
+	return config.CongestionControl
+}
+
+func populateLogger(config *Config) utils.Logger {
+	if config.Logger == nil {
+		return utils.DefaultLogger
+	}
+	return config.Logger
+}
+
+// transportParameters returns the parameters sent to the peer during the handshake
+func transportParameters(config *Config) *handshake.TransportParameters {
+	return &handshake.TransportParameters{
+		RequestConnectionIDTruncation:         config.RequestConnectionIDTruncation,
+		IdleTimeout:                           config.IdleTimeout,
+		ReceiveStreamFlowControlWindow:        config.ReceiveStreamFlowControlWindow,
+		ReceiveConnectionFlowControlWindow:    config.ReceiveConnectionFlowControlWindow,
+		MaxReceiveStreamFlowControlWindow:     config.MaxReceiveStreamFlowControlWindow,
+		MaxReceiveConnectionFlowControlWindow: config.MaxReceiveConnectionFlowControlWindow,
+		MaxIncomingStreams:                    config.MaxIncomingStreams,
+	}
+}
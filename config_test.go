@@ -0,0 +1,73 @@
+package quic
+
+import (
+	"math"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	It("accepts an empty config", func() {
+		Expect(validateConfig(&Config{}, protocol.PerspectiveServer)).To(Succeed())
+	})
+
+	It("accepts valid transport parameters", func() {
+		Expect(validateConfig(&Config{
+			HandshakeTimeout:                      time.Second,
+			IdleTimeout:                           time.Hour,
+			ReceiveStreamFlowControlWindow:        protocol.MinReceiveFlowControlWindow,
+			ReceiveConnectionFlowControlWindow:    math.MaxUint32,
+			MaxReceiveStreamFlowControlWindow:     protocol.MinReceiveFlowControlWindow,
+			MaxReceiveConnectionFlowControlWindow: 1 << 40,
+			MaxIncomingStreams:                    10000,
+		}, protocol.PerspectiveServer)).To(Succeed())
+	})
+
+	It("rejects negative handshake timeouts", func() {
+		Expect(validateConfig(&Config{HandshakeTimeout: -time.Second}, protocol.PerspectiveServer)).ToNot(Succeed())
+	})
+
+	It("rejects too short idle timeouts", func() {
+		Expect(validateConfig(&Config{IdleTimeout: time.Millisecond}, protocol.PerspectiveServer)).ToNot(Succeed())
+		Expect(validateConfig(&Config{IdleTimeout: -time.Second}, protocol.PerspectiveServer)).ToNot(Succeed())
+	})
+
+	It("rejects idle timeouts that can't be sent in the handshake", func() {
+		Expect(validateConfig(&Config{IdleTimeout: math.MaxUint32 * time.Second}, protocol.PerspectiveServer)).To(Succeed())
+		Expect(validateConfig(&Config{IdleTimeout: (math.MaxUint32 + 1) * time.Second}, protocol.PerspectiveServer)).ToNot(Succeed())
+	})
+
+	It("rejects too small flow control windows", func() {
+		Expect(validateConfig(&Config{ReceiveStreamFlowControlWindow: protocol.MinReceiveFlowControlWindow - 1}, protocol.PerspectiveServer)).ToNot(Succeed())
+		Expect(validateConfig(&Config{ReceiveConnectionFlowControlWindow: protocol.MinReceiveFlowControlWindow - 1}, protocol.PerspectiveServer)).ToNot(Succeed())
+	})
+
+	It("rejects flow control windows that can't be sent in the handshake", func() {
+		Expect(validateConfig(&Config{ReceiveStreamFlowControlWindow: math.MaxUint32 + 1}, protocol.PerspectiveServer)).ToNot(Succeed())
+	})
+
+	It("rejects maximum flow control windows smaller than the initial window", func() {
+		Expect(validateConfig(&Config{
+			ReceiveStreamFlowControlWindow:    1 << 20,
+			MaxReceiveStreamFlowControlWindow: 1 << 19,
+		}, protocol.PerspectiveServer)).ToNot(Succeed())
+		// the default initial window is used if the window is not set
+		Expect(validateConfig(&Config{MaxReceiveConnectionFlowControlWindow: protocol.ReceiveConnectionFlowControlWindow - 1}, protocol.PerspectiveServer)).ToNot(Succeed())
+	})
+
+	It("rejects too large values for the maximum number of incoming streams", func() {
+		Expect(validateConfig(&Config{MaxIncomingStreams: protocol.MaxIncomingStreamsLimit}, protocol.PerspectiveServer)).To(Succeed())
+		Expect(validateConfig(&Config{MaxIncomingStreams: protocol.MaxIncomingStreamsLimit + 1}, protocol.PerspectiveServer)).ToNot(Succeed())
+	})
+
+	It("rejects flow control windows larger than the default maximum window", func() {
+		conf := &Config{ReceiveConnectionFlowControlWindow: 2 * (1 << 20)}
+		Expect(validateConfig(conf, protocol.PerspectiveServer)).To(MatchError("maximum connection-level flow control window (1572864) smaller than the flow control window (2097152)"))
+		// the client's default maximum window is larger
+		Expect(validateConfig(conf, protocol.PerspectiveClient)).To(Succeed())
+	})
+})
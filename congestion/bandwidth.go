@@ -20,3 +20,11 @@ const (
 func BandwidthFromDelta(bytes protocol.ByteCount, delta time.Duration) Bandwidth {
 	return Bandwidth(bytes) * Bandwidth(time.Second) / Bandwidth(delta) * BytesPerSecond
 }
+
+// transferTime calculates the time it takes to send a number of bytes at a given bandwidth
+func transferTime(bytes protocol.ByteCount, bandwidth Bandwidth) time.Duration {
+	if bandwidth == 0 {
+		return 0
+	}
+	return time.Duration(float64(bytes) * float64(BytesPerSecond) / float64(bandwidth) * float64(time.Second))
+}
@@ -11,4 +11,9 @@ var _ = Describe("Bandwidth", func() {
 	It("converts from time delta", func() {
 		Expect(BandwidthFromDelta(1, time.Millisecond)).To(Equal(1000 * BytesPerSecond))
 	})
+
+	It("calculates the transfer time", func() {
+		Expect(transferTime(1000, 1000*BytesPerSecond)).To(Equal(time.Second))
+		Expect(transferTime(1000, 0)).To(BeZero())
+	})
 })
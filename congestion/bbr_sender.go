@@ -0,0 +1,401 @@
+package congestion
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
+)
+
+const (
+	// bbrHighGain is 2/ln(2), the smallest gain that allows the sending rate to double every round trip during startup
+	bbrHighGain  = 2.885
+	bbrDrainGain = 1 / bbrHighGain
+	// bbrCwndGain allows enough packets in flight to keep the pipe full if ACKs are delayed or aggregated
+	bbrCwndGain = 2.0
+	// The pipe is considered full if the bandwidth didn't grow by bbrStartupGrowthTarget for bbrStartupRounds round trips.
+	bbrStartupGrowthTarget = 1.25
+	bbrStartupRounds       = 3
+	// bbrBandwidthWindow is the number of round trips bandwidth samples are kept for
+	bbrBandwidthWindow = 10
+	// bbrMinRTTWindow is how long a min RTT sample is valid, before the min RTT is probed again
+	bbrMinRTTWindow = 10 * time.Second
+	// bbrProbeRTTDuration is the time spent with a minimal congestion window to probe the min RTT
+	bbrProbeRTTDuration = 200 * time.Millisecond
+
+	bbrMinCongestionWindow = 4 * protocol.DefaultTCPMSS
+)
+
+// bbrPacingGainCycle is used in the ProbeBW mode: probe for more bandwidth, drain the queue that probing might have built up, then cruise
+var bbrPacingGainCycle = [...]float64{1.25, 0.75, 1, 1, 1, 1, 1, 1}
+
+type bbrMode int
+
+const (
+	bbrModeStartup bbrMode = iota
+	bbrModeDrain
+	bbrModeProbeBW
+	bbrModeProbeRTT
+)
+
+func (m bbrMode) String() string {
+	switch m {
+	case bbrModeStartup:
+		return "Startup"
+	case bbrModeDrain:
+		return "Drain"
+	case bbrModeProbeBW:
+		return "ProbeBW"
+	case bbrModeProbeRTT:
+		return "ProbeRTT"
+	default:
+		return "unknown mode"
+	}
+}
+
+// bbrPacketState is the state of the sender when a packet was sent
+// It is used to calculate the delivery rate when the packet is acked.
+type bbrPacketState struct {
+	sentTime      time.Time
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+	firstSentTime time.Time
+}
+
+type bbrSender struct {
+	clock    Clock
+	rttStats *RTTStats
+
+	mode bbrMode
+
+	// the packets in flight
+	sentPackets map[protocol.PacketNumber]bbrPacketState
+	// the number of bytes acked so far, and the time when the last packet was acked
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+	// the time when the last acked packet was sent
+	firstSentTime time.Time
+
+	// maxBandwidth is the maximum delivery rate over the last bbrBandwidthWindow round trips, in bits per second
+	maxBandwidth *utils.WindowedMaxFilter
+	// A round trip ends when a packet sent after the start of the round is acked.
+	roundCount         uint64
+	nextRoundDelivered protocol.ByteCount
+	roundStart         bool
+
+	minRTT        time.Duration
+	minRTTStamp   time.Time
+	minRTTExpired bool
+
+	filledPipe         bool
+	fullBandwidth      Bandwidth
+	fullBandwidthCount int
+
+	pacingGain  float64
+	cwndGain    float64
+	cycleIndex  int
+	cycleStamp  time.Time
+	lossInCycle bool
+
+	probeRTTDoneStamp time.Time
+	probeRTTRoundDone bool
+	// the congestion window before entering ProbeRTT, restored when leaving it
+	priorCongestionWindow protocol.ByteCount
+
+	congestionWindow protocol.ByteCount
+	pacingRate       Bandwidth
+
+	initialCongestionWindow protocol.ByteCount
+	maxCongestionWindow     protocol.ByteCount
+}
+
+var _ SendAlgorithm = &bbrSender{}
+
+// NewBBRSender makes a new BBR sender
+// BBR models the path by measuring the bottleneck bandwidth and the min RTT.
+// It should be used with a pacing sender, since it relies on packets being paced at its PacingRate.
+// Unlike Cubic and Reno, it doesn't reduce its sending rate on packet loss.
+func NewBBRSender(clock Clock, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithm {
+	return &bbrSender{
+		clock:                   clock,
+		rttStats:                rttStats,
+		mode:                    bbrModeStartup,
+		sentPackets:             make(map[protocol.PacketNumber]bbrPacketState),
+		maxBandwidth:            utils.NewWindowedMaxFilter(bbrBandwidthWindow),
+		pacingGain:              bbrHighGain,
+		cwndGain:                bbrHighGain,
+		congestionWindow:        protocol.ByteCount(initialCongestionWindow) * protocol.DefaultTCPMSS,
+		initialCongestionWindow: protocol.ByteCount(initialCongestionWindow) * protocol.DefaultTCPMSS,
+		maxCongestionWindow:     protocol.ByteCount(initialMaxCongestionWindow) * protocol.DefaultTCPMSS,
+	}
+}
+
+func (b *bbrSender) TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration {
+	if bytesInFlight >= b.congestionWindow {
+		return utils.InfDuration
+	}
+	return 0
+}
+
+func (b *bbrSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
+	if !isRetransmittable {
+		return false
+	}
+	// bytesInFlight includes this packet
+	if bytesInFlight <= bytes {
+		// restart the delivery rate measurement, so that the idle time isn't counted
+		b.firstSentTime = sentTime
+		b.deliveredTime = sentTime
+	}
+	b.sentPackets[packetNumber] = bbrPacketState{
+		sentTime:      sentTime,
+		delivered:     b.delivered,
+		deliveredTime: b.deliveredTime,
+		firstSentTime: b.firstSentTime,
+	}
+	return true
+}
+
+func (b *bbrSender) GetCongestionWindow() protocol.ByteCount {
+	return b.congestionWindow
+}
+
+// MaybeExitSlowStart is called whenever the RTTStats got a new RTT sample
+// BBR leaves startup based on the bandwidth estimate, so this is only used to update the min RTT.
+func (b *bbrSender) MaybeExitSlowStart() {
+	now := b.clock.Now()
+	rtt := b.rttStats.LatestRTT()
+	b.minRTTExpired = !b.minRTTStamp.IsZero() && now.Sub(b.minRTTStamp) > bbrMinRTTWindow
+	if rtt > 0 && (b.minRTT == 0 || rtt <= b.minRTT || b.minRTTExpired) {
+		b.minRTT = rtt
+		b.minRTTStamp = now
+	}
+}
+
+func (b *bbrSender) OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	now := b.clock.Now()
+	b.roundStart = false
+	if p, ok := b.sentPackets[number]; ok {
+		delete(b.sentPackets, number)
+		b.updateBandwidth(now, p, ackedBytes)
+	}
+	if b.mode == bbrModeProbeBW {
+		b.updateCyclePhase(now, bytesInFlight)
+	}
+	b.checkFullPipe()
+	b.checkDrain(now, bytesInFlight)
+	b.checkProbeRTT(now, bytesInFlight)
+	b.updatePacingRate()
+	b.updateCongestionWindow(ackedBytes)
+}
+
+func (b *bbrSender) OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	delete(b.sentPackets, number)
+	b.lossInCycle = true
+}
+
+// SetNumEmulatedConnections does nothing, since BBR doesn't emulate TCP connections
+func (b *bbrSender) SetNumEmulatedConnections(n int) {}
+
+// OnRetransmissionTimeout is called on an retransmission timeout
+// Like TCP, BBR restarts with the minimum congestion window, and grows it again as packets are acked.
+func (b *bbrSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	if !packetsRetransmitted {
+		return
+	}
+	b.congestionWindow = bbrMinCongestionWindow
+}
+
+// OnConnectionMigration is called when the connection is migrated
+// The path might have changed, so all estimates are discarded.
+func (b *bbrSender) OnConnectionMigration() {
+	initialCongestionWindow := protocol.PacketNumber(b.initialCongestionWindow / protocol.DefaultTCPMSS)
+	maxCongestionWindow := protocol.PacketNumber(b.maxCongestionWindow / protocol.DefaultTCPMSS)
+	*b = *NewBBRSender(b.clock, b.rttStats, initialCongestionWindow, maxCongestionWindow).(*bbrSender)
+}
+
+// RetransmissionDelay gives the time to retransmission
+func (b *bbrSender) RetransmissionDelay() time.Duration {
+	if b.rttStats.SmoothedRTT() == 0 {
+		return 0
+	}
+	return b.rttStats.SmoothedRTT() + b.rttStats.MeanDeviation()*4
+}
+
+// SetSlowStartLargeReduction does nothing, since BBR doesn't use slow start
+func (b *bbrSender) SetSlowStartLargeReduction(enabled bool) {}
+
+// BandwidthEstimate returns the estimated bottleneck bandwidth
+func (b *bbrSender) BandwidthEstimate() Bandwidth {
+	return Bandwidth(b.maxBandwidth.Best())
+}
+
+// PacingRate returns the rate packets are sent at
+// Before the first bandwidth sample, the initial congestion window is paced over the smoothed RTT.
+func (b *bbrSender) PacingRate() Bandwidth {
+	if b.pacingRate != 0 {
+		return b.pacingRate
+	}
+	rtt := b.rttStats.SmoothedRTT()
+	if rtt == 0 {
+		rtt = initialRTTus * time.Microsecond
+	}
+	return Bandwidth(bbrHighGain * float64(BandwidthFromDelta(b.initialCongestionWindow, rtt)))
+}
+
+func (b *bbrSender) updateBandwidth(now time.Time, p bbrPacketState, ackedBytes protocol.ByteCount) {
+	b.delivered += ackedBytes
+	b.deliveredTime = now
+	b.firstSentTime = p.sentTime
+
+	if p.delivered >= b.nextRoundDelivered {
+		b.nextRoundDelivered = b.delivered
+		b.roundCount++
+		b.roundStart = true
+		if b.mode == bbrModeProbeRTT && !b.probeRTTDoneStamp.IsZero() {
+			b.probeRTTRoundDone = true
+		}
+	}
+
+	// The delivery rate is limited by both the rate that packets were sent at and the rate that they were acked at.
+	interval := utils.MaxDuration(p.sentTime.Sub(p.firstSentTime), now.Sub(p.deliveredTime))
+	if interval <= 0 {
+		return
+	}
+	b.maxBandwidth.Update(uint64(BandwidthFromDelta(b.delivered-p.delivered, interval)), b.roundCount)
+}
+
+// bdp calculates the bandwidth-delay product, multiplied by the gain
+func (b *bbrSender) bdp(gain float64) protocol.ByteCount {
+	bandwidth := b.BandwidthEstimate()
+	if bandwidth == 0 || b.minRTT == 0 {
+		return b.initialCongestionWindow
+	}
+	return protocol.ByteCount(gain * float64(bandwidth) / float64(BytesPerSecond) * b.minRTT.Seconds())
+}
+
+func (b *bbrSender) updateCyclePhase(now time.Time, bytesInFlight protocol.ByteCount) {
+	isFullLength := now.Sub(b.cycleStamp) > b.minRTT
+	var advance bool
+	switch {
+	case b.pacingGain > 1:
+		// probe until the pipe is full, i.e. until packets are lost or the queue was filled
+		advance = isFullLength && (b.lossInCycle || bytesInFlight >= b.bdp(b.pacingGain))
+	case b.pacingGain < 1:
+		// stop draining once the queue is empty
+		advance = isFullLength || bytesInFlight <= b.bdp(1)
+	default:
+		advance = isFullLength
+	}
+	if advance {
+		b.cycleIndex = (b.cycleIndex + 1) % len(bbrPacingGainCycle)
+		b.cycleStamp = now
+		b.lossInCycle = false
+		b.pacingGain = bbrPacingGainCycle[b.cycleIndex]
+	}
+}
+
+func (b *bbrSender) checkFullPipe() {
+	if b.filledPipe || !b.roundStart {
+		return
+	}
+	bandwidth := b.BandwidthEstimate()
+	if float64(bandwidth) >= float64(b.fullBandwidth)*bbrStartupGrowthTarget {
+		b.fullBandwidth = bandwidth
+		b.fullBandwidthCount = 0
+		return
+	}
+	b.fullBandwidthCount++
+	if b.fullBandwidthCount >= bbrStartupRounds {
+		b.filledPipe = true
+	}
+}
+
+func (b *bbrSender) checkDrain(now time.Time, bytesInFlight protocol.ByteCount) {
+	if b.mode == bbrModeStartup && b.filledPipe {
+		b.mode = bbrModeDrain
+		b.pacingGain = bbrDrainGain
+		b.cwndGain = bbrHighGain
+	}
+	if b.mode == bbrModeDrain && bytesInFlight <= b.bdp(1) {
+		b.enterProbeBW(now)
+	}
+}
+
+func (b *bbrSender) enterProbeBW(now time.Time) {
+	b.mode = bbrModeProbeBW
+	b.cwndGain = bbrCwndGain
+	// start in a random phase, but don't start by draining
+	b.cycleIndex = rand.Intn(len(bbrPacingGainCycle) - 1)
+	if b.cycleIndex >= 1 {
+		b.cycleIndex++
+	}
+	b.cycleStamp = now
+	b.lossInCycle = false
+	b.pacingGain = bbrPacingGainCycle[b.cycleIndex]
+}
+
+func (b *bbrSender) checkProbeRTT(now time.Time, bytesInFlight protocol.ByteCount) {
+	if b.mode != bbrModeProbeRTT && b.minRTTExpired {
+		b.mode = bbrModeProbeRTT
+		b.pacingGain = 1
+		b.cwndGain = 1
+		b.priorCongestionWindow = b.congestionWindow
+		b.probeRTTDoneStamp = time.Time{}
+	}
+	b.minRTTExpired = false
+	if b.mode != bbrModeProbeRTT {
+		return
+	}
+	if b.probeRTTDoneStamp.IsZero() {
+		if bytesInFlight <= bbrMinCongestionWindow {
+			b.probeRTTDoneStamp = now.Add(bbrProbeRTTDuration)
+			b.probeRTTRoundDone = false
+			b.nextRoundDelivered = b.delivered
+		}
+		return
+	}
+	if b.probeRTTRoundDone && !now.Before(b.probeRTTDoneStamp) {
+		b.minRTTStamp = now
+		b.restorePriorCongestionWindow()
+		if b.filledPipe {
+			b.enterProbeBW(now)
+		} else {
+			b.mode = bbrModeStartup
+			b.pacingGain = bbrHighGain
+			b.cwndGain = bbrHighGain
+		}
+	}
+}
+
+func (b *bbrSender) updatePacingRate() {
+	bandwidth := b.BandwidthEstimate()
+	if bandwidth == 0 {
+		return
+	}
+	rate := Bandwidth(b.pacingGain * float64(bandwidth))
+	// during startup, don't slow down before the pipe is full
+	if b.filledPipe || rate > b.pacingRate {
+		b.pacingRate = rate
+	}
+}
+
+func (b *bbrSender) updateCongestionWindow(ackedBytes protocol.ByteCount) {
+	// allow for 3 packets that are held back by the sender or receiver, e.g. due to delayed ACKs
+	target := b.bdp(b.cwndGain) + 3*protocol.DefaultTCPMSS
+	if b.filledPipe {
+		b.congestionWindow = utils.MinByteCount(b.congestionWindow+ackedBytes, target)
+	} else if b.congestionWindow < target || b.delivered < b.initialCongestionWindow {
+		b.congestionWindow += ackedBytes
+	}
+	b.congestionWindow = utils.MaxByteCount(b.congestionWindow, bbrMinCongestionWindow)
+	b.congestionWindow = utils.MinByteCount(b.congestionWindow, b.maxCongestionWindow)
+	if b.mode == bbrModeProbeRTT {
+		b.congestionWindow = utils.MinByteCount(b.congestionWindow, bbrMinCongestionWindow)
+	}
+}
+
+func (b *bbrSender) restorePriorCongestionWindow() {
+	b.congestionWindow = utils.MaxByteCount(b.congestionWindow, b.priorCongestionWindow)
+}
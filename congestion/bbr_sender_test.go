@@ -0,0 +1,196 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type simulatedPacket struct {
+	packetNumber protocol.PacketNumber
+	sentTime     time.Time
+	ackTime      time.Time
+}
+
+var _ = Describe("BBR Sender", func() {
+	const (
+		bottleneckBandwidth = 10 * 1000 * 1000 * BitsPerSecond
+		minRTT              = 50 * time.Millisecond
+		packetSize          = protocol.DefaultTCPMSS
+	)
+
+	var (
+		sender        *bbrSender
+		pacer         SendAlgorithm
+		clock         mockClock
+		rttStats      *RTTStats
+		bytesInFlight protocol.ByteCount
+		packetNumber  protocol.PacketNumber
+		// the packets in flight, in the order they will be acked
+		inFlight []simulatedPacket
+		// the time when the bottleneck link is idle again
+		linkIdle time.Time
+	)
+
+	BeforeEach(func() {
+		clock = mockClock(time.Unix(1500000000, 0))
+		rttStats = NewRTTStats()
+		sender = NewBBRSender(&clock, rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow).(*bbrSender)
+		pacer = NewPacingSender(sender, rttStats)
+		bytesInFlight = 0
+		packetNumber = 1
+		inFlight = nil
+		linkIdle = time.Time{}
+	})
+
+	sendPacket := func() {
+		now := clock.Now()
+		bytesInFlight += packetSize
+		pacer.OnPacketSent(now, bytesInFlight, packetNumber, packetSize, true)
+		// packets are queued at the bottleneck link, and the ACK takes the rest of the min RTT
+		if linkIdle.Before(now) {
+			linkIdle = now
+		}
+		linkIdle = linkIdle.Add(transferTime(packetSize, bottleneckBandwidth))
+		inFlight = append(inFlight, simulatedPacket{packetNumber: packetNumber, sentTime: now, ackTime: linkIdle.Add(minRTT)})
+		packetNumber++
+	}
+
+	ackPacket := func() {
+		p := inFlight[0]
+		inFlight = inFlight[1:]
+		rttStats.UpdateRTT(clock.Now().Sub(p.sentTime), 0, clock.Now())
+		sender.MaybeExitSlowStart()
+		bytesInFlight -= packetSize
+		sender.OnPacketAcked(p.packetNumber, packetSize, bytesInFlight)
+	}
+
+	// run simulates a bulk transfer over the bottleneck link
+	run := func(d time.Duration) {
+		end := clock.Now().Add(d)
+		for clock.Now().Before(end) {
+			if len(inFlight) > 0 && !clock.Now().Before(inFlight[0].ackTime) {
+				ackPacket()
+				continue
+			}
+			delay := pacer.TimeUntilSend(clock.Now(), bytesInFlight)
+			if delay == 0 {
+				sendPacket()
+				continue
+			}
+			next := end
+			if delay != utils.InfDuration {
+				next = utils.MinTime(next, clock.Now().Add(delay))
+			}
+			if len(inFlight) > 0 {
+				next = utils.MinTime(next, inFlight[0].ackTime)
+			}
+			clock.Advance(next.Sub(clock.Now()))
+		}
+	}
+
+	bdp := protocol.ByteCount(float64(bottleneckBandwidth) / float64(BytesPerSecond) * minRTT.Seconds())
+
+	It("starts in startup mode", func() {
+		Expect(sender.mode).To(Equal(bbrModeStartup))
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.InitialCongestionWindow * packetSize))
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+	})
+
+	It("paces packets at the initial rate before the first bandwidth sample", func() {
+		rate := sender.PacingRate()
+		Expect(rate).To(Equal(Bandwidth(bbrHighGain * float64(BandwidthFromDelta(protocol.InitialCongestionWindow*packetSize, 100*time.Millisecond)))))
+		Expect(pacer.(*pacingSender).PacingRate()).To(Equal(rate))
+	})
+
+	It("paces packets at a multiple of the bandwidth estimate", func() {
+		run(2 * time.Second)
+		Expect(sender.PacingRate()).To(Equal(Bandwidth(sender.pacingGain * float64(sender.BandwidthEstimate()))))
+	})
+
+	It("doesn't send more than the congestion window", func() {
+		Expect(sender.TimeUntilSend(clock.Now(), sender.GetCongestionWindow())).To(Equal(utils.InfDuration))
+	})
+
+	It("estimates the bottleneck bandwidth", func() {
+		run(2 * time.Second)
+		Expect(sender.filledPipe).To(BeTrue())
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		Expect(float64(sender.BandwidthEstimate())).To(BeNumerically("~", float64(bottleneckBandwidth), 0.05*float64(bottleneckBandwidth)))
+		Expect(sender.minRTT).To(BeNumerically("~", minRTT, 5*time.Millisecond))
+	})
+
+	It("limits the congestion window to a multiple of the bandwidth-delay product", func() {
+		run(5 * time.Second)
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<=", protocol.ByteCount(1.1*bbrCwndGain*float64(bdp))+3*packetSize))
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", bdp))
+	})
+
+	It("drains the queue built up during startup", func() {
+		run(5 * time.Second)
+		// the queueing delay is small, compared to the min RTT
+		Expect(rttStats.SmoothedRTT()).To(BeNumerically("<", 2*minRTT))
+	})
+
+	It("cycles through the pacing gains in ProbeBW", func() {
+		run(2 * time.Second)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		gains := make(map[float64]bool)
+		for i := 0; i < 40; i++ {
+			run(minRTT / 2)
+			gains[sender.pacingGain] = true
+		}
+		Expect(gains).To(HaveKey(1.25))
+		Expect(gains).To(HaveKey(0.75))
+		Expect(gains).To(HaveKey(1.0))
+	})
+
+	It("doesn't reduce the congestion window on packet loss", func() {
+		run(2 * time.Second)
+		cwnd := sender.GetCongestionWindow()
+		p := inFlight[len(inFlight)-1]
+		inFlight = inFlight[:len(inFlight)-1]
+		bytesInFlight -= packetSize
+		sender.OnPacketLost(p.packetNumber, packetSize, bytesInFlight)
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+		Expect(sender.sentPackets).ToNot(HaveKey(p.packetNumber))
+	})
+
+	It("probes the min RTT when it expires", func() {
+		run(2 * time.Second)
+		sender.minRTTStamp = clock.Now().Add(-bbrMinRTTWindow - time.Second)
+		ackPacket()
+		Expect(sender.mode).To(Equal(bbrModeProbeRTT))
+		Expect(sender.GetCongestionWindow()).To(Equal(bbrMinCongestionWindow))
+		run(bbrProbeRTTDuration + 2*minRTT)
+		Expect(sender.mode).To(Equal(bbrModeProbeBW))
+		// the congestion window is restored
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", bdp))
+		Expect(sender.minRTTStamp).To(BeTemporally(">", clock.Now().Add(-bbrProbeRTTDuration-2*minRTT)))
+	})
+
+	It("restarts with the minimum congestion window after a retransmission timeout", func() {
+		run(2 * time.Second)
+		sender.OnRetransmissionTimeout(false)
+		Expect(sender.GetCongestionWindow()).ToNot(Equal(bbrMinCongestionWindow))
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(bbrMinCongestionWindow))
+	})
+
+	It("resets on connection migration", func() {
+		run(2 * time.Second)
+		sender.OnConnectionMigration()
+		Expect(sender.mode).To(Equal(bbrModeStartup))
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.InitialCongestionWindow * packetSize))
+	})
+
+	It("calculates the retransmission delay", func() {
+		Expect(sender.RetransmissionDelay()).To(BeZero())
+		rttStats.UpdateRTT(100*time.Millisecond, 0, clock.Now())
+		Expect(sender.RetransmissionDelay()).To(Equal(rttStats.SmoothedRTT() + 4*rttStats.MeanDeviation()))
+	})
+})
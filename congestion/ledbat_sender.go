@@ -0,0 +1,176 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
+)
+
+const (
+	// DefaultLEDBATTarget is the default queuing delay that LEDBAT senders aim for, the maximum allowed by RFC 6817
+	DefaultLEDBATTarget = 100 * time.Millisecond
+	// ledbatGain is the number of packets the congestion window grows by per RTT when there's no queuing delay, like TCP
+	ledbatGain = 1.0
+	// ledbatDelayFilter is the number of RTT samples the current delay is the minimum of, filtering out noise
+	ledbatDelayFilter = 4
+	// ledbatAllowedIncrease limits how far the congestion window may grow beyond the bytes in flight, in packets
+	ledbatAllowedIncrease = 1
+	// In slow start, the congestion window grows until the queuing delay exceeds this fraction of the target.
+	ledbatSlowStartExit = 0.75
+
+	ledbatInitialCongestionWindow protocol.PacketNumber = 2
+	ledbatMinCongestionWindow                           = 2 * protocol.DefaultTCPMSS
+)
+
+// ledbatSender implements LEDBAT, Low Extra Delay Background Transport (RFC 6817)
+// It measures the queuing delay as the difference between the current RTT and the min RTT, and adjusts the congestion window so that the queuing delay stays at the target.
+// Since loss-based congestion controllers like Cubic keep increasing the delay until packets are lost, a LEDBAT flow yields to them.
+type ledbatSender struct {
+	rttStats *RTTStats
+	target   time.Duration
+
+	congestionWindow protocol.ByteCount
+	slowStart        bool
+
+	// the latest RTT samples, used to calculate the current delay
+	delaySamples    [ledbatDelayFilter]time.Duration
+	numDelaySamples int
+
+	// losses of packets sent before the last cutback are counted as a single loss event
+	largestSentPacketNumber  protocol.PacketNumber
+	largestSentAtLastCutback protocol.PacketNumber
+
+	initialCongestionWindow protocol.ByteCount
+	maxCongestionWindow     protocol.ByteCount
+}
+
+var _ SendAlgorithm = &ledbatSender{}
+
+// NewLEDBATSender makes a new LEDBAT sender, aiming for a queuing delay of target
+func NewLEDBATSender(rttStats *RTTStats, target time.Duration, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithm {
+	return &ledbatSender{
+		rttStats:                rttStats,
+		target:                  target,
+		congestionWindow:        protocol.ByteCount(initialCongestionWindow) * protocol.DefaultTCPMSS,
+		slowStart:               true,
+		initialCongestionWindow: protocol.ByteCount(initialCongestionWindow) * protocol.DefaultTCPMSS,
+		maxCongestionWindow:     protocol.ByteCount(initialMaxCongestionWindow) * protocol.DefaultTCPMSS,
+	}
+}
+
+func (l *ledbatSender) TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration {
+	if l.congestionWindow > bytesInFlight {
+		return 0
+	}
+	return utils.InfDuration
+}
+
+func (l *ledbatSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
+	if !isRetransmittable {
+		return false
+	}
+	l.largestSentPacketNumber = packetNumber
+	return true
+}
+
+func (l *ledbatSender) GetCongestionWindow() protocol.ByteCount {
+	return l.congestionWindow
+}
+
+// InSlowStart returns true if the sender is in slow start
+func (l *ledbatSender) InSlowStart() bool {
+	return l.slowStart
+}
+
+// MaybeExitSlowStart is called whenever the RTTStats got a new RTT sample
+func (l *ledbatSender) MaybeExitSlowStart() {
+	rtt := l.rttStats.LatestRTT()
+	if rtt == 0 {
+		return
+	}
+	copy(l.delaySamples[1:], l.delaySamples[:ledbatDelayFilter-1])
+	l.delaySamples[0] = rtt
+	if l.numDelaySamples < ledbatDelayFilter {
+		l.numDelaySamples++
+	}
+	if l.slowStart && float64(l.queuingDelay()) > ledbatSlowStartExit*float64(l.target) {
+		l.slowStart = false
+	}
+}
+
+// queuingDelay is the current delay, compared to the min RTT, which is used as the base delay
+func (l *ledbatSender) queuingDelay() time.Duration {
+	if l.numDelaySamples == 0 {
+		return 0
+	}
+	currentDelay := l.delaySamples[0]
+	for _, d := range l.delaySamples[1:l.numDelaySamples] {
+		currentDelay = utils.MinDuration(currentDelay, d)
+	}
+	if baseDelay := l.rttStats.MinRTT(); currentDelay > baseDelay {
+		return currentDelay - baseDelay
+	}
+	return 0
+}
+
+func (l *ledbatSender) OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	if l.slowStart {
+		l.congestionWindow += ackedBytes
+	} else {
+		offTarget := float64(l.target-l.queuingDelay()) / float64(l.target)
+		change := ledbatGain * offTarget * float64(ackedBytes) * float64(protocol.DefaultTCPMSS) / float64(l.congestionWindow)
+		if -change >= float64(l.congestionWindow) {
+			l.congestionWindow = 0
+		} else {
+			l.congestionWindow = protocol.ByteCount(float64(l.congestionWindow) + change)
+		}
+	}
+	// don't grow the congestion window if the sender doesn't use it
+	maxAllowed := bytesInFlight + ackedBytes + ledbatAllowedIncrease*protocol.DefaultTCPMSS
+	l.congestionWindow = utils.MinByteCount(l.congestionWindow, utils.MinByteCount(maxAllowed, l.maxCongestionWindow))
+	l.congestionWindow = utils.MaxByteCount(l.congestionWindow, ledbatMinCongestionWindow)
+}
+
+func (l *ledbatSender) OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	if number <= l.largestSentAtLastCutback {
+		return
+	}
+	l.slowStart = false
+	l.congestionWindow = utils.MaxByteCount(l.congestionWindow/2, ledbatMinCongestionWindow)
+	l.largestSentAtLastCutback = l.largestSentPacketNumber
+}
+
+// SetNumEmulatedConnections does nothing, since LEDBAT doesn't emulate TCP connections
+func (l *ledbatSender) SetNumEmulatedConnections(n int) {}
+
+// OnRetransmissionTimeout is called on an retransmission timeout
+func (l *ledbatSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	l.largestSentAtLastCutback = 0
+	if !packetsRetransmitted {
+		return
+	}
+	l.slowStart = false
+	l.congestionWindow = ledbatMinCongestionWindow
+}
+
+// OnConnectionMigration is called when the connection is migrated
+// The base delay of the new path is unknown, so the sender starts over.
+func (l *ledbatSender) OnConnectionMigration() {
+	l.congestionWindow = l.initialCongestionWindow
+	l.slowStart = true
+	l.numDelaySamples = 0
+	l.largestSentPacketNumber = 0
+	l.largestSentAtLastCutback = 0
+}
+
+// RetransmissionDelay gives the time to retransmission
+func (l *ledbatSender) RetransmissionDelay() time.Duration {
+	if l.rttStats.SmoothedRTT() == 0 {
+		return 0
+	}
+	return l.rttStats.SmoothedRTT() + l.rttStats.MeanDeviation()*4
+}
+
+// SetSlowStartLargeReduction does nothing
+func (l *ledbatSender) SetSlowStartLargeReduction(enabled bool) {}
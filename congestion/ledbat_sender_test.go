@@ -0,0 +1,192 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LEDBAT Sender", func() {
+	const (
+		target     = 100 * time.Millisecond
+		baseDelay  = 50 * time.Millisecond
+		packetSize = protocol.DefaultTCPMSS
+	)
+
+	var (
+		sender   *ledbatSender
+		rttStats *RTTStats
+		now      time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Unix(1500000000, 0)
+		rttStats = NewRTTStats()
+		sender = NewLEDBATSender(rttStats, target, ledbatInitialCongestionWindow, protocol.DefaultMaxCongestionWindow).(*ledbatSender)
+	})
+
+	rttSample := func(rtt time.Duration) {
+		rttStats.UpdateRTT(rtt, 0, now)
+		sender.MaybeExitSlowStart()
+	}
+
+	// ack acks a packet, while the congestion window is fully used
+	ack := func() {
+		sender.OnPacketAcked(1, packetSize, sender.GetCongestionWindow()-packetSize)
+	}
+
+	It("starts in slow start", func() {
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * packetSize))
+		Expect(sender.TimeUntilSend(now, 0)).To(BeZero())
+		Expect(sender.TimeUntilSend(now, 2*packetSize)).To(Equal(utils.InfDuration))
+	})
+
+	It("grows the congestion window by one packet per ACK in slow start", func() {
+		rttSample(baseDelay)
+		ack()
+		ack()
+		Expect(sender.GetCongestionWindow()).To(Equal(4 * packetSize))
+	})
+
+	It("exits slow start when the queuing delay approaches the target", func() {
+		rttSample(baseDelay)
+		rttSample(baseDelay + target/2)
+		Expect(sender.InSlowStart()).To(BeTrue())
+		for i := 0; i < ledbatDelayFilter; i++ {
+			rttSample(baseDelay + target*8/10)
+		}
+		Expect(sender.InSlowStart()).To(BeFalse())
+	})
+
+	It("filters the delay samples", func() {
+		rttSample(baseDelay)
+		rttSample(baseDelay + 2*target)
+		Expect(sender.queuingDelay()).To(BeZero())
+		Expect(sender.InSlowStart()).To(BeTrue())
+	})
+
+	Context("in congestion avoidance", func() {
+		BeforeEach(func() {
+			rttSample(baseDelay)
+			sender.slowStart = false
+			sender.congestionWindow = 20 * packetSize
+		})
+
+		It("grows by one packet per RTT without queuing delay", func() {
+			for i := 0; i < 20; i++ {
+				ack()
+			}
+			Expect(sender.GetCongestionWindow()).To(BeNumerically("~", 21*packetSize, packetSize/10))
+		})
+
+		It("grows slower when the queuing delay approaches the target", func() {
+			for i := 0; i < ledbatDelayFilter; i++ {
+				rttSample(baseDelay + target/2)
+			}
+			for i := 0; i < 20; i++ {
+				ack()
+			}
+			Expect(sender.GetCongestionWindow()).To(BeNumerically("~", 20*packetSize+packetSize/2, packetSize/10))
+		})
+
+		It("shrinks when the queuing delay exceeds the target", func() {
+			for i := 0; i < ledbatDelayFilter; i++ {
+				rttSample(baseDelay + 2*target)
+			}
+			for i := 0; i < 20; i++ {
+				ack()
+			}
+			Expect(sender.GetCongestionWindow()).To(BeNumerically("~", 19*packetSize, packetSize/10))
+		})
+
+		It("doesn't shrink below the minimum congestion window", func() {
+			for i := 0; i < ledbatDelayFilter; i++ {
+				rttSample(baseDelay + 100*target)
+			}
+			for i := 0; i < 100; i++ {
+				ack()
+			}
+			Expect(sender.GetCongestionWindow()).To(Equal(ledbatMinCongestionWindow))
+		})
+
+		It("doesn't grow if the congestion window isn't used", func() {
+			for i := 0; i < 100; i++ {
+				sender.OnPacketAcked(1, packetSize, 0)
+			}
+			Expect(sender.GetCongestionWindow()).To(Equal(ledbatMinCongestionWindow))
+		})
+
+		It("halves the congestion window once per loss event", func() {
+			for pn := protocol.PacketNumber(1); pn <= 10; pn++ {
+				sender.OnPacketSent(now, 0, pn, packetSize, true)
+			}
+			sender.OnPacketLost(5, packetSize, 0)
+			Expect(sender.GetCongestionWindow()).To(Equal(10 * packetSize))
+			sender.OnPacketLost(6, packetSize, 0)
+			Expect(sender.GetCongestionWindow()).To(Equal(10 * packetSize))
+			sender.OnPacketSent(now, 0, 11, packetSize, true)
+			sender.OnPacketLost(11, packetSize, 0)
+			Expect(sender.GetCongestionWindow()).To(Equal(5 * packetSize))
+		})
+
+		It("uses the minimum congestion window after a retransmission timeout", func() {
+			sender.OnRetransmissionTimeout(true)
+			Expect(sender.GetCongestionWindow()).To(Equal(ledbatMinCongestionWindow))
+		})
+	})
+
+	It("exits slow start on packet loss", func() {
+		sender.OnPacketSent(now, 0, 1, packetSize, true)
+		sender.OnPacketLost(1, packetSize, 0)
+		Expect(sender.InSlowStart()).To(BeFalse())
+	})
+
+	It("starts over on connection migration", func() {
+		rttSample(baseDelay)
+		sender.slowStart = false
+		sender.congestionWindow = 20 * packetSize
+		sender.OnConnectionMigration()
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * packetSize))
+		Expect(sender.queuingDelay()).To(BeZero())
+	})
+
+	It("keeps the queuing delay at the target", func() {
+		const bandwidth = 10 * 1000 * 1000 * BitsPerSecond
+		var (
+			inFlight      []simulatedPacket
+			bytesInFlight protocol.ByteCount
+			packetNumber  protocol.PacketNumber = 1
+			linkIdle      time.Time
+		)
+		end := now.Add(30 * time.Second)
+		for now.Before(end) {
+			if len(inFlight) > 0 && !now.Before(inFlight[0].ackTime) {
+				p := inFlight[0]
+				inFlight = inFlight[1:]
+				rttSample(now.Sub(p.sentTime))
+				bytesInFlight -= packetSize
+				sender.OnPacketAcked(p.packetNumber, packetSize, bytesInFlight)
+				continue
+			}
+			if sender.TimeUntilSend(now, bytesInFlight) == 0 {
+				bytesInFlight += packetSize
+				sender.OnPacketSent(now, bytesInFlight, packetNumber, packetSize, true)
+				if linkIdle.Before(now) {
+					linkIdle = now
+				}
+				linkIdle = linkIdle.Add(transferTime(packetSize, bandwidth))
+				inFlight = append(inFlight, simulatedPacket{packetNumber: packetNumber, sentTime: now, ackTime: linkIdle.Add(baseDelay)})
+				packetNumber++
+				continue
+			}
+			now = inFlight[0].ackTime
+		}
+		Expect(rttStats.MinRTT()).To(BeNumerically("~", baseDelay, 2*time.Millisecond))
+		Expect(sender.queuingDelay()).To(BeNumerically("~", target, target/5))
+	})
+})
@@ -0,0 +1,97 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+const (
+	// In slow start, packets are paced at twice the rate of the congestion window per RTT, so that the congestion window can still double every RTT.
+	pacingGainSlowStart = 2.0
+	pacingGain          = 1.25
+	// maxPacingBurst is the number of packets that may be sent back-to-back, e.g. at the beginning of a connection, or after the sender was idle
+	maxPacingBurst = 10
+	// minPacingDelay is the granularity of the pacer
+	// Packets that are due in less than minPacingDelay are sent immediately, so that the sender doesn't wake up for every single packet.
+	minPacingDelay = time.Millisecond
+)
+
+// A pacingRater is a SendAlgorithm that calculates its own pacing rate, e.g. BBR
+type pacingRater interface {
+	PacingRate() Bandwidth
+}
+
+type slowStarter interface {
+	InSlowStart() bool
+}
+
+type slowStartThresholder interface {
+	GetSlowStartThreshold() protocol.ByteCount
+}
+
+// pacingSender wraps a SendAlgorithm, and spreads the packets it allows over the RTT
+type pacingSender struct {
+	SendAlgorithm
+
+	rttStats     *RTTStats
+	nextSendTime time.Time
+}
+
+var _ SendAlgorithm = &pacingSender{}
+
+// NewPacingSender creates a SendAlgorithm that paces the packets allowed by the sender
+// The pacing rate is determined by the sender if it implements PacingRate() Bandwidth.
+// Otherwise, the congestion window is paced over the smoothed RTT.
+func NewPacingSender(sender SendAlgorithm, rttStats *RTTStats) SendAlgorithm {
+	return &pacingSender{
+		SendAlgorithm: sender,
+		rttStats:      rttStats,
+	}
+}
+
+func (p *pacingSender) TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration {
+	if delay := p.SendAlgorithm.TimeUntilSend(now, bytesInFlight); delay != 0 {
+		return delay
+	}
+	if delay := p.nextSendTime.Sub(now); delay > minPacingDelay {
+		return delay
+	}
+	return 0
+}
+
+func (p *pacingSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
+	if isRetransmittable {
+		rate := p.PacingRate()
+		// if the sender didn't use its pacing budget, it may send a burst of packets
+		if earliest := sentTime.Add(-transferTime((maxPacingBurst-1)*protocol.DefaultTCPMSS, rate)); p.nextSendTime.Before(earliest) {
+			p.nextSendTime = earliest
+		}
+		p.nextSendTime = p.nextSendTime.Add(transferTime(bytes, rate))
+	}
+	return p.SendAlgorithm.OnPacketSent(sentTime, bytesInFlight, packetNumber, bytes, isRetransmittable)
+}
+
+// PacingRate returns the rate that packets are sent at
+func (p *pacingSender) PacingRate() Bandwidth {
+	if s, ok := p.SendAlgorithm.(pacingRater); ok {
+		return s.PacingRate()
+	}
+	rtt := p.rttStats.SmoothedRTT()
+	if rtt == 0 {
+		rtt = initialRTTus * time.Microsecond
+	}
+	gain := pacingGain
+	if s, ok := p.SendAlgorithm.(slowStarter); ok && s.InSlowStart() {
+		gain = pacingGainSlowStart
+	}
+	return Bandwidth(gain * float64(BandwidthFromDelta(p.GetCongestionWindow(), rtt)))
+}
+
+// GetSlowStartThreshold returns the slow start threshold of the sender, or 0 if it doesn't have one
+func (p *pacingSender) GetSlowStartThreshold() protocol.ByteCount {
+	if s, ok := p.SendAlgorithm.(slowStartThresholder); ok {
+		return s.GetSlowStartThreshold()
+	}
+	return 0
+}
@@ -0,0 +1,105 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pacing Sender", func() {
+	var (
+		sender       SendAlgorithm
+		pacer        *pacingSender
+		clock        mockClock
+		rttStats     *RTTStats
+		packetNumber protocol.PacketNumber
+	)
+
+	BeforeEach(func() {
+		clock = mockClock(time.Unix(1500000000, 0))
+		rttStats = NewRTTStats()
+		rttStats.UpdateRTT(100*time.Millisecond, 0, clock.Now())
+		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, MaxCongestionWindow)
+		pacer = NewPacingSender(sender, rttStats).(*pacingSender)
+		packetNumber = 1
+	})
+
+	// sendBurst sends as many packets as the pacer allows without advancing the clock
+	sendBurst := func() int {
+		var n int
+		for pacer.TimeUntilSend(clock.Now(), 0) == 0 {
+			pacer.OnPacketSent(clock.Now(), protocol.DefaultTCPMSS, packetNumber, protocol.DefaultTCPMSS, true)
+			packetNumber++
+			n++
+		}
+		return n
+	}
+
+	It("paces the congestion window over the RTT, at twice the rate in slow start", func() {
+		Expect(sender.(*cubicSender).InSlowStart()).To(BeTrue())
+		Expect(pacer.PacingRate()).To(Equal(2 * BandwidthFromDelta(sender.GetCongestionWindow(), 100*time.Millisecond)))
+	})
+
+	It("paces at 1.25 times the rate in congestion avoidance", func() {
+		sender.(*cubicSender).ExitSlowstart()
+		Expect(pacer.PacingRate()).To(Equal(Bandwidth(1.25 * float64(BandwidthFromDelta(sender.GetCongestionWindow(), 100*time.Millisecond)))))
+	})
+
+	It("uses the initial RTT before the first RTT sample", func() {
+		rttStats = NewRTTStats()
+		pacer = NewPacingSender(sender, rttStats).(*pacingSender)
+		Expect(pacer.PacingRate()).To(Equal(2 * BandwidthFromDelta(sender.GetCongestionWindow(), 100*time.Millisecond)))
+	})
+
+	It("uses the pacing rate of the sender", func() {
+		bbr := NewBBRSender(&clock, rttStats, 10, 100)
+		pacer = NewPacingSender(bbr, rttStats).(*pacingSender)
+		Expect(pacer.PacingRate()).To(Equal(bbr.(*bbrSender).PacingRate()))
+	})
+
+	It("allows a burst, and then spreads packets", func() {
+		Expect(sendBurst()).To(Equal(maxPacingBurst))
+		delay := pacer.TimeUntilSend(clock.Now(), 0)
+		Expect(delay).To(BeNumerically(">", minPacingDelay))
+		// 10 packets are paced over 100ms, at twice the rate
+		interval := transferTime(protocol.DefaultTCPMSS, pacer.PacingRate())
+		Expect(interval).To(Equal(5 * time.Millisecond))
+		clock.Advance(delay)
+		Expect(sendBurst()).To(Equal(1))
+		clock.Advance(interval)
+		Expect(sendBurst()).To(Equal(1))
+	})
+
+	It("sends packets that are due in less than the pacing granularity", func() {
+		sendBurst()
+		clock.Advance(pacer.TimeUntilSend(clock.Now(), 0) - minPacingDelay)
+		Expect(pacer.TimeUntilSend(clock.Now(), 0)).To(BeZero())
+	})
+
+	It("allows a new burst after being idle", func() {
+		sendBurst()
+		clock.Advance(time.Second)
+		Expect(sendBurst()).To(Equal(maxPacingBurst))
+	})
+
+	It("doesn't pace packets that are not retransmittable", func() {
+		for i := 0; i < 100; i++ {
+			pacer.OnPacketSent(clock.Now(), 0, packetNumber, protocol.DefaultTCPMSS, false)
+			packetNumber++
+		}
+		Expect(pacer.TimeUntilSend(clock.Now(), 0)).To(BeZero())
+	})
+
+	It("respects the congestion window of the sender", func() {
+		Expect(pacer.TimeUntilSend(clock.Now(), sender.GetCongestionWindow())).To(Equal(utils.InfDuration))
+	})
+
+	It("returns the slow start threshold of the sender", func() {
+		Expect(pacer.GetSlowStartThreshold()).To(Equal(sender.(*cubicSender).GetSlowStartThreshold()))
+		ledbat := NewPacingSender(NewLEDBATSender(rttStats, DefaultLEDBATTarget, 2, MaxCongestionWindow), rttStats).(*pacingSender)
+		Expect(ledbat.GetSlowStartThreshold()).To(BeZero())
+	})
+})
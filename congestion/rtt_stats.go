@@ -37,6 +37,9 @@ type RTTStats struct {
 	recentMinRTT     rttSample
 	halfWindowRTT    rttSample
 	quarterWindowRTT rttSample
+
+	// logger is nil until SetLogger is called, utils.DefaultLogger is used then
+	logger utils.Logger
 }
 
 // NewRTTStats makes a properly initialized RTTStats object
@@ -47,6 +50,16 @@ func NewRTTStats() *RTTStats {
 	}
 }
 
+// SetLogger sets the logger, e.g. the logger of the session
+func (r *RTTStats) SetLogger(logger utils.Logger) { r.logger = logger }
+
+func (r *RTTStats) getLogger() utils.Logger {
+	if r.logger == nil {
+		return utils.DefaultLogger
+	}
+	return r.logger
+}
+
 // InitialRTTus is the initial RTT in us
 func (r *RTTStats) InitialRTTus() int64 { return r.initialRTTus }
 
@@ -83,7 +96,7 @@ func (r *RTTStats) SetRecentMinRTTwindow(recentMinRTTwindow time.Duration) {
 // UpdateRTT updates the RTT based on a new sample.
 func (r *RTTStats) UpdateRTT(sendDelta, ackDelay time.Duration, now time.Time) {
 	if sendDelta == utils.InfDuration || sendDelta <= 0 {
-		utils.Debugf("Ignoring measured sendDelta, because it's is either infinite, zero, or negative: %d", sendDelta/time.Microsecond)
+		r.getLogger().Debugf("Ignoring measured sendDelta, because it's is either infinite, zero, or negative: %d", sendDelta/time.Microsecond)
 		return
 	}
 
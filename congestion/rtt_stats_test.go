@@ -1,6 +1,8 @@
 package congestion
 
 import (
+	"bytes"
+	"os"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/utils"
@@ -196,6 +198,17 @@ var _ = Describe("RTT stats", func() {
 		}
 	})
 
+	It("logs to the logger set by SetLogger", func() {
+		var buf bytes.Buffer
+		utils.SetLogWriter(&buf)
+		defer utils.SetLogWriter(os.Stdout)
+		logger := utils.DefaultLogger.WithPrefix("rtt")
+		logger.SetLogLevel(utils.LogLevelDebug)
+		rttStats.SetLogger(logger)
+		rttStats.UpdateRTT(0, 0, time.Time{})
+		Expect(buf.String()).To(ContainSubstring("rtt Ignoring measured sendDelta"))
+	})
+
 	It("ResetAfterConnectionMigrations", func() {
 		rttStats.UpdateRTT((300 * time.Millisecond), (100 * time.Millisecond), time.Time{})
 		Expect(rttStats.LatestRTT()).To(Equal((200 * time.Millisecond)))
@@ -0,0 +1,47 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+// A SendAlgorithmFactory creates the SendAlgorithm used by a new connection
+type SendAlgorithmFactory func(clock Clock, rttStats *RTTStats) SendAlgorithm
+
+// CubicFactory creates Cubic senders emulating 2 TCP connections, like Chromium does
+func CubicFactory(clock Clock, rttStats *RTTStats) SendAlgorithm {
+	return NewCubicSender(clock, rttStats, false, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+}
+
+// RenoFactory creates Reno senders emulating 2 TCP connections
+func RenoFactory(clock Clock, rttStats *RTTStats) SendAlgorithm {
+	return NewCubicSender(clock, rttStats, true, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+}
+
+// EmulatedCubicFactory returns a SendAlgorithmFactory for Cubic senders that emulate n TCP connections.
+// A larger n makes the connection more aggressive towards competing flows. n is at least 1.
+func EmulatedCubicFactory(n int) SendAlgorithmFactory {
+	return func(clock Clock, rttStats *RTTStats) SendAlgorithm {
+		sender := CubicFactory(clock, rttStats)
+		sender.SetNumEmulatedConnections(n)
+		return sender
+	}
+}
+
+// BBRFactory creates BBR senders
+func BBRFactory(clock Clock, rttStats *RTTStats) SendAlgorithm {
+	return NewBBRSender(clock, rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+}
+
+// LEDBATFactory returns a SendAlgorithmFactory for LEDBAT senders, aiming for a queuing delay of target.
+// LEDBAT is a scavenger congestion controller: it yields to other flows on the same bottleneck, and is meant for background transfers.
+// If target is 0, DefaultLEDBATTarget is used.
+func LEDBATFactory(target time.Duration) SendAlgorithmFactory {
+	if target == 0 {
+		target = DefaultLEDBATTarget
+	}
+	return func(clock Clock, rttStats *RTTStats) SendAlgorithm {
+		return NewLEDBATSender(rttStats, target, ledbatInitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+	}
+}
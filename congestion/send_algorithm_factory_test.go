@@ -0,0 +1,65 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SendAlgorithmFactory", func() {
+	var (
+		clock    mockClock
+		rttStats *RTTStats
+	)
+
+	BeforeEach(func() {
+		clock = mockClock{}
+		rttStats = NewRTTStats()
+	})
+
+	It("creates Cubic senders", func() {
+		sender := CubicFactory(&clock, rttStats).(*cubicSender)
+		Expect(sender.reno).To(BeFalse())
+		Expect(sender.numConnections).To(Equal(defaultNumConnections))
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(protocol.InitialCongestionWindow) * protocol.DefaultTCPMSS))
+		Expect(sender.rttStats).To(Equal(rttStats))
+	})
+
+	It("creates Reno senders", func() {
+		sender := RenoFactory(&clock, rttStats).(*cubicSender)
+		Expect(sender.reno).To(BeTrue())
+		Expect(sender.numConnections).To(Equal(defaultNumConnections))
+	})
+
+	It("creates Cubic senders emulating N connections", func() {
+		sender := EmulatedCubicFactory(5)(&clock, rttStats).(*cubicSender)
+		Expect(sender.reno).To(BeFalse())
+		Expect(sender.numConnections).To(Equal(5))
+		Expect(sender.cubic.numConnections).To(Equal(5))
+	})
+
+	It("emulates at least one connection", func() {
+		sender := EmulatedCubicFactory(0)(&clock, rttStats).(*cubicSender)
+		Expect(sender.numConnections).To(Equal(1))
+	})
+
+	It("creates BBR senders", func() {
+		sender := BBRFactory(&clock, rttStats).(*bbrSender)
+		Expect(sender.mode).To(Equal(bbrModeStartup))
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(protocol.InitialCongestionWindow) * protocol.DefaultTCPMSS))
+	})
+
+	It("creates LEDBAT senders", func() {
+		sender := LEDBATFactory(50*time.Millisecond)(&clock, rttStats).(*ledbatSender)
+		Expect(sender.target).To(Equal(50 * time.Millisecond))
+		Expect(sender.rttStats).To(Equal(rttStats))
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(ledbatInitialCongestionWindow) * protocol.DefaultTCPMSS))
+	})
+
+	It("creates LEDBAT senders with the default target", func() {
+		sender := LEDBATFactory(0)(&clock, rttStats).(*ledbatSender)
+		Expect(sender.target).To(Equal(DefaultLEDBATTarget))
+	})
+})
@@ -3,15 +3,43 @@ package quic
 import (
 	"net"
 	"sync"
+
+	"github.com/lucas-clemente/quic-go/protocol"
 )
 
-type connection interface {
-	Write([]byte) error
-	Read([]byte) (int, net.Addr, error)
-	Close() error
-	LocalAddr() net.Addr
-	RemoteAddr() net.Addr
-	SetCurrentRemoteAddr(net.Addr)
+// UDPTransport sends QUIC packets directly over UDP.
+var UDPTransport Transport = udpTransport{}
+
+type udpTransport struct{}
+
+func (udpTransport) Listen(pconn net.PacketConn) (PacketTransport, error) {
+	return &udpPacketTransport{pconn: pconn}, nil
+}
+
+func (udpTransport) Dial(pconn net.PacketConn, remoteAddr net.Addr, _ protocol.ConnectionID) (PacketTransport, TransportConnection, error) {
+	return &udpPacketTransport{pconn: pconn}, &conn{pconn: pconn, currentAddr: remoteAddr}, nil
+}
+
+type udpPacketTransport struct {
+	pconn net.PacketConn
+}
+
+var _ PacketTransport = &udpPacketTransport{}
+
+func (t *udpPacketTransport) ReadPacket(p []byte) (int, net.Addr, TransportConnection, []byte, error) {
+	n, addr, err := t.pconn.ReadFrom(p)
+	if err != nil {
+		return 0, nil, nil, nil, err
+	}
+	return n, addr, &conn{pconn: t.pconn, currentAddr: addr}, nil, nil
+}
+
+func (t *udpPacketTransport) LocalAddr() net.Addr {
+	return t.pconn.LocalAddr()
+}
+
+func (t *udpPacketTransport) Close() error {
+	return t.pconn.Close()
 }
 
 type conn struct {
@@ -21,23 +49,29 @@ type conn struct {
 	currentAddr net.Addr
 }
 
-var _ connection = &conn{}
+var _ TransportConnection = &conn{}
 
 func (c *conn) Write(p []byte) error {
 	_, err := c.pconn.WriteTo(p, c.currentAddr)
 	return err
 }
 
-func (c *conn) Read(p []byte) (int, net.Addr, error) {
-	return c.pconn.ReadFrom(p)
-}
-
 func (c *conn) SetCurrentRemoteAddr(addr net.Addr) {
 	c.mutex.Lock()
 	c.currentAddr = addr
 	c.mutex.Unlock()
 }
 
+// AddFeedback does nothing, since plain UDP doesn't carry any feedback
+func (c *conn) AddFeedback([]byte) error {
+	return nil
+}
+
+// Close does nothing, since the net.PacketConn is shared by all connections
+func (c *conn) Close() error {
+	return nil
+}
+
 func (c *conn) LocalAddr() net.Addr {
 	return c.pconn.LocalAddr()
 }
@@ -48,7 +82,3 @@ func (c *conn) RemoteAddr() net.Addr {
 	c.mutex.RUnlock()
 	return addr
 }
-
-func (c *conn) Close() error {
-	return c.pconn.Close()
-}
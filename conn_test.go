@@ -2,6 +2,7 @@ package quic
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net"
 	"time"
@@ -44,65 +45,117 @@ func (c *mockPacketConn) SetWriteDeadline(t time.Time) error { panic("not implem
 
 var _ net.PacketConn = &mockPacketConn{}
 
-var _ = Describe("Connection", func() {
-	var c *conn
+var _ = Describe("UDP Transport", func() {
 	var packetConn *mockPacketConn
 
 	BeforeEach(func() {
-		addr := &net.UDPAddr{
-			IP:   net.IPv4(192, 168, 100, 200),
-			Port: 1337,
-		}
 		packetConn = &mockPacketConn{}
-		c = &conn{
-			currentAddr: addr,
-			pconn:       packetConn,
-		}
 	})
 
-	It("writes", func() {
-		err := c.Write([]byte("foobar"))
-		Expect(err).ToNot(HaveOccurred())
-		Expect(packetConn.dataWritten.Bytes()).To(Equal([]byte("foobar")))
-		Expect(packetConn.dataWrittenTo.String()).To(Equal("192.168.100.200:1337"))
+	Context("packet transport", func() {
+		var t PacketTransport
+
+		BeforeEach(func() {
+			var err error
+			t, err = UDPTransport.Listen(packetConn)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("reads", func() {
+			packetConn.dataToRead = []byte("foo")
+			packetConn.dataReadFrom = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1336}
+			p := make([]byte, 10)
+			n, raddr, c, feedback, err := t.ReadPacket(p)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(raddr.String()).To(Equal("127.0.0.1:1336"))
+			Expect(c.RemoteAddr().String()).To(Equal("127.0.0.1:1336"))
+			Expect(feedback).To(BeNil())
+			Expect(n).To(Equal(3))
+			Expect(p[0:3]).To(Equal([]byte("foo")))
+		})
+
+		It("returns read errors", func() {
+			testErr := errors.New("read error")
+			packetConn.readErr = testErr
+			_, _, _, _, err := t.ReadPacket(make([]byte, 10))
+			Expect(err).To(MatchError(testErr))
+		})
+
+		It("gets the local address", func() {
+			addr := &net.UDPAddr{
+				IP:   net.IPv4(192, 168, 0, 1),
+				Port: 1234,
+			}
+			packetConn.addr = addr
+			Expect(t.LocalAddr()).To(Equal(addr))
+		})
+
+		It("closes", func() {
+			err := t.Close()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packetConn.closed).To(BeTrue())
+		})
 	})
 
-	It("reads", func() {
-		packetConn.dataToRead = []byte("foo")
-		packetConn.dataReadFrom = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1336}
-		p := make([]byte, 10)
-		n, raddr, err := c.Read(p)
+	It("dials", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
+		t, c, err := UDPTransport.Dial(packetConn, addr, 0x1337)
 		Expect(err).ToNot(HaveOccurred())
-		Expect(raddr.String()).To(Equal("127.0.0.1:1336"))
-		Expect(n).To(Equal(3))
-		Expect(p[0:3]).To(Equal([]byte("foo")))
+		Expect(t.(*udpPacketTransport).pconn).To(Equal(packetConn))
+		Expect(c.RemoteAddr()).To(Equal(addr))
 	})
 
-	It("gets the remote address", func() {
-		Expect(c.RemoteAddr().String()).To(Equal("192.168.100.200:1337"))
-	})
+	Context("connection", func() {
+		var c *conn
 
-	It("gets the local address", func() {
-		addr := &net.UDPAddr{
-			IP:   net.IPv4(192, 168, 0, 1),
-			Port: 1234,
-		}
-		packetConn.addr = addr
-		Expect(c.LocalAddr()).To(Equal(addr))
-	})
+		BeforeEach(func() {
+			addr := &net.UDPAddr{
+				IP:   net.IPv4(192, 168, 100, 200),
+				Port: 1337,
+			}
+			c = &conn{
+				currentAddr: addr,
+				pconn:       packetConn,
+			}
+		})
 
-	It("changes the remote address", func() {
-		addr := &net.UDPAddr{
-			IP:   net.IPv4(127, 0, 0, 1),
-			Port: 7331,
-		}
-		c.SetCurrentRemoteAddr(addr)
-		Expect(c.RemoteAddr().String()).To(Equal(addr.String()))
-	})
+		It("writes", func() {
+			err := c.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packetConn.dataWritten.Bytes()).To(Equal([]byte("foobar")))
+			Expect(packetConn.dataWrittenTo.String()).To(Equal("192.168.100.200:1337"))
+		})
 
-	It("closes", func() {
-		err := c.Close()
-		Expect(err).ToNot(HaveOccurred())
-		Expect(packetConn.closed).To(BeTrue())
+		It("gets the remote address", func() {
+			Expect(c.RemoteAddr().String()).To(Equal("192.168.100.200:1337"))
+		})
+
+		It("gets the local address", func() {
+			addr := &net.UDPAddr{
+				IP:   net.IPv4(192, 168, 0, 1),
+				Port: 1234,
+			}
+			packetConn.addr = addr
+			Expect(c.LocalAddr()).To(Equal(addr))
+		})
+
+		It("changes the remote address", func() {
+			addr := &net.UDPAddr{
+				IP:   net.IPv4(127, 0, 0, 1),
+				Port: 7331,
+			}
+			c.SetCurrentRemoteAddr(addr)
+			Expect(c.RemoteAddr().String()).To(Equal(addr.String()))
+		})
+
+		It("ignores feedback", func() {
+			Expect(c.AddFeedback([]byte("foobar"))).To(Succeed())
+			Expect(packetConn.dataWritten.Len()).To(BeZero())
+		})
+
+		It("doesn't close the packet conn when closed", func() {
+			Expect(c.Close()).To(Succeed())
+			Expect(packetConn.closed).To(BeFalse())
+		})
 	})
 })
@@ -22,15 +22,37 @@ import (
 
 // DeriveKeysAESGCM derives the client and server keys and creates a matching AES-GCM AEAD instance
 func DeriveKeysAESGCM(forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo []byte, scfg []byte, cert []byte, divNonce []byte, pers protocol.Perspective) (AEAD, error) {
-	var swap bool
-	if pers == protocol.PerspectiveClient {
-		swap = true
-	}
-	otherKey, myKey, otherIV, myIV, err := deriveKeys(forwardSecure, sharedSecret, nonces, connID, chlo, scfg, cert, divNonce, 16, swap)
+	return DeriveKeysAESGCMWithKeyLog(nil, nil, forwardSecure, sharedSecret, nonces, connID, chlo, scfg, cert, divNonce, pers)
+}
+
+// DeriveKeysAESGCMWithKeyLog works like DeriveKeysAESGCM, and additionally writes the keys to keyLog, if it is not nil
+// Errors writing to the key log are logged to logger, but don't fail the key derivation.
+func DeriveKeysAESGCMWithKeyLog(keyLog io.Writer, logger utils.Logger, forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo []byte, scfg []byte, cert []byte, divNonce []byte, pers protocol.Perspective) (AEAD, error) {
+	clientKey, serverKey, clientIV, serverIV, err := deriveKeys(forwardSecure, sharedSecret, nonces, connID, chlo, scfg, cert, divNonce, 16, false)
 	if err != nil {
 		return nil, err
 	}
-	return NewAEADAESGCM(otherKey, myKey, otherIV, myIV)
+	if keyLog != nil {
+		encLevel := protocol.EncryptionSecure
+		if forwardSecure {
+			encLevel = protocol.EncryptionForwardSecure
+		}
+		err := WriteKeyLog(keyLog, &KeyLogEntry{
+			ConnectionID:    connID,
+			EncryptionLevel: encLevel,
+			ClientKey:       clientKey,
+			ClientIV:        clientIV,
+			ServerKey:       serverKey,
+			ServerIV:        serverIV,
+		})
+		if err != nil {
+			logger.Errorf("Error writing key log: %s", err.Error())
+		}
+	}
+	if pers == protocol.PerspectiveClient {
+		return NewAEADAESGCM(serverKey, clientKey, serverIV, clientIV)
+	}
+	return NewAEADAESGCM(clientKey, serverKey, clientIV, serverIV)
 }
 
 // deriveKeys derives the keys and the IVs
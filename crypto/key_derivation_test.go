@@ -1,7 +1,10 @@
 package crypto
 
 import (
+	"bytes"
+
 	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -193,5 +196,57 @@ var _ = Describe("KeyDerivation", func() {
 			Expect(aesgcm.myIV).To(Equal([]byte{0x7, 0xad, 0xab, 0xb8}))
 			Expect(aesgcm.otherIV).To(Equal([]byte{0xf2, 0x7a, 0xcc, 0x42}))
 		})
+
+		It("writes the keys to the key log", func() {
+			b := &bytes.Buffer{}
+			aead, err := DeriveKeysAESGCMWithKeyLog(
+				b,
+				utils.DefaultLogger,
+				false,
+				[]byte("0123456789012345678901"),
+				[]byte("nonce"),
+				protocol.ConnectionID(42),
+				[]byte("chlo"),
+				[]byte("scfg"),
+				[]byte("cert"),
+				[]byte("divnonce"),
+				protocol.PerspectiveServer,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			aesgcm := aead.(*aeadAESGCM)
+			entries, err := ParseKeyLog(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].ConnectionID).To(Equal(protocol.ConnectionID(42)))
+			Expect(entries[0].EncryptionLevel).To(Equal(protocol.EncryptionSecure))
+			Expect(entries[0].ClientIV).To(Equal(aesgcm.otherIV))
+			Expect(entries[0].ServerIV).To(Equal(aesgcm.myIV))
+			// the server can open packets sealed with the logged client keys
+			clientAEAD, err := NewAEADAESGCM(entries[0].ServerKey, entries[0].ClientKey, entries[0].ServerIV, entries[0].ClientIV)
+			Expect(err).ToNot(HaveOccurred())
+			sealed := clientAEAD.Seal(nil, []byte("foobar"), 42, []byte("aad"))
+			opened, err := aead.Open(nil, sealed, 42, []byte("aad"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal([]byte("foobar")))
+		})
+
+		It("logs forward secure keys", func() {
+			b := &bytes.Buffer{}
+			_, err := DeriveKeysAESGCMWithKeyLog(
+				b,
+				utils.DefaultLogger,
+				true,
+				[]byte("0123456789012345678901"),
+				[]byte("nonce"),
+				protocol.ConnectionID(42),
+				[]byte("chlo"),
+				[]byte("scfg"),
+				[]byte("cert"),
+				nil,
+				protocol.PerspectiveClient,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.String()).To(HavePrefix("QUIC_FORWARD_SECURE 000000000000002a "))
+		})
 	})
 })
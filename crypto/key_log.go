@@ -0,0 +1,111 @@
+package crypto
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+const (
+	keyLogLabelSecure        = "QUIC_SECURE"
+	keyLogLabelForwardSecure = "QUIC_FORWARD_SECURE"
+)
+
+// KeyLogEntry is a line of the key log.
+// The key log is a text file, similar to the NSS key log format used for TLS.
+// Every line contains the keys of one encryption level of a connection:
+//
+//	<label> <connection ID> <client key> <client IV> <server key> <server IV>
+//
+// The label is either QUIC_SECURE for the initial (non-forward secure) keys, or QUIC_FORWARD_SECURE for the forward secure keys.
+// The connection ID is written as 16 hexadecimal digits, all keys and IVs are hex-encoded.
+// The server keys of the initial encryption level are already diversified using the diversification nonce.
+// Empty lines and lines starting with # are ignored.
+type KeyLogEntry struct {
+	ConnectionID    protocol.ConnectionID
+	EncryptionLevel protocol.EncryptionLevel
+	ClientKey       []byte
+	ClientIV        []byte
+	ServerKey       []byte
+	ServerIV        []byte
+}
+
+// writing to the key log must be serialized, since multiple connections may use the same io.Writer
+var keyLogMutex sync.Mutex
+
+// WriteKeyLog writes a key log entry to w
+func WriteKeyLog(w io.Writer, e *KeyLogEntry) error {
+	var label string
+	switch e.EncryptionLevel {
+	case protocol.EncryptionSecure:
+		label = keyLogLabelSecure
+	case protocol.EncryptionForwardSecure:
+		label = keyLogLabelForwardSecure
+	default:
+		return fmt.Errorf("key log: invalid encryption level %s", e.EncryptionLevel)
+	}
+	line := fmt.Sprintf("%s %016x %x %x %x %x\n", label, uint64(e.ConnectionID), e.ClientKey, e.ClientIV, e.ServerKey, e.ServerIV)
+
+	keyLogMutex.Lock()
+	defer keyLogMutex.Unlock()
+	_, err := io.WriteString(w, line)
+	return err
+}
+
+// ParseKeyLog parses all entries of a key log
+func ParseKeyLog(r io.Reader) ([]KeyLogEntry, error) {
+	var entries []KeyLogEntry
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		e, err := parseKeyLogLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("key log: line %d: %s", lineNumber, err.Error())
+		}
+		entries = append(entries, *e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func parseKeyLogLine(line string) (*KeyLogEntry, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 6 {
+		return nil, errors.New("expected 6 fields")
+	}
+	e := &KeyLogEntry{}
+	switch fields[0] {
+	case keyLogLabelSecure:
+		e.EncryptionLevel = protocol.EncryptionSecure
+	case keyLogLabelForwardSecure:
+		e.EncryptionLevel = protocol.EncryptionForwardSecure
+	default:
+		return nil, fmt.Errorf("unknown label %s", fields[0])
+	}
+	connID, err := strconv.ParseUint(fields[1], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection ID: %s", err.Error())
+	}
+	e.ConnectionID = protocol.ConnectionID(connID)
+	values := make([][]byte, 4)
+	for i, f := range fields[2:] {
+		values[i], err = hex.DecodeString(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	e.ClientKey, e.ClientIV, e.ServerKey, e.ServerIV = values[0], values[1], values[2], values[3]
+	return e, nil
+}
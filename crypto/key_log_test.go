@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Key Log", func() {
+	entry := KeyLogEntry{
+		ConnectionID:    0xdecafbad,
+		EncryptionLevel: protocol.EncryptionForwardSecure,
+		ClientKey:       []byte{0x1, 0x2},
+		ClientIV:        []byte{0x3},
+		ServerKey:       []byte{0x4, 0x5},
+		ServerIV:        []byte{0x6},
+	}
+
+	It("writes an entry", func() {
+		b := &bytes.Buffer{}
+		err := WriteKeyLog(b, &entry)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b.String()).To(Equal("QUIC_FORWARD_SECURE 00000000decafbad 0102 03 0405 06\n"))
+	})
+
+	It("writes entries for the initial keys", func() {
+		b := &bytes.Buffer{}
+		e := entry
+		e.EncryptionLevel = protocol.EncryptionSecure
+		err := WriteKeyLog(b, &e)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b.String()).To(HavePrefix("QUIC_SECURE "))
+	})
+
+	It("refuses to write entries for unencrypted packets", func() {
+		e := entry
+		e.EncryptionLevel = protocol.EncryptionUnencrypted
+		err := WriteKeyLog(&bytes.Buffer{}, &e)
+		Expect(err).To(MatchError("key log: invalid encryption level unencrypted"))
+	})
+
+	It("parses the entries it wrote", func() {
+		b := &bytes.Buffer{}
+		e := entry
+		e.EncryptionLevel = protocol.EncryptionSecure
+		Expect(WriteKeyLog(b, &e)).To(Succeed())
+		b.WriteString("# a comment\n\n")
+		Expect(WriteKeyLog(b, &entry)).To(Succeed())
+		entries, err := ParseKeyLog(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(Equal([]KeyLogEntry{e, entry}))
+	})
+
+	It("errors on unknown labels", func() {
+		_, err := ParseKeyLog(strings.NewReader("CLIENT_RANDOM 0 01 02 03 04\n"))
+		Expect(err).To(MatchError("key log: line 1: unknown label CLIENT_RANDOM"))
+	})
+
+	It("errors on lines with a wrong number of fields", func() {
+		_, err := ParseKeyLog(strings.NewReader("# comment\nQUIC_SECURE 0 01 02 03\n"))
+		Expect(err).To(MatchError("key log: line 2: expected 6 fields"))
+	})
+
+	It("errors on invalid hex values", func() {
+		_, err := ParseKeyLog(strings.NewReader("QUIC_SECURE 0 01 02 03 xx\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
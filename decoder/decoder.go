@@ -0,0 +1,232 @@
+// Package decoder decrypts captured gQUIC packets offline, using the keys written to quic.Config.KeyLogWriter.
+//
+// The datagrams are read from a packet capture with an observer.PcapReader:
+//
+//	d, err := decoder.NewDecoder(keyLogFile)
+//	r, err := observer.NewPcapReader(captureFile)
+//	for {
+//		datagram, err := r.ReadDatagram()
+//		if err == io.EOF {
+//			break
+//		}
+//		packet, err := d.Decode(datagram)
+//	}
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/crypto"
+	"github.com/lucas-clemente/quic-go/frames"
+	"github.com/lucas-clemente/quic-go/observer"
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+// A Packet is a decrypted QUIC packet
+type Packet struct {
+	Time         time.Time
+	SentBy       protocol.Perspective
+	ConnectionID protocol.ConnectionID
+	// PLUSHeader is set if the QUIC packet was encapsulated in PLUS
+	PLUSHeader *observer.PLUSHeader
+	// Header is the public header. The packet number is the full packet number, not the truncated one sent on the wire.
+	Header          *quic.PublicHeader
+	EncryptionLevel protocol.EncryptionLevel
+	// Frames is empty for version negotiation packets and public resets
+	Frames []frames.Frame
+}
+
+type connection struct {
+	id         protocol.ConnectionID
+	version    protocol.VersionNumber
+	serverAddr string
+
+	largestPacketNumber map[protocol.Perspective]protocol.PacketNumber
+}
+
+// sentBy returns the perspective of the sender of a datagram
+func (c *connection) sentBy(datagram *observer.Datagram) protocol.Perspective {
+	if datagram.Src.String() == c.serverAddr {
+		return protocol.PerspectiveServer
+	}
+	return protocol.PerspectiveClient
+}
+
+// A Decoder decrypts the packets of all connections in a key log
+// Datagrams have to be passed in the order they were captured.
+type Decoder struct {
+	// the AEADs to open the packets sent by a perspective, ordered by descending encryption level
+	openers map[protocol.ConnectionID]map[protocol.Perspective][]opener
+
+	connections map[protocol.ConnectionID]*connection
+	// gQUIC servers can omit the connection ID, so connections are also looked up by the addresses of the last packet with a connection ID
+	addrConnections map[string]*connection
+}
+
+type opener struct {
+	encryptionLevel protocol.EncryptionLevel
+	aead            crypto.AEAD
+}
+
+// NewDecoder creates a Decoder for the connections in a key log
+func NewDecoder(keyLog io.Reader) (*Decoder, error) {
+	entries, err := crypto.ParseKeyLog(keyLog)
+	if err != nil {
+		return nil, err
+	}
+	d := &Decoder{
+		openers:         make(map[protocol.ConnectionID]map[protocol.Perspective][]opener),
+		connections:     make(map[protocol.ConnectionID]*connection),
+		addrConnections: make(map[string]*connection),
+	}
+	for _, e := range entries {
+		// a server opens the packets sent by the client, and vice versa
+		clientOpener, err := crypto.NewAEADAESGCM(e.ClientKey, e.ServerKey, e.ClientIV, e.ServerIV)
+		if err != nil {
+			return nil, err
+		}
+		serverOpener, err := crypto.NewAEADAESGCM(e.ServerKey, e.ClientKey, e.ServerIV, e.ClientIV)
+		if err != nil {
+			return nil, err
+		}
+		openers, ok := d.openers[e.ConnectionID]
+		if !ok {
+			openers = make(map[protocol.Perspective][]opener)
+			d.openers[e.ConnectionID] = openers
+		}
+		openers[protocol.PerspectiveClient] = insertOpener(openers[protocol.PerspectiveClient], opener{e.EncryptionLevel, clientOpener})
+		openers[protocol.PerspectiveServer] = insertOpener(openers[protocol.PerspectiveServer], opener{e.EncryptionLevel, serverOpener})
+	}
+	return d, nil
+}
+
+// insertOpener keeps the openers ordered by descending encryption level
+// If the key log contains multiple entries for the same encryption level, the last one is used.
+func insertOpener(openers []opener, o opener) []opener {
+	for i, other := range openers {
+		if other.encryptionLevel == o.encryptionLevel {
+			openers[i] = o
+			return openers
+		}
+		if other.encryptionLevel < o.encryptionLevel {
+			return append(openers[:i], append([]opener{o}, openers[i:]...)...)
+		}
+	}
+	return append(openers, o)
+}
+
+// Decode decrypts a datagram
+// The client of a connection is the endpoint that sent the first datagram seen.
+func (d *Decoder) Decode(datagram *observer.Datagram) (*Packet, error) {
+	data := datagram.Payload
+	var plusHeader *observer.PLUSHeader
+	if quic.IsPLUSPacket(data) {
+		var err error
+		plusHeader, data, err = observer.ParsePLUSHeader(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the perspective of the sender is needed to parse the public header
+	// It is only known if the addresses belong to a connection, the first datagram of a connection is sent by the client.
+	sentBy := protocol.PerspectiveClient
+	addrConn, ok := d.addrConnections[addrKey(datagram.Src, datagram.Dst)]
+	if ok {
+		sentBy = addrConn.sentBy(datagram)
+	}
+	r := bytes.NewReader(data)
+	hdr, err := quic.ParsePublicHeader(r, sentBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// connections are identified by their connection ID, the addresses are only used for packets that omit it
+	// Otherwise, a new connection using the same addresses would be decrypted with the keys of the old connection.
+	var c *connection
+	if hdr.TruncateConnectionID {
+		// only servers omit the connection ID, so the addresses belong to a connection
+		c = addrConn
+	} else {
+		c, ok = d.connections[hdr.ConnectionID]
+		if !ok {
+			c = &connection{
+				id:                  hdr.ConnectionID,
+				serverAddr:          datagram.Dst.String(),
+				largestPacketNumber: make(map[protocol.Perspective]protocol.PacketNumber),
+			}
+			d.connections[hdr.ConnectionID] = c
+		}
+		d.addrConnections[addrKey(datagram.Src, datagram.Dst)] = c
+		d.addrConnections[addrKey(datagram.Dst, datagram.Src)] = c
+	}
+
+	if connSentBy := c.sentBy(datagram); connSentBy != sentBy {
+		// the addresses didn't belong to this connection before
+		sentBy = connSentBy
+		r = bytes.NewReader(data)
+		if hdr, err = quic.ParsePublicHeader(r, sentBy); err != nil {
+			return nil, err
+		}
+	}
+	hdr.Raw = data[:len(data)-r.Len()]
+	p := &Packet{
+		Time:         datagram.Time,
+		SentBy:       sentBy,
+		ConnectionID: c.id,
+		PLUSHeader:   plusHeader,
+		Header:       hdr,
+	}
+	if sentBy == protocol.PerspectiveClient && hdr.VersionFlag {
+		c.version = hdr.VersionNumber
+	}
+	// public resets and version negotiation packets are not encrypted
+	if hdr.ResetFlag || (sentBy == protocol.PerspectiveServer && hdr.VersionFlag) {
+		return p, nil
+	}
+
+	hdr.PacketNumber = protocol.InferPacketNumber(hdr.PacketNumberLen, c.largestPacketNumber[sentBy], hdr.PacketNumber)
+	payload, encLevel, err := d.open(c, sentBy, data[len(hdr.Raw):], hdr)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.PacketNumber > c.largestPacketNumber[sentBy] {
+		c.largestPacketNumber[sentBy] = hdr.PacketNumber
+	}
+	p.EncryptionLevel = encLevel
+	p.Frames, err = parseFrames(payload, hdr, c.version)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// open tries to decrypt a packet with all keys of a connection, starting with the forward secure keys
+func (d *Decoder) open(c *connection, sentBy protocol.Perspective, data []byte, hdr *quic.PublicHeader) ([]byte, protocol.EncryptionLevel, error) {
+	for _, o := range d.openers[c.id][sentBy] {
+		if payload, err := o.aead.Open(nil, data, hdr.PacketNumber, hdr.Raw); err == nil {
+			return payload, o.encryptionLevel, nil
+		}
+	}
+	// the null AEAD of the receiver
+	receiver := protocol.PerspectiveServer
+	if sentBy == protocol.PerspectiveServer {
+		receiver = protocol.PerspectiveClient
+	}
+	if payload, err := crypto.NewNullAEAD(receiver, c.version).Open(nil, data, hdr.PacketNumber, hdr.Raw); err == nil {
+		return payload, protocol.EncryptionUnencrypted, nil
+	}
+	if _, ok := d.openers[c.id]; !ok {
+		return nil, protocol.EncryptionUnspecified, fmt.Errorf("decoder: no keys for connection %x", c.id)
+	}
+	return nil, protocol.EncryptionUnspecified, fmt.Errorf("decoder: failed to decrypt packet 0x%x of connection %x", hdr.PacketNumber, c.id)
+}
+
+func addrKey(src, dst net.Addr) string {
+	return src.String() + "-" + dst.String()
+}
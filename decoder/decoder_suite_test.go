@@ -0,0 +1,13 @@
+package decoder
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Decoder Suite")
+}
@@ -0,0 +1,243 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/crypto"
+	"github.com/lucas-clemente/quic-go/frames"
+	"github.com/lucas-clemente/quic-go/observer"
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Decoder", func() {
+	const (
+		connID  = protocol.ConnectionID(0xdecafbad)
+		version = protocol.Version37
+	)
+
+	var (
+		decoder    *Decoder
+		clientAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1234}
+		serverAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 443}
+
+		secureKeys = crypto.KeyLogEntry{
+			ConnectionID:    connID,
+			EncryptionLevel: protocol.EncryptionSecure,
+			ClientKey:       bytes.Repeat([]byte{0x1}, 16),
+			ClientIV:        bytes.Repeat([]byte{0x2}, 4),
+			ServerKey:       bytes.Repeat([]byte{0x3}, 16),
+			ServerIV:        bytes.Repeat([]byte{0x4}, 4),
+		}
+		forwardSecureKeys = crypto.KeyLogEntry{
+			ConnectionID:    connID,
+			EncryptionLevel: protocol.EncryptionForwardSecure,
+			ClientKey:       bytes.Repeat([]byte{0x5}, 16),
+			ClientIV:        bytes.Repeat([]byte{0x6}, 4),
+			ServerKey:       bytes.Repeat([]byte{0x7}, 16),
+			ServerIV:        bytes.Repeat([]byte{0x8}, 4),
+		}
+	)
+
+	// sealer returns the AEAD that a perspective uses to seal its packets
+	sealer := func(e crypto.KeyLogEntry, p protocol.Perspective) crypto.AEAD {
+		var aead crypto.AEAD
+		var err error
+		if p == protocol.PerspectiveClient {
+			aead, err = crypto.NewAEADAESGCM(e.ServerKey, e.ClientKey, e.ServerIV, e.ClientIV)
+		} else {
+			aead, err = crypto.NewAEADAESGCM(e.ClientKey, e.ServerKey, e.ClientIV, e.ServerIV)
+		}
+		Expect(err).ToNot(HaveOccurred())
+		return aead
+	}
+
+	packet := func(hdr *quic.PublicHeader, sentBy protocol.Perspective, aead crypto.AEAD, fs ...frames.Frame) []byte {
+		b := &bytes.Buffer{}
+		Expect(hdr.Write(b, version, sentBy)).To(Succeed())
+		raw := b.Bytes()
+		payload := &bytes.Buffer{}
+		for _, f := range fs {
+			Expect(f.Write(payload, version)).To(Succeed())
+		}
+		return append(raw, aead.Seal(nil, payload.Bytes(), hdr.PacketNumber, raw)...)
+	}
+
+	datagram := func(sentBy protocol.Perspective, data []byte) *observer.Datagram {
+		d := &observer.Datagram{Time: time.Unix(1500000000, 0), Src: clientAddr, Dst: serverAddr, Payload: data}
+		if sentBy == protocol.PerspectiveServer {
+			d.Src, d.Dst = serverAddr, clientAddr
+		}
+		return d
+	}
+
+	clientHello := func() *observer.Datagram {
+		hdr := &quic.PublicHeader{
+			ConnectionID:    connID,
+			VersionFlag:     true,
+			VersionNumber:   version,
+			PacketNumber:    1,
+			PacketNumberLen: protocol.PacketNumberLen1,
+		}
+		null := crypto.NewNullAEAD(protocol.PerspectiveClient, version)
+		return datagram(protocol.PerspectiveClient, packet(hdr, protocol.PerspectiveClient, null, &frames.StreamFrame{StreamID: 1, Data: []byte("CHLO")}))
+	}
+
+	BeforeEach(func() {
+		keyLog := &bytes.Buffer{}
+		Expect(crypto.WriteKeyLog(keyLog, &secureKeys)).To(Succeed())
+		Expect(crypto.WriteKeyLog(keyLog, &forwardSecureKeys)).To(Succeed())
+		var err error
+		decoder, err = NewDecoder(keyLog)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("decodes unencrypted packets", func() {
+		p, err := decoder.Decode(clientHello())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Time).To(Equal(time.Unix(1500000000, 0)))
+		Expect(p.SentBy).To(Equal(protocol.PerspectiveClient))
+		Expect(p.ConnectionID).To(Equal(connID))
+		Expect(p.EncryptionLevel).To(Equal(protocol.EncryptionUnencrypted))
+		Expect(p.Header.VersionNumber).To(Equal(version))
+		Expect(p.Frames).To(Equal([]frames.Frame{&frames.StreamFrame{StreamID: 1, Data: []byte("CHLO")}}))
+	})
+
+	It("decrypts packets sent by the client", func() {
+		_, err := decoder.Decode(clientHello())
+		Expect(err).ToNot(HaveOccurred())
+		hdr := &quic.PublicHeader{ConnectionID: connID, PacketNumber: 2, PacketNumberLen: protocol.PacketNumberLen2}
+		data := packet(hdr, protocol.PerspectiveClient, sealer(forwardSecureKeys, protocol.PerspectiveClient), &frames.PingFrame{})
+		p, err := decoder.Decode(datagram(protocol.PerspectiveClient, data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.SentBy).To(Equal(protocol.PerspectiveClient))
+		Expect(p.EncryptionLevel).To(Equal(protocol.EncryptionForwardSecure))
+		Expect(p.Header.PacketNumber).To(Equal(protocol.PacketNumber(2)))
+		Expect(p.Frames).To(Equal([]frames.Frame{&frames.PingFrame{}}))
+	})
+
+	It("decrypts packets sent by the server, with a truncated connection ID", func() {
+		_, err := decoder.Decode(clientHello())
+		Expect(err).ToNot(HaveOccurred())
+		hdr := &quic.PublicHeader{
+			TruncateConnectionID: true,
+			DiversificationNonce: bytes.Repeat([]byte{'d'}, 32),
+			PacketNumber:         1,
+			PacketNumberLen:      protocol.PacketNumberLen1,
+		}
+		data := packet(hdr, protocol.PerspectiveServer, sealer(secureKeys, protocol.PerspectiveServer), &frames.PingFrame{})
+		p, err := decoder.Decode(datagram(protocol.PerspectiveServer, data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.SentBy).To(Equal(protocol.PerspectiveServer))
+		Expect(p.ConnectionID).To(Equal(connID))
+		Expect(p.EncryptionLevel).To(Equal(protocol.EncryptionSecure))
+		Expect(p.Frames).To(Equal([]frames.Frame{&frames.PingFrame{}}))
+	})
+
+	It("infers the full packet number", func() {
+		_, err := decoder.Decode(clientHello())
+		Expect(err).ToNot(HaveOccurred())
+		aead := sealer(forwardSecureKeys, protocol.PerspectiveClient)
+		hdr := &quic.PublicHeader{ConnectionID: connID, PacketNumber: 0x1ff, PacketNumberLen: protocol.PacketNumberLen2}
+		_, err = decoder.Decode(datagram(protocol.PerspectiveClient, packet(hdr, protocol.PerspectiveClient, aead, &frames.PingFrame{})))
+		Expect(err).ToNot(HaveOccurred())
+		hdr = &quic.PublicHeader{ConnectionID: connID, PacketNumber: 0x200, PacketNumberLen: protocol.PacketNumberLen1}
+		data := packet(hdr, protocol.PerspectiveClient, aead, &frames.PingFrame{})
+		Expect(data[9]).To(BeZero()) // only the lowest byte of the packet number is sent
+		p, err := decoder.Decode(datagram(protocol.PerspectiveClient, data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Header.PacketNumber).To(Equal(protocol.PacketNumber(0x200)))
+	})
+
+	It("decodes packets encapsulated in PLUS", func() {
+		plusHeader := make([]byte, 20)
+		binary.BigEndian.PutUint32(plusHeader, 0xd8007ff<<4)
+		binary.BigEndian.PutUint64(plusHeader[4:], 0x1337)
+		d := clientHello()
+		d.Payload = append(plusHeader, d.Payload...)
+		p, err := decoder.Decode(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.PLUSHeader).ToNot(BeNil())
+		Expect(p.PLUSHeader.CAT).To(Equal(uint64(0x1337)))
+		Expect(p.Frames).To(HaveLen(1))
+	})
+
+	It("decodes version negotiation packets", func() {
+		_, err := decoder.Decode(clientHello())
+		Expect(err).ToNot(HaveOccurred())
+		b := &bytes.Buffer{}
+		hdr := &quic.PublicHeader{ConnectionID: connID, VersionFlag: true}
+		Expect(hdr.Write(b, version, protocol.PerspectiveServer)).To(Succeed())
+		utils.WriteUint32(b, protocol.VersionNumberToTag(protocol.Version36))
+		p, err := decoder.Decode(datagram(protocol.PerspectiveServer, b.Bytes()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Header.SupportedVersions).To(Equal([]protocol.VersionNumber{protocol.Version36}))
+		Expect(p.Frames).To(BeEmpty())
+	})
+
+	It("separates connections that use the same addresses by connection ID", func() {
+		_, err := decoder.Decode(clientHello())
+		Expect(err).ToNot(HaveOccurred())
+		// the client reuses its address for a new connection
+		d := clientHello()
+		hdr := &quic.PublicHeader{
+			ConnectionID:    0x1337,
+			VersionFlag:     true,
+			VersionNumber:   version,
+			PacketNumber:    1,
+			PacketNumberLen: protocol.PacketNumberLen1,
+		}
+		d.Payload = packet(hdr, protocol.PerspectiveClient, crypto.NewNullAEAD(protocol.PerspectiveClient, version), &frames.PingFrame{})
+		p, err := decoder.Decode(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.ConnectionID).To(Equal(protocol.ConnectionID(0x1337)))
+		// a late packet of the first connection is still decrypted with its keys
+		hdr = &quic.PublicHeader{ConnectionID: connID, PacketNumber: 2, PacketNumberLen: protocol.PacketNumberLen1}
+		data := packet(hdr, protocol.PerspectiveClient, sealer(forwardSecureKeys, protocol.PerspectiveClient), &frames.PingFrame{})
+		p, err = decoder.Decode(datagram(protocol.PerspectiveClient, data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.ConnectionID).To(Equal(connID))
+		Expect(p.EncryptionLevel).To(Equal(protocol.EncryptionForwardSecure))
+	})
+
+	It("errors on packets without a connection ID sent between unknown addresses", func() {
+		hdr := &quic.PublicHeader{
+			TruncateConnectionID: true,
+			PacketNumber:         1,
+			PacketNumberLen:      protocol.PacketNumberLen1,
+		}
+		data := packet(hdr, protocol.PerspectiveServer, sealer(forwardSecureKeys, protocol.PerspectiveServer), &frames.PingFrame{})
+		_, err := decoder.Decode(datagram(protocol.PerspectiveServer, data))
+		Expect(err).To(MatchError(ContainSubstring("truncated ConnectionID")))
+	})
+
+	It("errors when it doesn't have the keys for a connection", func() {
+		hdr := &quic.PublicHeader{ConnectionID: 0x1337, PacketNumber: 1, PacketNumberLen: protocol.PacketNumberLen1}
+		data := packet(hdr, protocol.PerspectiveClient, sealer(forwardSecureKeys, protocol.PerspectiveClient), &frames.PingFrame{})
+		_, err := decoder.Decode(datagram(protocol.PerspectiveClient, data))
+		Expect(err).To(MatchError("decoder: no keys for connection 1337"))
+	})
+
+	It("errors when the packet can't be decrypted", func() {
+		_, err := decoder.Decode(clientHello())
+		Expect(err).ToNot(HaveOccurred())
+		hdr := &quic.PublicHeader{ConnectionID: connID, PacketNumber: 2, PacketNumberLen: protocol.PacketNumberLen1}
+		// sealed with the server's keys
+		data := packet(hdr, protocol.PerspectiveClient, sealer(forwardSecureKeys, protocol.PerspectiveServer), &frames.PingFrame{})
+		_, err = decoder.Decode(datagram(protocol.PerspectiveClient, data))
+		Expect(err).To(MatchError("decoder: failed to decrypt packet 0x2 of connection decafbad"))
+	})
+
+	It("errors on invalid keys", func() {
+		_, err := NewDecoder(strings.NewReader("QUIC_SECURE 0 01 02 03 04\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,57 @@
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/frames"
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+// parseFrames parses the frames of a decrypted packet, skipping PADDING frames
+func parseFrames(payload []byte, hdr *quic.PublicHeader, version protocol.VersionNumber) ([]frames.Frame, error) {
+	r := bytes.NewReader(payload)
+	var fs []frames.Frame
+	for r.Len() > 0 {
+		typeByte, _ := r.ReadByte()
+		if typeByte == 0x0 { // PADDING frame
+			continue
+		}
+		r.UnreadByte()
+
+		var frame frames.Frame
+		var err error
+		if typeByte&0x80 == 0x80 {
+			frame, err = frames.ParseStreamFrame(r)
+		} else if typeByte&0xc0 == 0x40 {
+			frame, err = frames.ParseAckFrame(r, version)
+		} else {
+			switch typeByte {
+			case 0x01:
+				frame, err = frames.ParseRstStreamFrame(r)
+			case 0x02:
+				frame, err = frames.ParseConnectionCloseFrame(r)
+			case 0x03:
+				frame, err = frames.ParseGoawayFrame(r)
+			case 0x04:
+				frame, err = frames.ParseWindowUpdateFrame(r)
+			case 0x05:
+				frame, err = frames.ParseBlockedFrame(r)
+			case 0x06:
+				frame, err = frames.ParseStopWaitingFrame(r, hdr.PacketNumber, hdr.PacketNumberLen, version)
+			case 0x07:
+				frame, err = frames.ParsePingFrame(r)
+			case 0x08:
+				frame, err = frames.ParsePLUSFeedbackFrame(r)
+			default:
+				err = fmt.Errorf("unknown type byte 0x%x", typeByte)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoder: invalid frame in packet 0x%x: %s", hdr.PacketNumber, err.Error())
+		}
+		fs = append(fs, frame)
+	}
+	return fs, nil
+}
@@ -15,6 +15,7 @@ import (
 type flowControlManager struct {
 	connectionParameters handshake.ConnectionParametersManager
 	rttStats             *congestion.RTTStats
+	logger               utils.Logger
 
 	streamFlowController map[protocol.StreamID]*flowController
 	connFlowController   *flowController
@@ -26,12 +27,13 @@ var _ FlowControlManager = &flowControlManager{}
 var errMapAccess = errors.New("Error accessing the flowController map.")
 
 // NewFlowControlManager creates a new flow control manager
-func NewFlowControlManager(connectionParameters handshake.ConnectionParametersManager, rttStats *congestion.RTTStats) FlowControlManager {
+func NewFlowControlManager(connectionParameters handshake.ConnectionParametersManager, rttStats *congestion.RTTStats, logger utils.Logger) FlowControlManager {
 	return &flowControlManager{
 		connectionParameters: connectionParameters,
 		rttStats:             rttStats,
+		logger:               logger,
 		streamFlowController: make(map[protocol.StreamID]*flowController),
-		connFlowController:   newFlowController(0, false, connectionParameters, rttStats),
+		connFlowController:   newFlowController(0, false, connectionParameters, rttStats, logger),
 	}
 }
 
@@ -45,7 +47,7 @@ func (f *flowControlManager) NewStream(streamID protocol.StreamID, contributesTo
 		return
 	}
 
-	f.streamFlowController[streamID] = newFlowController(streamID, contributesToConnection, f.connectionParameters, f.rttStats)
+	f.streamFlowController[streamID] = newFlowController(streamID, contributesToConnection, f.connectionParameters, f.rttStats, f.logger)
 }
 
 // RemoveStream removes a closed stream from flow control
@@ -226,6 +228,17 @@ func (f *flowControlManager) UpdateWindow(streamID protocol.StreamID, offset pro
 	return fc.UpdateSendWindow(offset), nil
 }
 
+func (f *flowControlManager) GetStatistics() (Statistics, map[protocol.StreamID]Statistics) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	streams := make(map[protocol.StreamID]Statistics, len(f.streamFlowController))
+	for id, fc := range f.streamFlowController {
+		streams[id] = fc.getStatistics()
+	}
+	return f.connFlowController.getStatistics(), streams
+}
+
 func (f *flowControlManager) getFlowController(streamID protocol.StreamID) (*flowController, error) {
 	streamFlowController, ok := f.streamFlowController[streamID]
 	if !ok {
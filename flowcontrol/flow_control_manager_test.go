@@ -7,6 +7,7 @@ import (
 	"github.com/lucas-clemente/quic-go/handshake"
 	"github.com/lucas-clemente/quic-go/protocol"
 	"github.com/lucas-clemente/quic-go/qerr"
+	"github.com/lucas-clemente/quic-go/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -22,7 +23,7 @@ var _ = Describe("Flow Control Manager", func() {
 			maxReceiveStreamFlowControlWindow:     9999999,
 			maxReceiveConnectionFlowControlWindow: 9999999,
 		}
-		fcm = NewFlowControlManager(cpm, &congestion.RTTStats{}).(*flowControlManager)
+		fcm = NewFlowControlManager(cpm, &congestion.RTTStats{}, utils.DefaultLogger).(*flowControlManager)
 	})
 
 	It("creates a connection level flow controller", func() {
@@ -322,4 +323,24 @@ var _ = Describe("Flow Control Manager", func() {
 			})
 		})
 	})
+	It("gets the statistics", func() {
+		fcm.NewStream(5, true)
+		fcm.NewStream(7, false)
+		err := fcm.UpdateHighestReceived(5, 50)
+		Expect(err).ToNot(HaveOccurred())
+		err = fcm.AddBytesSent(5, 100)
+		Expect(err).ToNot(HaveOccurred())
+		err = fcm.AddBytesSent(7, 30)
+		Expect(err).ToNot(HaveOccurred())
+		conn, streams := fcm.GetStatistics()
+		Expect(conn.BytesSent).To(Equal(protocol.ByteCount(100)))
+		Expect(conn.BytesReceived).To(Equal(protocol.ByteCount(50)))
+		Expect(streams).To(HaveLen(2))
+		Expect(streams[5].BytesSent).To(Equal(protocol.ByteCount(100)))
+		Expect(streams[5].BytesReceived).To(Equal(protocol.ByteCount(50)))
+		Expect(streams[7].BytesSent).To(Equal(protocol.ByteCount(30)))
+		fcm.RemoveStream(7)
+		_, streams = fcm.GetStatistics()
+		Expect(streams).ToNot(HaveKey(protocol.StreamID(7)))
+	})
 })
@@ -22,23 +22,30 @@ type flowController struct {
 
 	lastWindowUpdateTime time.Time
 
+	// the time the send window was used up, zero if the send window is open
+	blockedSince time.Time
+	blockedTime  time.Duration
+
 	bytesRead                 protocol.ByteCount
 	highestReceived           protocol.ByteCount
 	receiveWindow             protocol.ByteCount
 	receiveWindowIncrement    protocol.ByteCount
 	maxReceiveWindowIncrement protocol.ByteCount
+
+	logger utils.Logger
 }
 
 // ErrReceivedSmallerByteOffset occurs if the ByteOffset received is smaller than a ByteOffset that was set previously
 var ErrReceivedSmallerByteOffset = errors.New("Received a smaller byte offset")
 
 // newFlowController gets a new flow controller
-func newFlowController(streamID protocol.StreamID, contributesToConnection bool, connectionParameters handshake.ConnectionParametersManager, rttStats *congestion.RTTStats) *flowController {
+func newFlowController(streamID protocol.StreamID, contributesToConnection bool, connectionParameters handshake.ConnectionParametersManager, rttStats *congestion.RTTStats, logger utils.Logger) *flowController {
 	fc := flowController{
 		streamID:                streamID,
 		contributesToConnection: contributesToConnection,
 		connectionParameters:    connectionParameters,
 		rttStats:                rttStats,
+		logger:                  logger,
 	}
 
 	if streamID == 0 {
@@ -70,6 +77,9 @@ func (c *flowController) getSendWindow() protocol.ByteCount {
 
 func (c *flowController) AddBytesSent(n protocol.ByteCount) {
 	c.bytesSent += n
+	if c.blockedSince.IsZero() && c.SendWindowSize() == 0 {
+		c.blockedSince = time.Now()
+	}
 }
 
 // UpdateSendWindow should be called after receiving a WindowUpdateFrame
@@ -77,11 +87,31 @@ func (c *flowController) AddBytesSent(n protocol.ByteCount) {
 func (c *flowController) UpdateSendWindow(newOffset protocol.ByteCount) bool {
 	if newOffset > c.sendWindow {
 		c.sendWindow = newOffset
+		if !c.blockedSince.IsZero() && c.SendWindowSize() > 0 {
+			c.blockedTime += time.Since(c.blockedSince)
+			c.blockedSince = time.Time{}
+		}
 		return true
 	}
 	return false
 }
 
+// BlockedTime is the total time the send window was used up
+func (c *flowController) BlockedTime() time.Duration {
+	if c.blockedSince.IsZero() {
+		return c.blockedTime
+	}
+	return c.blockedTime + time.Since(c.blockedSince)
+}
+
+func (c *flowController) getStatistics() Statistics {
+	return Statistics{
+		BytesSent:     c.bytesSent,
+		BytesReceived: c.highestReceived,
+		BlockedTime:   c.BlockedTime(),
+	}
+}
+
 func (c *flowController) SendWindowSize() protocol.ByteCount {
 	sendWindow := c.getSendWindow()
 
@@ -176,9 +206,9 @@ func (c *flowController) maybeAdjustWindowIncrement() {
 	if oldWindowSize < c.receiveWindowIncrement {
 		newWindowSize := c.receiveWindowIncrement / (1 << 10)
 		if c.streamID == 0 {
-			utils.Debugf("Increasing receive flow control window for the connection to %d kB", newWindowSize)
+			c.logger.Debugf("Increasing receive flow control window for the connection to %d kB", newWindowSize)
 		} else {
-			utils.Debugf("Increasing receive flow control window increment for stream %d to %d kB", c.streamID, newWindowSize)
+			c.logger.Debugf("Increasing receive flow control window increment for stream %d to %d kB", c.streamID, newWindowSize)
 		}
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/handshake"
 	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -56,7 +57,7 @@ var _ = Describe("Flow controller", func() {
 	var controller *flowController
 
 	BeforeEach(func() {
-		controller = &flowController{}
+		controller = &flowController{logger: utils.DefaultLogger}
 		controller.rttStats = &congestion.RTTStats{}
 	})
 
@@ -77,33 +78,33 @@ var _ = Describe("Flow controller", func() {
 		})
 
 		It("reads the stream send and receive windows when acting as stream-level flow controller", func() {
-			fc := newFlowController(5, true, cpm, rttStats)
+			fc := newFlowController(5, true, cpm, rttStats, utils.DefaultLogger)
 			Expect(fc.streamID).To(Equal(protocol.StreamID(5)))
 			Expect(fc.receiveWindow).To(Equal(protocol.ByteCount(2000)))
 			Expect(fc.maxReceiveWindowIncrement).To(Equal(cpm.GetMaxReceiveStreamFlowControlWindow()))
 		})
 
 		It("reads the stream send and receive windows when acting as connection-level flow controller", func() {
-			fc := newFlowController(0, false, cpm, rttStats)
+			fc := newFlowController(0, false, cpm, rttStats, utils.DefaultLogger)
 			Expect(fc.streamID).To(Equal(protocol.StreamID(0)))
 			Expect(fc.receiveWindow).To(Equal(protocol.ByteCount(4000)))
 			Expect(fc.maxReceiveWindowIncrement).To(Equal(cpm.GetMaxReceiveConnectionFlowControlWindow()))
 		})
 
 		It("does not set the stream flow control windows for sending", func() {
-			fc := newFlowController(5, true, cpm, rttStats)
+			fc := newFlowController(5, true, cpm, rttStats, utils.DefaultLogger)
 			Expect(fc.sendWindow).To(BeZero())
 		})
 
 		It("does not set the connection flow control windows for sending", func() {
-			fc := newFlowController(0, false, cpm, rttStats)
+			fc := newFlowController(0, false, cpm, rttStats, utils.DefaultLogger)
 			Expect(fc.sendWindow).To(BeZero())
 		})
 
 		It("says if it contributes to connection-level flow control", func() {
-			fc := newFlowController(1, false, cpm, rttStats)
+			fc := newFlowController(1, false, cpm, rttStats, utils.DefaultLogger)
 			Expect(fc.ContributesToConnection()).To(BeFalse())
-			fc = newFlowController(5, true, cpm, rttStats)
+			fc = newFlowController(5, true, cpm, rttStats, utils.DefaultLogger)
 			Expect(fc.ContributesToConnection()).To(BeTrue())
 		})
 	})
@@ -183,6 +184,36 @@ var _ = Describe("Flow controller", func() {
 			cpm.sendConnectionFlowControlWindow = 9000
 			Expect(controller.getSendWindow()).To(Equal(protocol.ByteCount(7000)))
 		})
+
+		Context("blocked time", func() {
+			BeforeEach(func() {
+				controller.sendWindow = 10
+			})
+
+			It("is not blocked while the window is open", func() {
+				controller.AddBytesSent(9)
+				Expect(controller.blockedSince.IsZero()).To(BeTrue())
+				Expect(controller.BlockedTime()).To(BeZero())
+			})
+
+			It("includes the time since the window was used up", func() {
+				controller.AddBytesSent(10)
+				Expect(controller.blockedSince).ToNot(BeZero())
+				controller.blockedSince = controller.blockedSince.Add(-time.Second)
+				Expect(controller.BlockedTime()).To(BeNumerically(">=", time.Second))
+			})
+
+			It("accumulates the blocked time when the window is opened", func() {
+				controller.AddBytesSent(10)
+				controller.blockedSince = controller.blockedSince.Add(-time.Second)
+				Expect(controller.UpdateSendWindow(20)).To(BeTrue())
+				Expect(controller.blockedSince.IsZero()).To(BeTrue())
+				blocked := controller.BlockedTime()
+				Expect(blocked).To(BeNumerically("~", time.Second, 100*time.Millisecond))
+				controller.AddBytesSent(5)
+				Expect(controller.BlockedTime()).To(Equal(blocked))
+			})
+		})
 	})
 
 	Context("receive flow control", func() {
@@ -1,6 +1,10 @@
 package flowcontrol
 
-import "github.com/lucas-clemente/quic-go/protocol"
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+)
 
 // WindowUpdate provides the data for WindowUpdateFrames.
 type WindowUpdate struct {
@@ -23,4 +27,14 @@ type FlowControlManager interface {
 	SendWindowSize(streamID protocol.StreamID) (protocol.ByteCount, error)
 	RemainingConnectionWindowSize() protocol.ByteCount
 	UpdateWindow(streamID protocol.StreamID, offset protocol.ByteCount) (bool, error)
+	// GetStatistics returns the statistics of the connection, and of all streams that were not removed yet
+	GetStatistics() (connection Statistics, streams map[protocol.StreamID]Statistics)
+}
+
+// Statistics are the flow control statistics of a stream or the connection
+type Statistics struct {
+	BytesSent     protocol.ByteCount
+	BytesReceived protocol.ByteCount
+	// BlockedTime is the time sending was blocked because the send window was used up
+	BlockedTime time.Duration
 }
@@ -30,17 +30,17 @@ var _ = Describe("Frame logging", func() {
 
 	It("doesn't log when debug is disabled", func() {
 		utils.SetLogLevel(utils.LogLevelInfo)
-		LogFrame(&RstStreamFrame{}, true)
+		LogFrame(utils.DefaultLogger, &RstStreamFrame{}, true)
 		Expect(buf.Len()).To(BeZero())
 	})
 
 	It("logs sent frames", func() {
-		LogFrame(&RstStreamFrame{}, true)
+		LogFrame(utils.DefaultLogger, &RstStreamFrame{}, true)
 		Expect(string(buf.Bytes())).To(Equal("\t-> &frames.RstStreamFrame{StreamID:0x0, ErrorCode:0x0, ByteOffset:0x0}\n"))
 	})
 
 	It("logs received frames", func() {
-		LogFrame(&RstStreamFrame{}, false)
+		LogFrame(utils.DefaultLogger, &RstStreamFrame{}, false)
 		Expect(string(buf.Bytes())).To(Equal("\t<- &frames.RstStreamFrame{StreamID:0x0, ErrorCode:0x0, ByteOffset:0x0}\n"))
 	})
 
@@ -50,7 +50,7 @@ var _ = Describe("Frame logging", func() {
 			Offset:   0x1337,
 			Data:     bytes.Repeat([]byte{'f'}, 0x100),
 		}
-		LogFrame(frame, false)
+		LogFrame(utils.DefaultLogger, frame, false)
 		Expect(string(buf.Bytes())).To(Equal("\t<- &frames.StreamFrame{StreamID: 42, FinBit: false, Offset: 0x1337, Data length: 0x100, Offset + Data length: 0x1437}\n"))
 	})
 
@@ -60,7 +60,7 @@ var _ = Describe("Frame logging", func() {
 			LowestAcked:  0x42,
 			DelayTime:    1 * time.Millisecond,
 		}
-		LogFrame(frame, false)
+		LogFrame(utils.DefaultLogger, frame, false)
 		Expect(string(buf.Bytes())).To(Equal("\t<- &frames.AckFrame{LargestAcked: 0x1337, LowestAcked: 0x42, AckRanges: []frames.AckRange(nil), DelayTime: 1ms}\n"))
 	})
 
@@ -68,7 +68,7 @@ var _ = Describe("Frame logging", func() {
 		frame := &StopWaitingFrame{
 			LeastUnacked: 0x1337,
 		}
-		LogFrame(frame, false)
+		LogFrame(utils.DefaultLogger, frame, false)
 		Expect(string(buf.Bytes())).To(Equal("\t<- &frames.StopWaitingFrame{LeastUnacked: 0x1337}\n"))
 	})
 
@@ -77,7 +77,7 @@ var _ = Describe("Frame logging", func() {
 			LeastUnacked:    0x1337,
 			PacketNumberLen: protocol.PacketNumberLen4,
 		}
-		LogFrame(frame, true)
+		LogFrame(utils.DefaultLogger, frame, true)
 		Expect(string(buf.Bytes())).To(Equal("\t-> &frames.StopWaitingFrame{LeastUnacked: 0x1337, PacketNumberLen: 0x4}\n"))
 	})
 })
@@ -2,92 +2,134 @@ package frames
 
 import (
 	"bytes"
+	"encoding/binary"
+	"io"
 
 	"github.com/lucas-clemente/quic-go/protocol"
-
-	"errors"
+	"github.com/lucas-clemente/quic-go/qerr"
 )
 
-var (
-	errInvalidLenByte = errors.New("PLUSFeedbackFrame: Invalid len byte!")
-	errUnexpectedEndOfData = errors.New("PLUSFeedbackFrame: Unexpected end of data!")
-	errInvalidFrameType = errors.New("PLUSFeedbackFrame: Invalid fame type!")
+const (
 	plusFeedbackFrameType byte = 0x08
+	// the version of the PLUSFeedbackFrame wire format
+	plusFeedbackFrameVersion byte = 0x01
+
+	plusFeedbackFlagFragment      byte = 0x01
+	plusFeedbackFlagMoreFragments byte = 0x02
 )
 
-// A PLUSFeedbackFrame in QUIC
+var errTruncatedPLUSFeedbackFrame = qerr.Error(qerr.InvalidFrameData, "PLUSFeedbackFrame: unexpected end of data")
+
+// A PLUSFeedbackFrame carries PCF feedback that the PLUS layer received from the peer.
+// Feedback that doesn't fit into a single packet is split into multiple fragments.
 type PLUSFeedbackFrame struct {
-	Data []byte
+	// FeedbackID identifies the feedback that a fragment belongs to. It is only sent for fragments.
+	FeedbackID uint64
+	// Offset is the position of Data in the reassembled feedback
+	Offset protocol.ByteCount
+	// MoreFragments is set for all but the last fragment
+	MoreFragments bool
+	Data          []byte
 }
 
-// MinLength of a written frame
-// Returns the FULL length. Not if packet_packer/stream_framer can handle variably sized
-// *control* frames.
-func (f *PLUSFeedbackFrame) MinLength(version protocol.VersionNumber) (protocol.ByteCount, error) {
-	return protocol.ByteCount(2 + len(f.Data)), nil
+// IsFragment says if the frame only contains a part of the feedback
+func (f *PLUSFeedbackFrame) IsFragment() bool {
+	return f.Offset != 0 || f.MoreFragments
 }
 
-// ParsePLUSFeedbackFrame reads a pcf frame
+// ParsePLUSFeedbackFrame reads a PLUSFeedbackFrame
 func ParsePLUSFeedbackFrame(r *bytes.Reader) (*PLUSFeedbackFrame, error) {
 	frame := &PLUSFeedbackFrame{}
 
-	// read type byte
 	typeByte, err := r.ReadByte()
 	if err != nil {
-		return nil, err
+		return nil, errTruncatedPLUSFeedbackFrame
 	}
-
 	if typeByte != plusFeedbackFrameType {
-		return nil, errInvalidFrameType
+		return nil, qerr.Error(qerr.InvalidFrameData, "PLUSFeedbackFrame: invalid frame type")
 	}
 
-	// read the len byte
-	lenByte, err := r.ReadByte()
-
-	
-
-	data := make([]byte, lenByte)
-
-	n, err := r.Read(data)
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, errTruncatedPLUSFeedbackFrame
+	}
+	if version != plusFeedbackFrameVersion {
+		return nil, qerr.Error(qerr.InvalidFrameData, "PLUSFeedbackFrame: unsupported version")
+	}
 
-	if n != int(lenByte) {
-		return nil, errUnexpectedEndOfData
+	flags, err := r.ReadByte()
+	if err != nil {
+		return nil, errTruncatedPLUSFeedbackFrame
+	}
+	if flags&plusFeedbackFlagFragment != 0 {
+		frame.FeedbackID, err = binary.ReadUvarint(r)
+		if err != nil {
+			return nil, errTruncatedPLUSFeedbackFrame
+		}
+		offset, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, errTruncatedPLUSFeedbackFrame
+		}
+		frame.Offset = protocol.ByteCount(offset)
+		frame.MoreFragments = flags&plusFeedbackFlagMoreFragments != 0
+	} else if flags&plusFeedbackFlagMoreFragments != 0 {
+		return nil, qerr.Error(qerr.InvalidFrameData, "PLUSFeedbackFrame: invalid flags")
 	}
 
-	frame.Data = data
+	dataLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errTruncatedPLUSFeedbackFrame
+	}
+	if dataLen > uint64(r.Len()) {
+		return nil, errTruncatedPLUSFeedbackFrame
+	}
+	frame.Data = make([]byte, dataLen)
+	if _, err := io.ReadFull(r, frame.Data); err != nil {
+		return nil, errTruncatedPLUSFeedbackFrame
+	}
 
 	return frame, nil
 }
 
-//Write writes a PLUSFeedbackFrame frame
+// Write writes a PLUSFeedbackFrame
 func (f *PLUSFeedbackFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
-	// Write type byte
-	err := b.WriteByte(plusFeedbackFrameType)
-
-	if err != nil {
-		return err
+	b.WriteByte(plusFeedbackFrameType)
+	b.WriteByte(plusFeedbackFrameVersion)
+
+	var flags byte
+	if f.IsFragment() {
+		flags |= plusFeedbackFlagFragment
+		if f.MoreFragments {
+			flags |= plusFeedbackFlagMoreFragments
+		}
 	}
+	b.WriteByte(flags)
 
-	// Write len byte
-	if len(f.Data) > 255 {
-		return errInvalidLenByte
+	if f.IsFragment() {
+		writeUvarint(b, f.FeedbackID)
+		writeUvarint(b, uint64(f.Offset))
 	}
+	writeUvarint(b, uint64(len(f.Data)))
+	b.Write(f.Data)
+	return nil
+}
 
-	err = b.WriteByte(byte(len(f.Data)))
-
-	if err != nil {
-		return err
-	}
-
-	n, err := b.Write(f.Data)
-
-	if err != nil {
-		return err
+// MinLength of a written frame
+func (f *PLUSFeedbackFrame) MinLength(version protocol.VersionNumber) (protocol.ByteCount, error) {
+	length := 3 + uvarintLen(uint64(len(f.Data))) + len(f.Data)
+	if f.IsFragment() {
+		length += uvarintLen(f.FeedbackID) + uvarintLen(uint64(f.Offset))
 	}
+	return protocol.ByteCount(length), nil
+}
 
-	if n != len(f.Data) {
-		return errors.New("PLUSFeedbackFrame: Write did not write enough bytes!")
-	}
+func writeUvarint(b *bytes.Buffer, i uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], i)
+	b.Write(buf[:n])
+}
 
-	return nil
+func uvarintLen(i uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], i)
 }
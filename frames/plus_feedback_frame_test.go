@@ -3,6 +3,8 @@ package frames
 import (
 	"bytes"
 
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/qerr"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -10,16 +12,62 @@ import (
 var _ = Describe("PLUSFeedbackFrame", func() {
 	Context("when parsing", func() {
 		It("accepts sample frame", func() {
-			b := bytes.NewReader([]byte{0x08, 0x03, 0x01, 0x02, 0x03})
+			b := bytes.NewReader([]byte{0x08, 0x01, 0x00, 0x03, 0x01, 0x02, 0x03})
 			frame, err := ParsePLUSFeedbackFrame(b)
 			Expect(err).ToNot(HaveOccurred())
-			Expect(frame.data).To(Equal([]byte{0x01, 0x02, 0x03}))
+			Expect(frame.IsFragment()).To(BeFalse())
+			Expect(frame.Data).To(Equal([]byte{0x01, 0x02, 0x03}))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("accepts a fragment", func() {
+			b := bytes.NewReader([]byte{0x08, 0x01, 0x03, 0x2a, 0xac, 0x02, 0x03, 0x01, 0x02, 0x03})
+			frame, err := ParsePLUSFeedbackFrame(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.IsFragment()).To(BeTrue())
+			Expect(frame.FeedbackID).To(Equal(uint64(42)))
+			Expect(frame.Offset).To(Equal(protocol.ByteCount(300)))
+			Expect(frame.MoreFragments).To(BeTrue())
+			Expect(frame.Data).To(Equal([]byte{0x01, 0x02, 0x03}))
+		})
+
+		It("accepts the last fragment", func() {
+			b := bytes.NewReader([]byte{0x08, 0x01, 0x01, 0x2a, 0x05, 0x01, 0x01})
+			frame, err := ParsePLUSFeedbackFrame(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.IsFragment()).To(BeTrue())
+			Expect(frame.Offset).To(Equal(protocol.ByteCount(5)))
+			Expect(frame.MoreFragments).To(BeFalse())
+		})
+
+		It("accepts feedback longer than 255 bytes", func() {
+			data := bytes.Repeat([]byte{'f'}, 1000)
+			b := bytes.NewReader(append([]byte{0x08, 0x01, 0x00, 0xe8, 0x07}, data...))
+			frame, err := ParsePLUSFeedbackFrame(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.Data).To(Equal(data))
+		})
+
+		It("rejects unknown versions", func() {
+			b := bytes.NewReader([]byte{0x08, 0x02, 0x00, 0x01, 0x01})
+			_, err := ParsePLUSFeedbackFrame(b)
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidFrameData, "PLUSFeedbackFrame: unsupported version")))
+		})
+
+		It("rejects the MoreFragments flag on non-fragments", func() {
+			b := bytes.NewReader([]byte{0x08, 0x01, 0x02, 0x01, 0x01})
+			_, err := ParsePLUSFeedbackFrame(b)
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidFrameData, "PLUSFeedbackFrame: invalid flags")))
 		})
 
 		It("errors on EOFs", func() {
-			data := []byte{0x05, 0x03, 0x01, 0x02}
+			data := []byte{0x08, 0x01, 0x03, 0x2a, 0xac, 0x02, 0x03, 0x01, 0x02, 0x03}
 			_, err := ParsePLUSFeedbackFrame(bytes.NewReader(data))
-			Expect(err).To(HaveOccurred())
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := ParsePLUSFeedbackFrame(bytes.NewReader(data[0:i]))
+				Expect(err).To(MatchError(errTruncatedPLUSFeedbackFrame))
+			}
 		})
 	})
 
@@ -27,8 +75,46 @@ var _ = Describe("PLUSFeedbackFrame", func() {
 		It("writes a sample frame", func() {
 			b := &bytes.Buffer{}
 			frame := PLUSFeedbackFrame{Data: []byte{0x00, 0x99, 0x88, 0x77}}
-			frame.Write(b, 0)
-			Expect(b.Bytes()).To(Equal([]byte{0x08, 0x04, 0x00, 0x99, 0x88, 0x77}))
+			err := frame.Write(b, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.Bytes()).To(Equal([]byte{0x08, 0x01, 0x00, 0x04, 0x00, 0x99, 0x88, 0x77}))
+		})
+
+		It("writes a fragment", func() {
+			b := &bytes.Buffer{}
+			frame := PLUSFeedbackFrame{
+				FeedbackID:    42,
+				Offset:        300,
+				MoreFragments: true,
+				Data:          []byte{0x01, 0x02, 0x03},
+			}
+			err := frame.Write(b, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.Bytes()).To(Equal([]byte{0x08, 0x01, 0x03, 0x2a, 0xac, 0x02, 0x03, 0x01, 0x02, 0x03}))
+		})
+
+		It("writes feedback longer than 255 bytes", func() {
+			b := &bytes.Buffer{}
+			frame := PLUSFeedbackFrame{Data: bytes.Repeat([]byte{'f'}, 1000)}
+			err := frame.Write(b, 0)
+			Expect(err).ToNot(HaveOccurred())
+			readFrame, err := ParsePLUSFeedbackFrame(bytes.NewReader(b.Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(readFrame.Data).To(Equal(frame.Data))
+		})
+
+		It("has the correct min length", func() {
+			for _, frame := range []*PLUSFeedbackFrame{
+				{Data: []byte{0x01}},
+				{Data: bytes.Repeat([]byte{'f'}, 1000)},
+				{FeedbackID: 1337, Offset: 0x10000, MoreFragments: true, Data: []byte("foobar")},
+				{FeedbackID: 1, Offset: 5, Data: []byte("foobar")},
+			} {
+				b := &bytes.Buffer{}
+				err := frame.Write(b, 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.MinLength(0)).To(BeEquivalentTo(b.Len()))
+			}
 		})
 	})
 })
@@ -40,10 +40,14 @@ type Client struct {
 	requestWriter *requestWriter
 
 	responses map[protocol.StreamID]chan *http.Response
+	// goingAway is set when the server sent a GOAWAY frame. New requests have to use a new connection.
+	goingAway bool
 }
 
 var _ h2quicClient = &Client{}
 
+var errGoingAway = errors.New("h2quic: the server is going away")
+
 // NewClient creates a new client
 func NewClient(t *QuicRoundTripper, tlsConfig *tls.Config, hostname string) *Client {
 	return &Client{
@@ -77,6 +81,10 @@ func NewClientEx(t *QuicRoundTripper, tlsConfig *tls.Config, hostname string, la
 	}
 }
 
+type staticStreamAdder interface {
+	AddStaticStream(protocol.StreamID)
+}
+
 // Dial dials the connection
 func (c *Client) Dial() (err error) {
 	defer func() {
@@ -97,6 +105,10 @@ func (c *Client) Dial() (err error) {
 	if c.headerStream.StreamID() != 3 {
 		return errors.New("h2quic Client BUG: StreamID of Header Stream is not 3")
 	}
+	// the header stream stays open for the lifetime of the session
+	if sess, ok := c.session.(staticStreamAdder); ok {
+		sess.AddStaticStream(c.headerStream.StreamID())
+	}
 	c.requestWriter = newRequestWriter(c.headerStream)
 	go c.handleHeaderStream()
 	return
@@ -114,6 +126,13 @@ func (c *Client) handleHeaderStream() {
 			c.headerErr = qerr.Error(qerr.HeadersStreamDataDecompressFailure, "cannot read frame")
 			break
 		}
+		// the server is going away, but it still sends the responses for the running requests
+		if _, ok := frame.(*http2.GoAwayFrame); ok {
+			c.mutex.Lock()
+			c.goingAway = true
+			c.mutex.Unlock()
+			continue
+		}
 		lastStream = protocol.StreamID(frame.Header().StreamID)
 		hframe, ok := frame.(*http2.HeadersFrame)
 		if !ok {
@@ -169,6 +188,9 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	if c.handshakeErr != nil {
 		return nil, c.handshakeErr
 	}
+	if c.isGoingAway() {
+		return nil, errGoingAway
+	}
 
 	responseChan := make(chan *http.Response)
 	dataStream, err := c.session.OpenStreamSync()
@@ -268,6 +290,13 @@ func (c *Client) writeRequestBody(dataStream quic.Stream, body io.ReadCloser) (e
 	return dataStream.Close()
 }
 
+// isGoingAway returns true if the server sent a GOAWAY frame
+func (c *Client) isGoingAway() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.goingAway
+}
+
 // Close closes the client
 func (c *Client) Close(e error) {
 	_ = c.session.Close(e)
@@ -61,6 +61,15 @@ var _ = Describe("Client", func() {
 		Expect(client.session).To(Equal(session))
 	})
 
+	It("marks the header stream as static", func() {
+		session.streamToOpen = &mockStream{id: 3}
+		client.dialAddr = func(hostname string, conf *quic.Config) (quic.Session, error) {
+			return session, nil
+		}
+		Expect(client.Dial()).To(Succeed())
+		Expect(session.staticStreams).To(Equal([]protocol.StreamID{3}))
+	})
+
 	It("errors when dialing fails", func() {
 		testErr := errors.New("handshake error")
 		client = NewClient(quicTransport, nil, "localhost")
@@ -194,6 +203,14 @@ var _ = Describe("Client", func() {
 			Expect(client.session.(*mockSession).closedWithError).To(MatchError(client.headerErr))
 		})
 
+		It("refuses new requests after receiving a GOAWAY frame", func() {
+			Expect(client.isGoingAway()).To(BeFalse())
+			client.goingAway = true
+			_, err := client.Do(request)
+			Expect(err).To(MatchError(errGoingAway))
+			Expect(headerStream.dataWritten.Len()).To(BeZero())
+		})
+
 		It("blocks if no stream is available", func() {
 			session.blockOpenStreamSync = true
 			var doReturned bool
@@ -439,6 +456,18 @@ var _ = Describe("Client", func() {
 				Expect(rsp.Header).To(HaveKeyWithValue("Cache-Control", []string{"private"}))
 			})
 
+			It("continues reading responses after a GOAWAY frame", func() {
+				h2framer.WriteGoAway(23, http2.ErrCodeNo, nil)
+				data := []byte{0x48, 0x03, 0x33, 0x30, 0x32} // :status: 302
+				headerStream.dataToRead.Write([]byte{0x0, 0x0, byte(len(data)), 0x1, 0x5, 0x0, 0x0, 0x0, 23})
+				headerStream.dataToRead.Write(data)
+				go client.handleHeaderStream()
+				var rsp *http.Response
+				Eventually(client.responses[23]).Should(Receive(&rsp))
+				Expect(rsp.StatusCode).To(Equal(302))
+				Expect(client.isGoingAway()).To(BeTrue())
+			})
+
 			It("errors if the H2 frame is not a HeadersFrame", func() {
 				h2framer.WritePing(true, [8]byte{0, 0, 0, 0, 0, 0, 0, 0})
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"net/http"
 	"sync"
+	"time"
 
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/hpack"
@@ -20,6 +21,8 @@ type mockStream struct {
 	reset        bool
 	closed       bool
 	remoteClosed bool
+	priority     protocol.StreamPriority
+	weight       uint16
 }
 
 func (s *mockStream) Close() error                          { s.closed = true; return nil }
@@ -30,6 +33,15 @@ func (s mockStream) StreamID() protocol.StreamID            { return s.id }
 func (s *mockStream) Read(p []byte) (int, error)  { return s.dataToRead.Read(p) }
 func (s *mockStream) Write(p []byte) (int, error) { return s.dataWritten.Write(p) }
 
+func (s *mockStream) SetDeadline(time.Time) error      { panic("not implemented") }
+func (s *mockStream) SetReadDeadline(time.Time) error  { panic("not implemented") }
+func (s *mockStream) SetWriteDeadline(time.Time) error { panic("not implemented") }
+
+func (s *mockStream) SetPriority(p protocol.StreamPriority, w uint16) {
+	s.priority = p
+	s.weight = w
+}
+
 var _ = Describe("Response Writer", func() {
 	var (
 		w            *responseWriter
@@ -15,6 +15,7 @@ import (
 type h2quicClient interface {
 	Dial() error
 	Do(*http.Request) (*http.Response, error)
+	isGoingAway() bool
 }
 
 // QuicRoundTripper implements the http.RoundTripper interface
@@ -36,6 +37,8 @@ type QuicRoundTripper struct {
 	TLSClientConfig *tls.Config
 
 	clients map[string]h2quicClient
+	// newClient creates the clients. If not set, NewClient or NewClientEx is used.
+	newClient func(hostname string) h2quicClient
 
 	LocalAddr *net.UDPAddr
 }
@@ -83,7 +86,16 @@ func (r *QuicRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 	if err != nil {
 		return nil, err
 	}
-	return client.Do(req)
+	rsp, err := client.Do(req)
+	if err == errGoingAway {
+		// the GOAWAY frame arrived after the client was selected, retry using a new connection
+		client, err = r.getClient(hostname)
+		if err != nil {
+			return nil, err
+		}
+		return client.Do(req)
+	}
+	return rsp, err
 }
 
 func (r *QuicRoundTripper) getClient(hostname string) (h2quicClient, error) {
@@ -94,9 +106,12 @@ func (r *QuicRoundTripper) getClient(hostname string) (h2quicClient, error) {
 		r.clients = make(map[string]h2quicClient)
 	}
 
+	// a client that received a GOAWAY frame still handles its running requests, but new requests use a new connection
 	client, ok := r.clients[hostname]
-	if !ok {
-		if r.LocalAddr == nil {
+	if !ok || client.isGoingAway() {
+		if r.newClient != nil {
+			client = r.newClient(hostname)
+		} else if r.LocalAddr == nil {
 			client = NewClient(r, r.TLSClientConfig, hostname)
 		} else {
 			client = NewClientEx(r, r.TLSClientConfig, hostname, r.LocalAddr)
@@ -9,14 +9,29 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-type mockQuicRoundTripper struct{}
+type mockQuicRoundTripper struct {
+	goingAway bool
+	doErr     error
+	dialed    bool
+	requests  []*http.Request
+}
 
 func (m *mockQuicRoundTripper) Dial() error {
+	m.dialed = true
 	return nil
 }
 func (m *mockQuicRoundTripper) Do(req *http.Request) (*http.Response, error) {
+	m.requests = append(m.requests, req)
+	if m.doErr != nil {
+		// a real client learns that the server is going away from the GOAWAY frame
+		m.goingAway = m.doErr == errGoingAway
+		return nil, m.doErr
+	}
 	return &http.Response{Request: req}, nil
 }
+func (m *mockQuicRoundTripper) isGoingAway() bool {
+	return m.goingAway
+}
 
 type mockBody struct {
 	reader   bytes.Reader
@@ -66,6 +81,35 @@ var _ = Describe("RoundTripper", func() {
 		Expect(rt.clients).To(HaveLen(1))
 	})
 
+	It("dials a new client if the existing one is going away", func() {
+		oldClient := &mockQuicRoundTripper{goingAway: true}
+		newClient := &mockQuicRoundTripper{}
+		rt.clients = map[string]h2quicClient{"www.example.org:443": oldClient}
+		rt.newClient = func(hostname string) h2quicClient {
+			Expect(hostname).To(Equal("www.example.org:443"))
+			return newClient
+		}
+		rsp, err := rt.RoundTrip(req1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rsp.Request).To(Equal(req1))
+		Expect(oldClient.requests).To(BeEmpty())
+		Expect(newClient.dialed).To(BeTrue())
+		Expect(newClient.requests).To(Equal([]*http.Request{req1}))
+		Expect(rt.clients).To(HaveKeyWithValue("www.example.org:443", newClient))
+	})
+
+	It("retries the request using a new client if the GOAWAY frame arrives while the request is started", func() {
+		oldClient := &mockQuicRoundTripper{doErr: errGoingAway}
+		newClient := &mockQuicRoundTripper{}
+		rt.clients = map[string]h2quicClient{"www.example.org:443": oldClient}
+		rt.newClient = func(string) h2quicClient { return newClient }
+		rsp, err := rt.RoundTrip(req1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rsp.Request).To(Equal(req1))
+		Expect(oldClient.requests).To(HaveLen(1))
+		Expect(newClient.requests).To(Equal([]*http.Request{req1}))
+	})
+
 	It("disable compression", func() {
 		Expect(rt.disableCompression()).To(BeFalse())
 		rt.DisableCompression = true
@@ -23,8 +23,12 @@ import (
 type streamCreator interface {
 	quic.Session
 	GetOrOpenStream(protocol.StreamID) (quic.Stream, error)
+	GetStream(protocol.StreamID) quic.Stream
+	AddStaticStream(protocol.StreamID)
 }
 
+var errServerClosing = errors.New("h2quic: server is closing")
+
 type remoteCloser interface {
 	CloseRemote(protocol.ByteCount)
 }
@@ -41,9 +45,25 @@ type Server struct {
 	listenerMutex sync.Mutex
 	listener      quic.Listener
 
+	sessionsMutex sync.Mutex
+	sessions      map[streamCreator]*serverSession
+	// closing is set by CloseGracefully. No new sessions and requests are accepted after that.
+	closing bool
+	// handlers counts the requests that are currently being handled
+	handlers sync.WaitGroup
+
 	supportedVersionsAsString string
 }
 
+// serverSession is a session whose header stream was accepted
+type serverSession struct {
+	session           streamCreator
+	headerStream      quic.Stream
+	headerStreamMutex *sync.Mutex
+	// the highest stream ID a request was received on, only accessed with the sessionsMutex held
+	lastStreamID protocol.StreamID
+}
+
 // ListenAndServe listens on the UDP address s.Addr and calls s.Handler to handle HTTP/2 requests on incoming connections.
 func (s *Server) ListenAndServe() error {
 	if s.Server == nil {
@@ -87,6 +107,8 @@ func (s *Server) serveImpl(tlsConfig *tls.Config, conn *net.UDPConn) error {
 		TLSConfig: tlsConfig,
 		Versions:  protocol.SupportedVersions,
 		UsePLUS: true,
+		// the priorities of the HTTP/2 HEADERS and PRIORITY frames are applied to the data streams
+		StreamScheduling: quic.StreamSchedulingStrictPriority,
 	}
 
 	var ln quic.Listener
@@ -122,12 +144,20 @@ func (s *Server) handleHeaderStream(session streamCreator) {
 		session.Close(qerr.Error(qerr.InternalError, "h2quic server BUG: header stream does not have stream ID 3"))
 		return
 	}
+	// the header stream stays open for the lifetime of the session
+	session.AddStaticStream(stream.StreamID())
+
+	var headerStreamMutex sync.Mutex // Protects concurrent calls to Write()
+	if !s.addSession(&serverSession{session: session, headerStream: stream, headerStreamMutex: &headerStreamMutex}) {
+		session.Close(nil)
+		return
+	}
 
 	hpackDecoder := hpack.NewDecoder(4096, nil)
 	h2framer := http2.NewFramer(nil, stream)
 
 	go func() {
-		var headerStreamMutex sync.Mutex // Protects concurrent calls to Write()
+		defer s.removeSession(session)
 		for {
 			if err := s.handleRequest(session, stream, &headerStreamMutex, hpackDecoder, h2framer); err != nil {
 				// QuicErrors must originate from stream.Read() returning an error.
@@ -148,6 +178,9 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 	if err != nil {
 		return qerr.Error(qerr.HeadersStreamDataDecompressFailure, "cannot read frame")
 	}
+	if h2priorityFrame, ok := h2frame.(*http2.PriorityFrame); ok {
+		return handlePriorityFrame(session, h2priorityFrame)
+	}
 	h2headersFrame, ok := h2frame.(*http2.HeadersFrame)
 	if !ok {
 		return qerr.Error(qerr.InvalidHeadersStreamData, "expected a header frame")
@@ -182,6 +215,14 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 	if dataStream == nil {
 		return nil
 	}
+	if h2headersFrame.HasPriority() {
+		setPriority(dataStream, h2headersFrame.Priority)
+	}
+	// the request was sent after we sent the GOAWAY frame
+	if !s.startHandler(session, protocol.StreamID(h2headersFrame.StreamID)) {
+		dataStream.Reset(errServerClosing)
+		return nil
+	}
 
 	var streamEnded bool
 	if h2headersFrame.StreamEnded() {
@@ -196,6 +237,7 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 	responseWriter := newResponseWriter(headerStream, headerStreamMutex, dataStream, protocol.StreamID(h2headersFrame.StreamID))
 
 	go func() {
+		defer s.handlers.Done()
 		handler := s.Handler
 		if handler == nil {
 			handler = http.DefaultServeMux
@@ -234,6 +276,27 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 	return nil
 }
 
+// handlePriorityFrame applies the priority to an open data stream.
+// PRIORITY frames for streams that are not open (yet) are ignored, they must not open the stream. The HEADERS frame of a request carries its priority.
+func handlePriorityFrame(session streamCreator, frame *http2.PriorityFrame) error {
+	dataStream := session.GetStream(protocol.StreamID(frame.StreamID))
+	if dataStream == nil {
+		return nil
+	}
+	setPriority(dataStream, frame.PriorityParam)
+	return nil
+}
+
+// setPriority applies the HTTP/2 priority to a QUIC stream.
+// Stream dependencies are not supported. The HTTP/2 weight is used as the weight of the stream,
+// and converted to a SPDY priority for the priority of the stream, the same way as Chromium does.
+func setPriority(str quic.Stream, p http2.PriorityParam) {
+	weight := uint16(p.Weight) + 1 // the weight is sent as weight - 1
+	// priority = 7 - (weight - 1) / (255.9 / 7), rounded down
+	priority := (7*2559 - (uint32(weight)-1)*70) / 2559
+	str.SetPriority(protocol.StreamPriority(priority), weight)
+}
+
 // Close the server immediately, aborting requests and sending CONNECTION_CLOSE frames to connected clients.
 // Close in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) Close() error {
@@ -248,10 +311,106 @@ func (s *Server) Close() error {
 }
 
 // CloseGracefully shuts down the server gracefully. The server sends a GOAWAY frame first, then waits for either timeout to trigger, or for all running requests to complete.
+// New connections are refused before their handshake completes, and requests received after the GOAWAY frame are refused by resetting their data stream.
+// Once the requests completed, every session is closed gracefully, such that the responses are still delivered until the timeout expires.
 // CloseGracefully in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) CloseGracefully(timeout time.Duration) error {
-	// TODO: implement
-	return nil
+	deadline := time.Now().Add(timeout)
+
+	s.listenerMutex.Lock()
+	if s.listener != nil {
+		s.listener.StopAccepting()
+	}
+	s.listenerMutex.Unlock()
+
+	s.sessionsMutex.Lock()
+	s.closing = true
+	sessions := make([]*serverSession, 0, len(s.sessions))
+	lastStreamIDs := make([]protocol.StreamID, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+		lastStreamIDs = append(lastStreamIDs, sess.lastStreamID)
+	}
+	s.sessionsMutex.Unlock()
+
+	for i, sess := range sessions {
+		if err := sess.writeGoaway(lastStreamIDs[i]); err != nil {
+			utils.Errorf("error sending GOAWAY frame to %s: %s", sess.session.RemoteAddr(), err.Error())
+		}
+	}
+
+	handlersDone := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(handlersDone)
+	}()
+	timer := time.NewTimer(timeout)
+	select {
+	case <-handlersDone:
+		timer.Stop()
+	case <-timer.C:
+	}
+
+	// the sessions are closed once the responses were delivered, or when the deadline is reached
+	errChan := make(chan error, len(sessions))
+	for _, sess := range sessions {
+		go func(session streamCreator) {
+			errChan <- session.CloseGracefully(deadline.Sub(time.Now()))
+		}(sess.session)
+	}
+	var closeErr error
+	for range sessions {
+		if err := <-errChan; err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+	if err := s.Close(); err != nil && closeErr == nil {
+		closeErr = err
+	}
+	return closeErr
+}
+
+// addSession registers a session. It returns false if the server is already closing.
+func (s *Server) addSession(sess *serverSession) bool {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	if s.closing {
+		return false
+	}
+	if s.sessions == nil {
+		s.sessions = make(map[streamCreator]*serverSession)
+	}
+	s.sessions[sess.session] = sess
+	return true
+}
+
+func (s *Server) removeSession(session streamCreator) {
+	s.sessionsMutex.Lock()
+	delete(s.sessions, session)
+	s.sessionsMutex.Unlock()
+}
+
+// startHandler must be called before a request is handled, and s.handlers.Done() once it has been handled.
+// It returns false if the server is closing, and the request must not be handled.
+func (s *Server) startHandler(session streamCreator, id protocol.StreamID) bool {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	if s.closing {
+		return false
+	}
+	if sess, ok := s.sessions[session]; ok && id > sess.lastStreamID {
+		sess.lastStreamID = id
+	}
+	s.handlers.Add(1)
+	return true
+}
+
+// writeGoaway sends a HTTP/2 GOAWAY frame on the header stream
+func (s *serverSession) writeGoaway(lastStreamID protocol.StreamID) error {
+	s.headerStreamMutex.Lock()
+	defer s.headerStreamMutex.Unlock()
+	h2framer := http2.NewFramer(s.headerStream, nil)
+	return h2framer.WriteGoAway(uint32(lastStreamID), http2.ErrCodeNo, nil)
 }
 
 // SetQuicHeaders can be used to set the proper headers that announce that this server supports QUIC.
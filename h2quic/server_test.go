@@ -2,6 +2,7 @@ package h2quic
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net"
 	"net/http"
@@ -18,6 +19,7 @@ import (
 	"github.com/lucas-clemente/quic-go/protocol"
 	"github.com/lucas-clemente/quic-go/qerr"
 	"github.com/lucas-clemente/quic-go/testdata"
+	"github.com/lucas-clemente/quic-go/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -31,11 +33,30 @@ type mockSession struct {
 	streamToOpen        quic.Stream
 	blockOpenStreamSync bool
 	streamOpenErr       error
+
+	closedGracefully       bool
+	closeGracefullyTimeout time.Duration
+	closeGracefullyErr     error
+
+	staticStreams []protocol.StreamID
+	// streamsNotOpen makes GetStream return nil
+	streamsNotOpen bool
+	openedStreams  []protocol.StreamID
 }
 
 func (s *mockSession) GetOrOpenStream(id protocol.StreamID) (quic.Stream, error) {
+	s.openedStreams = append(s.openedStreams, id)
 	return s.dataStream, nil
 }
+func (s *mockSession) GetStream(id protocol.StreamID) quic.Stream {
+	if s.streamsNotOpen {
+		return nil
+	}
+	return s.dataStream
+}
+func (s *mockSession) AddStaticStream(id protocol.StreamID) {
+	s.staticStreams = append(s.staticStreams, id)
+}
 func (s *mockSession) AcceptStream() (quic.Stream, error) {
 	return s.streamToAccept, nil
 }
@@ -62,6 +83,50 @@ func (s *mockSession) LocalAddr() net.Addr {
 func (s *mockSession) RemoteAddr() net.Addr {
 	return &net.UDPAddr{IP: []byte{127, 0, 0, 1}, Port: 42}
 }
+func (s *mockSession) PLUSInfo() quic.PLUSInfo {
+	panic("not implemented")
+}
+func (s *mockSession) ConnectionStats() quic.ConnectionStats {
+	panic("not implemented")
+}
+func (s *mockSession) CloseGracefully(timeout time.Duration) error {
+	s.closedGracefully = true
+	s.closeGracefullyTimeout = timeout
+	return s.closeGracefullyErr
+}
+func (s *mockSession) RequestPCF(*quic.PCFRequest) error {
+	panic("not implemented")
+}
+func (s *mockSession) CancelPCFRequest(*quic.PCFRequest) {
+	panic("not implemented")
+}
+func (s *mockSession) PCFFeedback() <-chan []byte {
+	panic("not implemented")
+}
+func (s *mockSession) SetLogLevel(utils.LogLevel) {
+	panic("not implemented")
+}
+
+type mockListener struct {
+	stoppedAccepting bool
+	closed           bool
+}
+
+func (l *mockListener) Close() error {
+	l.closed = true
+	return nil
+}
+func (l *mockListener) Addr() net.Addr {
+	panic("not implemented")
+}
+func (l *mockListener) Accept() (quic.Session, error) {
+	panic("not implemented")
+}
+func (l *mockListener) StopAccepting() {
+	l.stoppedAccepting = true
+}
+
+var _ quic.Listener = &mockListener{}
 
 var _ = Describe("H2 server", func() {
 	var (
@@ -228,6 +293,77 @@ var _ = Describe("H2 server", func() {
 			Expect(dataStream.reset).To(BeFalse())
 		})
 
+		Context("priorities", func() {
+			// Taken from https://http2.github.io/http2-spec/compression.html#request.examples.with.huffman.coding
+			requestHeaders := []byte{0x82, 0x86, 0x84, 0x41, 0x8c, 0xf1, 0xe3, 0xc2, 0xe5, 0xf2, 0x3a, 0x6b, 0xa0, 0xab, 0x90, 0xf4, 0xff}
+
+			It("applies the priority of the HEADERS frame to the data stream", func() {
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+				err := http2.NewFramer(&headerStream.dataToRead, nil).WriteHeaders(http2.HeadersFrameParam{
+					StreamID:      5,
+					BlockFragment: requestHeaders,
+					EndStream:     true,
+					EndHeaders:    true,
+					Priority:      http2.PriorityParam{Weight: 255},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				err = s.handleRequest(session, headerStream, &sync.Mutex{}, hpackDecoder, h2framer)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dataStream.priority).To(Equal(protocol.HighestStreamPriority))
+				Expect(dataStream.weight).To(BeEquivalentTo(256))
+			})
+
+			It("doesn't change the priority if the HEADERS frame doesn't have one", func() {
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+				err := http2.NewFramer(&headerStream.dataToRead, nil).WriteHeaders(http2.HeadersFrameParam{
+					StreamID:      5,
+					BlockFragment: requestHeaders,
+					EndStream:     true,
+					EndHeaders:    true,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				err = s.handleRequest(session, headerStream, &sync.Mutex{}, hpackDecoder, h2framer)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dataStream.weight).To(BeZero())
+			})
+
+			It("handles PRIORITY frames", func() {
+				var handlerCalled bool
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					handlerCalled = true
+				})
+				err := http2.NewFramer(&headerStream.dataToRead, nil).WritePriority(5, http2.PriorityParam{Weight: 15})
+				Expect(err).ToNot(HaveOccurred())
+				err = s.handleRequest(session, headerStream, &sync.Mutex{}, hpackDecoder, h2framer)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dataStream.priority).To(Equal(protocol.StreamPriority(6)))
+				Expect(dataStream.weight).To(BeEquivalentTo(16))
+				Consistently(func() bool { return handlerCalled }).Should(BeFalse())
+				Expect(session.openedStreams).To(BeEmpty())
+			})
+
+			It("doesn't open streams when receiving PRIORITY frames", func() {
+				session.streamsNotOpen = true
+				err := http2.NewFramer(&headerStream.dataToRead, nil).WritePriority(7, http2.PriorityParam{Weight: 15})
+				Expect(err).ToNot(HaveOccurred())
+				err = s.handleRequest(session, headerStream, &sync.Mutex{}, hpackDecoder, h2framer)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(session.openedStreams).To(BeEmpty())
+				Expect(dataStream.weight).To(BeZero())
+			})
+
+			It("converts the weights sent by Chromium back to their SPDY priority", func() {
+				for p := 0; p <= 7; p++ {
+					// see Spdy3PriorityToHttp2Weight in Chromium
+					weight := int(255.9/7*float64(7-p)) + 1
+					str := &mockStream{}
+					setPriority(str, http2.PriorityParam{Weight: uint8(weight - 1)})
+					Expect(str.priority).To(BeEquivalentTo(p))
+					Expect(str.weight).To(BeEquivalentTo(weight))
+				}
+			})
+		})
+
 		It("errors when non-header frames are received", func() {
 			headerStream.dataToRead.Write([]byte{
 				0x0, 0x0, 0x06, 0x0, 0x0, 0x0, 0x0, 0x0, 0x5,
@@ -253,6 +389,7 @@ var _ = Describe("H2 server", func() {
 		session.streamToAccept = headerStream
 		go s.handleHeaderStream(session)
 		Eventually(func() bool { return handlerCalled }).Should(BeTrue())
+		Expect(session.staticStreams).To(Equal([]protocol.StreamID{3}))
 	})
 
 	It("closes the connection if it encounters an error on the header stream", func() {
@@ -434,6 +571,116 @@ var _ = Describe("H2 server", func() {
 		Expect(err).NotTo(HaveOccurred())
 	})
 
+	Context("closing gracefully", func() {
+		var (
+			headerStream      *mockStream
+			headerStreamMutex *sync.Mutex
+			request           []byte
+		)
+
+		BeforeEach(func() {
+			headerStream = &mockStream{id: 3}
+			headerStreamMutex = &sync.Mutex{}
+			request = []byte{
+				0x0, 0x0, 0x11, 0x1, 0x5, 0x0, 0x0, 0x0, 0x5,
+				// Taken from https://http2.github.io/http2-spec/compression.html#request.examples.with.huffman.coding
+				0x82, 0x86, 0x84, 0x41, 0x8c, 0xf1, 0xe3, 0xc2, 0xe5, 0xf2, 0x3a, 0x6b, 0xa0, 0xab, 0x90, 0xf4, 0xff,
+			}
+			Expect(s.addSession(&serverSession{session: session, headerStream: headerStream, headerStreamMutex: headerStreamMutex})).To(BeTrue())
+		})
+
+		handleRequest := func() error {
+			headerStream.dataToRead.Write(request)
+			return s.handleRequest(session, headerStream, headerStreamMutex, hpack.NewDecoder(4096, nil), http2.NewFramer(nil, headerStream))
+		}
+
+		It("sends a GOAWAY frame and waits for running requests", func() {
+			unblock := make(chan struct{})
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-unblock
+			})
+			Expect(handleRequest()).To(Succeed())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				err := s.CloseGracefully(time.Hour)
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			Eventually(func() int { return headerStream.dataWritten.Len() }).ShouldNot(BeZero())
+			frame, err := http2.NewFramer(nil, bytes.NewReader(headerStream.dataWritten.Bytes())).ReadFrame()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(BeAssignableToTypeOf(&http2.GoAwayFrame{}))
+			Expect(frame.(*http2.GoAwayFrame).LastStreamID).To(BeEquivalentTo(5))
+			Expect(frame.(*http2.GoAwayFrame).ErrCode).To(Equal(http2.ErrCodeNo))
+			Consistently(done).ShouldNot(BeClosed())
+			Expect(session.closedGracefully).To(BeFalse())
+			close(unblock)
+			Eventually(done).Should(BeClosed())
+			Expect(session.closedGracefully).To(BeTrue())
+			Expect(session.closeGracefullyTimeout).To(BeNumerically(">", 59*time.Minute))
+		})
+
+		It("refuses requests received after the GOAWAY frame", func() {
+			var handlerCalled bool
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerCalled = true
+			})
+			Expect(s.CloseGracefully(time.Second)).To(Succeed())
+			Expect(handleRequest()).To(Succeed())
+			Expect(dataStream.reset).To(BeTrue())
+			Consistently(func() bool { return handlerCalled }).Should(BeFalse())
+		})
+
+		It("stops accepting new connections right away", func() {
+			ln := &mockListener{}
+			s.listener = ln
+			unblock := make(chan struct{})
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-unblock
+			})
+			Expect(handleRequest()).To(Succeed())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(s.CloseGracefully(time.Hour)).To(Succeed())
+				close(done)
+			}()
+			Eventually(func() int { return headerStream.dataWritten.Len() }).ShouldNot(BeZero())
+			s.listenerMutex.Lock()
+			Expect(ln.stoppedAccepting).To(BeTrue())
+			Expect(ln.closed).To(BeFalse())
+			s.listenerMutex.Unlock()
+			close(unblock)
+			Eventually(done).Should(BeClosed())
+			Expect(ln.closed).To(BeTrue())
+		})
+
+		It("closes new sessions", func() {
+			Expect(s.CloseGracefully(time.Second)).To(Succeed())
+			newSession := &mockSession{streamToAccept: &mockStream{id: 3}}
+			s.handleHeaderStream(newSession)
+			Expect(newSession.closed).To(BeTrue())
+			Expect(newSession.closedWithError).ToNot(HaveOccurred())
+			Expect(newSession.closedGracefully).To(BeFalse())
+		})
+
+		It("closes the sessions when the timeout expires", func() {
+			testErr := errors.New("timeout")
+			session.closeGracefullyErr = testErr
+			unblock := make(chan struct{})
+			defer close(unblock)
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-unblock
+			})
+			Expect(handleRequest()).To(Succeed())
+			err := s.CloseGracefully(50 * time.Millisecond)
+			Expect(err).To(MatchError(testErr))
+			Expect(session.closedGracefully).To(BeTrue())
+			Expect(session.closeGracefullyTimeout).To(BeNumerically("<=", 0))
+		})
+	})
+
 	It("at least errors in global ListenAndServeQUIC", func() {
 		// It's quite hard to test this, since we cannot properly shutdown the server
 		// once it's started. So, we open a socket on the same port before the test,
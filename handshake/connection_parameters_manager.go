@@ -50,6 +50,11 @@ type connectionParametersManager struct {
 	sendConnectionFlowControlWindow        protocol.ByteCount
 	receiveStreamFlowControlWindow         protocol.ByteCount
 	receiveConnectionFlowControlWindow     protocol.ByteCount
+
+	maxIdleConnectionStateLifetime        time.Duration
+	maxReceiveStreamFlowControlWindow     protocol.ByteCount
+	maxReceiveConnectionFlowControlWindow protocol.ByteCount
+	maxIncomingStreams                    uint32
 }
 
 var _ ConnectionParametersManager = &connectionParametersManager{}
@@ -61,7 +66,7 @@ var (
 )
 
 // NewConnectionParamatersManager creates a new connection parameters manager
-func NewConnectionParamatersManager(pers protocol.Perspective, v protocol.VersionNumber) ConnectionParametersManager {
+func NewConnectionParamatersManager(pers protocol.Perspective, v protocol.VersionNumber, params *TransportParameters) ConnectionParametersManager {
 	h := &connectionParametersManager{
 		perspective:                        pers,
 		version:                            v,
@@ -69,18 +74,44 @@ func NewConnectionParamatersManager(pers protocol.Perspective, v protocol.Versio
 		sendConnectionFlowControlWindow:    protocol.InitialConnectionFlowControlWindow, // can only be changed by the client
 		receiveStreamFlowControlWindow:     protocol.ReceiveStreamFlowControlWindow,
 		receiveConnectionFlowControlWindow: protocol.ReceiveConnectionFlowControlWindow,
+		maxIncomingStreams:                 protocol.MaxIncomingDynamicStreamsPerConnection,
 	}
 
 	if h.perspective == protocol.PerspectiveServer {
 		h.idleConnectionStateLifetime = protocol.DefaultIdleTimeout
-		h.maxStreamsPerConnection = protocol.MaxStreamsPerConnection                // this is the value negotiated based on what the client sent
-		h.maxIncomingDynamicStreamsPerConnection = protocol.MaxStreamsPerConnection // "incoming" seen from the client's perspective
+		h.maxIdleConnectionStateLifetime = protocol.MaxIdleTimeoutServer
+		h.maxReceiveStreamFlowControlWindow = protocol.MaxReceiveStreamFlowControlWindowServer
+		h.maxReceiveConnectionFlowControlWindow = protocol.MaxReceiveConnectionFlowControlWindowServer
 	} else {
 		h.idleConnectionStateLifetime = protocol.MaxIdleTimeoutClient
-		h.maxStreamsPerConnection = protocol.MaxStreamsPerConnection                // this is the value negotiated based on what the client sent
-		h.maxIncomingDynamicStreamsPerConnection = protocol.MaxStreamsPerConnection // "incoming" seen from the server's perspective
+		h.maxIdleConnectionStateLifetime = protocol.MaxIdleTimeoutClient
+		h.maxReceiveStreamFlowControlWindow = protocol.MaxReceiveStreamFlowControlWindowClient
+		h.maxReceiveConnectionFlowControlWindow = protocol.MaxReceiveConnectionFlowControlWindowClient
+	}
+
+	if params.IdleTimeout != 0 {
+		h.idleConnectionStateLifetime = params.IdleTimeout
+		h.maxIdleConnectionStateLifetime = params.IdleTimeout
+	}
+	if params.ReceiveStreamFlowControlWindow != 0 {
+		h.receiveStreamFlowControlWindow = params.ReceiveStreamFlowControlWindow
+	}
+	if params.ReceiveConnectionFlowControlWindow != 0 {
+		h.receiveConnectionFlowControlWindow = params.ReceiveConnectionFlowControlWindow
+	}
+	if params.MaxReceiveStreamFlowControlWindow != 0 {
+		h.maxReceiveStreamFlowControlWindow = params.MaxReceiveStreamFlowControlWindow
+	}
+	if params.MaxReceiveConnectionFlowControlWindow != 0 {
+		h.maxReceiveConnectionFlowControlWindow = params.MaxReceiveConnectionFlowControlWindow
+	}
+	if params.MaxIncomingStreams != 0 {
+		h.maxIncomingStreams = params.MaxIncomingStreams
 	}
 
+	h.maxStreamsPerConnection = h.maxIncomingStreams                            // this is the value negotiated based on what the client sent
+	h.maxIncomingDynamicStreamsPerConnection = protocol.MaxStreamsPerConnection // "incoming" seen from the peer's perspective
+
 	return h
 }
 
@@ -148,7 +179,7 @@ func (h *connectionParametersManager) SetFromMap(params map[Tag][]byte) error {
 }
 
 func (h *connectionParametersManager) negotiateMaxStreamsPerConnection(clientValue uint32) uint32 {
-	return utils.MinUint32(clientValue, protocol.MaxStreamsPerConnection)
+	return utils.MinUint32(clientValue, h.maxIncomingStreams)
 }
 
 func (h *connectionParametersManager) negotiateMaxIncomingDynamicStreamsPerConnection(clientValue uint32) uint32 {
@@ -156,10 +187,7 @@ func (h *connectionParametersManager) negotiateMaxIncomingDynamicStreamsPerConne
 }
 
 func (h *connectionParametersManager) negotiateIdleConnectionStateLifetime(clientValue time.Duration) time.Duration {
-	if h.perspective == protocol.PerspectiveServer {
-		return utils.MinDuration(clientValue, protocol.MaxIdleTimeoutServer)
-	}
-	return utils.MinDuration(clientValue, protocol.MaxIdleTimeoutClient)
+	return utils.MinDuration(clientValue, h.maxIdleConnectionStateLifetime)
 }
 
 // GetHelloMap gets all parameters needed for the Hello message
@@ -171,7 +199,7 @@ func (h *connectionParametersManager) GetHelloMap() (map[Tag][]byte, error) {
 	mspc := bytes.NewBuffer([]byte{})
 	utils.WriteUint32(mspc, h.maxStreamsPerConnection)
 	mids := bytes.NewBuffer([]byte{})
-	utils.WriteUint32(mids, protocol.MaxIncomingDynamicStreamsPerConnection)
+	utils.WriteUint32(mids, h.maxIncomingStreams)
 	icsl := bytes.NewBuffer([]byte{})
 	utils.WriteUint32(icsl, uint32(h.GetIdleConnectionStateLifetime()/time.Second))
 
@@ -207,10 +235,7 @@ func (h *connectionParametersManager) GetReceiveStreamFlowControlWindow() protoc
 
 // GetMaxReceiveStreamFlowControlWindow gets the maximum size of the stream-level flow control window for sending data
 func (h *connectionParametersManager) GetMaxReceiveStreamFlowControlWindow() protocol.ByteCount {
-	if h.perspective == protocol.PerspectiveServer {
-		return protocol.MaxReceiveStreamFlowControlWindowServer
-	}
-	return protocol.MaxReceiveStreamFlowControlWindowClient
+	return h.maxReceiveStreamFlowControlWindow
 }
 
 // GetReceiveConnectionFlowControlWindow gets the size of the stream-level flow control window for receiving data
@@ -222,10 +247,7 @@ func (h *connectionParametersManager) GetReceiveConnectionFlowControlWindow() pr
 
 // GetMaxReceiveConnectionFlowControlWindow gets the maximum size of the stream-level flow control window for sending data
 func (h *connectionParametersManager) GetMaxReceiveConnectionFlowControlWindow() protocol.ByteCount {
-	if h.perspective == protocol.PerspectiveServer {
-		return protocol.MaxReceiveConnectionFlowControlWindowServer
-	}
-	return protocol.MaxReceiveConnectionFlowControlWindowClient
+	return h.maxReceiveConnectionFlowControlWindow
 }
 
 // GetMaxOutgoingStreams gets the maximum number of outgoing streams per connection
@@ -241,7 +263,7 @@ func (h *connectionParametersManager) GetMaxIncomingStreams() uint32 {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
 
-	maxStreams := protocol.MaxIncomingDynamicStreamsPerConnection
+	maxStreams := h.maxIncomingStreams
 	return utils.MaxUint32(uint32(maxStreams)+protocol.MaxStreamsMinimumIncrement, uint32(float64(maxStreams)*protocol.MaxStreamsMultiplier))
 }
 
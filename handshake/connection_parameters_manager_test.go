@@ -2,6 +2,7 @@ package handshake
 
 import (
 	"encoding/binary"
+	"math"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/protocol"
@@ -14,8 +15,8 @@ var _ = Describe("ConnectionsParameterManager", func() {
 	var cpmClient *connectionParametersManager
 
 	BeforeEach(func() {
-		cpm = NewConnectionParamatersManager(protocol.PerspectiveServer, protocol.Version36).(*connectionParametersManager)
-		cpmClient = NewConnectionParamatersManager(protocol.PerspectiveClient, protocol.Version36).(*connectionParametersManager)
+		cpm = NewConnectionParamatersManager(protocol.PerspectiveServer, protocol.Version36, &TransportParameters{}).(*connectionParametersManager)
+		cpmClient = NewConnectionParamatersManager(protocol.PerspectiveClient, protocol.Version36, &TransportParameters{}).(*connectionParametersManager)
 	})
 
 	Context("SHLO", func() {
@@ -278,4 +279,70 @@ var _ = Describe("ConnectionsParameterManager", func() {
 			})
 		})
 	})
+
+	Context("configured transport parameters", func() {
+		var params *TransportParameters
+
+		BeforeEach(func() {
+			params = &TransportParameters{
+				IdleTimeout:                           42 * time.Second,
+				ReceiveStreamFlowControlWindow:        0x10000,
+				ReceiveConnectionFlowControlWindow:    0x20000,
+				MaxReceiveStreamFlowControlWindow:     0x100000,
+				MaxReceiveConnectionFlowControlWindow: 0x200000,
+				MaxIncomingStreams:                    1000,
+			}
+		})
+
+		It("sends the configured values in the hello message", func() {
+			for _, pers := range []protocol.Perspective{protocol.PerspectiveServer, protocol.PerspectiveClient} {
+				cpm := NewConnectionParamatersManager(pers, protocol.Version36, params)
+				entryMap, err := cpm.GetHelloMap()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(binary.LittleEndian.Uint32(entryMap[TagICSL])).To(BeEquivalentTo(42))
+				Expect(binary.LittleEndian.Uint32(entryMap[TagSFCW])).To(BeEquivalentTo(0x10000))
+				Expect(binary.LittleEndian.Uint32(entryMap[TagCFCW])).To(BeEquivalentTo(0x20000))
+				Expect(binary.LittleEndian.Uint32(entryMap[TagMSPC])).To(BeEquivalentTo(1000))
+				Expect(binary.LittleEndian.Uint32(entryMap[TagMIDS])).To(BeEquivalentTo(1000))
+			}
+		})
+
+		It("uses the configured flow control windows", func() {
+			cpm := NewConnectionParamatersManager(protocol.PerspectiveServer, protocol.Version36, params)
+			Expect(cpm.GetReceiveStreamFlowControlWindow()).To(Equal(protocol.ByteCount(0x10000)))
+			Expect(cpm.GetReceiveConnectionFlowControlWindow()).To(Equal(protocol.ByteCount(0x20000)))
+			Expect(cpm.GetMaxReceiveStreamFlowControlWindow()).To(Equal(protocol.ByteCount(0x100000)))
+			Expect(cpm.GetMaxReceiveConnectionFlowControlWindow()).To(Equal(protocol.ByteCount(0x200000)))
+		})
+
+		It("accepts at most the configured idle timeout", func() {
+			cpm := NewConnectionParamatersManager(protocol.PerspectiveServer, protocol.Version36, params)
+			err := cpm.SetFromMap(map[Tag][]byte{TagICSL: {50, 0, 0, 0}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpm.GetIdleConnectionStateLifetime()).To(Equal(42 * time.Second))
+			err = cpm.SetFromMap(map[Tag][]byte{TagICSL: {10, 0, 0, 0}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpm.GetIdleConnectionStateLifetime()).To(Equal(10 * time.Second))
+		})
+
+		It("allows the configured number of incoming streams", func() {
+			cpm := NewConnectionParamatersManager(protocol.PerspectiveServer, protocol.Version36, params)
+			Expect(cpm.GetMaxIncomingStreams()).To(BeNumerically(">=", 1000))
+			Expect(cpm.GetMaxIncomingStreams()).To(BeNumerically("<", 1200))
+			err := cpm.SetFromMap(map[Tag][]byte{TagMSPC: {0xd0, 0x07, 0, 0}}) // 2000
+			Expect(err).ToNot(HaveOccurred())
+			entryMap, err := cpm.GetHelloMap()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(binary.LittleEndian.Uint32(entryMap[TagMSPC])).To(BeEquivalentTo(1000))
+		})
+
+		It("doesn't overflow when adding the slack to the largest number of incoming streams", func() {
+			params.MaxIncomingStreams = protocol.MaxIncomingStreamsLimit
+			cpm := NewConnectionParamatersManager(protocol.PerspectiveServer, protocol.Version36, params)
+			maxStreams := cpm.GetMaxIncomingStreams()
+			Expect(maxStreams).To(BeNumerically(">", protocol.MaxIncomingStreamsLimit))
+			// the StreamID delta derived from it fits into a StreamID, too
+			Expect(uint64(maxStreams) * protocol.MaxNewStreamIDDeltaMultiplier).To(BeNumerically("<", math.MaxUint32))
+		})
+	})
 })
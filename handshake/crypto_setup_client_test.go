@@ -111,10 +111,12 @@ var _ = Describe("Client Crypto Setup", func() {
 			version,
 			stream,
 			nil,
-			NewConnectionParamatersManager(protocol.PerspectiveClient, version),
+			NewConnectionParamatersManager(protocol.PerspectiveClient, version, &TransportParameters{}),
 			aeadChanged,
 			&TransportParameters{},
 			nil,
+			nil,
+			utils.DefaultLogger,
 		)
 		Expect(err).ToNot(HaveOccurred())
 		cs = csInt.(*cryptoSetupClient)
@@ -20,6 +20,17 @@ type KeyDerivationFunction func(forwardSecure bool, sharedSecret, nonces []byte,
 // KeyExchangeFunction is used to make a new KEX
 type KeyExchangeFunction func() crypto.KeyExchange
 
+// newKeyDerivation returns the key derivation used by the crypto setups
+// If keyLogWriter is not nil, the keys are written to it, and errors writing them are logged to logger.
+func newKeyDerivation(keyLogWriter io.Writer, logger utils.Logger) KeyDerivationFunction {
+	if keyLogWriter == nil {
+		return crypto.DeriveKeysAESGCM
+	}
+	return func(forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo []byte, scfg []byte, cert []byte, divNonce []byte, pers protocol.Perspective) (crypto.AEAD, error) {
+		return crypto.DeriveKeysAESGCMWithKeyLog(keyLogWriter, logger, forwardSecure, sharedSecret, nonces, connID, chlo, scfg, cert, divNonce, pers)
+	}
+}
+
 // The CryptoSetupServer handles all things crypto for the Session
 type cryptoSetupServer struct {
 	connID               protocol.ConnectionID
@@ -46,6 +57,8 @@ type cryptoSetupServer struct {
 	connectionParameters ConnectionParametersManager
 
 	mutex sync.RWMutex
+
+	logger utils.Logger
 }
 
 var _ CryptoSetup = &cryptoSetupServer{}
@@ -65,6 +78,8 @@ func NewCryptoSetup(
 	connectionParametersManager ConnectionParametersManager,
 	supportedVersions []protocol.VersionNumber,
 	aeadChanged chan<- protocol.EncryptionLevel,
+	keyLogWriter io.Writer,
+	logger utils.Logger,
 ) (CryptoSetup, error) {
 	return &cryptoSetupServer{
 		connID:               connID,
@@ -72,12 +87,13 @@ func NewCryptoSetup(
 		version:              version,
 		supportedVersions:    supportedVersions,
 		scfg:                 scfg,
-		keyDerivation:        crypto.DeriveKeysAESGCM,
+		keyDerivation:        newKeyDerivation(keyLogWriter, logger),
 		keyExchange:          getEphermalKEX,
 		nullAEAD:             crypto.NewNullAEAD(protocol.PerspectiveServer, version),
 		cryptoStream:         cryptoStream,
 		connectionParameters: connectionParametersManager,
 		aeadChanged:          aeadChanged,
+		logger:               logger,
 	}, nil
 }
 
@@ -93,7 +109,7 @@ func (h *cryptoSetupServer) HandleCryptoStream() error {
 			return qerr.InvalidCryptoMessageType
 		}
 
-		utils.Debugf("Got %s", message)
+		h.logger.Debugf("Got %s", message)
 		done, err := h.handleMessage(chloData.Bytes(), message.Data)
 		if err != nil {
 			return err
@@ -264,7 +280,7 @@ func (h *cryptoSetupServer) isInchoateCHLO(cryptoData map[Tag][]byte, cert []byt
 		return true
 	}
 	if err := h.scfg.stkSource.VerifyToken(h.sourceAddr, cryptoData[TagSTK]); err != nil {
-		utils.Debugf("STK invalid: %s", err.Error())
+		h.logger.Debugf("STK invalid: %s", err.Error())
 		return true
 	}
 	return false
@@ -311,7 +327,7 @@ func (h *cryptoSetupServer) handleInchoateCHLO(sni string, chlo []byte, cryptoDa
 
 	var serverReply bytes.Buffer
 	message.Write(&serverReply)
-	utils.Debugf("Sending %s", message)
+	h.logger.Debugf("Sending %s", message)
 	return serverReply.Bytes(), nil
 }
 
@@ -423,7 +439,7 @@ func (h *cryptoSetupServer) handleCHLO(sni string, data []byte, cryptoData map[T
 	}
 	var reply bytes.Buffer
 	message.Write(&reply)
-	utils.Debugf("Sending %s", message)
+	h.logger.Debugf("Sending %s", message)
 
 	h.aeadChanged <- protocol.EncryptionForwardSecure
 
@@ -191,8 +191,8 @@ var _ = Describe("Server Crypto Setup", func() {
 		scfg.stkSource = &mockStkSource{}
 		version = protocol.SupportedVersions[len(protocol.SupportedVersions)-1]
 		supportedVersions = []protocol.VersionNumber{version, 98, 99}
-		cpm = NewConnectionParamatersManager(protocol.PerspectiveServer, protocol.VersionWhatever)
-		csInt, err := NewCryptoSetup(protocol.ConnectionID(42), sourceAddr, version, scfg, stream, cpm, supportedVersions, aeadChanged)
+		cpm = NewConnectionParamatersManager(protocol.PerspectiveServer, protocol.VersionWhatever, &TransportParameters{})
+		csInt, err := NewCryptoSetup(protocol.ConnectionID(42), sourceAddr, version, scfg, stream, cpm, supportedVersions, aeadChanged, nil, utils.DefaultLogger)
 		Expect(err).NotTo(HaveOccurred())
 		cs = csInt.(*cryptoSetupServer)
 		cs.keyDerivation = mockKeyDerivation
@@ -203,6 +203,14 @@ var _ = Describe("Server Crypto Setup", func() {
 		close(stream.unblockRead)
 	})
 
+	It("writes the keys to the key log", func() {
+		b := &bytes.Buffer{}
+		keyDerivation := newKeyDerivation(b, utils.DefaultLogger)
+		_, err := keyDerivation(true, []byte("0123456789012345678901"), []byte("nonce"), 42, []byte("chlo"), []byte("scfg"), []byte("cert"), nil, protocol.PerspectiveServer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b.String()).To(HavePrefix("QUIC_FORWARD_SECURE 000000000000002a "))
+	})
+
 	Context("diversification nonce", func() {
 		BeforeEach(func() {
 			cs.version = protocol.Version35
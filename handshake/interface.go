@@ -1,6 +1,10 @@
 package handshake
 
-import "github.com/lucas-clemente/quic-go/protocol"
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+)
 
 // Sealer seals a packet
 type Sealer func(dst, src []byte, packetNumber protocol.PacketNumber, associatedData []byte) []byte
@@ -18,6 +22,15 @@ type CryptoSetup interface {
 }
 
 // TransportParameters are parameters sent to the peer during the handshake
+// Parameters that are not set use the defaults in the protocol package.
 type TransportParameters struct {
 	RequestConnectionIDTruncation bool
+	// IdleTimeout is the idle timeout offered to the peer, and the maximum idle timeout accepted from the peer
+	IdleTimeout                           time.Duration
+	ReceiveStreamFlowControlWindow        protocol.ByteCount
+	ReceiveConnectionFlowControlWindow    protocol.ByteCount
+	MaxReceiveStreamFlowControlWindow     protocol.ByteCount
+	MaxReceiveConnectionFlowControlWindow protocol.ByteCount
+	// MaxIncomingStreams is the number of streams the peer may open
+	MaxIncomingStreams uint32
 }
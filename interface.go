@@ -4,8 +4,12 @@ import (
 	"crypto/tls"
 	"io"
 	"net"
+	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/frames"
 	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
 )
 
 // Stream is the interface implemented by QUIC streams
@@ -16,6 +20,19 @@ type Stream interface {
 	StreamID() protocol.StreamID
 	// Reset closes the stream with an error.
 	Reset(error)
+	// SetPriority sets the priority and the weight of the stream, which are used by the Config.StreamScheduling.
+	// The priority ranges from protocol.HighestStreamPriority to protocol.LowestStreamPriority, the weight from protocol.MinStreamWeight to protocol.MaxStreamWeight.
+	// Values out of range are clamped.
+	SetPriority(priority protocol.StreamPriority, weight uint16)
+	// SetReadDeadline sets the deadline for future Read calls and any currently-blocked Read call.
+	// When the deadline is reached, Read returns a net.Error with Timeout() == true. A zero value for t means Read will not time out.
+	SetReadDeadline(t time.Time) error
+	// SetWriteDeadline sets the deadline for future Write calls and any currently-blocked Write call.
+	// When the deadline is reached, Write returns a net.Error with Timeout() == true. The data that wasn't sent yet is dropped.
+	// A zero value for t means Write will not time out.
+	SetWriteDeadline(t time.Time) error
+	// SetDeadline sets the read and write deadlines, it is equivalent to calling both SetReadDeadline and SetWriteDeadline.
+	SetDeadline(t time.Time) error
 }
 
 // A Session is a QUIC connection between two peers.
@@ -36,6 +53,102 @@ type Session interface {
 	RemoteAddr() net.Addr
 	// Close closes the connection. The error will be sent to the remote peer in a CONNECTION_CLOSE frame. An error value of nil is allowed and will cause a normal PeerGoingAway to be sent.
 	Close(error) error
+	// CloseGracefully sends a GOAWAY frame, and closes the connection once all open streams have finished.
+	// New streams can't be opened or accepted after calling CloseGracefully, OpenStream and AcceptStream return a *GoawayError.
+	// If the streams don't finish within the timeout, the connection is closed anyway, and an error is returned.
+	// When the peer sends a GOAWAY frame, the connection is closed the same way.
+	CloseGracefully(timeout time.Duration) error
+	// PLUSInfo returns a snapshot of what PLUS observed for this connection.
+	PLUSInfo() PLUSInfo
+	// ConnectionStats returns a snapshot of the statistics of the connection.
+	// After the connection is closed, it returns the statistics at the time it was closed.
+	ConnectionStats() ConnectionStats
+	// RequestPCF asks the PLUS layer to send a PCF request, either once or repeatedly.
+	// It returns an error if the connection doesn't use PLUS, or if the integrity is invalid.
+	RequestPCF(*PCFRequest) error
+	// CancelPCFRequest stops repeating a PCF request. It must be called with the same *PCFRequest that was passed to RequestPCF.
+	CancelPCFRequest(*PCFRequest)
+	// PCFFeedback returns a channel that receives the PCF feedback that the peer sends back in PLUSFeedbackFrames.
+	// Feedback is dropped if the application doesn't read it fast enough.
+	// The channel is closed when the connection is closed.
+	PCFFeedback() <-chan []byte
+	// SetLogLevel sets the log level of this connection, without affecting other connections using the same Config.Logger.
+	SetLogLevel(utils.LogLevel)
+}
+
+// A PCFRequest asks devices on the path to fill in the Path Communication Function (PCF) of a PLUS packet.
+// The peer sends the data it receives back in a PLUSFeedbackFrame.
+type PCFRequest struct {
+	// Type is the PCF type
+	Type uint16
+	// Integrity is the PCF integrity protection level, from 0 to 3
+	Integrity uint16
+	// Value is the payload of the request
+	Value []byte
+	// Every makes the request repeat every N packets. If it is 0, the request is only sent once.
+	Every int
+}
+
+// PLUSInfo contains statistics about the PLUS layer of a connection.
+// All fields except the PLUSFeedbackFrame counters are zero if the connection doesn't use PLUS.
+type PLUSInfo struct {
+	// UsesPLUS is set if the connection runs over PLUS
+	UsesPLUS bool
+	// PacketsReceived is the number of PLUS packets received on this connection
+	PacketsReceived uint64
+	// PSN is the packet serial number of the last PLUS packet received
+	PSN uint32
+	// PSE is the packet serial echo of the last PLUS packet received, i.e. the last PSN that the peer received from us
+	PSE uint32
+	// FeedbackReceived is the number of PCF feedbacks the PLUS layer delivered
+	FeedbackReceived uint64
+	// RTT is the latest RTT sample of the PLUS layer, i.e. the time between sending a PSN and receiving the first packet that echoes it.
+	// Just like the RTT measured by devices on the path, it includes the time the peer waits before sending the next packet.
+	// It is 0 before the first sample.
+	RTT time.Duration
+	// FeedbackFramesSent is the number of PLUSFeedbackFrames sent, including retransmissions
+	FeedbackFramesSent uint64
+	// FeedbackFramesReceived is the number of PLUSFeedbackFrames received
+	FeedbackFramesReceived uint64
+}
+
+// ConnectionStats contains statistics about the congestion controller, loss recovery and flow control of a connection.
+type ConnectionStats struct {
+	// SmoothedRTT, MinRTT and LatestRTT are the RTT estimates. They are 0 before the first RTT sample.
+	SmoothedRTT time.Duration
+	MinRTT      time.Duration
+	LatestRTT   time.Duration
+	// CongestionWindow is the congestion window, in bytes
+	CongestionWindow protocol.ByteCount
+	// SlowStartThreshold is the slow start threshold, in bytes. It is 0 if the congestion controller doesn't use one.
+	SlowStartThreshold protocol.ByteCount
+	// BytesInFlight is the number of bytes sent, but not yet acknowledged or declared lost
+	BytesInFlight protocol.ByteCount
+	// PacketsSent is the number of packets sent, including retransmissions
+	PacketsSent uint64
+	// PacketsReceived is the number of packets received, not counting duplicates and undecryptable packets
+	PacketsReceived uint64
+	// PacketsLost is the number of packets declared lost, either by loss detection or by a retransmission timeout
+	PacketsLost uint64
+	// PacketsRetransmitted is the number of lost packets whose frames were retransmitted
+	PacketsRetransmitted uint64
+	// BytesSent and BytesReceived count the stream data sent and received on streams that contribute to connection-level flow control
+	BytesSent     protocol.ByteCount
+	BytesReceived protocol.ByteCount
+	// FlowControlBlockedTime is the time sending was blocked by connection-level flow control
+	FlowControlBlockedTime time.Duration
+	// Streams contains the statistics of the open streams
+	Streams map[protocol.StreamID]StreamStats
+}
+
+// StreamStats contains statistics about a stream.
+type StreamStats struct {
+	// BytesSent is the number of bytes sent on the stream
+	BytesSent protocol.ByteCount
+	// BytesReceived is the highest offset received on the stream
+	BytesReceived protocol.ByteCount
+	// FlowControlBlockedTime is the time sending was blocked by stream-level flow control
+	FlowControlBlockedTime time.Duration
 }
 
 // A NonFWSession is a QUIC connection between two peers half-way through the handshake.
@@ -46,7 +159,6 @@ type NonFWSession interface {
 }
 
 // Config contains all configuration data needed for a QUIC server or client.
-// More config parameters (such as timeouts) will be added soon, see e.g. https://github.com/lucas-clemente/quic-go/issues/441.
 type Config struct {
 	TLSConfig *tls.Config
 	// The QUIC versions that can be negotiated.
@@ -57,8 +169,180 @@ type Config struct {
 	// This saves 8 bytes in the Public Header in every packet. However, if the IP address of the server changes, the connection cannot be migrated.
 	// Currently only valid for the client.
 	RequestConnectionIDTruncation bool
-    // Use PLUS?
-    UsePLUS bool
+	// Use PLUS?
+	// This is a shorthand for setting Transport to PLUSTransport.
+	UsePLUS bool
+	// The Transport that packets are sent and received over.
+	// If not set, it uses PLUSTransport if UsePLUS is set, and UDPTransport otherwise.
+	// Servers can use PLUSAndUDPTransport to accept both PLUS and non-PLUS clients.
+	Transport Transport
+	// FallbackTransport is used by the client if the server or the path doesn't support the Transport, e.g. to fall back from PLUS to UDP.
+	// The DialAddr functions start a second handshake over the FallbackTransport after the FallbackDelay, or as soon as the first handshake fails.
+	// The session that is established first is used, the other handshake is aborted. Use Session.PLUSInfo() to find out if PLUS is used.
+	// Dial and DialNonFWSecure don't use the FallbackTransport, since both handshakes need their own net.PacketConn.
+	FallbackTransport Transport
+	// FallbackDelay is the time after which the FallbackTransport is tried.
+	// If not set, protocol.DefaultFallbackDelay is used.
+	FallbackDelay time.Duration
+	// HandshakeTimeout is the maximum duration that the cryptographic handshake may take.
+	// If not set, protocol.MaxTimeForCryptoHandshake is used.
+	HandshakeTimeout time.Duration
+	// IdleTimeout is the maximum duration that may pass without any incoming network activity.
+	// The idle timeout of a connection is the smaller of the values of both peers. It must be at least protocol.MinIdleTimeout.
+	// If not set, the server uses protocol.DefaultIdleTimeout, and accepts values of up to protocol.MaxIdleTimeoutServer, and the client uses protocol.MaxIdleTimeoutClient.
+	IdleTimeout time.Duration
+	// ReceiveStreamFlowControlWindow is the initial stream-level flow control window for receiving data.
+	// It must be at least protocol.MinReceiveFlowControlWindow. If not set, protocol.ReceiveStreamFlowControlWindow is used.
+	ReceiveStreamFlowControlWindow protocol.ByteCount
+	// ReceiveConnectionFlowControlWindow is the initial connection-level flow control window for receiving data.
+	// It must be at least protocol.MinReceiveFlowControlWindow. If not set, protocol.ReceiveConnectionFlowControlWindow is used.
+	ReceiveConnectionFlowControlWindow protocol.ByteCount
+	// MaxReceiveStreamFlowControlWindow is the size the stream-level flow control window can grow to by auto-tuning.
+	// It must not be smaller than the ReceiveStreamFlowControlWindow.
+	// If not set, protocol.MaxReceiveStreamFlowControlWindowServer or protocol.MaxReceiveStreamFlowControlWindowClient is used.
+	MaxReceiveStreamFlowControlWindow protocol.ByteCount
+	// MaxReceiveConnectionFlowControlWindow is the size the connection-level flow control window can grow to by auto-tuning.
+	// It must not be smaller than the ReceiveConnectionFlowControlWindow.
+	// If not set, protocol.MaxReceiveConnectionFlowControlWindowServer or protocol.MaxReceiveConnectionFlowControlWindowClient is used.
+	MaxReceiveConnectionFlowControlWindow protocol.ByteCount
+	// StreamScheduling decides how the bandwidth is shared between the streams that have data to send.
+	// If not set, StreamSchedulingRoundRobin is used, which ignores the priorities set with Stream.SetPriority.
+	StreamScheduling StreamSchedulingMode
+	// MaxIncomingStreams is the maximum number of streams that the peer may open.
+	// It must not be larger than protocol.MaxIncomingStreamsLimit.
+	// If not set, protocol.MaxIncomingDynamicStreamsPerConnection is used.
+	MaxIncomingStreams uint32
+	// CongestionControl creates the congestion controller of every connection.
+	// Built-in choices are congestion.CubicFactory, congestion.RenoFactory, congestion.EmulatedCubicFactory, congestion.BBRFactory,
+	// and congestion.LEDBATFactory for background transfers that should yield to other traffic.
+	// If not set, congestion.CubicFactory is used.
+	CongestionControl congestion.SendAlgorithmFactory
+	// Tracer is notified about the events of every connection, e.g. qlog.NewTracer writes them to a JSON file.
+	Tracer Tracer
+	// Logger is used for the logs of the server or client. Every session logs to a child logger, prefixed with its perspective and connection ID.
+	// If not set, utils.DefaultLogger is used, which uses the log level from the QUIC_GO_LOG_LEVEL environment variable.
+	Logger utils.Logger
+	// KeyLogWriter optionally specifies a destination for the keys of every connection, in the format documented at crypto.KeyLogEntry.
+	// It can be used to decrypt packet captures, e.g. with the decoder package.
+	// Using KeyLogWriter compromises security and should only be used for debugging.
+	KeyLogWriter io.Writer
+}
+
+// A StreamSchedulingMode decides which streams send their data first
+type StreamSchedulingMode uint8
+
+const (
+	// StreamSchedulingRoundRobin shares the bandwidth equally between all streams
+	StreamSchedulingRoundRobin StreamSchedulingMode = iota
+	// StreamSchedulingStrictPriority sends the data of the streams with the highest priority first.
+	// Streams with the same priority share the bandwidth according to their weights.
+	StreamSchedulingStrictPriority
+	// StreamSchedulingWeightedFair shares the bandwidth between all streams according to their weights, ignoring their priorities
+	StreamSchedulingWeightedFair
+)
+
+// A Tracer creates a ConnectionTracer for every new connection.
+type Tracer interface {
+	// TracerForConnection is called when a connection is created. If it returns nil, the connection is not traced.
+	TracerForConnection(p protocol.Perspective, connectionID protocol.ConnectionID) ConnectionTracer
+}
+
+// A ConnectionTracer is notified about the events of a single connection.
+// Most methods are called from the run loop of the connection, so they should return quickly.
+// DroppedPacket may be called from a different goroutine.
+type ConnectionTracer interface {
+	SentPacket(packetNumber protocol.PacketNumber, size protocol.ByteCount, encLevel protocol.EncryptionLevel, frames []frames.Frame)
+	ReceivedPacket(packetNumber protocol.PacketNumber, size protocol.ByteCount, encLevel protocol.EncryptionLevel, frames []frames.Frame)
+	DroppedPacket(size protocol.ByteCount, reason PacketDropReason)
+	// BufferedUndecryptablePacket is called when a packet that can't be decrypted yet is queued until the handshake progresses
+	BufferedUndecryptablePacket(size protocol.ByteCount)
+	LostPacket(packetNumber protocol.PacketNumber, size protocol.ByteCount)
+	// RetransmittedPacket is called when the frames of a lost packet are queued for retransmission
+	RetransmittedPacket(packetNumber protocol.PacketNumber)
+	// UpdatedCongestionState is called when the congestion window or the slow start threshold changed
+	UpdatedCongestionState(CongestionState)
+	ChangedEncryptionLevel(protocol.EncryptionLevel)
+	// SentWindowUpdate and ReceivedWindowUpdate are called for flow control window updates. The StreamID is 0 for the connection-level window.
+	SentWindowUpdate(streamID protocol.StreamID, offset protocol.ByteCount)
+	ReceivedWindowUpdate(streamID protocol.StreamID, offset protocol.ByteCount)
+	// SentPLUSFeedback and ReceivedPLUSFeedback are called with PLUS feedback before it is fragmented, and after it was reassembled
+	SentPLUSFeedback(data []byte)
+	ReceivedPLUSFeedback(data []byte)
+	// Closed is called when the connection is closed. No other methods are called afterwards, except for DroppedPacket.
+	Closed(err error)
+}
+
+// A PacketDropReason is the reason why a packet was dropped
+type PacketDropReason uint8
+
+const (
+	// PacketDropQueueFull means that the run loop didn't keep up with the incoming packets
+	PacketDropQueueFull PacketDropReason = iota + 1
+	// PacketDropDuplicate means that a packet with the same packet number was already received
+	PacketDropDuplicate
+	// PacketDropBelowStopWaiting means that the packet number is smaller than the LeastUnacked of a STOP_WAITING frame
+	PacketDropBelowStopWaiting
+	// PacketDropUndecryptable means that the packet couldn't be decrypted, and wasn't buffered
+	PacketDropUndecryptable
+)
+
+func (r PacketDropReason) String() string {
+	switch r {
+	case PacketDropQueueFull:
+		return "queue full"
+	case PacketDropDuplicate:
+		return "duplicate"
+	case PacketDropBelowStopWaiting:
+		return "below STOP_WAITING"
+	case PacketDropUndecryptable:
+		return "undecryptable"
+	default:
+		return "unknown"
+	}
+}
+
+// CongestionState is the state of the congestion controller, passed to ConnectionTracer.UpdatedCongestionState
+type CongestionState struct {
+	CongestionWindow protocol.ByteCount
+	// SlowStartThreshold is 0 if the congestion controller doesn't use one
+	SlowStartThreshold protocol.ByteCount
+	BytesInFlight      protocol.ByteCount
+	SmoothedRTT        time.Duration
+	MinRTT             time.Duration
+}
+
+// A Transport creates the PacketTransport that a QUIC server or client sends and receives packets over.
+type Transport interface {
+	// Listen is called by the server. The PacketTransport takes ownership of the net.PacketConn.
+	Listen(pconn net.PacketConn) (PacketTransport, error)
+	// Dial is called by the client. It returns the PacketTransport and the TransportConnection used to talk to the server.
+	Dial(pconn net.PacketConn, remoteAddr net.Addr, connectionID protocol.ConnectionID) (PacketTransport, TransportConnection, error)
+}
+
+// A PacketTransport reads QUIC packets from the network.
+type PacketTransport interface {
+	// ReadPacket reads the next QUIC packet into p.
+	// It returns the address the packet was received from and the TransportConnection it belongs to.
+	// If the transport received feedback that has to be echoed to the peer (e.g. PLUS PCF feedback), it is returned as well. Otherwise, feedback is nil.
+	ReadPacket(p []byte) (n int, remoteAddr net.Addr, conn TransportConnection, feedback []byte, err error)
+	// Close closes the transport, including the underlying net.PacketConn.
+	Close() error
+	// LocalAddr returns the local address.
+	LocalAddr() net.Addr
+}
+
+// A TransportConnection is used by a single QUIC connection to send packets to its peer.
+type TransportConnection interface {
+	Write([]byte) error
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+	// SetCurrentRemoteAddr is called when the peer's address changed.
+	SetCurrentRemoteAddr(net.Addr)
+	// AddFeedback passes feedback received from the peer in a PLUSFeedbackFrame to the transport.
+	AddFeedback([]byte) error
+	// Close releases the state the transport keeps for this connection.
+	// It must not close the underlying net.PacketConn.
+	Close() error
 }
 
 // A Listener for incoming QUIC connections
@@ -69,4 +353,7 @@ type Listener interface {
 	Addr() net.Addr
 	// Accept returns new sessions. It should be called in a loop.
 	Accept() (Session, error)
+	// StopAccepting refuses new connections by sending a Public Reset, and closes sessions whose handshake is not complete yet.
+	// Sessions that were already returned by Accept are not affected.
+	StopAccepting()
 }
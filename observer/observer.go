@@ -0,0 +1,324 @@
+// Package observer reconstructs PLUS and gQUIC flows from packet captures, and computes the metrics an on-path observer can measure.
+package observer
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+// maxTrackedPSNs limits the number of PSNs per direction that are waiting to be echoed
+const maxTrackedPSNs = 1024
+
+// Protocol is the protocol of a flow
+type Protocol string
+
+const (
+	// ProtocolPLUS is used for QUIC packets encapsulated in PLUS
+	ProtocolPLUS Protocol = "PLUS"
+	// ProtocolQUIC is used for gQUIC packets sent directly over UDP
+	ProtocolQUIC Protocol = "QUIC"
+)
+
+// DirectionStats are the metrics of one direction of a flow
+type DirectionStats struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+	// Lost is the number of sequence numbers (PSNs for PLUS, packet numbers for gQUIC) that were skipped, and not received later
+	Lost uint64 `json:"lost"`
+	// Reordered is the number of packets that arrived after a packet with a higher sequence number
+	Reordered uint64 `json:"reordered"`
+	// EchoDelay is the time between a packet in this direction and the first packet in the opposite direction echoing its PSN, in milliseconds.
+	// It is the part of the RTT between the observer and the receiver of this direction. It is only measured for PLUS flows.
+	EchoDelay float64 `json:"echo_delay_ms,omitempty"`
+}
+
+// A Sample contains the metrics of a flow at a point in time
+type Sample struct {
+	Time time.Time `json:"time"`
+	// RTT is the latest passive RTT estimate, in milliseconds, i.e. the sum of the EchoDelays of both directions
+	// It is only available for PLUS flows.
+	RTT            float64        `json:"rtt_ms,omitempty"`
+	ClientToServer DirectionStats `json:"client_to_server"`
+	ServerToClient DirectionStats `json:"server_to_client"`
+}
+
+// A Flow is a PLUS or gQUIC connection
+// The client is the endpoint that sent the first packet seen.
+type Flow struct {
+	ID       string   `json:"id"`
+	Protocol Protocol `json:"protocol"`
+	// CAT is the PLUS Connection and Association Token
+	CAT          uint64    `json:"cat,omitempty"`
+	ConnectionID uint64    `json:"connection_id,omitempty"`
+	Client       string    `json:"client"`
+	Server       string    `json:"server"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	// Stopped is set if a PLUS packet with the stop flag was seen
+	Stopped bool     `json:"stopped,omitempty"`
+	Series  []Sample `json:"series"`
+
+	interval       time.Duration
+	serverAddr     string
+	clientToServer direction
+	serverToClient direction
+	nextSample     time.Time
+	// set if the flow changed since the last sample
+	changed bool
+}
+
+type direction struct {
+	stats DirectionStats
+
+	hasSequenceNumber bool
+	lowest            uint64
+	highest           uint64
+	numbered          uint64
+
+	// the time when PSNs were first seen, until they are echoed in the opposite direction
+	psnTimes  map[uint32]time.Time
+	echoDelay time.Duration
+}
+
+// An Observer reconstructs flows from the datagrams of a packet capture
+// Datagrams have to be passed in the order they were captured.
+type Observer struct {
+	interval time.Duration
+
+	flows     []*Flow
+	plusFlows map[uint64]*Flow
+	quicFlows map[protocol.ConnectionID]*Flow
+	// gQUIC servers can omit the connection ID, so flows are also looked up by the addresses of the last packet with a connection ID
+	addrFlows map[string]*Flow
+}
+
+// NewObserver creates a new Observer
+// A flow is sampled at most once per interval. If the interval is 0, a sample is taken for every packet.
+func NewObserver(interval time.Duration) *Observer {
+	return &Observer{
+		interval:  interval,
+		plusFlows: make(map[uint64]*Flow),
+		quicFlows: make(map[protocol.ConnectionID]*Flow),
+		addrFlows: make(map[string]*Flow),
+	}
+}
+
+// Observe processes a datagram
+// It returns false if the datagram is neither a PLUS nor a gQUIC packet.
+func (o *Observer) Observe(d *Datagram) bool {
+	if quic.IsPLUSPacket(d.Payload) {
+		return o.observePLUS(d)
+	}
+	return o.observeQUIC(d)
+}
+
+// Flows returns all flows, in the order they were first seen
+func (o *Observer) Flows() []*Flow {
+	for _, f := range o.flows {
+		if f.changed {
+			f.sample(f.End)
+		}
+	}
+	return o.flows
+}
+
+func (o *Observer) observePLUS(d *Datagram) bool {
+	h, payload, err := ParsePLUSHeader(d.Payload)
+	if err != nil {
+		return false
+	}
+	f, ok := o.plusFlows[h.CAT]
+	if !ok {
+		f = o.newFlow(ProtocolPLUS, d)
+		f.CAT = h.CAT
+		f.ID = fmt.Sprintf("plus:%016x", h.CAT)
+		o.plusFlows[h.CAT] = f
+	}
+	sender, receiver, sentBy := f.directions(d)
+	if f.ConnectionID == 0 {
+		if hdr, err := quic.ParsePublicHeader(bytes.NewReader(payload), sentBy); err == nil {
+			f.ConnectionID = uint64(hdr.ConnectionID)
+		}
+	}
+	if h.Stop {
+		f.Stopped = true
+	}
+
+	var seq uint64
+	if sender.hasSequenceNumber {
+		seq = sender.highest + uint64(int32(h.PSN-uint32(sender.highest)))
+	} else {
+		// leave room for PSNs that arrive reordered before the first PSN seen
+		seq = uint64(h.PSN) + 1<<32
+	}
+	sender.receivedSequenceNumber(seq)
+	sender.sentPSN(h.PSN, d.Time)
+	receiver.echoedPSN(h.PSE, d.Time)
+
+	f.receivedPacket(d, sender)
+	return true
+}
+
+func (o *Observer) observeQUIC(d *Datagram) bool {
+	// the perspective of the sender is needed to parse the public header
+	// It is only known if the addresses belong to a flow, the first datagram of a flow is sent by the client.
+	sentBy := protocol.PerspectiveClient
+	addrFlow, ok := o.addrFlows[addrKey(d.Src, d.Dst)]
+	if ok {
+		_, _, sentBy = addrFlow.directions(d)
+	}
+	hdr, err := quic.ParsePublicHeader(bytes.NewReader(d.Payload), sentBy)
+	if err != nil {
+		return false
+	}
+
+	// flows are identified by their connection ID, the addresses are only used for packets that omit it
+	// Otherwise, a new connection using the same addresses would be attributed to the old flow.
+	var f *Flow
+	if hdr.TruncateConnectionID {
+		// only servers omit the connection ID, so the addresses belong to a flow
+		f = addrFlow
+	} else {
+		f, ok = o.quicFlows[hdr.ConnectionID]
+		if !ok {
+			// a flow starts with a packet the client sends with the version flag set
+			// Other packets with unknown connection IDs could be anything, e.g. other UDP traffic.
+			if !hdr.VersionFlag || sentBy != protocol.PerspectiveClient {
+				return false
+			}
+			f = o.newFlow(ProtocolQUIC, d)
+			f.ConnectionID = uint64(hdr.ConnectionID)
+			f.ID = fmt.Sprintf("quic:%016x", uint64(hdr.ConnectionID))
+			o.quicFlows[hdr.ConnectionID] = f
+		}
+		o.addrFlows[addrKey(d.Src, d.Dst)] = f
+		o.addrFlows[addrKey(d.Dst, d.Src)] = f
+	}
+
+	sender, _, flowSentBy := f.directions(d)
+	if flowSentBy != sentBy {
+		// the addresses didn't belong to this flow before
+		if hdr, err = quic.ParsePublicHeader(bytes.NewReader(d.Payload), flowSentBy); err != nil {
+			return false
+		}
+	}
+	// version negotiation packets and public resets don't have a packet number
+	if hdr.PacketNumberLen != 0 {
+		pn := hdr.PacketNumber
+		if sender.hasSequenceNumber {
+			pn = protocol.InferPacketNumber(hdr.PacketNumberLen, protocol.PacketNumber(sender.highest), hdr.PacketNumber)
+		}
+		sender.receivedSequenceNumber(uint64(pn))
+	}
+
+	f.receivedPacket(d, sender)
+	return true
+}
+
+func (o *Observer) newFlow(p Protocol, d *Datagram) *Flow {
+	f := &Flow{
+		Protocol:   p,
+		Client:     d.Src.String(),
+		Server:     d.Dst.String(),
+		Start:      d.Time,
+		interval:   o.interval,
+		serverAddr: d.Dst.String(),
+		clientToServer: direction{
+			psnTimes: make(map[uint32]time.Time),
+		},
+		serverToClient: direction{
+			psnTimes: make(map[uint32]time.Time),
+		},
+	}
+	o.flows = append(o.flows, f)
+	return f
+}
+
+// directions returns the direction a datagram was sent in, the opposite direction, and the perspective of the sender
+// The client might change its address (e.g. due to NAT rebinding), so datagrams are only attributed to the server if they're sent from the server's address.
+func (f *Flow) directions(d *Datagram) (*direction, *direction, protocol.Perspective) {
+	if d.Src.String() == f.serverAddr {
+		return &f.serverToClient, &f.clientToServer, protocol.PerspectiveServer
+	}
+	return &f.clientToServer, &f.serverToClient, protocol.PerspectiveClient
+}
+
+func (f *Flow) receivedPacket(d *Datagram, sender *direction) {
+	sender.stats.Packets++
+	sender.stats.Bytes += uint64(len(d.Payload))
+	f.End = d.Time
+	f.changed = true
+	if f.nextSample.IsZero() || !d.Time.Before(f.nextSample) {
+		f.sample(d.Time)
+	}
+}
+
+func (f *Flow) sample(t time.Time) {
+	s := Sample{
+		Time:           t,
+		ClientToServer: f.clientToServer.stats,
+		ServerToClient: f.serverToClient.stats,
+	}
+	if f.clientToServer.echoDelay != 0 && f.serverToClient.echoDelay != 0 {
+		s.RTT = milliseconds(f.clientToServer.echoDelay + f.serverToClient.echoDelay)
+	}
+	f.Series = append(f.Series, s)
+	f.nextSample = t.Add(f.interval)
+	f.changed = false
+}
+
+func (d *direction) receivedSequenceNumber(seq uint64) {
+	d.numbered++
+	if !d.hasSequenceNumber {
+		d.hasSequenceNumber = true
+		d.lowest = seq
+		d.highest = seq
+		return
+	}
+	if seq > d.highest {
+		d.highest = seq
+	} else if seq < d.highest {
+		d.stats.Reordered++
+	}
+	if seq < d.lowest {
+		d.lowest = seq
+	}
+	if expected := d.highest - d.lowest + 1; expected > d.numbered {
+		d.stats.Lost = expected - d.numbered
+	} else {
+		d.stats.Lost = 0
+	}
+}
+
+func (d *direction) sentPSN(psn uint32, t time.Time) {
+	if _, ok := d.psnTimes[psn]; ok {
+		return
+	}
+	if len(d.psnTimes) >= maxTrackedPSNs {
+		d.psnTimes = make(map[uint32]time.Time)
+	}
+	d.psnTimes[psn] = t
+}
+
+func (d *direction) echoedPSN(pse uint32, t time.Time) {
+	sent, ok := d.psnTimes[pse]
+	if !ok {
+		return
+	}
+	delete(d.psnTimes, pse)
+	d.echoDelay = t.Sub(sent)
+	d.stats.EchoDelay = milliseconds(d.echoDelay)
+}
+
+func addrKey(src, dst net.Addr) string {
+	return src.String() + "-" + dst.String()
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,250 @@
+package observer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func plusPacket(cat uint64, psn, pse uint32, flags byte, extension []byte, payload []byte) []byte {
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.BigEndian, uint32(quic.PLUSMagic<<4)|uint32(flags))
+	binary.Write(b, binary.BigEndian, cat)
+	binary.Write(b, binary.BigEndian, psn)
+	binary.Write(b, binary.BigEndian, pse)
+	b.Write(extension)
+	b.Write(payload)
+	return b.Bytes()
+}
+
+// quicPacket creates a gQUIC packet with a 2 byte packet number
+// If the connection ID is 0, it is omitted. gQUIC uses little endian for connection IDs and packet numbers.
+func quicPacket(connID uint64, version bool, pn uint16) []byte {
+	b := &bytes.Buffer{}
+	flags := byte(0x10)
+	if connID != 0 {
+		flags |= 0x08
+	}
+	if version {
+		flags |= 0x01
+	}
+	b.WriteByte(flags)
+	if connID != 0 {
+		binary.Write(b, binary.LittleEndian, connID)
+	}
+	if version {
+		b.WriteString("Q036")
+	}
+	binary.Write(b, binary.LittleEndian, pn)
+	b.WriteString("encrypted payload")
+	return b.Bytes()
+}
+
+var _ = Describe("PLUS header", func() {
+	It("parses the basic header", func() {
+		h, payload, err := ParsePLUSHeader(plusPacket(0xdecafbad, 42, 41, plusFlagStop, nil, []byte("foobar")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(h).To(Equal(&PLUSHeader{CAT: 0xdecafbad, PSN: 42, PSE: 41, Stop: true}))
+		Expect(payload).To(Equal([]byte("foobar")))
+	})
+
+	It("parses the extended header", func() {
+		h, payload, err := ParsePLUSHeader(plusPacket(1, 2, 3, plusFlagExtended, []byte{0x01, 3<<2 | 0x01, 'f', 'o', 'o'}, []byte("bar")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(h.Extended).To(BeTrue())
+		Expect(h.PCFType).To(Equal(uint16(1)))
+		Expect(h.PCFValue).To(Equal([]byte("foo")))
+		Expect(payload).To(Equal([]byte("bar")))
+	})
+
+	It("parses two byte PCF types", func() {
+		h, payload, err := ParsePLUSHeader(plusPacket(1, 2, 3, plusFlagExtended, []byte{0x00, 0x42, 0x00}, []byte("bar")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(h.PCFType).To(Equal(uint16(0x4200)))
+		Expect(h.PCFValue).To(BeEmpty())
+		Expect(payload).To(Equal([]byte("bar")))
+	})
+
+	It("parses extended headers without a PCF value", func() {
+		h, payload, err := ParsePLUSHeader(plusPacket(1, 2, 3, plusFlagExtended, []byte{0xff}, []byte("bar")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(h.PCFType).To(Equal(uint16(0xff)))
+		Expect(payload).To(Equal([]byte("bar")))
+	})
+
+	It("errors on truncated headers", func() {
+		data := plusPacket(1, 2, 3, plusFlagExtended, []byte{0x01, 3 << 2, 'f', 'o', 'o'}, nil)
+		_, _, err := ParsePLUSHeader(data)
+		Expect(err).ToNot(HaveOccurred())
+		for i := 4; i < len(data); i++ {
+			_, _, err := ParsePLUSHeader(data[:i])
+			Expect(err).To(MatchError(errTruncatedPLUSHeader))
+		}
+	})
+})
+
+var _ = Describe("Observer", func() {
+	var (
+		o      *Observer
+		t0     time.Time
+		client *net.UDPAddr
+		server *net.UDPAddr
+	)
+
+	BeforeEach(func() {
+		o = NewObserver(0)
+		t0 = time.Unix(1500000000, 0)
+		client = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+		server = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 443}
+	})
+
+	fromClient := func(t time.Duration, payload []byte) *Datagram {
+		return &Datagram{Time: t0.Add(t), Src: client, Dst: server, Payload: payload}
+	}
+	fromServer := func(t time.Duration, payload []byte) *Datagram {
+		return &Datagram{Time: t0.Add(t), Src: server, Dst: client, Payload: payload}
+	}
+
+	Context("PLUS flows", func() {
+		It("reconstructs a flow", func() {
+			Expect(o.Observe(fromClient(0, plusPacket(0xdecafbad, 1, 0, 0, nil, quicPacket(0x1337, true, 1))))).To(BeTrue())
+			Expect(o.Observe(fromServer(time.Millisecond, plusPacket(0xdecafbad, 100, 1, plusFlagStop, nil, quicPacket(0, false, 1))))).To(BeTrue())
+			flows := o.Flows()
+			Expect(flows).To(HaveLen(1))
+			f := flows[0]
+			Expect(f.ID).To(Equal("plus:00000000decafbad"))
+			Expect(f.Protocol).To(Equal(ProtocolPLUS))
+			Expect(f.CAT).To(Equal(uint64(0xdecafbad)))
+			Expect(f.ConnectionID).To(Equal(uint64(0x1337)))
+			Expect(f.Client).To(Equal(client.String()))
+			Expect(f.Server).To(Equal(server.String()))
+			Expect(f.Start).To(Equal(t0))
+			Expect(f.End).To(Equal(t0.Add(time.Millisecond)))
+			Expect(f.Stopped).To(BeTrue())
+			last := f.Series[len(f.Series)-1]
+			Expect(last.ClientToServer.Packets).To(Equal(uint64(1)))
+			Expect(last.ServerToClient.Packets).To(Equal(uint64(1)))
+		})
+
+		It("separates flows by CAT", func() {
+			o.Observe(fromClient(0, plusPacket(1, 1, 0, 0, nil, nil)))
+			o.Observe(fromClient(0, plusPacket(2, 1, 0, 0, nil, nil)))
+			Expect(o.Flows()).To(HaveLen(2))
+		})
+
+		It("measures the RTT from PSN echoes", func() {
+			o.Observe(fromClient(0, plusPacket(1, 1, 0, 0, nil, nil)))
+			o.Observe(fromServer(10*time.Millisecond, plusPacket(1, 100, 1, 0, nil, nil)))
+			// repeated echoes are ignored
+			o.Observe(fromServer(15*time.Millisecond, plusPacket(1, 101, 1, 0, nil, nil)))
+			o.Observe(fromClient(30*time.Millisecond, plusPacket(1, 2, 100, 0, nil, nil)))
+			series := o.Flows()[0].Series
+			Expect(series[1].RTT).To(BeZero())
+			Expect(series[1].ClientToServer.EchoDelay).To(Equal(10.0))
+			last := series[len(series)-1]
+			Expect(last.ServerToClient.EchoDelay).To(Equal(20.0))
+			Expect(last.RTT).To(Equal(30.0))
+		})
+
+		It("estimates loss and reordering", func() {
+			for _, psn := range []uint32{1, 2, 4, 5, 3, 7} {
+				o.Observe(fromClient(0, plusPacket(1, psn, 0, 0, nil, nil)))
+			}
+			stats := o.Flows()[0].Series[5].ClientToServer
+			Expect(stats.Packets).To(Equal(uint64(6)))
+			Expect(stats.Lost).To(Equal(uint64(1)))
+			Expect(stats.Reordered).To(Equal(uint64(1)))
+		})
+
+		It("handles PSN wraparound", func() {
+			for _, psn := range []uint32{0xfffffffe, 0, 0xffffffff, 1} {
+				o.Observe(fromClient(0, plusPacket(1, psn, 0, 0, nil, nil)))
+			}
+			stats := o.Flows()[0].Series[3].ClientToServer
+			Expect(stats.Lost).To(BeZero())
+			Expect(stats.Reordered).To(Equal(uint64(1)))
+		})
+	})
+
+	Context("gQUIC flows", func() {
+		It("reconstructs a flow", func() {
+			Expect(o.Observe(fromClient(0, quicPacket(0x1337, true, 1)))).To(BeTrue())
+			Expect(o.Observe(fromServer(time.Millisecond, quicPacket(0, false, 1)))).To(BeTrue())
+			Expect(o.Observe(fromServer(2*time.Millisecond, quicPacket(0x1337, false, 2)))).To(BeTrue())
+			flows := o.Flows()
+			Expect(flows).To(HaveLen(1))
+			f := flows[0]
+			Expect(f.ID).To(Equal("quic:0000000000001337"))
+			Expect(f.Protocol).To(Equal(ProtocolQUIC))
+			Expect(f.Client).To(Equal(client.String()))
+			last := f.Series[len(f.Series)-1]
+			Expect(last.ClientToServer.Packets).To(Equal(uint64(1)))
+			Expect(last.ServerToClient.Packets).To(Equal(uint64(2)))
+			Expect(last.RTT).To(BeZero())
+		})
+
+		It("estimates loss and reordering from packet numbers", func() {
+			for _, pn := range []uint16{1, 3, 2, 6} {
+				o.Observe(fromClient(0, quicPacket(0x1337, pn == 1, pn)))
+			}
+			stats := o.Flows()[0].Series[3].ClientToServer
+			Expect(stats.Lost).To(Equal(uint64(2)))
+			Expect(stats.Reordered).To(Equal(uint64(1)))
+		})
+
+		It("separates flows that use the same addresses by connection ID", func() {
+			Expect(o.Observe(fromClient(0, quicPacket(0x1337, true, 1)))).To(BeTrue())
+			Expect(o.Observe(fromServer(time.Millisecond, quicPacket(0, false, 1)))).To(BeTrue())
+			// the client reuses its address for a new connection
+			Expect(o.Observe(fromClient(2*time.Millisecond, quicPacket(0xbeef, true, 1)))).To(BeTrue())
+			Expect(o.Observe(fromServer(3*time.Millisecond, quicPacket(0, false, 1)))).To(BeTrue())
+			// a late packet of the first connection
+			Expect(o.Observe(fromClient(4*time.Millisecond, quicPacket(0x1337, false, 2)))).To(BeTrue())
+			flows := o.Flows()
+			Expect(flows).To(HaveLen(2))
+			Expect(flows[0].ConnectionID).To(Equal(uint64(0x1337)))
+			Expect(flows[0].Series[len(flows[0].Series)-1].ClientToServer.Packets).To(Equal(uint64(2)))
+			Expect(flows[0].Series[len(flows[0].Series)-1].ServerToClient.Packets).To(Equal(uint64(1)))
+			Expect(flows[1].ConnectionID).To(Equal(uint64(0xbeef)))
+			Expect(flows[1].Series[len(flows[1].Series)-1].ClientToServer.Packets).To(Equal(uint64(1)))
+			Expect(flows[1].Series[len(flows[1].Series)-1].ServerToClient.Packets).To(Equal(uint64(1)))
+		})
+
+		It("only starts flows with packets sent with the version flag", func() {
+			Expect(o.Observe(fromClient(0, quicPacket(0x1337, false, 1)))).To(BeFalse())
+			Expect(o.Flows()).To(BeEmpty())
+			Expect(o.Observe(fromClient(time.Millisecond, quicPacket(0x1337, true, 2)))).To(BeTrue())
+			Expect(o.Observe(fromClient(2*time.Millisecond, quicPacket(0x1337, false, 3)))).To(BeTrue())
+			Expect(o.Flows()).To(HaveLen(1))
+		})
+
+		It("ignores packets without a connection ID for unknown addresses", func() {
+			Expect(o.Observe(fromServer(0, quicPacket(0, false, 1)))).To(BeFalse())
+			Expect(o.Flows()).To(BeEmpty())
+		})
+
+		It("ignores datagrams that are not gQUIC packets", func() {
+			Expect(o.Observe(fromClient(0, []byte("foobar")))).To(BeFalse())
+			Expect(o.Flows()).To(BeEmpty())
+		})
+	})
+
+	It("samples flows once per interval", func() {
+		o = NewObserver(time.Second)
+		for i := 0; i < 4; i++ {
+			o.Observe(fromClient(time.Duration(i)*500*time.Millisecond, plusPacket(1, uint32(i), 0, 0, nil, nil)))
+		}
+		series := o.Flows()[0].Series
+		Expect(series).To(HaveLen(3))
+		Expect(series[0].Time).To(Equal(t0))
+		Expect(series[1].Time).To(Equal(t0.Add(time.Second)))
+		Expect(series[2].Time).To(Equal(t0.Add(1500 * time.Millisecond)))
+		Expect(series[2].ClientToServer.Packets).To(Equal(uint64(4)))
+	})
+})
@@ -0,0 +1,228 @@
+package observer
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// link types, see http://www.tcpdump.org/linktypes.html
+const (
+	linkTypeNull     uint32 = 0
+	linkTypeEthernet uint32 = 1
+	linkTypeRaw      uint32 = 101
+	linkTypeLinuxSLL uint32 = 113
+)
+
+const (
+	etherTypeIPv4 uint16 = 0x0800
+	etherTypeIPv6 uint16 = 0x86dd
+	etherTypeVLAN uint16 = 0x8100
+
+	ipProtocolUDP = 17
+)
+
+var (
+	errNotAPcapFile        = errors.New("pcap: not a pcap file")
+	errUnsupportedLinkType = errors.New("pcap: unsupported link type")
+	errPacketTooLarge      = errors.New("pcap: packet too large")
+)
+
+// maxPcapPacketSize limits the size of a single captured packet, such that a corrupt capture can't cause huge allocations
+const maxPcapPacketSize = 1 << 18
+
+// A Datagram is a UDP datagram read from a capture file
+type Datagram struct {
+	Time    time.Time
+	Src     *net.UDPAddr
+	Dst     *net.UDPAddr
+	Payload []byte
+}
+
+// A PcapReader reads UDP datagrams from a capture file in the pcap format
+// Packets that are not UDP, and IP fragments, are skipped.
+type PcapReader struct {
+	r         io.Reader
+	byteOrder binary.ByteOrder
+	nanosec   bool
+	linkType  uint32
+}
+
+// NewPcapReader reads the pcap file header and returns a PcapReader
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	reader := &PcapReader{r: r}
+	switch binary.LittleEndian.Uint32(header) {
+	case 0xa1b2c3d4:
+		reader.byteOrder = binary.LittleEndian
+	case 0xa1b23c4d:
+		reader.byteOrder = binary.LittleEndian
+		reader.nanosec = true
+	case 0xd4c3b2a1:
+		reader.byteOrder = binary.BigEndian
+	case 0x4d3cb2a1:
+		reader.byteOrder = binary.BigEndian
+		reader.nanosec = true
+	default:
+		return nil, errNotAPcapFile
+	}
+	reader.linkType = reader.byteOrder.Uint32(header[20:])
+	switch reader.linkType {
+	case linkTypeNull, linkTypeEthernet, linkTypeRaw, linkTypeLinuxSLL:
+	default:
+		return nil, errUnsupportedLinkType
+	}
+	return reader, nil
+}
+
+// ReadDatagram returns the next UDP datagram
+// It returns io.EOF at the end of the capture file, and io.ErrUnexpectedEOF if the last record is truncated.
+func (r *PcapReader) ReadDatagram() (*Datagram, error) {
+	header := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r.r, header); err != nil {
+			return nil, err
+		}
+		sec := r.byteOrder.Uint32(header)
+		frac := r.byteOrder.Uint32(header[4:])
+		capLen := r.byteOrder.Uint32(header[8:])
+		if capLen > maxPcapPacketSize {
+			return nil, errPacketTooLarge
+		}
+		data := make([]byte, capLen)
+		if _, err := io.ReadFull(r.r, data); err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		var t time.Time
+		if r.nanosec {
+			t = time.Unix(int64(sec), int64(frac))
+		} else {
+			t = time.Unix(int64(sec), int64(frac)*int64(time.Microsecond))
+		}
+		if d := r.parseLinkLayer(data); d != nil {
+			d.Time = t
+			return d, nil
+		}
+	}
+}
+
+func (r *PcapReader) parseLinkLayer(data []byte) *Datagram {
+	switch r.linkType {
+	case linkTypeNull:
+		if len(data) < 4 {
+			return nil
+		}
+		// the address family is stored in host byte order
+		family := binary.LittleEndian.Uint32(data)
+		if family > 0xffff {
+			family = binary.BigEndian.Uint32(data)
+		}
+		switch family {
+		case 2:
+			return parseIPv4(data[4:])
+		case 10, 24, 28, 30:
+			return parseIPv6(data[4:])
+		}
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return nil
+		}
+		etherType := binary.BigEndian.Uint16(data[12:])
+		data = data[14:]
+		for etherType == etherTypeVLAN {
+			if len(data) < 4 {
+				return nil
+			}
+			etherType = binary.BigEndian.Uint16(data[2:])
+			data = data[4:]
+		}
+		return parseIP(etherType, data)
+	case linkTypeRaw:
+		if len(data) == 0 {
+			return nil
+		}
+		switch data[0] >> 4 {
+		case 4:
+			return parseIPv4(data)
+		case 6:
+			return parseIPv6(data)
+		}
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil
+		}
+		return parseIP(binary.BigEndian.Uint16(data[14:]), data[16:])
+	}
+	return nil
+}
+
+func parseIP(etherType uint16, data []byte) *Datagram {
+	switch etherType {
+	case etherTypeIPv4:
+		return parseIPv4(data)
+	case etherTypeIPv6:
+		return parseIPv6(data)
+	}
+	return nil
+}
+
+func parseIPv4(data []byte) *Datagram {
+	if len(data) < 20 || data[0]>>4 != 4 {
+		return nil
+	}
+	headerLen := int(data[0]&0x0f) * 4
+	totalLen := int(binary.BigEndian.Uint16(data[2:]))
+	if headerLen < 20 || totalLen < headerLen || len(data) < totalLen {
+		return nil
+	}
+	// skip fragments
+	if binary.BigEndian.Uint16(data[6:])&0x3fff != 0 {
+		return nil
+	}
+	if data[9] != ipProtocolUDP {
+		return nil
+	}
+	return parseUDP(net.IP(data[12:16]), net.IP(data[16:20]), data[headerLen:totalLen])
+}
+
+// parseIPv6 only handles UDP datagrams directly following the IPv6 header
+func parseIPv6(data []byte) *Datagram {
+	if len(data) < 40 || data[0]>>4 != 6 {
+		return nil
+	}
+	payloadLen := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 40+payloadLen || data[6] != ipProtocolUDP {
+		return nil
+	}
+	return parseUDP(net.IP(data[8:24]), net.IP(data[24:40]), data[40:40+payloadLen])
+}
+
+func parseUDP(src, dst net.IP, data []byte) *Datagram {
+	if len(data) < 8 {
+		return nil
+	}
+	length := int(binary.BigEndian.Uint16(data[4:]))
+	if length < 8 || len(data) < length {
+		return nil
+	}
+	return &Datagram{
+		Src:     &net.UDPAddr{IP: copyIP(src), Port: int(binary.BigEndian.Uint16(data))},
+		Dst:     &net.UDPAddr{IP: copyIP(dst), Port: int(binary.BigEndian.Uint16(data[2:]))},
+		Payload: data[8:length],
+	}
+}
+
+func copyIP(ip net.IP) net.IP {
+	c := make(net.IP, len(ip))
+	copy(c, ip)
+	return c
+}
@@ -0,0 +1,214 @@
+package observer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type pcapRecord struct {
+	t    time.Time
+	data []byte
+}
+
+func writePcap(byteOrder binary.ByteOrder, nanosec bool, linkType uint32, records ...pcapRecord) []byte {
+	b := &bytes.Buffer{}
+	magic := uint32(0xa1b2c3d4)
+	if nanosec {
+		magic = 0xa1b23c4d
+	}
+	binary.Write(b, byteOrder, magic)
+	binary.Write(b, byteOrder, uint16(2))
+	binary.Write(b, byteOrder, uint16(4))
+	binary.Write(b, byteOrder, uint64(0))
+	binary.Write(b, byteOrder, uint32(65535))
+	binary.Write(b, byteOrder, linkType)
+	for _, r := range records {
+		binary.Write(b, byteOrder, uint32(r.t.Unix()))
+		if nanosec {
+			binary.Write(b, byteOrder, uint32(r.t.Nanosecond()))
+		} else {
+			binary.Write(b, byteOrder, uint32(r.t.Nanosecond()/1000))
+		}
+		binary.Write(b, byteOrder, uint32(len(r.data)))
+		binary.Write(b, byteOrder, uint32(len(r.data)))
+		b.Write(r.data)
+	}
+	return b.Bytes()
+}
+
+func udpHeader(src, dst *net.UDPAddr, payload []byte) []byte {
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.BigEndian, uint16(src.Port))
+	binary.Write(b, binary.BigEndian, uint16(dst.Port))
+	binary.Write(b, binary.BigEndian, uint16(8+len(payload)))
+	binary.Write(b, binary.BigEndian, uint16(0))
+	return append(b.Bytes(), payload...)
+}
+
+func ipv4Packet(src, dst *net.UDPAddr, protocol byte, payload []byte) []byte {
+	udp := udpHeader(src, dst, payload)
+	b := &bytes.Buffer{}
+	b.Write([]byte{0x45, 0})
+	binary.Write(b, binary.BigEndian, uint16(20+len(udp)))
+	b.Write([]byte{0, 0, 0x40, 0, 64, protocol, 0, 0})
+	b.Write(src.IP.To4())
+	b.Write(dst.IP.To4())
+	return append(b.Bytes(), udp...)
+}
+
+func ipv6Packet(src, dst *net.UDPAddr, payload []byte) []byte {
+	udp := udpHeader(src, dst, payload)
+	b := &bytes.Buffer{}
+	b.Write([]byte{0x60, 0, 0, 0})
+	binary.Write(b, binary.BigEndian, uint16(len(udp)))
+	b.Write([]byte{ipProtocolUDP, 64})
+	b.Write(src.IP.To16())
+	b.Write(dst.IP.To16())
+	return append(b.Bytes(), udp...)
+}
+
+func ethernetFrame(etherType uint16, payload []byte) []byte {
+	b := &bytes.Buffer{}
+	b.Write(make([]byte, 12))
+	binary.Write(b, binary.BigEndian, etherType)
+	return append(b.Bytes(), payload...)
+}
+
+// udpFrame creates an Ethernet frame containing an IPv4 UDP datagram
+func udpFrame(src, dst *net.UDPAddr, payload []byte) []byte {
+	return ethernetFrame(etherTypeIPv4, ipv4Packet(src, dst, ipProtocolUDP, payload))
+}
+
+var _ = Describe("pcap", func() {
+	var (
+		t0   time.Time
+		src  *net.UDPAddr
+		dst  *net.UDPAddr
+		src6 *net.UDPAddr
+		dst6 *net.UDPAddr
+	)
+
+	BeforeEach(func() {
+		t0 = time.Unix(1500000000, 123456000)
+		src = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+		dst = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 443}
+		src6 = &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234}
+		dst6 = &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443}
+	})
+
+	readAll := func(data []byte) []*Datagram {
+		r, err := NewPcapReader(bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+		var datagrams []*Datagram
+		for {
+			d, err := r.ReadDatagram()
+			if err == io.EOF {
+				return datagrams
+			}
+			Expect(err).ToNot(HaveOccurred())
+			datagrams = append(datagrams, d)
+		}
+	}
+
+	It("rejects files that are not pcap files", func() {
+		_, err := NewPcapReader(bytes.NewReader(bytes.Repeat([]byte{'f'}, 24)))
+		Expect(err).To(MatchError(errNotAPcapFile))
+	})
+
+	It("rejects unsupported link types", func() {
+		_, err := NewPcapReader(bytes.NewReader(writePcap(binary.LittleEndian, false, 105)))
+		Expect(err).To(MatchError(errUnsupportedLinkType))
+	})
+
+	It("reads UDP datagrams from Ethernet frames", func() {
+		data := writePcap(binary.LittleEndian, false, linkTypeEthernet, pcapRecord{t: t0, data: udpFrame(src, dst, []byte("foobar"))})
+		datagrams := readAll(data)
+		Expect(datagrams).To(HaveLen(1))
+		d := datagrams[0]
+		Expect(d.Time).To(Equal(t0))
+		Expect(d.Src.String()).To(Equal(src.String()))
+		Expect(d.Dst.String()).To(Equal(dst.String()))
+		Expect(d.Payload).To(Equal([]byte("foobar")))
+	})
+
+	It("reads big endian files with nanosecond timestamps", func() {
+		t := time.Unix(1500000000, 123456789)
+		data := writePcap(binary.BigEndian, true, linkTypeEthernet, pcapRecord{t: t, data: udpFrame(src, dst, []byte("foobar"))})
+		datagrams := readAll(data)
+		Expect(datagrams).To(HaveLen(1))
+		Expect(datagrams[0].Time).To(Equal(t))
+	})
+
+	It("reads VLAN tagged frames", func() {
+		vlan := append([]byte{0x00, 0x2a, 0x08, 0x00}, ipv4Packet(src, dst, ipProtocolUDP, []byte("foobar"))...)
+		data := writePcap(binary.LittleEndian, false, linkTypeEthernet, pcapRecord{t: t0, data: ethernetFrame(etherTypeVLAN, vlan)})
+		datagrams := readAll(data)
+		Expect(datagrams).To(HaveLen(1))
+		Expect(datagrams[0].Payload).To(Equal([]byte("foobar")))
+	})
+
+	It("reads IPv6 datagrams", func() {
+		data := writePcap(binary.LittleEndian, false, linkTypeRaw, pcapRecord{t: t0, data: ipv6Packet(src6, dst6, []byte("foobar"))})
+		datagrams := readAll(data)
+		Expect(datagrams).To(HaveLen(1))
+		Expect(datagrams[0].Src.String()).To(Equal(src6.String()))
+		Expect(datagrams[0].Dst.String()).To(Equal(dst6.String()))
+		Expect(datagrams[0].Payload).To(Equal([]byte("foobar")))
+	})
+
+	It("reads Linux cooked captures", func() {
+		sll := append(make([]byte, 14), 0x08, 0x00)
+		data := writePcap(binary.LittleEndian, false, linkTypeLinuxSLL, pcapRecord{t: t0, data: append(sll, ipv4Packet(src, dst, ipProtocolUDP, []byte("foobar"))...)})
+		datagrams := readAll(data)
+		Expect(datagrams).To(HaveLen(1))
+		Expect(datagrams[0].Payload).To(Equal([]byte("foobar")))
+	})
+
+	It("reads loopback captures", func() {
+		data := writePcap(binary.LittleEndian, false, linkTypeNull, pcapRecord{t: t0, data: append([]byte{2, 0, 0, 0}, ipv4Packet(src, dst, ipProtocolUDP, []byte("foobar"))...)})
+		datagrams := readAll(data)
+		Expect(datagrams).To(HaveLen(1))
+		Expect(datagrams[0].Payload).To(Equal([]byte("foobar")))
+	})
+
+	It("skips packets that are not UDP", func() {
+		data := writePcap(binary.LittleEndian, false, linkTypeEthernet,
+			pcapRecord{t: t0, data: ethernetFrame(etherTypeIPv4, ipv4Packet(src, dst, 6, []byte("foo")))},
+			pcapRecord{t: t0, data: ethernetFrame(0x0806, []byte("arp"))},
+			pcapRecord{t: t0, data: udpFrame(src, dst, []byte("bar"))},
+		)
+		datagrams := readAll(data)
+		Expect(datagrams).To(HaveLen(1))
+		Expect(datagrams[0].Payload).To(Equal([]byte("bar")))
+	})
+
+	It("skips IP fragments", func() {
+		packet := ipv4Packet(src, dst, ipProtocolUDP, []byte("foobar"))
+		packet[6] = 0x20 // more fragments
+		data := writePcap(binary.LittleEndian, false, linkTypeEthernet, pcapRecord{t: t0, data: ethernetFrame(etherTypeIPv4, packet)})
+		Expect(readAll(data)).To(BeEmpty())
+	})
+
+	It("errors on truncated records", func() {
+		data := writePcap(binary.LittleEndian, false, linkTypeEthernet,
+			pcapRecord{t: t0, data: udpFrame(src, dst, []byte("foo"))},
+			pcapRecord{t: t0, data: udpFrame(src, dst, []byte("bar"))},
+		)
+		recordLen := (len(data) - 24) / 2
+		// truncate the data of the second record, and its record header
+		for _, l := range []int{len(data) - 5, len(data) - recordLen + 1, len(data) - recordLen + 16} {
+			r, err := NewPcapReader(bytes.NewReader(data[:l]))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = r.ReadDatagram()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = r.ReadDatagram()
+			Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		}
+	})
+})
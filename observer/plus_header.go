@@ -0,0 +1,82 @@
+package observer
+
+import (
+	"encoding/binary"
+	"errors"
+
+	quic "github.com/lucas-clemente/quic-go"
+)
+
+const (
+	plusBasicHeaderLen = 20
+
+	plusFlagExtended byte = 0x01
+	plusFlagStop     byte = 0x02
+)
+
+// pcfTypeNoValue is the PCF type of extended headers that don't contain a PCF length and value
+const pcfTypeNoValue = 0xff
+
+var errTruncatedPLUSHeader = errors.New("PLUS header too short")
+
+// A PLUSHeader is the header of a PLUS packet, as seen by an on-path observer
+type PLUSHeader struct {
+	CAT  uint64
+	PSN  uint32
+	PSE  uint32
+	Stop bool
+	// Extended is set if the packet carries a PCF (Path Communication Function) extension
+	Extended bool
+	PCFType  uint16
+	PCFValue []byte
+}
+
+// ParsePLUSHeader parses the header of a PLUS packet, and returns the payload
+func ParsePLUSHeader(data []byte) (*PLUSHeader, []byte, error) {
+	if !quic.IsPLUSPacket(data) {
+		return nil, nil, errors.New("not a PLUS packet")
+	}
+	if len(data) < plusBasicHeaderLen {
+		return nil, nil, errTruncatedPLUSHeader
+	}
+	flags := data[3] & 0x0f
+	h := &PLUSHeader{
+		CAT:      binary.BigEndian.Uint64(data[4:]),
+		PSN:      binary.BigEndian.Uint32(data[12:]),
+		PSE:      binary.BigEndian.Uint32(data[16:]),
+		Stop:     flags&plusFlagStop != 0,
+		Extended: flags&plusFlagExtended != 0,
+	}
+	data = data[plusBasicHeaderLen:]
+	if !h.Extended {
+		return h, data, nil
+	}
+
+	// the PCF type is one byte long, unless the first byte is 0x00
+	if len(data) < 1 {
+		return nil, nil, errTruncatedPLUSHeader
+	}
+	h.PCFType = uint16(data[0])
+	data = data[1:]
+	if h.PCFType == 0 {
+		if len(data) < 1 {
+			return nil, nil, errTruncatedPLUSHeader
+		}
+		h.PCFType = uint16(data[0]) << 8
+		data = data[1:]
+	}
+	if h.PCFType == pcfTypeNoValue {
+		return h, data, nil
+	}
+	// the upper 6 bits are the length of the PCF value, the lower 2 bits the integrity
+	if len(data) < 1 {
+		return nil, nil, errTruncatedPLUSHeader
+	}
+	valueLen := int(data[0] >> 2)
+	data = data[1:]
+	if len(data) < valueLen {
+		return nil, nil, errTruncatedPLUSHeader
+	}
+	h.PCFValue = data[:valueLen]
+	return h, data[valueLen:], nil
+}
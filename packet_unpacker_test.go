@@ -203,11 +203,11 @@ var _ = Describe("Packet unpacker", func() {
 	})
 
 	It("accepts PLUSFEEDBACK frames", func() {
-		setData([]byte{0x08,0x02, 0xFF, 0xFF})
+		setData([]byte{0x08, 0x01, 0x00, 0x02, 0xFF, 0xFF})
 		packet, err := unpacker.Unpack(hdrBin, hdr, data)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(packet.frames).To(Equal([]frames.Frame{
-			&frames.PLUSFeedbackFrame{Data : []byte{0xFF, 0xFF}},
+			&frames.PLUSFeedbackFrame{Data: []byte{0xFF, 0xFF}},
 		}))
 	})
 
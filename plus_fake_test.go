@@ -0,0 +1,423 @@
+package quic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+// This file contains an in-memory stand-in for the PLUS library, such that PLUS code paths can be tested without the network.
+// The fake PLUS header doesn't match the PLUS wire format, and the path never modifies PCF requests,
+// i.e. the feedback returned by ReadAndProcessPacket is the PCF value sent by the peer.
+
+var errMemPacketConnClosed = errors.New("use of closed memPacketConn")
+
+type memPacket struct {
+	data []byte
+	from net.Addr
+}
+
+// A memNetwork delivers packets between memPacketConns
+type memNetwork struct {
+	mutex sync.Mutex
+	conns map[string]*memPacketConn
+}
+
+func newMemNetwork() *memNetwork {
+	return &memNetwork{conns: make(map[string]*memPacketConn)}
+}
+
+// newMemPacketConnPair creates two memPacketConns that can send packets to each other
+func newMemPacketConnPair() (*memPacketConn, *memPacketConn) {
+	n := newMemNetwork()
+	return n.newPacketConn(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}), n.newPacketConn(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 2000})
+}
+
+func (n *memNetwork) newPacketConn(addr net.Addr) *memPacketConn {
+	c := &memPacketConn{
+		network:  n,
+		addr:     addr,
+		incoming: make(chan memPacket, 1000),
+		closed:   make(chan struct{}),
+	}
+	n.mutex.Lock()
+	n.conns[addr.String()] = c
+	n.mutex.Unlock()
+	return c
+}
+
+type memPacketConn struct {
+	network  *memNetwork
+	addr     net.Addr
+	incoming chan memPacket
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ net.PacketConn = &memPacketConn{}
+
+func (c *memPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.incoming:
+		return copy(b, p.data), p.from, nil
+	case <-c.closed:
+		return 0, nil, errMemPacketConnClosed
+	}
+}
+
+// WriteTo drops packets to unknown addresses, just like the network would
+func (c *memPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, errMemPacketConnClosed
+	default:
+	}
+	c.network.mutex.Lock()
+	peer, ok := c.network.conns[addr.String()]
+	c.network.mutex.Unlock()
+	if !ok {
+		return len(b), nil
+	}
+	data := make([]byte, len(b))
+	copy(data, b)
+	select {
+	case peer.incoming <- memPacket{data: data, from: c.addr}:
+	default: // drop the packet if the receiver is too slow
+	}
+	return len(b), nil
+}
+
+func (c *memPacketConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.network.mutex.Lock()
+		delete(c.network.conns, c.addr.String())
+		c.network.mutex.Unlock()
+		close(c.closed)
+	})
+	return nil
+}
+
+func (c *memPacketConn) LocalAddr() net.Addr                { return c.addr }
+func (c *memPacketConn) SetDeadline(t time.Time) error      { panic("not implemented") }
+func (c *memPacketConn) SetReadDeadline(t time.Time) error  { panic("not implemented") }
+func (c *memPacketConn) SetWriteDeadline(t time.Time) error { panic("not implemented") }
+
+const (
+	fakePLUSFlagStop       byte = 0x01
+	fakePLUSFlagPCFRequest byte = 0x02
+)
+
+type fakePCFRequest struct {
+	pcfType   uint16
+	integrity uint16
+	value     []byte
+}
+
+// fakePLUSPacket is a packet with a fake PLUS header
+// The header consists of the PLUS magic (4 bytes), CAT (8 bytes), PSN (4 bytes), PSE (4 bytes) and flags (1 byte), optionally followed by a PCF request.
+type fakePLUSPacket struct {
+	cat        uint64
+	psn        uint32
+	pse        uint32
+	stop       bool
+	pcfRequest *fakePCFRequest
+	payload    []byte
+}
+
+var _ plusPacket = &fakePLUSPacket{}
+
+func (p *fakePLUSPacket) Payload() []byte { return p.payload }
+func (p *fakePLUSPacket) CAT() uint64     { return p.cat }
+func (p *fakePLUSPacket) PSN() uint32     { return p.psn }
+func (p *fakePLUSPacket) PSE() uint32     { return p.pse }
+
+func (p *fakePLUSPacket) write(b *bytes.Buffer) {
+	binary.Write(b, binary.BigEndian, uint32(PLUSMagic<<4))
+	binary.Write(b, binary.BigEndian, p.cat)
+	binary.Write(b, binary.BigEndian, p.psn)
+	binary.Write(b, binary.BigEndian, p.pse)
+	var flags byte
+	if p.stop {
+		flags |= fakePLUSFlagStop
+	}
+	if p.pcfRequest != nil {
+		flags |= fakePLUSFlagPCFRequest
+	}
+	b.WriteByte(flags)
+	if p.pcfRequest != nil {
+		binary.Write(b, binary.BigEndian, p.pcfRequest.pcfType)
+		binary.Write(b, binary.BigEndian, p.pcfRequest.integrity)
+		binary.Write(b, binary.BigEndian, uint16(len(p.pcfRequest.value)))
+		b.Write(p.pcfRequest.value)
+	}
+	b.Write(p.payload)
+}
+
+func parseFakePLUSPacket(data []byte) (*fakePLUSPacket, error) {
+	if !IsPLUSPacket(data) {
+		return nil, errors.New("not a PLUS packet")
+	}
+	r := bytes.NewReader(data[4:])
+	p := &fakePLUSPacket{}
+	var flags byte
+	for _, v := range []interface{}{&p.cat, &p.psn, &p.pse, &flags} {
+		if err := binary.Read(r, binary.BigEndian, v); err != nil {
+			return nil, err
+		}
+	}
+	p.stop = flags&fakePLUSFlagStop != 0
+	if flags&fakePLUSFlagPCFRequest != 0 {
+		p.pcfRequest = &fakePCFRequest{}
+		var valueLen uint16
+		for _, v := range []interface{}{&p.pcfRequest.pcfType, &p.pcfRequest.integrity, &valueLen} {
+			if err := binary.Read(r, binary.BigEndian, v); err != nil {
+				return nil, err
+			}
+		}
+		p.pcfRequest.value = make([]byte, valueLen)
+		if _, err := io.ReadFull(r, p.pcfRequest.value); err != nil {
+			return nil, err
+		}
+	}
+	p.payload = data[len(data)-r.Len():]
+	return p, nil
+}
+
+// A fakePLUSRecorder records everything that happens in the fake PLUS layers of a test
+type fakePLUSRecorder struct {
+	mutex sync.Mutex
+
+	sent     []*fakePLUSPacket
+	received []*fakePLUSPacket
+	// feedback passed to AddPCFFeedback
+	feedback [][]byte
+	// number of packets returned by ReturnPacketAndBuffer
+	returned int
+	// CATs of connections that were closed
+	closed []uint64
+}
+
+func (r *fakePLUSRecorder) sentPackets() []*fakePLUSPacket {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]*fakePLUSPacket(nil), r.sent...)
+}
+
+func (r *fakePLUSRecorder) receivedPackets() []*fakePLUSPacket {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]*fakePLUSPacket(nil), r.received...)
+}
+
+func (r *fakePLUSRecorder) returnedPackets() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.returned
+}
+
+func (r *fakePLUSRecorder) addedFeedback() [][]byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([][]byte(nil), r.feedback...)
+}
+
+func (r *fakePLUSRecorder) closedConnections() []uint64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]uint64(nil), r.closed...)
+}
+
+// newFakePLUSTransport creates a Transport that uses the fake PLUS layer
+func newFakePLUSTransport() (*plusTransport, *fakePLUSRecorder) {
+	rec := &fakePLUSRecorder{}
+	return &plusTransport{
+		listen: func(pconn net.PacketConn) plusConnectionManager {
+			return newFakePLUSConnectionManager(pconn, rec)
+		},
+		dial: func(pconn net.PacketConn, connID uint64, remoteAddr net.Addr) (plusConnectionManager, plusConnection) {
+			m := newFakePLUSConnectionManager(pconn, rec)
+			m.client = m.newConnection(connID, remoteAddr)
+			return m, m.client
+		},
+	}, rec
+}
+
+type fakePLUSConnectionManager struct {
+	pconn    net.PacketConn
+	recorder *fakePLUSRecorder
+
+	mutex       sync.Mutex
+	connections map[uint64]*fakePLUSConnection
+	// only set for clients
+	client *fakePLUSConnection
+}
+
+var _ plusConnectionManager = &fakePLUSConnectionManager{}
+
+func newFakePLUSConnectionManager(pconn net.PacketConn, rec *fakePLUSRecorder) *fakePLUSConnectionManager {
+	return &fakePLUSConnectionManager{
+		pconn:       pconn,
+		recorder:    rec,
+		connections: make(map[uint64]*fakePLUSConnection),
+	}
+}
+
+func (m *fakePLUSConnectionManager) newConnection(cat uint64, remoteAddr net.Addr) *fakePLUSConnection {
+	c := &fakePLUSConnection{cat: cat, remoteAddr: remoteAddr, manager: m}
+	m.mutex.Lock()
+	m.connections[cat] = c
+	m.mutex.Unlock()
+	return c
+}
+
+func (m *fakePLUSConnectionManager) ReadAndProcessPacket() (plusConnection, plusPacket, net.Addr, []byte, error) {
+	for {
+		buf := make([]byte, protocol.MaxReceivePacketSize)
+		n, remoteAddr, err := m.pconn.ReadFrom(buf)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		p, err := parseFakePLUSPacket(buf[:n])
+		if err != nil {
+			// PLUS drops packets it can't parse
+			continue
+		}
+		m.recorder.mutex.Lock()
+		m.recorder.received = append(m.recorder.received, p)
+		m.recorder.mutex.Unlock()
+
+		m.mutex.Lock()
+		c, ok := m.connections[p.cat]
+		m.mutex.Unlock()
+		if !ok {
+			if m.client != nil {
+				// clients only accept packets for their own connection
+				continue
+			}
+			c = m.newConnection(p.cat, remoteAddr)
+		}
+		c.receivedPacket(p)
+
+		var feedback []byte
+		if p.pcfRequest != nil {
+			feedback = p.pcfRequest.value
+		}
+		return c, p, remoteAddr, feedback, nil
+	}
+}
+
+func (m *fakePLUSConnectionManager) ReturnPacketAndBuffer(plusPacket) {
+	m.recorder.mutex.Lock()
+	m.recorder.returned++
+	m.recorder.mutex.Unlock()
+}
+
+func (m *fakePLUSConnectionManager) Close() error {
+	return m.pconn.Close()
+}
+
+func (m *fakePLUSConnectionManager) LocalAddr() net.Addr {
+	return m.pconn.LocalAddr()
+}
+
+type fakePLUSConnection struct {
+	manager *fakePLUSConnectionManager
+
+	mutex       sync.Mutex
+	cat         uint64
+	psn         uint32
+	pse         uint32
+	stop        bool
+	remoteAddr  net.Addr
+	pcfRequests []*fakePCFRequest
+}
+
+var _ plusConnection = &fakePLUSConnection{}
+
+func (c *fakePLUSConnection) receivedPacket(p *fakePLUSPacket) {
+	c.mutex.Lock()
+	c.pse = p.psn
+	c.mutex.Unlock()
+}
+
+func (c *fakePLUSConnection) Write(payload []byte) (int, error) {
+	c.mutex.Lock()
+	c.psn++
+	p := &fakePLUSPacket{
+		cat:     c.cat,
+		psn:     c.psn,
+		pse:     c.pse,
+		stop:    c.stop,
+		payload: append([]byte(nil), payload...),
+	}
+	if len(c.pcfRequests) > 0 {
+		p.pcfRequest = c.pcfRequests[0]
+		c.pcfRequests = c.pcfRequests[1:]
+	}
+	remoteAddr := c.remoteAddr
+	c.mutex.Unlock()
+
+	c.manager.recorder.mutex.Lock()
+	c.manager.recorder.sent = append(c.manager.recorder.sent, p)
+	c.manager.recorder.mutex.Unlock()
+
+	b := &bytes.Buffer{}
+	p.write(b)
+	if _, err := c.manager.pconn.WriteTo(b.Bytes(), remoteAddr); err != nil {
+		return 0, err
+	}
+	return len(payload), nil
+}
+
+func (c *fakePLUSConnection) AddPCFFeedback(data []byte) error {
+	c.manager.recorder.mutex.Lock()
+	c.manager.recorder.feedback = append(c.manager.recorder.feedback, append([]byte(nil), data...))
+	c.manager.recorder.mutex.Unlock()
+	return nil
+}
+
+func (c *fakePLUSConnection) QueuePCFRequest(pcfType uint16, pcfIntegrity uint16, pcfValue []byte) error {
+	c.mutex.Lock()
+	c.pcfRequests = append(c.pcfRequests, &fakePCFRequest{pcfType: pcfType, integrity: pcfIntegrity, value: append([]byte(nil), pcfValue...)})
+	c.mutex.Unlock()
+	return nil
+}
+
+func (c *fakePLUSConnection) SetSFlag(stop bool) {
+	c.mutex.Lock()
+	c.stop = stop
+	c.mutex.Unlock()
+}
+
+func (c *fakePLUSConnection) SetRemoteAddr(addr net.Addr) {
+	c.mutex.Lock()
+	c.remoteAddr = addr
+	c.mutex.Unlock()
+}
+
+func (c *fakePLUSConnection) RemoteAddr() net.Addr {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.remoteAddr
+}
+
+func (c *fakePLUSConnection) LocalAddr() net.Addr {
+	return c.manager.pconn.LocalAddr()
+}
+
+func (c *fakePLUSConnection) Close() error {
+	c.manager.mutex.Lock()
+	delete(c.manager.connections, c.cat)
+	c.manager.mutex.Unlock()
+	c.manager.recorder.mutex.Lock()
+	c.manager.recorder.closed = append(c.manager.recorder.closed, c.cat)
+	c.manager.recorder.mutex.Unlock()
+	return nil
+}
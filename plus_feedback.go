@@ -0,0 +1,150 @@
+package quic
+
+import (
+	"sort"
+
+	"github.com/lucas-clemente/quic-go/frames"
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/qerr"
+	"github.com/lucas-clemente/quic-go/utils"
+)
+
+var (
+	errPLUSFeedbackTooLarge              = qerr.Error(qerr.InvalidFrameData, "PLUS feedback too large")
+	errTooManyIncompletePLUSFeedbacks    = qerr.Error(qerr.InvalidFrameData, "too many incomplete PLUS feedbacks")
+	errInconsistentPLUSFeedbackFragments = qerr.Error(qerr.InvalidFrameData, "inconsistent PLUS feedback fragments")
+)
+
+// fragmentPLUSFeedback splits feedback into PLUSFeedbackFrames that carry at most maxFragmentSize bytes each
+// Feedback that fits into a single frame is not fragmented, and the id is not used.
+func fragmentPLUSFeedback(data []byte, id uint64, maxFragmentSize protocol.ByteCount) []*frames.PLUSFeedbackFrame {
+	if protocol.ByteCount(len(data)) <= maxFragmentSize {
+		return []*frames.PLUSFeedbackFrame{{Data: data}}
+	}
+
+	var fs []*frames.PLUSFeedbackFrame
+	for offset := protocol.ByteCount(0); offset < protocol.ByteCount(len(data)); offset += maxFragmentSize {
+		end := utils.MinByteCount(offset+maxFragmentSize, protocol.ByteCount(len(data)))
+		fs = append(fs, &frames.PLUSFeedbackFrame{
+			FeedbackID:    id,
+			Offset:        offset,
+			MoreFragments: end < protocol.ByteCount(len(data)),
+			Data:          data[offset:end],
+		})
+	}
+	return fs
+}
+
+type incompletePLUSFeedback struct {
+	fragments map[protocol.ByteCount][]byte
+	received  protocol.ByteCount
+	// the length is only known once the last fragment was received
+	length protocol.ByteCount
+}
+
+// The plusFeedbackAssembler reassembles feedback that was split into multiple PLUSFeedbackFrames.
+// Since PLUSFeedbackFrames are retransmitted, fragments can arrive in any order, and more than once.
+type plusFeedbackAssembler struct {
+	incomplete map[uint64]*incompletePLUSFeedback
+	// all feedbacks with an ID smaller than lowestIncompleteID were already reassembled
+	lowestIncompleteID uint64
+	// feedbacks with an ID larger than lowestIncompleteID that were already reassembled
+	completed map[uint64]struct{}
+}
+
+func newPLUSFeedbackAssembler() *plusFeedbackAssembler {
+	return &plusFeedbackAssembler{
+		incomplete: make(map[uint64]*incompletePLUSFeedback),
+		completed:  make(map[uint64]struct{}),
+	}
+}
+
+// Push adds a PLUSFeedbackFrame
+// It returns the feedback as soon as all its fragments were received, and nil otherwise.
+func (a *plusFeedbackAssembler) Push(frame *frames.PLUSFeedbackFrame) ([]byte, error) {
+	if !frame.IsFragment() {
+		return frame.Data, nil
+	}
+
+	id := frame.FeedbackID
+	if _, ok := a.completed[id]; ok || id < a.lowestIncompleteID {
+		// a retransmission of a fragment that was already received
+		return nil, nil
+	}
+
+	// check the offset first, such that calculating the end can't overflow
+	if frame.Offset > protocol.MaxPLUSFeedbackSize {
+		return nil, errPLUSFeedbackTooLarge
+	}
+	start := frame.Offset
+	end := frame.Offset + protocol.ByteCount(len(frame.Data))
+	if end > protocol.MaxPLUSFeedbackSize {
+		return nil, errPLUSFeedbackTooLarge
+	}
+
+	f, ok := a.incomplete[id]
+	if !ok {
+		if len(a.incomplete) >= protocol.MaxIncompletePLUSFeedbacks {
+			return nil, errTooManyIncompletePLUSFeedbacks
+		}
+		f = &incompletePLUSFeedback{fragments: make(map[protocol.ByteCount][]byte)}
+		a.incomplete[id] = f
+	}
+
+	if data, ok := f.fragments[start]; ok && len(data) == len(frame.Data) {
+		// duplicate fragment
+		return nil, nil
+	}
+	for offset, data := range f.fragments {
+		if start < offset+protocol.ByteCount(len(data)) && offset < end {
+			return nil, errInconsistentPLUSFeedbackFragments
+		}
+	}
+	if f.length != 0 && end > f.length {
+		return nil, errInconsistentPLUSFeedbackFragments
+	}
+	if !frame.MoreFragments {
+		if f.length != 0 || end < f.maxEnd() {
+			return nil, errInconsistentPLUSFeedbackFragments
+		}
+		f.length = end
+	}
+
+	f.fragments[start] = frame.Data
+	f.received += protocol.ByteCount(len(frame.Data))
+	if f.length == 0 || f.received < f.length {
+		return nil, nil
+	}
+
+	delete(a.incomplete, id)
+	a.completed[id] = struct{}{}
+	for {
+		if _, ok := a.completed[a.lowestIncompleteID]; !ok {
+			break
+		}
+		delete(a.completed, a.lowestIncompleteID)
+		a.lowestIncompleteID++
+	}
+	return f.assemble(), nil
+}
+
+func (f *incompletePLUSFeedback) maxEnd() protocol.ByteCount {
+	var maxEnd protocol.ByteCount
+	for offset, data := range f.fragments {
+		maxEnd = utils.MaxByteCount(maxEnd, offset+protocol.ByteCount(len(data)))
+	}
+	return maxEnd
+}
+
+func (f *incompletePLUSFeedback) assemble() []byte {
+	offsets := make([]int, 0, len(f.fragments))
+	for offset := range f.fragments {
+		offsets = append(offsets, int(offset))
+	}
+	sort.Ints(offsets)
+	data := make([]byte, 0, f.length)
+	for _, offset := range offsets {
+		data = append(data, f.fragments[protocol.ByteCount(offset)]...)
+	}
+	return data
+}
@@ -0,0 +1,187 @@
+package quic
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+
+	"github.com/lucas-clemente/quic-go/frames"
+	"github.com/lucas-clemente/quic-go/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PLUS feedback", func() {
+	Context("fragmenting", func() {
+		It("doesn't fragment small feedback", func() {
+			fs := fragmentPLUSFeedback([]byte("foobar"), 7, 6)
+			Expect(fs).To(HaveLen(1))
+			Expect(fs[0].IsFragment()).To(BeFalse())
+			Expect(fs[0].Data).To(Equal([]byte("foobar")))
+		})
+
+		It("fragments large feedback", func() {
+			fs := fragmentPLUSFeedback([]byte("foobar"), 7, 4)
+			Expect(fs).To(Equal([]*frames.PLUSFeedbackFrame{
+				{FeedbackID: 7, Offset: 0, MoreFragments: true, Data: []byte("foob")},
+				{FeedbackID: 7, Offset: 4, MoreFragments: false, Data: []byte("ar")},
+			}))
+		})
+
+		It("fragments feedback that is a multiple of the fragment size", func() {
+			fs := fragmentPLUSFeedback([]byte("foobar"), 7, 3)
+			Expect(fs).To(HaveLen(2))
+			Expect(fs[1].Offset).To(Equal(protocol.ByteCount(3)))
+			Expect(fs[1].MoreFragments).To(BeFalse())
+		})
+	})
+
+	Context("reassembling", func() {
+		var a *plusFeedbackAssembler
+
+		BeforeEach(func() {
+			a = newPLUSFeedbackAssembler()
+		})
+
+		It("returns unfragmented feedback", func() {
+			data, err := a.Push(&frames.PLUSFeedbackFrame{Data: []byte("foobar")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+
+		It("reassembles fragments received in order", func() {
+			fs := fragmentPLUSFeedback([]byte("foobar"), 0, 2)
+			data, err := a.Push(fs[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(BeNil())
+			data, err = a.Push(fs[1])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(BeNil())
+			data, err = a.Push(fs[2])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(a.incomplete).To(BeEmpty())
+			Expect(a.lowestIncompleteID).To(Equal(uint64(1)))
+		})
+
+		It("reassembles fragments received in reverse order", func() {
+			fs := fragmentPLUSFeedback([]byte("foobar"), 0, 2)
+			for i := len(fs) - 1; i > 0; i-- {
+				data, err := a.Push(fs[i])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(BeNil())
+			}
+			data, err := a.Push(fs[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+
+		It("reassembles interleaved feedbacks", func() {
+			fs1 := fragmentPLUSFeedback([]byte("foobar"), 0, 3)
+			fs2 := fragmentPLUSFeedback([]byte("raboof"), 1, 3)
+			_, err := a.Push(fs2[0])
+			Expect(err).ToNot(HaveOccurred())
+			_, err = a.Push(fs1[1])
+			Expect(err).ToNot(HaveOccurred())
+			data, err := a.Push(fs2[1])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("raboof")))
+			Expect(a.lowestIncompleteID).To(BeZero())
+			data, err = a.Push(fs1[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(a.lowestIncompleteID).To(Equal(uint64(2)))
+			Expect(a.completed).To(BeEmpty())
+		})
+
+		It("ignores duplicate fragments", func() {
+			fs := fragmentPLUSFeedback([]byte("foobar"), 0, 3)
+			_, err := a.Push(fs[0])
+			Expect(err).ToNot(HaveOccurred())
+			data, err := a.Push(fs[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(BeNil())
+			data, err = a.Push(fs[1])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+
+		It("ignores retransmissions of fragments of reassembled feedback", func() {
+			fs := fragmentPLUSFeedback([]byte("foobar"), 0, 3)
+			_, err := a.Push(fs[0])
+			Expect(err).ToNot(HaveOccurred())
+			_, err = a.Push(fs[1])
+			Expect(err).ToNot(HaveOccurred())
+			data, err := a.Push(fs[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(BeNil())
+			Expect(a.incomplete).To(BeEmpty())
+		})
+
+		It("errors on overlapping fragments", func() {
+			_, err := a.Push(&frames.PLUSFeedbackFrame{FeedbackID: 1, Offset: 0, MoreFragments: true, Data: []byte("foo")})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = a.Push(&frames.PLUSFeedbackFrame{FeedbackID: 1, Offset: 2, Data: []byte("obar")})
+			Expect(err).To(MatchError(errInconsistentPLUSFeedbackFragments))
+		})
+
+		It("errors on data after the last fragment", func() {
+			_, err := a.Push(&frames.PLUSFeedbackFrame{FeedbackID: 1, Offset: 3, Data: []byte("bar")})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = a.Push(&frames.PLUSFeedbackFrame{FeedbackID: 1, Offset: 6, MoreFragments: true, Data: []byte("baz")})
+			Expect(err).To(MatchError(errInconsistentPLUSFeedbackFragments))
+		})
+
+		It("errors on a last fragment before other fragments", func() {
+			_, err := a.Push(&frames.PLUSFeedbackFrame{FeedbackID: 1, Offset: 6, MoreFragments: true, Data: []byte("baz")})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = a.Push(&frames.PLUSFeedbackFrame{FeedbackID: 1, Offset: 3, Data: []byte("bar")})
+			Expect(err).To(MatchError(errInconsistentPLUSFeedbackFragments))
+		})
+
+		It("errors on too large feedback", func() {
+			_, err := a.Push(&frames.PLUSFeedbackFrame{FeedbackID: 1, Offset: protocol.MaxPLUSFeedbackSize, Data: []byte("f")})
+			Expect(err).To(MatchError(errPLUSFeedbackTooLarge))
+		})
+
+		It("errors on fragments with an offset close to the maximum ByteCount", func() {
+			_, err := a.Push(&frames.PLUSFeedbackFrame{FeedbackID: 1, Offset: protocol.ByteCount(math.MaxUint64 - 4), MoreFragments: true, Data: []byte("foobar")})
+			Expect(err).To(MatchError(errPLUSFeedbackTooLarge))
+			data, err := a.Push(&frames.PLUSFeedbackFrame{FeedbackID: 1, Offset: 0, Data: []byte("f")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("f")))
+		})
+
+		It("reassembles random fragmentations", func() {
+			for i := 0; i < 100; i++ {
+				data := make([]byte, 1+rand.Intn(1000))
+				rand.Read(data)
+				fs := fragmentPLUSFeedback(data, uint64(i), protocol.ByteCount(1+rand.Intn(100)))
+				for j := range fs {
+					k := rand.Intn(j + 1)
+					fs[j], fs[k] = fs[k], fs[j]
+				}
+				var reassembled []byte
+				for _, f := range fs {
+					res, err := a.Push(f)
+					Expect(err).ToNot(HaveOccurred())
+					if res != nil {
+						Expect(reassembled).To(BeNil())
+						reassembled = res
+					}
+				}
+				Expect(reassembled).To(Equal(data))
+			}
+		})
+
+		It("errors on too many incomplete feedbacks", func() {
+			data := bytes.Repeat([]byte{'f'}, 10)
+			for i := 0; i < protocol.MaxIncompletePLUSFeedbacks; i++ {
+				_, err := a.Push(fragmentPLUSFeedback(data, uint64(i), 5)[0])
+				Expect(err).ToNot(HaveOccurred())
+			}
+			_, err := a.Push(fragmentPLUSFeedback(data, protocol.MaxIncompletePLUSFeedbacks, 5)[0])
+			Expect(err).To(MatchError(errTooManyIncompletePLUSFeedbacks))
+		})
+	})
+})
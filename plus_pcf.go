@@ -0,0 +1,79 @@
+package quic
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// maxPCFIntegrity is the highest PCF integrity protection level
+const maxPCFIntegrity = 3
+
+var (
+	errPCFRequestsUnsupported = errors.New("PCF requests are only supported by the PLUS transport")
+	errInvalidPCFIntegrity    = fmt.Errorf("invalid PCF integrity (maximum %d)", maxPCFIntegrity)
+)
+
+// pcfRequester is implemented by TransportConnections that can send PCF requests
+type pcfRequester interface {
+	queuePCFRequest(pcfType uint16, integrity uint16, value []byte) error
+}
+
+type repeatedPCFRequest struct {
+	// id is the PCFRequest passed to Session.RequestPCF, which is needed to cancel the request
+	id      *PCFRequest
+	request *PCFRequest
+	// the number of packets that still have to be sent before the request is queued again
+	packetsUntilNext int
+}
+
+// The pcfRequestScheduler keeps track of PCF requests that are repeated every N packets
+type pcfRequestScheduler struct {
+	mutex    sync.Mutex
+	requests []*repeatedPCFRequest
+}
+
+func newPCFRequestScheduler() *pcfRequestScheduler {
+	return &pcfRequestScheduler{}
+}
+
+// Add adds a PCF request that was just sent
+// Requests that are only sent once are ignored.
+func (s *pcfRequestScheduler) Add(id, r *PCFRequest) {
+	if r.Every <= 0 {
+		return
+	}
+	s.mutex.Lock()
+	s.requests = append(s.requests, &repeatedPCFRequest{id: id, request: r, packetsUntilNext: r.Every})
+	s.mutex.Unlock()
+}
+
+// Remove stops repeating a PCF request
+func (s *pcfRequestScheduler) Remove(id *PCFRequest) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, r := range s.requests {
+		if r.id == id {
+			s.requests = append(s.requests[:i], s.requests[i+1:]...)
+			return
+		}
+	}
+}
+
+// SentPacket is called for every packet sent
+// It returns the requests that are due to be sent again.
+func (s *pcfRequestScheduler) SentPacket() []*PCFRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var due []*PCFRequest
+	for _, r := range s.requests {
+		r.packetsUntilNext--
+		if r.packetsUntilNext <= 0 {
+			due = append(due, r.request)
+			r.packetsUntilNext = r.request.Every
+		}
+	}
+	return due
+}
@@ -0,0 +1,63 @@
+package quic
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PCF request scheduler", func() {
+	var s *pcfRequestScheduler
+
+	BeforeEach(func() {
+		s = newPCFRequestScheduler()
+	})
+
+	It("doesn't repeat requests that are only sent once", func() {
+		s.Add(nil, &PCFRequest{Type: 1})
+		Expect(s.requests).To(BeEmpty())
+		Expect(s.SentPacket()).To(BeEmpty())
+	})
+
+	It("repeats requests every N packets", func() {
+		r := &PCFRequest{Type: 1, Every: 3}
+		s.Add(r, r)
+		Expect(s.SentPacket()).To(BeEmpty())
+		Expect(s.SentPacket()).To(BeEmpty())
+		Expect(s.SentPacket()).To(Equal([]*PCFRequest{r}))
+		Expect(s.SentPacket()).To(BeEmpty())
+		Expect(s.SentPacket()).To(BeEmpty())
+		Expect(s.SentPacket()).To(Equal([]*PCFRequest{r}))
+	})
+
+	It("repeats requests on every packet", func() {
+		r := &PCFRequest{Type: 1, Every: 1}
+		s.Add(r, r)
+		Expect(s.SentPacket()).To(Equal([]*PCFRequest{r}))
+		Expect(s.SentPacket()).To(Equal([]*PCFRequest{r}))
+	})
+
+	It("stops repeating cancelled requests", func() {
+		r1 := &PCFRequest{Type: 1, Every: 1}
+		r2 := &PCFRequest{Type: 2, Every: 1}
+		s.Add(r1, r1)
+		s.Add(r2, r2)
+		Expect(s.SentPacket()).To(Equal([]*PCFRequest{r1, r2}))
+		s.Remove(r1)
+		Expect(s.SentPacket()).To(Equal([]*PCFRequest{r2}))
+		s.Remove(r2)
+		Expect(s.SentPacket()).To(BeEmpty())
+	})
+
+	It("handles multiple requests", func() {
+		r1 := &PCFRequest{Type: 1, Every: 2}
+		r2 := &PCFRequest{Type: 2, Every: 3}
+		s.Add(r1, r1)
+		s.Add(r2, r2)
+		Expect(s.SentPacket()).To(BeEmpty())
+		Expect(s.SentPacket()).To(Equal([]*PCFRequest{r1}))
+		Expect(s.SentPacket()).To(Equal([]*PCFRequest{r2}))
+		Expect(s.SentPacket()).To(Equal([]*PCFRequest{r1}))
+		Expect(s.SentPacket()).To(BeEmpty())
+		Expect(s.SentPacket()).To(Equal([]*PCFRequest{r1, r2}))
+	})
+})
@@ -0,0 +1,84 @@
+package quic
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+// plusBasicHeaderLen is the length of the basic PLUS header: magic and flags (4 bytes), CAT (8 bytes), PSN (4 bytes) and PSE (4 bytes)
+const plusBasicHeaderLen = 20
+
+// parsePLUSCATAndPSN reads the CAT and the PSN from the header of a PLUS packet
+func parsePLUSCATAndPSN(data []byte) (uint64, uint32, bool) {
+	if len(data) < plusBasicHeaderLen || !IsPLUSPacket(data) {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint64(data[4:12]), binary.BigEndian.Uint32(data[12:16]), true
+}
+
+// The plusRTTTracker measures the RTT of PLUS connections.
+// It remembers when every PSN was sent, and matches it with the PSE of the packets received from the peer.
+// Since the PLUS layer writes the headers, the PSNs are read from the packets written to the net.PacketConn.
+type plusRTTTracker struct {
+	mutex sync.Mutex
+	// the send times of the PSNs that weren't echoed yet, by CAT
+	sentPSNs map[uint64]map[uint32]time.Time
+}
+
+func newPLUSRTTTracker() *plusRTTTracker {
+	return &plusRTTTracker{sentPSNs: make(map[uint64]map[uint32]time.Time)}
+}
+
+// sentPacket records the send time of a PLUS packet
+func (t *plusRTTTracker) sentPacket(data []byte, sendTime time.Time) {
+	cat, psn, ok := parsePLUSCATAndPSN(data)
+	if !ok {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	psns, ok := t.sentPSNs[cat]
+	// if the peer doesn't echo the PSNs, start over instead of growing without bounds
+	if !ok || len(psns) >= protocol.MaxTrackedPLUSPSNs {
+		psns = make(map[uint32]time.Time)
+		t.sentPSNs[cat] = psns
+	}
+	if _, ok := psns[psn]; !ok {
+		psns[psn] = sendTime
+	}
+}
+
+// receivedPSE returns the RTT sample for a PSE received from the peer
+// A PSN is only used for a single sample, since the peer echoes it until it receives the next packet.
+func (t *plusRTTTracker) receivedPSE(cat uint64, pse uint32, rcvTime time.Time) (time.Duration, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	sendTime, ok := t.sentPSNs[cat][pse]
+	if !ok {
+		return 0, false
+	}
+	delete(t.sentPSNs[cat], pse)
+	return rcvTime.Sub(sendTime), true
+}
+
+// removeConnection forgets the PSNs sent on a connection
+func (t *plusRTTTracker) removeConnection(cat uint64) {
+	t.mutex.Lock()
+	delete(t.sentPSNs, cat)
+	t.mutex.Unlock()
+}
+
+// plusRTTPacketConn is the net.PacketConn passed to the PLUS layer, it passes all packets written to the plusRTTTracker
+type plusRTTPacketConn struct {
+	net.PacketConn
+	tracker *plusRTTTracker
+}
+
+func (c *plusRTTPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.tracker.sentPacket(b, time.Now())
+	return c.PacketConn.WriteTo(b, addr)
+}
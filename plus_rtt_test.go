@@ -0,0 +1,78 @@
+package quic
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PLUS RTT tracker", func() {
+	var tracker *plusRTTTracker
+
+	getPacket := func(cat uint64, psn uint32) []byte {
+		b := &bytes.Buffer{}
+		(&fakePLUSPacket{cat: cat, psn: psn, payload: []byte("foobar")}).write(b)
+		return b.Bytes()
+	}
+
+	BeforeEach(func() {
+		tracker = newPLUSRTTTracker()
+	})
+
+	It("parses the CAT and the PSN", func() {
+		cat, psn, ok := parsePLUSCATAndPSN(getPacket(0xdeadbeef, 1337))
+		Expect(ok).To(BeTrue())
+		Expect(cat).To(Equal(uint64(0xdeadbeef)))
+		Expect(psn).To(Equal(uint32(1337)))
+	})
+
+	It("doesn't parse packets that aren't PLUS packets", func() {
+		_, _, ok := parsePLUSCATAndPSN([]byte("foobar"))
+		Expect(ok).To(BeFalse())
+		_, _, ok = parsePLUSCATAndPSN(getPacket(1, 2)[:plusBasicHeaderLen-1])
+		Expect(ok).To(BeFalse())
+	})
+
+	It("measures the RTT", func() {
+		t := time.Now()
+		tracker.sentPacket(getPacket(1, 10), t)
+		tracker.sentPacket(getPacket(1, 11), t.Add(time.Millisecond))
+		rtt, ok := tracker.receivedPSE(1, 11, t.Add(50*time.Millisecond))
+		Expect(ok).To(BeTrue())
+		Expect(rtt).To(Equal(49 * time.Millisecond))
+	})
+
+	It("only uses the first echo of a PSN", func() {
+		t := time.Now()
+		tracker.sentPacket(getPacket(1, 10), t)
+		_, ok := tracker.receivedPSE(1, 10, t.Add(50*time.Millisecond))
+		Expect(ok).To(BeTrue())
+		_, ok = tracker.receivedPSE(1, 10, t.Add(80*time.Millisecond))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("keeps the connections apart", func() {
+		t := time.Now()
+		tracker.sentPacket(getPacket(1, 10), t)
+		_, ok := tracker.receivedPSE(2, 10, t.Add(50*time.Millisecond))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("forgets removed connections", func() {
+		tracker.sentPacket(getPacket(1, 10), time.Now())
+		tracker.removeConnection(1)
+		Expect(tracker.sentPSNs).To(BeEmpty())
+	})
+
+	It("limits the number of PSNs it keeps", func() {
+		t := time.Now()
+		for i := 0; i < protocol.MaxTrackedPLUSPSNs+10; i++ {
+			tracker.sentPacket(getPacket(1, uint32(i)), t)
+		}
+		Expect(len(tracker.sentPSNs[1])).To(BeNumerically("<=", protocol.MaxTrackedPLUSPSNs))
+	})
+})
@@ -0,0 +1,276 @@
+package quic
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/mami-project/plus-lib"
+	"github.com/mami-project/plus-lib/packet"
+)
+
+// PLUSTransport encapsulates QUIC packets in PLUS packets, see https://github.com/mami-project/plus-lib.
+var PLUSTransport Transport = &plusTransport{
+	listen: func(pconn net.PacketConn) plusConnectionManager {
+		return &plusLibConnectionManager{PLUS.NewConnectionManager(pconn)}
+	},
+	dial: func(pconn net.PacketConn, connID uint64, remoteAddr net.Addr) (plusConnectionManager, plusConnection) {
+		connManager, connection := PLUS.NewConnectionManagerClient(pconn, connID, remoteAddr)
+		return &plusLibConnectionManager{connManager}, connection
+	},
+}
+
+// plusConnectionManager contains the operations of a PLUS.ConnectionManager used by the PLUS transport
+type plusConnectionManager interface {
+	ReadAndProcessPacket() (plusConnection, plusPacket, net.Addr, []byte, error)
+	ReturnPacketAndBuffer(plusPacket)
+	Close() error
+	LocalAddr() net.Addr
+}
+
+// plusConnection contains the operations of a PLUS.Connection used by the PLUS transport
+type plusConnection interface {
+	Write([]byte) (int, error)
+	AddPCFFeedback([]byte) error
+	QueuePCFRequest(pcfType uint16, pcfIntegrity uint16, pcfValue []byte) error
+	SetSFlag(bool)
+	SetRemoteAddr(net.Addr)
+	RemoteAddr() net.Addr
+	LocalAddr() net.Addr
+	Close() error
+}
+
+// plusPacket contains the operations of a PLUS packet used by the PLUS transport
+type plusPacket interface {
+	Payload() []byte
+	CAT() uint64
+	PSN() uint32
+	PSE() uint32
+}
+
+var _ plusConnection = &PLUS.Connection{}
+var _ plusPacket = &packet.PLUSPacket{}
+
+// plusLibConnectionManager adapts a PLUS.ConnectionManager to the plusConnectionManager interface
+type plusLibConnectionManager struct {
+	*PLUS.ConnectionManager
+}
+
+var _ plusConnectionManager = &plusLibConnectionManager{}
+
+func (m *plusLibConnectionManager) ReadAndProcessPacket() (plusConnection, plusPacket, net.Addr, []byte, error) {
+	connection, plusPacket, remoteAddr, feedbackData, err := m.ConnectionManager.ReadAndProcessPacket()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return connection, plusPacket, remoteAddr, feedbackData, nil
+}
+
+func (m *plusLibConnectionManager) ReturnPacketAndBuffer(p plusPacket) {
+	m.ConnectionManager.ReturnPacketAndBuffer(p.(*packet.PLUSPacket))
+}
+
+type plusTransport struct {
+	listen func(pconn net.PacketConn) plusConnectionManager
+	dial   func(pconn net.PacketConn, connID uint64, remoteAddr net.Addr) (plusConnectionManager, plusConnection)
+}
+
+func (t *plusTransport) Listen(pconn net.PacketConn) (PacketTransport, error) {
+	rttTracker := newPLUSRTTTracker()
+	return newPLUSPacketTransport(t.listen(&plusRTTPacketConn{PacketConn: pconn, tracker: rttTracker}), rttTracker), nil
+}
+
+func (t *plusTransport) Dial(pconn net.PacketConn, remoteAddr net.Addr, connectionID protocol.ConnectionID) (PacketTransport, TransportConnection, error) {
+	rttTracker := newPLUSRTTTracker()
+	connManager, connection := t.dial(&plusRTTPacketConn{PacketConn: pconn, tracker: rttTracker}, uint64(connectionID), remoteAddr)
+	pt := newPLUSPacketTransport(connManager, rttTracker)
+	return pt, pt.getConn(connection), nil
+}
+
+type plusPacketTransport struct {
+	connManager plusConnectionManager
+	rttTracker  *plusRTTTracker
+
+	mutex sync.Mutex
+	stats map[plusConnection]*plusStats
+}
+
+var _ PacketTransport = &plusPacketTransport{}
+
+func newPLUSPacketTransport(connManager plusConnectionManager, rttTracker *plusRTTTracker) *plusPacketTransport {
+	return &plusPacketTransport{
+		connManager: connManager,
+		rttTracker:  rttTracker,
+		stats:       make(map[plusConnection]*plusStats),
+	}
+}
+
+func (t *plusPacketTransport) ReadPacket(p []byte) (int, net.Addr, TransportConnection, []byte, error) {
+	connection, plusPacket, remoteAddr, feedbackData, err := t.connManager.ReadAndProcessPacket()
+	if err != nil {
+		return 0, nil, nil, nil, err
+	}
+
+	c := t.getConn(connection)
+	c.stats.receivedPacket(plusPacket.CAT(), plusPacket.PSN(), plusPacket.PSE(), feedbackData != nil)
+	if rtt, ok := t.rttTracker.receivedPSE(plusPacket.CAT(), plusPacket.PSE(), time.Now()); ok {
+		c.stats.updateRTT(rtt)
+	}
+
+	n := copy(p, plusPacket.Payload())
+	// the feedback data is only valid until the packet is returned
+	var feedback []byte
+	if feedbackData != nil {
+		feedback = make([]byte, len(feedbackData))
+		copy(feedback, feedbackData)
+	}
+	t.connManager.ReturnPacketAndBuffer(plusPacket)
+
+	return n, remoteAddr, c, feedback, nil
+}
+
+// getConn returns a plusConn that shares its statistics with all other plusConns for the same PLUS connection
+func (t *plusPacketTransport) getConn(connection plusConnection) *plusConn {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	stats, ok := t.stats[connection]
+	if !ok {
+		stats = &plusStats{}
+		t.stats[connection] = stats
+	}
+	return &plusConn{connection: connection, transport: t, stats: stats}
+}
+
+func (t *plusPacketTransport) removeConn(connection plusConnection) {
+	t.mutex.Lock()
+	stats, ok := t.stats[connection]
+	delete(t.stats, connection)
+	t.mutex.Unlock()
+	if ok {
+		t.rttTracker.removeConnection(stats.getCAT())
+	}
+}
+
+func (t *plusPacketTransport) LocalAddr() net.Addr {
+	return t.connManager.LocalAddr()
+}
+
+func (t *plusPacketTransport) Close() error {
+	return t.connManager.Close()
+}
+
+// plusStats are collected from the PLUS packets received for a PLUS connection
+type plusStats struct {
+	mutex sync.RWMutex
+
+	cat              uint64
+	packetsReceived  uint64
+	psn              uint32
+	pse              uint32
+	feedbackReceived uint64
+	rtt              time.Duration
+}
+
+func (s *plusStats) receivedPacket(cat uint64, psn, pse uint32, hasFeedback bool) {
+	s.mutex.Lock()
+	s.cat = cat
+	s.packetsReceived++
+	s.psn = psn
+	s.pse = pse
+	if hasFeedback {
+		s.feedbackReceived++
+	}
+	s.mutex.Unlock()
+}
+
+func (s *plusStats) updateRTT(rtt time.Duration) {
+	s.mutex.Lock()
+	s.rtt = rtt
+	s.mutex.Unlock()
+}
+
+func (s *plusStats) getCAT() uint64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.cat
+}
+
+// A flowStopper can tell devices on the path that a connection ends
+type flowStopper interface {
+	// writeStop writes the last packet of a connection
+	writeStop([]byte) error
+}
+
+// plusInfoProvider is implemented by TransportConnections that collect PLUS statistics
+type plusInfoProvider interface {
+	plusInfo() PLUSInfo
+}
+
+type plusConn struct {
+	connection plusConnection
+	transport  *plusPacketTransport
+	stats      *plusStats
+}
+
+var _ TransportConnection = &plusConn{}
+var _ plusInfoProvider = &plusConn{}
+var _ pcfRequester = &plusConn{}
+var _ flowStopper = &plusConn{}
+var _ keyedTransportConnection = &plusConn{}
+
+func (c *plusConn) Write(p []byte) error {
+	_, err := c.connection.Write(p)
+	return err
+}
+
+func (c *plusConn) SetCurrentRemoteAddr(addr net.Addr) {
+	c.connection.SetRemoteAddr(addr)
+}
+
+func (c *plusConn) AddFeedback(data []byte) error {
+	return c.connection.AddPCFFeedback(data)
+}
+
+func (c *plusConn) queuePCFRequest(pcfType uint16, integrity uint16, value []byte) error {
+	return c.connection.QueuePCFRequest(pcfType, integrity, value)
+}
+
+// writeStop sets the PLUS stop flag, signaling the end of the flow to devices on the path
+func (c *plusConn) writeStop(p []byte) error {
+	c.connection.SetSFlag(true)
+	_, err := c.connection.Write(p)
+	return err
+}
+
+// key returns the PLUS connection, which is shared by all plusConns for this connection
+func (c *plusConn) key() interface{} {
+	return c.connection
+}
+
+// Close removes the connection from the PLUS ConnectionManager
+func (c *plusConn) Close() error {
+	c.transport.removeConn(c.connection)
+	return c.connection.Close()
+}
+
+func (c *plusConn) LocalAddr() net.Addr {
+	return c.connection.LocalAddr()
+}
+
+func (c *plusConn) RemoteAddr() net.Addr {
+	return c.connection.RemoteAddr()
+}
+
+func (c *plusConn) plusInfo() PLUSInfo {
+	c.stats.mutex.RLock()
+	defer c.stats.mutex.RUnlock()
+	return PLUSInfo{
+		UsesPLUS:         true,
+		PacketsReceived:  c.stats.packetsReceived,
+		PSN:              c.stats.psn,
+		PSE:              c.stats.pse,
+		FeedbackReceived: c.stats.feedbackReceived,
+		RTT:              c.stats.rtt,
+	}
+}
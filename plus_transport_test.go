@@ -0,0 +1,259 @@
+package quic
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PLUS Transport", func() {
+	Context("statistics", func() {
+		var (
+			stats *plusStats
+			c     *plusConn
+		)
+
+		BeforeEach(func() {
+			stats = &plusStats{}
+			c = &plusConn{stats: stats}
+		})
+
+		It("reports that the connection uses PLUS", func() {
+			Expect(c.plusInfo()).To(Equal(PLUSInfo{UsesPLUS: true}))
+		})
+
+		It("counts received packets", func() {
+			stats.receivedPacket(42, 1, 10, false)
+			stats.receivedPacket(42, 2, 11, false)
+			info := c.plusInfo()
+			Expect(info.PacketsReceived).To(Equal(uint64(2)))
+			Expect(info.FeedbackReceived).To(BeZero())
+		})
+
+		It("saves PSN and PSE of the last packet received", func() {
+			stats.receivedPacket(42, 1, 10, false)
+			stats.receivedPacket(42, 2, 11, false)
+			info := c.plusInfo()
+			Expect(info.PSN).To(Equal(uint32(2)))
+			Expect(info.PSE).To(Equal(uint32(11)))
+		})
+
+		It("counts feedback", func() {
+			stats.receivedPacket(42, 1, 10, true)
+			stats.receivedPacket(42, 2, 11, false)
+			stats.receivedPacket(42, 3, 12, true)
+			Expect(c.plusInfo().FeedbackReceived).To(Equal(uint64(2)))
+		})
+	})
+
+	Context("using the fake PLUS layer", func() {
+		var (
+			transport            *plusTransport
+			rec                  *fakePLUSRecorder
+			serverConn           *memPacketConn
+			clientConn           *memPacketConn
+			serverTransport      PacketTransport
+			clientTransport      PacketTransport
+			clientTConn          TransportConnection
+			connID               = protocol.ConnectionID(0x1337)
+			readPacket           func(PacketTransport) ([]byte, net.Addr, TransportConnection, []byte)
+			receivedOnServerConn TransportConnection
+		)
+
+		readPacket = func(t PacketTransport) ([]byte, net.Addr, TransportConnection, []byte) {
+			b := make([]byte, protocol.MaxReceivePacketSize)
+			n, addr, conn, feedback, err := t.ReadPacket(b)
+			Expect(err).ToNot(HaveOccurred())
+			return b[:n], addr, conn, feedback
+		}
+
+		BeforeEach(func() {
+			var err error
+			transport, rec = newFakePLUSTransport()
+			serverConn, clientConn = newMemPacketConnPair()
+			serverTransport, err = transport.Listen(serverConn)
+			Expect(err).ToNot(HaveOccurred())
+			clientTransport, clientTConn, err = transport.Dial(clientConn, serverConn.LocalAddr(), connID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(clientTConn.Write([]byte("foobar"))).To(Succeed())
+			var data []byte
+			data, _, receivedOnServerConn, _ = readPacket(serverTransport)
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+
+		AfterEach(func() {
+			serverTransport.Close()
+			clientTransport.Close()
+		})
+
+		It("sends packets in both directions", func() {
+			Expect(receivedOnServerConn.RemoteAddr()).To(Equal(clientConn.LocalAddr()))
+			Expect(receivedOnServerConn.Write([]byte("raboof"))).To(Succeed())
+			data, addr, _, _ := readPacket(clientTransport)
+			Expect(data).To(Equal([]byte("raboof")))
+			Expect(addr).To(Equal(serverConn.LocalAddr()))
+			Expect(rec.sentPackets()).To(HaveLen(2))
+			Expect(rec.receivedPackets()).To(HaveLen(2))
+			Expect(rec.returnedPackets()).To(Equal(2))
+		})
+
+		It("collects PSN and PSE", func() {
+			Expect(receivedOnServerConn.Write([]byte("raboof"))).To(Succeed())
+			readPacket(clientTransport)
+			Expect(clientTConn.Write([]byte("foobar"))).To(Succeed())
+			_, _, conn, _ := readPacket(serverTransport)
+			info := conn.(plusInfoProvider).plusInfo()
+			Expect(info.PacketsReceived).To(Equal(uint64(2)))
+			Expect(info.PSN).To(Equal(uint32(2)))
+			Expect(info.PSE).To(Equal(uint32(1)))
+			// all TransportConnections for the same PLUS connection share the statistics
+			Expect(receivedOnServerConn.(plusInfoProvider).plusInfo()).To(Equal(info))
+		})
+
+		It("uses the same key for all TransportConnections for the same PLUS connection", func() {
+			Expect(clientTConn.Write([]byte("foobar"))).To(Succeed())
+			_, _, conn, _ := readPacket(serverTransport)
+			Expect(conn).ToNot(BeIdenticalTo(receivedOnServerConn))
+			Expect(transportConnKey(conn)).To(Equal(transportConnKey(receivedOnServerConn)))
+		})
+
+		It("measures the RTT", func() {
+			Expect(clientTConn.(plusInfoProvider).plusInfo().RTT).To(BeZero())
+			// the client sent a packet in the BeforeEach
+			time.Sleep(10 * time.Millisecond)
+			Expect(receivedOnServerConn.Write([]byte("raboof"))).To(Succeed())
+			readPacket(clientTransport)
+			rtt := clientTConn.(plusInfoProvider).plusInfo().RTT
+			Expect(rtt).To(BeNumerically(">=", 10*time.Millisecond))
+			Expect(rtt).To(BeNumerically("<", time.Second))
+		})
+
+		It("returns the PCF value of PCF requests as feedback", func() {
+			Expect(clientTConn.(pcfRequester).queuePCFRequest(1, 2, []byte("request"))).To(Succeed())
+			Expect(clientTConn.Write([]byte("foobar"))).To(Succeed())
+			_, _, conn, feedback := readPacket(serverTransport)
+			Expect(feedback).To(Equal([]byte("request")))
+			Expect(conn.(plusInfoProvider).plusInfo().FeedbackReceived).To(Equal(uint64(1)))
+		})
+
+		It("passes feedback to the PLUS layer", func() {
+			Expect(clientTConn.AddFeedback([]byte("feedback"))).To(Succeed())
+			Expect(rec.addedFeedback()).To(Equal([][]byte{[]byte("feedback")}))
+		})
+
+		It("sets the stop flag", func() {
+			Expect(receivedOnServerConn.(flowStopper).writeStop([]byte("bye"))).To(Succeed())
+			readPacket(clientTransport)
+			Expect(rec.receivedPackets()[1].stop).To(BeTrue())
+		})
+
+		It("sends packets to the new address after a migration", func() {
+			newAddr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 3), Port: 3000}
+			newClientConn := clientConn.network.newPacketConn(newAddr)
+			receivedOnServerConn.SetCurrentRemoteAddr(newAddr)
+			Expect(receivedOnServerConn.RemoteAddr()).To(Equal(newAddr))
+			Expect(receivedOnServerConn.Write([]byte("raboof"))).To(Succeed())
+			b := make([]byte, 100)
+			_, addr, err := newClientConn.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr).To(Equal(serverConn.LocalAddr()))
+		})
+
+		It("removes closed connections", func() {
+			Expect(receivedOnServerConn.Close()).To(Succeed())
+			Expect(rec.closedConnections()).To(Equal([]uint64{uint64(connID)}))
+			Expect(serverTransport.(*plusPacketTransport).stats).To(BeEmpty())
+		})
+	})
+
+	Context("running QUIC over the fake PLUS layer", func() {
+		var (
+			rec        *fakePLUSRecorder
+			ln         Listener
+			clientConn *memPacketConn
+			serverConn *memPacketConn
+			serverConf *Config
+			clientConf *Config
+			serverSess chan Session
+		)
+
+		BeforeEach(func() {
+			var transport Transport
+			transport, rec = newFakePLUSTransport()
+			serverConn, clientConn = newMemPacketConnPair()
+			serverConf = &Config{TLSConfig: testdata.GetTLSConfig(), Transport: transport}
+			clientConf = &Config{TLSConfig: &tls.Config{InsecureSkipVerify: true}, Transport: transport}
+			var err error
+			ln, err = Listen(serverConn, serverConf)
+			Expect(err).ToNot(HaveOccurred())
+			serverSess = make(chan Session, 1)
+			go func() {
+				defer GinkgoRecover()
+				sess, err := ln.Accept()
+				Expect(err).ToNot(HaveOccurred())
+				serverSess <- sess
+			}()
+		})
+
+		AfterEach(func() {
+			ln.Close()
+		})
+
+		It("completes the handshake and transfers data", func() {
+			sess, err := Dial(clientConn, serverConn.LocalAddr(), "quic.clemente.io:1337", clientConf)
+			Expect(err).ToNot(HaveOccurred())
+			var ssess Session
+			Eventually(serverSess).Should(Receive(&ssess))
+			str, err := ssess.OpenStreamSync()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			cstr, err := sess.AcceptStream()
+			Expect(err).ToNot(HaveOccurred())
+			data := make([]byte, 6)
+			_, err = io.ReadFull(cstr, data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(sess.PLUSInfo().UsesPLUS).To(BeTrue())
+			Expect(ssess.PLUSInfo().UsesPLUS).To(BeTrue())
+			Expect(rec.sentPackets()).ToNot(BeEmpty())
+			Expect(rec.receivedPackets()).ToNot(BeEmpty())
+			sess.Close(nil)
+		})
+
+		It("echoes feedback for PCF requests", func() {
+			sess, err := Dial(clientConn, serverConn.LocalAddr(), "quic.clemente.io:1337", clientConf)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(serverSess).Should(Receive())
+			err = sess.RequestPCF(&PCFRequest{Type: 1, Value: []byte("request")})
+			Expect(err).ToNot(HaveOccurred())
+			// send a packet that carries the PCF request
+			_, err = sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			sess.(*session).queueResetStreamFrame(3, 0)
+			Eventually(sess.PCFFeedback()).Should(Receive(Equal([]byte("request"))))
+			Expect(rec.addedFeedback()).To(ContainElement([]byte("request")))
+			Eventually(func() uint64 { return sess.PLUSInfo().FeedbackFramesReceived }).Should(Equal(uint64(1)))
+			sess.Close(nil)
+		})
+
+		It("sets the stop flag when closing", func() {
+			sess, err := Dial(clientConn, serverConn.LocalAddr(), "quic.clemente.io:1337", clientConf)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(serverSess).Should(Receive())
+			sess.Close(nil)
+			Eventually(func() bool {
+				received := rec.receivedPackets()
+				return len(received) > 0 && received[len(received)-1].stop
+			}).Should(BeTrue())
+		})
+	})
+})
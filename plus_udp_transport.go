@@ -0,0 +1,223 @@
+package quic
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+// PLUSAndUDPTransport lets a server accept PLUS and non-PLUS clients on the same net.PacketConn.
+// Datagrams starting with the PLUS magic are handed to PLUS, all other datagrams are treated as QUIC packets sent directly over UDP.
+// Clients have to decide on a transport, so it can only be used by servers.
+var PLUSAndUDPTransport Transport = &plusAndUDPTransport{plus: PLUSTransport.(*plusTransport)}
+
+// PLUSMagic is contained in the first 28 bits of every PLUS packet.
+// It can't be confused with a QUIC public header, since the most significant bit of the public flags is never set.
+const PLUSMagic = 0xd8007ff
+
+var (
+	errPLUSAndUDPTransportDial = errors.New("PLUSAndUDPTransport can only be used by servers")
+	errDemuxPacketConnClosed   = errors.New("use of closed demuxPacketConn")
+	errDeadlinesNotSupported   = errors.New("deadlines are not supported")
+)
+
+// IsPLUSPacket says if a UDP payload is a PLUS packet
+func IsPLUSPacket(data []byte) bool {
+	return len(data) >= 4 && binary.BigEndian.Uint32(data)>>4 == PLUSMagic
+}
+
+type plusAndUDPTransport struct {
+	plus *plusTransport
+}
+
+func (t *plusAndUDPTransport) Listen(pconn net.PacketConn) (PacketTransport, error) {
+	plusPConn := newDemuxPacketConn(pconn)
+	plus, err := t.plus.Listen(plusPConn)
+	if err != nil {
+		return nil, err
+	}
+	pt := &plusAndUDPPacketTransport{
+		pconn:     pconn,
+		plusPConn: plusPConn,
+		plus:      plus,
+		packets:   make(chan *demuxedPacket, protocol.MaxDemultiplexedPackets),
+		errorChan: make(chan struct{}),
+	}
+	go pt.readUDP()
+	go pt.readPLUS()
+	return pt, nil
+}
+
+func (t *plusAndUDPTransport) Dial(net.PacketConn, net.Addr, protocol.ConnectionID) (PacketTransport, TransportConnection, error) {
+	return nil, nil, errPLUSAndUDPTransportDial
+}
+
+type demuxedPacket struct {
+	data       []byte
+	remoteAddr net.Addr
+	conn       TransportConnection
+	feedback   []byte
+}
+
+// The plusAndUDPPacketTransport reads all datagrams from the net.PacketConn.
+// PLUS packets are passed on to the PLUS PacketTransport, which reads them from a demuxPacketConn.
+type plusAndUDPPacketTransport struct {
+	pconn     net.PacketConn
+	plusPConn *demuxPacketConn
+	plus      PacketTransport
+
+	packets chan *demuxedPacket
+
+	errorOnce sync.Once
+	err       error
+	errorChan chan struct{}
+}
+
+var _ PacketTransport = &plusAndUDPPacketTransport{}
+
+func (t *plusAndUDPPacketTransport) ReadPacket(p []byte) (int, net.Addr, TransportConnection, []byte, error) {
+	select {
+	case packet := <-t.packets:
+		n := copy(p, packet.data)
+		putPacketBuffer(packet.data)
+		return n, packet.remoteAddr, packet.conn, packet.feedback, nil
+	case <-t.errorChan:
+		return 0, nil, nil, nil, t.err
+	}
+}
+
+func (t *plusAndUDPPacketTransport) readUDP() {
+	for {
+		data := getPacketBuffer()
+		data = data[:protocol.MaxReceivePacketSize]
+		n, remoteAddr, err := t.pconn.ReadFrom(data)
+		if err != nil {
+			putPacketBuffer(data)
+			t.closeWithError(err)
+			return
+		}
+		data = data[:n]
+		if IsPLUSPacket(data) {
+			t.plusPConn.deliver(data, remoteAddr)
+			continue
+		}
+		t.queuePacket(&demuxedPacket{
+			data:       data,
+			remoteAddr: remoteAddr,
+			conn:       &conn{pconn: t.pconn, currentAddr: remoteAddr},
+		})
+	}
+}
+
+func (t *plusAndUDPPacketTransport) readPLUS() {
+	for {
+		data := getPacketBuffer()
+		data = data[:protocol.MaxReceivePacketSize]
+		n, remoteAddr, conn, feedback, err := t.plus.ReadPacket(data)
+		if err != nil {
+			putPacketBuffer(data)
+			t.closeWithError(err)
+			return
+		}
+		t.queuePacket(&demuxedPacket{
+			data:       data[:n],
+			remoteAddr: remoteAddr,
+			conn:       conn,
+			feedback:   feedback,
+		})
+	}
+}
+
+func (t *plusAndUDPPacketTransport) queuePacket(p *demuxedPacket) {
+	select {
+	case t.packets <- p:
+	case <-t.errorChan:
+		putPacketBuffer(p.data)
+	}
+}
+
+func (t *plusAndUDPPacketTransport) closeWithError(err error) {
+	t.errorOnce.Do(func() {
+		t.err = err
+		close(t.errorChan)
+	})
+}
+
+func (t *plusAndUDPPacketTransport) LocalAddr() net.Addr {
+	return t.pconn.LocalAddr()
+}
+
+// Close closes the net.PacketConn, and the PLUS PacketTransport
+func (t *plusAndUDPPacketTransport) Close() error {
+	err := t.pconn.Close()
+	t.plus.Close()
+	return err
+}
+
+type demuxedDatagram struct {
+	data       []byte
+	remoteAddr net.Addr
+}
+
+// A demuxPacketConn is the net.PacketConn used by the PLUS PacketTransport of the PLUSAndUDPTransport.
+// It reads the PLUS packets handed to it by the plusAndUDPPacketTransport, and writes to the underlying net.PacketConn.
+type demuxPacketConn struct {
+	pconn    net.PacketConn
+	incoming chan demuxedDatagram
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ net.PacketConn = &demuxPacketConn{}
+
+func newDemuxPacketConn(pconn net.PacketConn) *demuxPacketConn {
+	return &demuxPacketConn{
+		pconn:    pconn,
+		incoming: make(chan demuxedDatagram, protocol.MaxDemultiplexedPackets),
+		closed:   make(chan struct{}),
+	}
+}
+
+// deliver queues a datagram read from the underlying net.PacketConn
+// If the queue is full, the datagram is dropped, just like a full socket receive buffer would.
+func (c *demuxPacketConn) deliver(data []byte, remoteAddr net.Addr) {
+	select {
+	case c.incoming <- demuxedDatagram{data: data, remoteAddr: remoteAddr}:
+	default:
+		putPacketBuffer(data)
+	}
+}
+
+func (c *demuxPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case d := <-c.incoming:
+		n := copy(b, d.data)
+		putPacketBuffer(d.data)
+		return n, d.remoteAddr, nil
+	case <-c.closed:
+		return 0, nil, errDemuxPacketConnClosed
+	}
+}
+
+func (c *demuxPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.pconn.WriteTo(b, addr)
+}
+
+// Close doesn't close the underlying net.PacketConn
+func (c *demuxPacketConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *demuxPacketConn) LocalAddr() net.Addr {
+	return c.pconn.LocalAddr()
+}
+
+func (c *demuxPacketConn) SetDeadline(time.Time) error      { return errDeadlinesNotSupported }
+func (c *demuxPacketConn) SetReadDeadline(time.Time) error  { return errDeadlinesNotSupported }
+func (c *demuxPacketConn) SetWriteDeadline(time.Time) error { return errDeadlinesNotSupported }
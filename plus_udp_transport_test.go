@@ -0,0 +1,159 @@
+package quic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+
+	"github.com/lucas-clemente/quic-go/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PLUS and UDP Transport", func() {
+	It("recognizes PLUS packets", func() {
+		Expect(IsPLUSPacket([]byte{0xd8, 0x00, 0x7f, 0xf0, 0x01})).To(BeTrue())
+		Expect(IsPLUSPacket([]byte{0xd8, 0x00, 0x7f, 0xfa})).To(BeTrue())
+		Expect(IsPLUSPacket([]byte{0xd8, 0x00, 0x7f})).To(BeFalse())
+		Expect(IsPLUSPacket([]byte{0xd8, 0x00, 0x7e, 0xf0})).To(BeFalse())
+		// a QUIC public header, with version flag and an 8 byte connection ID
+		Expect(IsPLUSPacket([]byte{0x09, 0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe})).To(BeFalse())
+	})
+
+	It("can't be used by clients", func() {
+		_, _, err := PLUSAndUDPTransport.Dial(&mockPacketConn{}, nil, 0)
+		Expect(err).To(MatchError(errPLUSAndUDPTransportDial))
+	})
+
+	Context("demultiplexing", func() {
+		var (
+			rec        *fakePLUSRecorder
+			pt         PacketTransport
+			serverConn *memPacketConn
+			peerConn   *memPacketConn
+		)
+
+		BeforeEach(func() {
+			var plus *plusTransport
+			plus, rec = newFakePLUSTransport()
+			serverConn, peerConn = newMemPacketConnPair()
+			var err error
+			pt, err = (&plusAndUDPTransport{plus: plus}).Listen(serverConn)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			pt.Close()
+		})
+
+		readPacket := func() (net.Addr, TransportConnection, []byte) {
+			data := make([]byte, 100)
+			n, remoteAddr, conn, _, err := pt.ReadPacket(data)
+			Expect(err).ToNot(HaveOccurred())
+			return remoteAddr, conn, data[:n]
+		}
+
+		It("reads QUIC packets sent over UDP", func() {
+			_, err := peerConn.WriteTo([]byte("foobar"), serverConn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			remoteAddr, c, data := readPacket()
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(remoteAddr).To(Equal(peerConn.LocalAddr()))
+			Expect(c).To(BeAssignableToTypeOf(&conn{}))
+			Expect(rec.receivedPackets()).To(BeEmpty())
+		})
+
+		It("reads QUIC packets sent over PLUS", func() {
+			b := &bytes.Buffer{}
+			(&fakePLUSPacket{cat: 42, psn: 7, payload: []byte("foobar")}).write(b)
+			_, err := peerConn.WriteTo(b.Bytes(), serverConn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			remoteAddr, c, data := readPacket()
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(remoteAddr).To(Equal(peerConn.LocalAddr()))
+			Expect(c).To(BeAssignableToTypeOf(&plusConn{}))
+			Expect(c.(*plusConn).plusInfo().PSN).To(Equal(uint32(7)))
+			Expect(rec.receivedPackets()).To(HaveLen(1))
+		})
+
+		It("writes packets on the net.PacketConn", func() {
+			b := &bytes.Buffer{}
+			(&fakePLUSPacket{cat: 42, payload: []byte("foo")}).write(b)
+			_, err := peerConn.WriteTo(b.Bytes(), serverConn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			_, plusConn, _ := readPacket()
+			_, err = peerConn.WriteTo([]byte("bar"), serverConn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			_, udpConn, _ := readPacket()
+
+			Expect(plusConn.Write([]byte("decafbad"))).To(Succeed())
+			Expect(udpConn.Write([]byte("foobar"))).To(Succeed())
+			data := make([]byte, 100)
+			n, _, err := peerConn.ReadFrom(data)
+			Expect(err).ToNot(HaveOccurred())
+			p, err := parseFakePLUSPacket(data[:n])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.cat).To(Equal(uint64(42)))
+			Expect(p.payload).To(Equal([]byte("decafbad")))
+			n, _, err = peerConn.ReadFrom(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data[:n]).To(Equal([]byte("foobar")))
+		})
+
+		It("returns an error when closed", func() {
+			Expect(pt.Close()).To(Succeed())
+			_, _, _, _, err := pt.ReadPacket(make([]byte, 100))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns errors from the net.PacketConn", func() {
+			testErr := errors.New("read error")
+			pconn := &mockPacketConn{}
+			pconn.readErr = testErr
+			plus, _ := newFakePLUSTransport()
+			pt, err := (&plusAndUDPTransport{plus: plus}).Listen(pconn)
+			Expect(err).ToNot(HaveOccurred())
+			defer pt.Close()
+			_, _, _, _, err = pt.ReadPacket(make([]byte, 100))
+			Expect(err).To(MatchError(testErr))
+		})
+	})
+
+	It("serves PLUS and non-PLUS clients on the same net.PacketConn", func() {
+		network := newMemNetwork()
+		serverConn := network.newPacketConn(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000})
+		plusClientConn := network.newPacketConn(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 2000})
+		udpClientConn := network.newPacketConn(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 3), Port: 3000})
+		plus, _ := newFakePLUSTransport()
+		ln, err := Listen(serverConn, &Config{
+			TLSConfig: testdata.GetTLSConfig(),
+			Transport: &plusAndUDPTransport{plus: plus},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		clientConf := &Config{TLSConfig: &tls.Config{InsecureSkipVerify: true}}
+		plusConf := *clientConf
+		plusConf.Transport = plus
+		plusSess, err := Dial(plusClientConn, serverConn.LocalAddr(), "quic.clemente.io:1337", &plusConf)
+		Expect(err).ToNot(HaveOccurred())
+		defer plusSess.Close(nil)
+		udpConf := *clientConf
+		udpConf.Transport = UDPTransport
+		udpSess, err := Dial(udpClientConn, serverConn.LocalAddr(), "quic.clemente.io:1337", &udpConf)
+		Expect(err).ToNot(HaveOccurred())
+		defer udpSess.Close(nil)
+
+		var usesPLUS []bool
+		for i := 0; i < 2; i++ {
+			sess, err := ln.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			usesPLUS = append(usesPLUS, sess.PLUSInfo().UsesPLUS)
+		}
+		Expect(usesPLUS).To(ConsistOf(true, false))
+		Expect(plusSess.PLUSInfo().UsesPLUS).To(BeTrue())
+		Expect(udpSess.PLUSInfo().UsesPLUS).To(BeFalse())
+	})
+})
@@ -0,0 +1,14 @@
+package protocol
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Perspective", func() {
+	It("has a string representation", func() {
+		Expect(PerspectiveServer.String()).To(Equal("server"))
+		Expect(PerspectiveClient.String()).To(Equal("client"))
+		Expect(Perspective(0).String()).To(Equal("invalid perspective"))
+	})
+})
@@ -27,6 +27,27 @@ type ConnectionID uint64
 // A StreamID in QUIC
 type StreamID uint32
 
+// A StreamPriority is the priority of a stream, as in SPDY. 0 is the highest priority.
+type StreamPriority uint8
+
+const (
+	// HighestStreamPriority is the highest priority of a stream
+	HighestStreamPriority StreamPriority = 0
+	// LowestStreamPriority is the lowest priority of a stream
+	LowestStreamPriority StreamPriority = 7
+	// DefaultStreamPriority is the priority of a stream if none was set
+	DefaultStreamPriority StreamPriority = 3
+)
+
+const (
+	// MinStreamWeight is the smallest weight of a stream, as in HTTP/2
+	MinStreamWeight = 1
+	// MaxStreamWeight is the largest weight of a stream, as in HTTP/2
+	MaxStreamWeight = 256
+	// DefaultStreamWeight is the weight of a stream if none was set
+	DefaultStreamWeight = 16
+)
+
 // A ByteCount in QUIC
 type ByteCount uint64
 
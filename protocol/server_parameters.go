@@ -39,6 +39,10 @@ const ReceiveStreamFlowControlWindow ByteCount = (1 << 10) * 32 // 32 kB
 // This is the value that Google servers are using
 const ReceiveConnectionFlowControlWindow ByteCount = (1 << 10) * 48 // 48 kB
 
+// MinReceiveFlowControlWindow is the smallest flow control window for receiving data that can be configured
+// This is the smallest value that Chromium accepts
+const MinReceiveFlowControlWindow ByteCount = (1 << 10) * 16 // 16 kB
+
 // MaxReceiveStreamFlowControlWindowServer is the maximum stream-level flow control window for receiving data
 // This is the value that Google servers are using
 const MaxReceiveStreamFlowControlWindowServer ByteCount = 1 * (1 << 20) // 1 MB
@@ -71,9 +75,16 @@ const MaxStreamsMultiplier = 1.1
 // MaxStreamsMinimumIncrement is the slack the client is allowed for the maximum number of streams per connection, needed e.g. when packets are out of order or dropped. The minimum of this absolute increment and the procentual increase specified by MaxStreamsMultiplier is used.
 const MaxStreamsMinimumIncrement = 10
 
-// MaxNewStreamIDDelta is the maximum difference between and a newly opened Stream and the highest StreamID that a client has ever opened
-// note that the number of streams is half this value, since the client can only open streams with open StreamID
-const MaxNewStreamIDDelta = 4 * MaxStreamsPerConnection
+// MaxNewStreamIDDeltaMultiplier determines the maximum difference between a newly opened Stream and the highest StreamID that the peer has ever opened, relative to the maximum number of incoming streams
+// note that the number of streams is half the difference, since the peer can only open every other StreamID
+const MaxNewStreamIDDeltaMultiplier = 4
+
+// MaxIncomingStreamsLimit is the largest value accepted for the maximum number of incoming streams.
+// It makes sure that the slack added to the number of streams, and the StreamID delta derived from it, fit into a uint32.
+const MaxIncomingStreamsLimit = 1 << 28
+
+// MaxDemultiplexedPackets is the max number of packets that a transport serving PLUS and non-PLUS clients queues for a single path, until they are read.
+const MaxDemultiplexedPackets = DefaultMaxCongestionWindow
 
 // MaxSessionUnprocessedPackets is the max number of packets stored in each session that are not yet processed.
 const MaxSessionUnprocessedPackets = DefaultMaxCongestionWindow
@@ -119,6 +130,9 @@ const EphermalKeyLifetime = time.Minute
 // InitialIdleTimeout is the timeout before the handshake succeeds.
 const InitialIdleTimeout = 5 * time.Second
 
+// MinIdleTimeout is the smallest idle timeout that can be configured, since the idle timeout is negotiated in seconds
+const MinIdleTimeout = time.Second
+
 // DefaultIdleTimeout is the default idle timeout, for the server
 const DefaultIdleTimeout = 30 * time.Second
 
@@ -131,9 +145,36 @@ const MaxIdleTimeoutClient = 2 * time.Minute
 // MaxTimeForCryptoHandshake is the default timeout for a connection until the crypto handshake succeeds.
 const MaxTimeForCryptoHandshake = 10 * time.Second
 
+// StreamConnCloseTimeout is the time that the session of a net.Conn accepted by a quic.StreamListener has to deliver the remaining data after the net.Conn was closed
+const StreamConnCloseTimeout = 10 * time.Second
+
 // ClosedSessionDeleteTimeout the server ignores packets arriving on a connection that is already closed
 // after this time all information about the old connection will be deleted
 const ClosedSessionDeleteTimeout = time.Minute
 
 // NumCachedCertificates is the number of cached compressed certificate chains, each taking ~1K space
 const NumCachedCertificates = 128
+
+// PLUSStopRepeatTimeout is the time a client keeps receiving packets after closing a PLUS connection, in order to repeat the stop packet
+const PLUSStopRepeatTimeout = 3 * time.Second
+
+// MaxPLUSFeedbackFragmentSize is the maximum number of feedback bytes sent in a single PLUSFeedbackFrame
+// Larger feedback is split into multiple fragments. This value makes sure that a fragment always fits into a packet.
+const MaxPLUSFeedbackFragmentSize ByteCount = 1000
+
+// MaxPLUSFeedbackSize is the maximum size of reassembled PLUS feedback
+const MaxPLUSFeedbackSize ByteCount = 1 << 16 // 64 kB
+
+// MaxIncompletePLUSFeedbacks is the maximum number of fragmented PLUS feedbacks that are reassembled at the same time
+// prevents DoS attacks against the plusFeedbackAssembler
+const MaxIncompletePLUSFeedbacks = 16
+
+// MaxTrackedPLUSPSNs is the maximum number of PSNs per PLUS connection whose send time is kept, until the peer echoes them
+const MaxTrackedPLUSPSNs = 1024
+
+// MaxQueuedPCFFeedbacks is the maximum number of PCF feedbacks that are queued for the application
+// If the application doesn't read from Session.PCFFeedback(), newer feedback is dropped.
+const MaxQueuedPCFFeedbacks = 32
+
+// DefaultFallbackDelay is the time after which a client starts a second handshake using the fallback transport
+const DefaultFallbackDelay = 300 * time.Millisecond
@@ -6,6 +6,7 @@ import (
 
 	"github.com/lucas-clemente/quic-go/protocol"
 	"github.com/lucas-clemente/quic-go/qerr"
+	"github.com/lucas-clemente/quic-go/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -92,7 +93,7 @@ var _ = Describe("Public Header", func() {
 			}
 
 			It("parses version negotiation packets sent by the server", func() {
-				b := bytes.NewReader(composeVersionNegotiation(0x1337, protocol.SupportedVersions))
+				b := bytes.NewReader(composeVersionNegotiation(0x1337, protocol.SupportedVersions, utils.DefaultLogger))
 				hdr, err := ParsePublicHeader(b, protocol.PerspectiveServer)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(hdr.VersionFlag).To(BeTrue())
@@ -125,7 +126,7 @@ var _ = Describe("Public Header", func() {
 			})
 
 			It("errors on invalid version tags", func() {
-				data := composeVersionNegotiation(0x1337, protocol.SupportedVersions)
+				data := composeVersionNegotiation(0x1337, protocol.SupportedVersions, utils.DefaultLogger)
 				data = append(data, []byte{0x13, 0x37}...)
 				b := bytes.NewReader(data)
 				_, err := ParsePublicHeader(b, protocol.PerspectiveServer)
@@ -0,0 +1,99 @@
+package qlog
+
+import (
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go/frames"
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+// a frame is the JSON representation of a frame
+// Stream data and PLUS feedback are only logged by length.
+type frame map[string]interface{}
+
+func transformFrames(fs []frames.Frame) []frame {
+	res := make([]frame, len(fs))
+	for i, f := range fs {
+		res[i] = transformFrame(f)
+	}
+	return res
+}
+
+func transformFrame(f frames.Frame) frame {
+	switch f := f.(type) {
+	case *frames.StreamFrame:
+		return frame{
+			"frame_type": "stream",
+			"stream_id":  f.StreamID,
+			"offset":     f.Offset,
+			"length":     len(f.Data),
+			"fin":        f.FinBit,
+		}
+	case *frames.AckFrame:
+		return frame{
+			"frame_type":   "ack",
+			"ack_delay":    milliseconds(f.DelayTime),
+			"acked_ranges": ackRanges(f),
+		}
+	case *frames.StopWaitingFrame:
+		return frame{
+			"frame_type":    "stop_waiting",
+			"least_unacked": f.LeastUnacked,
+		}
+	case *frames.WindowUpdateFrame:
+		return frame{
+			"frame_type": "window_update",
+			"stream_id":  f.StreamID,
+			"offset":     f.ByteOffset,
+		}
+	case *frames.BlockedFrame:
+		return frame{
+			"frame_type": "blocked",
+			"stream_id":  f.StreamID,
+		}
+	case *frames.RstStreamFrame:
+		return frame{
+			"frame_type": "rst_stream",
+			"stream_id":  f.StreamID,
+			"error_code": f.ErrorCode,
+			"offset":     f.ByteOffset,
+		}
+	case *frames.ConnectionCloseFrame:
+		return frame{
+			"frame_type": "connection_close",
+			"error_code": f.ErrorCode.String(),
+			"reason":     f.ReasonPhrase,
+		}
+	case *frames.GoawayFrame:
+		return frame{
+			"frame_type":       "goaway",
+			"error_code":       f.ErrorCode.String(),
+			"last_good_stream": f.LastGoodStream,
+			"reason":           f.ReasonPhrase,
+		}
+	case *frames.PingFrame:
+		return frame{"frame_type": "ping"}
+	case *frames.PLUSFeedbackFrame:
+		return frame{
+			"frame_type":     "plus_feedback",
+			"feedback_id":    f.FeedbackID,
+			"offset":         f.Offset,
+			"length":         len(f.Data),
+			"more_fragments": f.MoreFragments,
+		}
+	default:
+		return frame{"frame_type": fmt.Sprintf("%T", f)}
+	}
+}
+
+// ackRanges returns the ranges of packet numbers acknowledged by an ACK frame, starting with the highest range
+func ackRanges(f *frames.AckFrame) [][2]protocol.PacketNumber {
+	if !f.HasMissingRanges() {
+		return [][2]protocol.PacketNumber{{f.LowestAcked, f.LargestAcked}}
+	}
+	ranges := make([][2]protocol.PacketNumber, len(f.AckRanges))
+	for i, r := range f.AckRanges {
+		ranges[i] = [2]protocol.PacketNumber{r.FirstPacketNumber, r.LastPacketNumber}
+	}
+	return ranges
+}
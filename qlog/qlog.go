@@ -0,0 +1,229 @@
+// Package qlog writes the events of QUIC connections to JSON files, one file per connection.
+// Every line of a file is a JSON object. The first line describes the connection, all following lines are events.
+// This makes it easy to compare runs, e.g. with jq.
+package qlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/frames"
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
+)
+
+type tracer struct {
+	dir string
+}
+
+var _ quic.Tracer = &tracer{}
+
+// NewTracer creates a quic.Tracer that writes one file per connection into dir.
+// The files are named after the connection ID and the perspective, e.g. 1234567890abcdef_server.qlog.
+func NewTracer(dir string) quic.Tracer {
+	return &tracer{dir: dir}
+}
+
+func (t *tracer) TracerForConnection(p protocol.Perspective, connectionID protocol.ConnectionID) quic.ConnectionTracer {
+	filename := filepath.Join(t.dir, fmt.Sprintf("%016x_%s.qlog", uint64(connectionID), p.String()))
+	f, err := os.Create(filename)
+	if err != nil {
+		utils.Errorf("Not tracing connection %x: %s", connectionID, err.Error())
+		return nil
+	}
+	return NewConnectionTracer(f, p, connectionID)
+}
+
+type connectionTracer struct {
+	mutex sync.Mutex
+
+	w             io.WriteCloser
+	buf           *bufio.Writer
+	enc           *json.Encoder
+	referenceTime time.Time
+	closed        bool
+}
+
+var _ quic.ConnectionTracer = &connectionTracer{}
+
+// NewConnectionTracer creates a quic.ConnectionTracer that writes the events of a single connection to w.
+// w is closed when the connection is closed.
+func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, connectionID protocol.ConnectionID) quic.ConnectionTracer {
+	buf := bufio.NewWriter(w)
+	t := &connectionTracer{
+		w:             w,
+		buf:           buf,
+		enc:           json.NewEncoder(buf),
+		referenceTime: time.Now(),
+	}
+	t.write(header{
+		VantagePoint:  p.String(),
+		ConnectionID:  fmt.Sprintf("%016x", uint64(connectionID)),
+		ReferenceTime: t.referenceTime.UnixNano() / int64(time.Millisecond),
+	})
+	return t
+}
+
+// header is the first line of a file
+type header struct {
+	VantagePoint string `json:"vantage_point"`
+	ConnectionID string `json:"connection_id"`
+	// ReferenceTime is the time the connection was created, in milliseconds since the Unix epoch
+	ReferenceTime int64 `json:"reference_time"`
+}
+
+type event struct {
+	// Time is the time since the reference time, in milliseconds
+	Time float64     `json:"time"`
+	Name string      `json:"name"`
+	Data interface{} `json:"data"`
+}
+
+type packetEvent struct {
+	PacketNumber    protocol.PacketNumber `json:"packet_number"`
+	Size            protocol.ByteCount    `json:"size"`
+	EncryptionLevel string                `json:"encryption_level"`
+	Frames          []frame               `json:"frames"`
+}
+
+func (t *connectionTracer) SentPacket(pn protocol.PacketNumber, size protocol.ByteCount, encLevel protocol.EncryptionLevel, fs []frames.Frame) {
+	t.recordEvent("transport:packet_sent", packetEvent{
+		PacketNumber:    pn,
+		Size:            size,
+		EncryptionLevel: encLevel.String(),
+		Frames:          transformFrames(fs),
+	})
+}
+
+func (t *connectionTracer) ReceivedPacket(pn protocol.PacketNumber, size protocol.ByteCount, encLevel protocol.EncryptionLevel, fs []frames.Frame) {
+	t.recordEvent("transport:packet_received", packetEvent{
+		PacketNumber:    pn,
+		Size:            size,
+		EncryptionLevel: encLevel.String(),
+		Frames:          transformFrames(fs),
+	})
+}
+
+func (t *connectionTracer) DroppedPacket(size protocol.ByteCount, reason quic.PacketDropReason) {
+	t.recordEvent("transport:packet_dropped", struct {
+		Size    protocol.ByteCount `json:"size"`
+		Trigger string             `json:"trigger"`
+	}{size, reason.String()})
+}
+
+func (t *connectionTracer) BufferedUndecryptablePacket(size protocol.ByteCount) {
+	t.recordEvent("transport:packet_buffered", struct {
+		Size protocol.ByteCount `json:"size"`
+	}{size})
+}
+
+func (t *connectionTracer) LostPacket(pn protocol.PacketNumber, size protocol.ByteCount) {
+	t.recordEvent("recovery:packet_lost", struct {
+		PacketNumber protocol.PacketNumber `json:"packet_number"`
+		Size         protocol.ByteCount    `json:"size"`
+	}{pn, size})
+}
+
+func (t *connectionTracer) RetransmittedPacket(pn protocol.PacketNumber) {
+	t.recordEvent("recovery:packet_retransmitted", struct {
+		PacketNumber protocol.PacketNumber `json:"packet_number"`
+	}{pn})
+}
+
+func (t *connectionTracer) UpdatedCongestionState(s quic.CongestionState) {
+	t.recordEvent("recovery:metrics_updated", struct {
+		CongestionWindow   protocol.ByteCount `json:"congestion_window"`
+		SlowStartThreshold protocol.ByteCount `json:"ssthresh,omitempty"`
+		BytesInFlight      protocol.ByteCount `json:"bytes_in_flight"`
+		SmoothedRTT        float64            `json:"smoothed_rtt"`
+		MinRTT             float64            `json:"min_rtt"`
+	}{
+		CongestionWindow:   s.CongestionWindow,
+		SlowStartThreshold: s.SlowStartThreshold,
+		BytesInFlight:      s.BytesInFlight,
+		SmoothedRTT:        milliseconds(s.SmoothedRTT),
+		MinRTT:             milliseconds(s.MinRTT),
+	})
+}
+
+func (t *connectionTracer) ChangedEncryptionLevel(encLevel protocol.EncryptionLevel) {
+	t.recordEvent("security:encryption_level_updated", struct {
+		EncryptionLevel string `json:"encryption_level"`
+	}{encLevel.String()})
+}
+
+type windowUpdateEvent struct {
+	StreamID protocol.StreamID  `json:"stream_id"`
+	Offset   protocol.ByteCount `json:"offset"`
+}
+
+func (t *connectionTracer) SentWindowUpdate(streamID protocol.StreamID, offset protocol.ByteCount) {
+	t.recordEvent("transport:window_update_sent", windowUpdateEvent{streamID, offset})
+}
+
+func (t *connectionTracer) ReceivedWindowUpdate(streamID protocol.StreamID, offset protocol.ByteCount) {
+	t.recordEvent("transport:window_update_received", windowUpdateEvent{streamID, offset})
+}
+
+type plusFeedbackEvent struct {
+	Data string `json:"data"`
+}
+
+func (t *connectionTracer) SentPLUSFeedback(data []byte) {
+	t.recordEvent("plus:feedback_sent", plusFeedbackEvent{fmt.Sprintf("%x", data)})
+}
+
+func (t *connectionTracer) ReceivedPLUSFeedback(data []byte) {
+	t.recordEvent("plus:feedback_received", plusFeedbackEvent{fmt.Sprintf("%x", data)})
+}
+
+func (t *connectionTracer) Closed(err error) {
+	var reason string
+	if err != nil {
+		reason = err.Error()
+	}
+	t.recordEvent("transport:connection_closed", struct {
+		Reason string `json:"reason,omitempty"`
+	}{reason})
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closed = true
+	if err := t.buf.Flush(); err != nil {
+		utils.Errorf("Error writing qlog: %s", err.Error())
+	}
+	if err := t.w.Close(); err != nil {
+		utils.Errorf("Error closing qlog: %s", err.Error())
+	}
+}
+
+func (t *connectionTracer) recordEvent(name string, data interface{}) {
+	t.write(event{
+		Time: milliseconds(time.Since(t.referenceTime)),
+		Name: name,
+		Data: data,
+	})
+}
+
+func (t *connectionTracer) write(v interface{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	// packets might still be dropped after the connection was closed
+	if t.closed {
+		return
+	}
+	if err := t.enc.Encode(v); err != nil {
+		utils.Errorf("Error writing qlog: %s", err.Error())
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,213 @@
+package qlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/frames"
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/qerr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type nopCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *nopCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+var _ = Describe("qlog", func() {
+	var (
+		buf    *nopCloser
+		tracer quic.ConnectionTracer
+	)
+
+	BeforeEach(func() {
+		buf = &nopCloser{}
+		tracer = NewConnectionTracer(buf, protocol.PerspectiveServer, 0xdeadbeef)
+	})
+
+	// lines closes the tracer, and returns all lines of the output
+	lines := func() []map[string]interface{} {
+		tracer.Closed(nil)
+		var res []map[string]interface{}
+		dec := json.NewDecoder(&buf.Buffer)
+		for dec.More() {
+			var m map[string]interface{}
+			Expect(dec.Decode(&m)).To(Succeed())
+			res = append(res, m)
+		}
+		return res
+	}
+
+	// lastEvent returns the last event before the connection_closed event
+	lastEvent := func() map[string]interface{} {
+		l := lines()
+		Expect(len(l)).To(BeNumerically(">=", 3))
+		return l[len(l)-2]
+	}
+
+	It("writes a header", func() {
+		l := lines()
+		Expect(l[0]).To(HaveKeyWithValue("vantage_point", "server"))
+		Expect(l[0]).To(HaveKeyWithValue("connection_id", "00000000deadbeef"))
+		Expect(l[0]["reference_time"]).To(BeNumerically("~", time.Now().UnixNano()/1e6, 1000))
+	})
+
+	It("closes the writer when the connection is closed", func() {
+		tracer.Closed(qerr.Error(qerr.NetworkIdleTimeout, "No recent network activity."))
+		Expect(buf.closed).To(BeTrue())
+		Expect(buf.String()).To(ContainSubstring(`"name":"transport:connection_closed"`))
+		Expect(buf.String()).To(ContainSubstring("No recent network activity."))
+	})
+
+	It("doesn't write events after the connection was closed", func() {
+		tracer.Closed(nil)
+		n := buf.Len()
+		tracer.DroppedPacket(100, quic.PacketDropQueueFull)
+		Expect(buf.Len()).To(Equal(n))
+	})
+
+	It("records sent packets", func() {
+		tracer.SentPacket(42, 1337, protocol.EncryptionForwardSecure, []frames.Frame{
+			&frames.StreamFrame{StreamID: 5, Offset: 100, Data: []byte("foobar"), FinBit: true},
+			&frames.AckFrame{LowestAcked: 1, LargestAcked: 10, DelayTime: 2 * time.Millisecond},
+		})
+		ev := lastEvent()
+		Expect(ev).To(HaveKeyWithValue("name", "transport:packet_sent"))
+		Expect(ev["time"]).To(BeNumerically(">=", 0))
+		data := ev["data"].(map[string]interface{})
+		Expect(data).To(HaveKeyWithValue("packet_number", 42.0))
+		Expect(data).To(HaveKeyWithValue("size", 1337.0))
+		Expect(data).To(HaveKeyWithValue("encryption_level", "forward-secure"))
+		fs := data["frames"].([]interface{})
+		Expect(fs).To(HaveLen(2))
+		Expect(fs[0]).To(Equal(map[string]interface{}{
+			"frame_type": "stream",
+			"stream_id":  5.0,
+			"offset":     100.0,
+			"length":     6.0,
+			"fin":        true,
+		}))
+		Expect(fs[1]).To(Equal(map[string]interface{}{
+			"frame_type":   "ack",
+			"ack_delay":    2.0,
+			"acked_ranges": []interface{}{[]interface{}{1.0, 10.0}},
+		}))
+	})
+
+	It("records received packets", func() {
+		tracer.ReceivedPacket(7, 100, protocol.EncryptionUnencrypted, []frames.Frame{&frames.PingFrame{}})
+		ev := lastEvent()
+		Expect(ev).To(HaveKeyWithValue("name", "transport:packet_received"))
+		data := ev["data"].(map[string]interface{})
+		Expect(data).To(HaveKeyWithValue("packet_number", 7.0))
+		Expect(data).To(HaveKeyWithValue("encryption_level", "unencrypted"))
+		Expect(data["frames"]).To(Equal([]interface{}{map[string]interface{}{"frame_type": "ping"}}))
+	})
+
+	It("records dropped packets", func() {
+		tracer.DroppedPacket(100, quic.PacketDropDuplicate)
+		ev := lastEvent()
+		Expect(ev).To(HaveKeyWithValue("name", "transport:packet_dropped"))
+		Expect(ev["data"]).To(Equal(map[string]interface{}{"size": 100.0, "trigger": "duplicate"}))
+	})
+
+	It("records lost and retransmitted packets", func() {
+		tracer.LostPacket(3, 1200)
+		tracer.RetransmittedPacket(3)
+		l := lines()
+		Expect(l[1]).To(HaveKeyWithValue("name", "recovery:packet_lost"))
+		Expect(l[1]["data"]).To(Equal(map[string]interface{}{"packet_number": 3.0, "size": 1200.0}))
+		Expect(l[2]).To(HaveKeyWithValue("name", "recovery:packet_retransmitted"))
+	})
+
+	It("records congestion state changes", func() {
+		tracer.UpdatedCongestionState(quic.CongestionState{
+			CongestionWindow: 20000,
+			BytesInFlight:    10000,
+			SmoothedRTT:      50 * time.Millisecond,
+			MinRTT:           40 * time.Millisecond,
+		})
+		ev := lastEvent()
+		Expect(ev).To(HaveKeyWithValue("name", "recovery:metrics_updated"))
+		Expect(ev["data"]).To(Equal(map[string]interface{}{
+			"congestion_window": 20000.0,
+			"bytes_in_flight":   10000.0,
+			"smoothed_rtt":      50.0,
+			"min_rtt":           40.0,
+		}))
+	})
+
+	It("records window updates", func() {
+		tracer.SentWindowUpdate(0, 1000)
+		tracer.ReceivedWindowUpdate(5, 2000)
+		l := lines()
+		Expect(l[1]).To(HaveKeyWithValue("name", "transport:window_update_sent"))
+		Expect(l[1]["data"]).To(Equal(map[string]interface{}{"stream_id": 0.0, "offset": 1000.0}))
+		Expect(l[2]).To(HaveKeyWithValue("name", "transport:window_update_received"))
+		Expect(l[2]["data"]).To(Equal(map[string]interface{}{"stream_id": 5.0, "offset": 2000.0}))
+	})
+
+	It("records PLUS feedback", func() {
+		tracer.SentPLUSFeedback([]byte{0xde, 0xad})
+		tracer.ReceivedPLUSFeedback([]byte{0xbe, 0xef})
+		l := lines()
+		Expect(l[1]).To(HaveKeyWithValue("name", "plus:feedback_sent"))
+		Expect(l[1]["data"]).To(Equal(map[string]interface{}{"data": "dead"}))
+		Expect(l[2]).To(HaveKeyWithValue("name", "plus:feedback_received"))
+		Expect(l[2]["data"]).To(Equal(map[string]interface{}{"data": "beef"}))
+	})
+
+	It("records encryption level changes", func() {
+		tracer.ChangedEncryptionLevel(protocol.EncryptionSecure)
+		ev := lastEvent()
+		Expect(ev).To(HaveKeyWithValue("name", "security:encryption_level_updated"))
+		Expect(ev["data"]).To(Equal(map[string]interface{}{"encryption_level": "encrypted (not forward-secure)"}))
+	})
+
+	Context("creating files", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "qlog")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("writes one file per connection", func() {
+			t := NewTracer(dir)
+			t.TracerForConnection(protocol.PerspectiveServer, 0x1337).Closed(nil)
+			t.TracerForConnection(protocol.PerspectiveClient, 0x42).Closed(errors.New("foobar"))
+			files, err := filepath.Glob(filepath.Join(dir, "*.qlog"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(ConsistOf(
+				filepath.Join(dir, "0000000000001337_server.qlog"),
+				filepath.Join(dir, "0000000000000042_client.qlog"),
+			))
+			data, err := ioutil.ReadFile(filepath.Join(dir, "0000000000000042_client.qlog"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring("foobar"))
+		})
+
+		It("doesn't trace the connection if the file can't be created", func() {
+			t := NewTracer(filepath.Join(dir, "nonexistent"))
+			Expect(t.TracerForConnection(protocol.PerspectiveServer, 0x1337)).To(BeNil())
+		})
+	})
+})
@@ -12,8 +12,6 @@ import (
 	"github.com/lucas-clemente/quic-go/protocol"
 	"github.com/lucas-clemente/quic-go/qerr"
 	"github.com/lucas-clemente/quic-go/utils"
-   "github.com/mami-project/plus-lib"
-   "fmt"
 )
 
 // packetHandler handles packets
@@ -27,13 +25,13 @@ type packetHandler interface {
 type server struct {
 	config *Config
 
-	conn net.PacketConn
-    plusConnManager *PLUS.ConnectionManager
+	transport PacketTransport
 
 	certChain crypto.CertChain
 	scfg      *handshake.ServerConfig
 
 	sessions                  map[protocol.ConnectionID]packetHandler
+	transportConns            map[interface{}]protocol.ConnectionID // the transport connections used by sessions, keyed by transportConnKey
 	sessionsMutex             sync.RWMutex
 	deleteClosedSessionsAfter time.Duration
 
@@ -41,7 +39,10 @@ type server struct {
 	sessionQueue chan Session
 	errorChan    chan struct{}
 
-	newSession func(conn connection, v protocol.VersionNumber, connectionID protocol.ConnectionID, sCfg *handshake.ServerConfig, config *Config, plusConnection *PLUS.Connection) (packetHandler, <-chan handshakeEvent, error)
+	stopAcceptingOnce sync.Once
+	acceptingStopped  chan struct{} // closed by StopAccepting
+
+	newSession func(conn TransportConnection, v protocol.VersionNumber, connectionID protocol.ConnectionID, sCfg *handshake.ServerConfig, config *Config) (packetHandler, <-chan handshakeEvent, error)
 }
 
 var _ Listener = &server{}
@@ -63,6 +64,9 @@ func ListenAddr(addr string, config *Config) (Listener, error) {
 // Listen listens for QUIC connections on a given net.PacketConn.
 // The listener is not active until Serve() is called.
 func Listen(conn net.PacketConn, config *Config) (Listener, error) {
+	if err := validateConfig(config, protocol.PerspectiveServer); err != nil {
+		return nil, err
+	}
 	certChain := crypto.NewCertChain(config.TLSConfig)
 	kex, err := crypto.NewCurve25519KEX()
 	if err != nil {
@@ -73,35 +77,25 @@ func Listen(conn net.PacketConn, config *Config) (Listener, error) {
 		return nil, err
 	}
 
-    var s *server
-
-    if !config.UsePLUS {
-        s = &server{
-            conn:                      conn,
-            plusConnManager:           nil,
-            config:                    populateServerConfig(config),
-            certChain:                 certChain,
-            scfg:                      scfg,
-            sessions:                  map[protocol.ConnectionID]packetHandler{},
-            newSession:                newSession,
-            deleteClosedSessionsAfter: protocol.ClosedSessionDeleteTimeout,
-            sessionQueue:              make(chan Session, 5),
-            errorChan:                 make(chan struct{}),
-        }
-    } else {
-        s = &server{
-            conn:                      nil,
-            plusConnManager:           PLUS.NewConnectionManager(conn),
-            config:                    populateServerConfig(config),
-            certChain:                 certChain,
-            scfg:                      scfg,
-            sessions:                  map[protocol.ConnectionID]packetHandler{},
-            newSession:                newSession,
-            deleteClosedSessionsAfter: protocol.ClosedSessionDeleteTimeout,
-            sessionQueue:              make(chan Session, 5),
-            errorChan:                 make(chan struct{}),
-        }
-    }
+	config = populateServerConfig(config)
+	transport, err := config.Transport.Listen(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &server{
+		transport:                 transport,
+		config:                    config,
+		certChain:                 certChain,
+		scfg:                      scfg,
+		sessions:                  map[protocol.ConnectionID]packetHandler{},
+		transportConns:            map[interface{}]protocol.ConnectionID{},
+		newSession:                newSession,
+		deleteClosedSessionsAfter: protocol.ClosedSessionDeleteTimeout,
+		sessionQueue:              make(chan Session, 5),
+		errorChan:                 make(chan struct{}),
+		acceptingStopped:          make(chan struct{}),
+	}
 	go s.serve()
 	return s, nil
 }
@@ -113,68 +107,43 @@ func populateServerConfig(config *Config) *Config {
 	}
 
 	return &Config{
-		TLSConfig: config.TLSConfig,
-		Versions:  versions,
-        UsePLUS:  config.UsePLUS,
+		TLSConfig:                             config.TLSConfig,
+		Versions:                              versions,
+		UsePLUS:                               config.UsePLUS,
+		Transport:                             populateTransport(config),
+		HandshakeTimeout:                      populateHandshakeTimeout(config),
+		IdleTimeout:                           config.IdleTimeout,
+		ReceiveStreamFlowControlWindow:        config.ReceiveStreamFlowControlWindow,
+		ReceiveConnectionFlowControlWindow:    config.ReceiveConnectionFlowControlWindow,
+		MaxReceiveStreamFlowControlWindow:     config.MaxReceiveStreamFlowControlWindow,
+		MaxReceiveConnectionFlowControlWindow: config.MaxReceiveConnectionFlowControlWindow,
+		MaxIncomingStreams:                    config.MaxIncomingStreams,
+		StreamScheduling:                      config.StreamScheduling,
+		CongestionControl:                     populateCongestionControl(config),
+		Tracer:                                config.Tracer,
+		Logger:                                populateLogger(config),
+		KeyLogWriter:                          config.KeyLogWriter,
 	}
 }
 
-// serve with PLUS
-func (s *server) servePLUS() {
-    utils.Infof("servePLUS")
-    for {
-        plusConnection, plusPacket, remoteAddr, feedbackData_, err := s.plusConnManager.ReadAndProcessPacket()
-
-        if err != nil {
-            s.serverError = err
-            close(s.errorChan)
-            _ = s.Close()
-            return
-        }
-
-		  data := getPacketBuffer()
-		  data = data[:protocol.MaxReceivePacketSize]
-
-		  var feedbackData []byte = nil
-
-		  if feedbackData_ != nil {
-		  	feedbackData = getPacketBuffer()
-	     	copy(feedbackData, feedbackData_)
-		  	feedbackData = feedbackData[:len(feedbackData_)] 
-		  }
-
-		  payload := plusPacket.Payload()
-		  
-        copy(data, payload)
-		  //fmt.Printf("len(data) := %d, len(payload) := %d, cap(data) := %d\n", len(data), len(payload), cap(data))
-		  data = data[:len(payload)]
-
-		  s.plusConnManager.ReturnPacketAndBuffer(plusPacket)
-
-        if feedbackData != nil {
-			utils.Debugf("Have to send feedback data %x", feedbackData)
-		}
-
-        if err := s.handlePacketPLUS(s.conn, remoteAddr, data, plusConnection, feedbackData); err != nil {
-            fmt.Printf("error handling PLUS packet: %s\n", err.Error())
-            utils.Errorf("error handling PLUS packet: %s", err.Error())
-        }
-    }
+func populateTransport(config *Config) Transport {
+	if config.Transport != nil {
+		return config.Transport
+	}
+	if config.UsePLUS {
+		return PLUSTransport
+	}
+	return UDPTransport
 }
 
-// serve listens on an existing PacketConn
+// serve listens on the PacketTransport
 func (s *server) serve() {
-    if(s.config.UsePLUS) {
-        s.servePLUS()
-        return
-    }
-
 	for {
 		data := getPacketBuffer()
 		data = data[:protocol.MaxReceivePacketSize]
 		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
 		// If it does, we only read a truncated packet, which will then end up undecryptable
-		n, remoteAddr, err := s.conn.ReadFrom(data)
+		n, remoteAddr, conn, feedbackData, err := s.transport.ReadPacket(data)
 		if err != nil {
 			s.serverError = err
 			close(s.errorChan)
@@ -182,8 +151,8 @@ func (s *server) serve() {
 			return
 		}
 		data = data[:n]
-		if err := s.handlePacket(s.conn, remoteAddr, data); err != nil {
-			utils.Errorf("error handling packet: %s", err.Error())
+		if err := s.handlePacket(conn, remoteAddr, data, feedbackData); err != nil {
+			s.config.Logger.Errorf("error handling packet: %s", err.Error())
 		}
 	}
 }
@@ -211,60 +180,38 @@ func (s *server) Close() error {
 	}
 	s.sessionsMutex.Unlock()
 
-	if s.conn == nil {
+	if s.transport == nil {
 		return nil
 	}
-
-    if !s.config.UsePLUS {
-        return s.conn.Close()
-    } else {
-        return s.plusConnManager.Close()
-    }
-}
-
-// Addr returns the server's network address
-func (s *server) Addr() net.Addr {
-    if !s.config.UsePLUS {
-        return s.conn.LocalAddr()
-    } else {
-        return s.plusConnManager.LocalAddr()
-    }
+	return s.transport.Close()
 }
 
-func (s *server) writeTo(pconn net.PacketConn, data []byte, remoteAddr net.Addr) error {
-    if pconn == nil {
-        pconn = s.conn
-    }
-
-    if(!s.config.UsePLUS) {
-        _, err := pconn.WriteTo(data, remoteAddr)
-        return err
-    } else {
-        return nil
-    }
+// StopAccepting refuses new connections
+func (s *server) StopAccepting() {
+	s.stopAcceptingOnce.Do(func() { close(s.acceptingStopped) })
 }
 
-func (s *server) writeToPLUS(plusConnection *PLUS.Connection, data []byte) error {
-    //fmt.Println("server.go: writeToPLUS")
-
-    if(s.config.UsePLUS) {
-        _, err := plusConnection.Write(data)
-        return err
-    } else {
-        return nil
-    }
+func (s *server) isAcceptingStopped() bool {
+	select {
+	case <-s.acceptingStopped:
+		return true
+	default:
+		return false
+	}
 }
 
-func (s *server) handlePacket(pconn net.PacketConn, remoteAddr net.Addr, packet []byte) error {
-    return s.handlePacketPLUS(pconn, remoteAddr, packet, nil, nil)
+// Addr returns the server's network address
+func (s *server) Addr() net.Addr {
+	return s.transport.LocalAddr()
 }
 
-func (s *server) handlePacketPLUS(pconn net.PacketConn, remoteAddr net.Addr, packet []byte, plusConnection *PLUS.Connection, feedbackData []byte) error {
+func (s *server) handlePacket(conn TransportConnection, remoteAddr net.Addr, packet []byte, feedbackData []byte) error {
 	rcvTime := time.Now()
 
 	r := bytes.NewReader(packet)
 	hdr, err := ParsePublicHeader(r, protocol.PerspectiveClient)
 	if err != nil {
+		s.closeTransportConnection(conn)
 		return qerr.Error(qerr.InvalidPacketHeader, err.Error())
 	}
 	hdr.Raw = packet[:len(packet)-r.Len()]
@@ -279,12 +226,13 @@ func (s *server) handlePacketPLUS(pconn net.PacketConn, remoteAddr net.Addr, pac
 			var pr *publicReset
 			pr, err = parsePublicReset(r)
 			if err != nil {
-				utils.Infof("Received a Public Reset for connection %x. An error occurred parsing the packet.")
+				s.config.Logger.Infof("Received a Public Reset for connection %x. An error occurred parsing the packet.")
 			} else {
-				utils.Infof("Received a Public Reset for connection %x, rejected packet number: 0x%x.", hdr.ConnectionID, pr.rejectedPacketNumber)
+				s.config.Logger.Infof("Received a Public Reset for connection %x, rejected packet number: 0x%x.", hdr.ConnectionID, pr.rejectedPacketNumber)
 			}
 		} else {
-			utils.Infof("Received Public Reset for unknown connection %x.", hdr.ConnectionID)
+			s.config.Logger.Infof("Received Public Reset for unknown connection %x.", hdr.ConnectionID)
+			s.closeTransportConnection(conn)
 		}
 		return nil
 	}
@@ -299,71 +247,77 @@ func (s *server) handlePacketPLUS(pconn net.PacketConn, remoteAddr net.Addr, pac
 	// Send Version Negotiation Packet if the client is speaking a different protocol version
 	if hdr.VersionFlag && !protocol.IsSupportedVersion(s.config.Versions, hdr.VersionNumber) {
 		// drop packets that are too small to be valid first packets
+		defer s.closeTransportConnection(conn)
 		if len(packet) < protocol.ClientHelloMinimumSize+len(hdr.Raw) {
 			return errors.New("dropping small packet with unknown version")
 		}
-		utils.Infof("Client offered version %d, sending VersionNegotiationPacket", hdr.VersionNumber)
-        if !s.config.UsePLUS {
-            err = s.writeTo(pconn, composeVersionNegotiation(hdr.ConnectionID, s.config.Versions), remoteAddr)
-        } else {
-            err = s.writeToPLUS(plusConnection, composeVersionNegotiation(hdr.ConnectionID, s.config.Versions))
-        }
-		return err
+		s.config.Logger.Infof("Client offered version %d, sending VersionNegotiationPacket", hdr.VersionNumber)
+		return conn.Write(composeVersionNegotiation(hdr.ConnectionID, s.config.Versions, s.config.Logger))
 	}
 
 	if !ok {
 		if !hdr.VersionFlag {
-            if !s.config.UsePLUS {
-                err = s.writeTo(pconn, writePublicReset(hdr.ConnectionID, hdr.PacketNumber, 0), remoteAddr)
-            } else {
-                err = s.writeToPLUS(plusConnection, composeVersionNegotiation(hdr.ConnectionID, s.config.Versions))
-            }
-			return err
+			defer s.closeTransportConnection(conn)
+			return conn.Write(writePublicReset(hdr.ConnectionID, hdr.PacketNumber, 0))
+		}
+		if s.isAcceptingStopped() {
+			s.config.Logger.Infof("Not accepting new connections, sending a Public Reset for connection %x", hdr.ConnectionID)
+			defer s.closeTransportConnection(conn)
+			return conn.Write(writePublicReset(hdr.ConnectionID, hdr.PacketNumber, 0))
 		}
 		version := hdr.VersionNumber
 		if !protocol.IsSupportedVersion(s.config.Versions, version) {
+			s.closeTransportConnection(conn)
 			return errors.New("Server BUG: negotiated version not supported")
 		}
 
-		utils.Infof("Serving new connection: %x, version %d from %v", hdr.ConnectionID, version, remoteAddr)
+		s.config.Logger.Infof("Serving new connection: %x, version %d from %v", hdr.ConnectionID, version, remoteAddr)
 		var handshakeChan <-chan handshakeEvent
 
 		session, handshakeChan, err = s.newSession(
-			&conn{pconn: pconn, currentAddr: remoteAddr},
+			conn,
 			version,
 			hdr.ConnectionID,
 			s.scfg,
 			s.config,
-            plusConnection,
 		)
 		if err != nil {
 			return err
 		}
 		s.sessionsMutex.Lock()
 		s.sessions[hdr.ConnectionID] = session
+		if conn != nil {
+			s.transportConns[transportConnKey(conn)] = hdr.ConnectionID
+		}
 		s.sessionsMutex.Unlock()
 
 		go func() {
 			// session.run() returns as soon as the session is closed
 			_ = session.run()
-			s.removeConnection(hdr.ConnectionID)
+			s.removeConnection(hdr.ConnectionID, conn, session)
 		}()
 
 		go func() {
 			for {
-				ev := <-handshakeChan
-				if ev.err != nil {
-					return
-				}
-				if ev.encLevel == protocol.EncryptionForwardSecure {
-					break
+				select {
+				case ev := <-handshakeChan:
+					if ev.err != nil {
+						return
+					}
+					if ev.encLevel != protocol.EncryptionForwardSecure {
+						continue
+					}
+					s.sessionQueue <- session
+				case <-s.acceptingStopped:
+					session.Close(qerr.Error(qerr.PeerGoingAway, "server is not accepting new connections"))
 				}
+				return
 			}
-			s.sessionQueue <- session
 		}()
 	}
 	if session == nil {
 		// Late packet for closed session
+		// The transport state is torn down by removeConnection.
 		return nil
 	}
 	session.handlePacket(&receivedPacket{
@@ -376,19 +330,66 @@ func (s *server) handlePacketPLUS(pconn net.PacketConn, remoteAddr net.Addr, pac
 	return nil
 }
 
-func (s *server) removeConnection(id protocol.ConnectionID) {
+// closeTransportConnection tears down the state the transport keeps for a connection, unless it is used by a session.
+// Transports like PLUS set up state for every packet they receive, so this is also needed for packets that don't belong to a session.
+func (s *server) closeTransportConnection(conn TransportConnection) {
+	s.sessionsMutex.RLock()
+	_, inUse := s.transportConns[transportConnKey(conn)]
+	s.sessionsMutex.RUnlock()
+	if inUse {
+		return
+	}
+	if err := conn.Close(); err != nil {
+		s.config.Logger.Errorf("Error closing transport connection: %s", err.Error())
+	}
+}
+
+func (s *server) removeConnection(id protocol.ConnectionID, conn TransportConnection, session packetHandler) {
 	s.sessionsMutex.Lock()
-	s.sessions[id] = nil
+	// a session that sent a PLUS stop packet keeps handling the late packets, such that it can repeat the stop packet
+	if sess, ok := session.(stopPacketSender); !ok || !sess.sentStopPacket() {
+		s.sessions[id] = nil
+	}
 	s.sessionsMutex.Unlock()
 
 	time.AfterFunc(s.deleteClosedSessionsAfter, func() {
 		s.sessionsMutex.Lock()
 		delete(s.sessions, id)
+		if conn != nil {
+			key := transportConnKey(conn)
+			if s.transportConns[key] == id {
+				delete(s.transportConns, key)
+			}
+		}
 		s.sessionsMutex.Unlock()
+		// the transport state is kept until now, such that the transport can finish tearing down the connection
+		// e.g. PLUS waits for the peer to confirm the stop
+		if conn != nil {
+			s.closeTransportConnection(conn)
+		}
 	})
 }
 
-func composeVersionNegotiation(connectionID protocol.ConnectionID, versions []protocol.VersionNumber) []byte {
+// A stopPacketSender is a session that can repeat its PLUS stop packet after it was closed
+type stopPacketSender interface {
+	sentStopPacket() bool
+}
+
+// A keyedTransportConnection is a TransportConnection that is created for every packet received,
+// but shares its state with the other TransportConnections for the same connection, e.g. a plusConn.
+type keyedTransportConnection interface {
+	// key is the same for all TransportConnections for the same connection
+	key() interface{}
+}
+
+func transportConnKey(conn TransportConnection) interface{} {
+	if c, ok := conn.(keyedTransportConnection); ok {
+		return c.key()
+	}
+	return conn
+}
+
+func composeVersionNegotiation(connectionID protocol.ConnectionID, versions []protocol.VersionNumber, logger utils.Logger) []byte {
 	fullReply := &bytes.Buffer{}
 	responsePublicHeader := PublicHeader{
 		ConnectionID: connectionID,
@@ -397,7 +398,7 @@ func composeVersionNegotiation(connectionID protocol.ConnectionID, versions []pr
 	}
 	err := responsePublicHeader.Write(fullReply, protocol.VersionWhatever, protocol.PerspectiveServer)
 	if err != nil {
-		utils.Errorf("error composing version negotiation packet: %s", err.Error())
+		logger.Errorf("error composing version negotiation packet: %s", err.Error())
 	}
 	for _, v := range versions {
 		utils.WriteUint32(fullReply, protocol.VersionNumberToTag(v))
@@ -7,6 +7,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/crypto"
 	"github.com/lucas-clemente/quic-go/handshake"
 	"github.com/lucas-clemente/quic-go/protocol"
@@ -15,22 +16,27 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-    
-   "github.com/mami-project/plus-lib"
 )
 
 type mockSession struct {
 	connectionID      protocol.ConnectionID
 	packetCount       int
+	lastPacket        *receivedPacket
 	closed            bool
 	closeReason       error
 	stopRunLoop       chan struct{} // run returns as soon as this channel receives a value
 	handshakeChan     chan handshakeEvent
 	handshakeComplete chan error // for WaitUntilHandshakeComplete
+	sentStop          bool       // for sentStopPacket
 }
 
-func (s *mockSession) handlePacket(*receivedPacket) {
+func (s *mockSession) handlePacket(p *receivedPacket) {
 	s.packetCount++
+	s.lastPacket = p
+}
+
+func (s *mockSession) sentStopPacket() bool {
+	return s.sentStop
 }
 
 func (s *mockSession) run() error {
@@ -64,16 +70,37 @@ func (s *mockSession) LocalAddr() net.Addr {
 func (s *mockSession) RemoteAddr() net.Addr {
 	panic("not implemented")
 }
+func (s *mockSession) PLUSInfo() PLUSInfo {
+	panic("not implemented")
+}
+func (s *mockSession) ConnectionStats() ConnectionStats {
+	panic("not implemented")
+}
+func (s *mockSession) CloseGracefully(time.Duration) error {
+	panic("not implemented")
+}
+func (s *mockSession) RequestPCF(*PCFRequest) error {
+	panic("not implemented")
+}
+func (s *mockSession) CancelPCFRequest(*PCFRequest) {
+	panic("not implemented")
+}
+func (s *mockSession) PCFFeedback() <-chan []byte {
+	panic("not implemented")
+}
+func (s *mockSession) SetLogLevel(utils.LogLevel) {
+	panic("not implemented")
+}
 
 var _ Session = &mockSession{}
 var _ NonFWSession = &mockSession{}
 
 func newMockSession(
-	_ connection,
+	_ TransportConnection,
 	_ protocol.VersionNumber,
 	connectionID protocol.ConnectionID,
 	_ *handshake.ServerConfig,
-	_ *Config, _ *PLUS.Connection,
+	_ *Config,
 ) (packetHandler, <-chan handshakeEvent, error) {
 	s := mockSession{
 		connectionID:      connectionID,
@@ -96,6 +123,7 @@ var _ = Describe("Server", func() {
 		config = &Config{
 			TLSConfig: &tls.Config{},
 			Versions:  protocol.SupportedVersions,
+			Logger:    utils.DefaultLogger,
 		}
 	})
 
@@ -108,12 +136,15 @@ var _ = Describe("Server", func() {
 
 		BeforeEach(func() {
 			serv = &server{
-				sessions:     make(map[protocol.ConnectionID]packetHandler),
-				newSession:   newMockSession,
-				conn:         conn,
-				config:       config,
-				sessionQueue: make(chan Session, 5),
-				errorChan:    make(chan struct{}),
+				sessions:       make(map[protocol.ConnectionID]packetHandler),
+				transportConns: make(map[interface{}]protocol.ConnectionID),
+				newSession:     newMockSession,
+				transport:      &udpPacketTransport{pconn: conn},
+				config:         config,
+				sessionQueue:   make(chan Session, 5),
+				errorChan:      make(chan struct{}),
+
+				acceptingStopped: make(chan struct{}),
 			}
 			b := &bytes.Buffer{}
 			utils.WriteUint32(b, protocol.VersionNumberToTag(protocol.SupportedVersions[0]))
@@ -134,11 +165,11 @@ var _ = Describe("Server", func() {
 				[]byte{0x01 | 0x08, 0x1, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0},
 				[]byte{'Q', '0', '9', '9'}...,
 			)
-			Expect(composeVersionNegotiation(1, []protocol.VersionNumber{99})).To(Equal(expected))
+			Expect(composeVersionNegotiation(1, []protocol.VersionNumber{99}, utils.DefaultLogger)).To(Equal(expected))
 		})
 
 		It("creates new sessions", func() {
-			err := serv.handlePacket(nil, nil, firstPacket)
+			err := serv.handlePacket(nil, nil, firstPacket, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(serv.sessions).To(HaveLen(1))
 			sess := serv.sessions[connID].(*mockSession)
@@ -146,6 +177,50 @@ var _ = Describe("Server", func() {
 			Expect(sess.packetCount).To(Equal(1))
 		})
 
+		It("creates new sessions for PLUS connections", func() {
+			fakeTransport, rec := newFakePLUSTransport()
+			serverConn, clientConn := newMemPacketConnPair()
+			defer clientConn.Close()
+			var err error
+			serv.transport, err = fakeTransport.Listen(serverConn)
+			Expect(err).ToNot(HaveOccurred())
+			sessConns := make(chan TransportConnection, 1)
+			serv.newSession = func(
+				c TransportConnection,
+				v protocol.VersionNumber,
+				connectionID protocol.ConnectionID,
+				sc *handshake.ServerConfig,
+				conf *Config,
+			) (packetHandler, <-chan handshakeEvent, error) {
+				sessConns <- c
+				return newMockSession(c, v, connectionID, sc, conf)
+			}
+			go serv.serve()
+			defer serv.Close()
+			_, cconn, err := fakeTransport.Dial(clientConn, serverConn.LocalAddr(), connID)
+			Expect(err).ToNot(HaveOccurred())
+			err = cconn.Write(firstPacket)
+			Expect(err).ToNot(HaveOccurred())
+			var sconn TransportConnection
+			Eventually(sessConns).Should(Receive(&sconn))
+			Expect(sconn).To(BeAssignableToTypeOf(&plusConn{}))
+			Expect(rec.receivedPackets()[0].cat).To(Equal(uint64(connID)))
+			err = sconn.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			sent := rec.sentPackets()
+			Expect(sent).To(HaveLen(2))
+			Expect(sent[1].cat).To(Equal(uint64(connID)))
+			Expect(sent[1].payload).To(Equal([]byte("foobar")))
+		})
+
+		It("passes PLUS feedback to the session", func() {
+			err := serv.handlePacket(nil, udpAddr, firstPacket, []byte("feedback"))
+			Expect(err).ToNot(HaveOccurred())
+			sess := serv.sessions[connID].(*mockSession)
+			Expect(sess.lastPacket.remoteAddr).To(Equal(udpAddr))
+			Expect(sess.lastPacket.feedbackData).To(Equal([]byte("feedback")))
+		})
+
 		It("accepts a session once the connection it is forward secure", func(done Done) {
 			var acceptedSess Session
 			go func() {
@@ -154,7 +229,7 @@ var _ = Describe("Server", func() {
 				acceptedSess, err = serv.Accept()
 				Expect(err).ToNot(HaveOccurred())
 			}()
-			err := serv.handlePacket(nil, nil, firstPacket)
+			err := serv.handlePacket(nil, nil, firstPacket, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(serv.sessions).To(HaveLen(1))
 			sess := serv.sessions[connID].(*mockSession)
@@ -165,6 +240,42 @@ var _ = Describe("Server", func() {
 			close(done)
 		}, 0.5)
 
+		It("refuses new connections after StopAccepting is called", func() {
+			serv.StopAccepting()
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, udpAddr, firstPacket, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(mconn.written[0][0] & 0x02).ToNot(BeZero()) // check that the ResetFlag is set
+			Expect(mconn.closed).To(BeTrue())
+			Expect(serv.sessions).To(BeEmpty())
+		})
+
+		It("closes sessions that are still handshaking when StopAccepting is called", func() {
+			err := serv.handlePacket(&mockConnection{}, nil, firstPacket, nil)
+			Expect(err).ToNot(HaveOccurred())
+			sess := serv.sessions[connID].(*mockSession)
+			serv.StopAccepting()
+			serv.StopAccepting() // calling it twice doesn't panic
+			Eventually(func() bool { return sess.closed }).Should(BeTrue())
+			Expect(sess.closeReason.(*qerr.QuicError).ErrorCode).To(Equal(qerr.PeerGoingAway))
+			Expect(serv.sessionQueue).To(BeEmpty())
+		})
+
+		It("keeps handling packets for existing sessions after StopAccepting is called", func() {
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, nil, firstPacket, nil)
+			Expect(err).ToNot(HaveOccurred())
+			sess := serv.sessions[connID].(*mockSession)
+			sess.handshakeChan <- handshakeEvent{encLevel: protocol.EncryptionForwardSecure}
+			Eventually(serv.sessionQueue).Should(Receive(Equal(sess)))
+			serv.StopAccepting()
+			err = serv.handlePacket(mconn, nil, []byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x01}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.packetCount).To(Equal(2))
+			Consistently(func() bool { return sess.closed }).Should(BeFalse())
+		})
+
 		It("doesn't accept session that error during the handshake", func(done Done) {
 			var accepted bool
 			go func() {
@@ -172,7 +283,7 @@ var _ = Describe("Server", func() {
 				serv.Accept()
 				accepted = true
 			}()
-			err := serv.handlePacket(nil, nil, firstPacket)
+			err := serv.handlePacket(nil, nil, firstPacket, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(serv.sessions).To(HaveLen(1))
 			sess := serv.sessions[connID].(*mockSession)
@@ -182,9 +293,9 @@ var _ = Describe("Server", func() {
 		})
 
 		It("assigns packets to existing sessions", func() {
-			err := serv.handlePacket(nil, nil, firstPacket)
+			err := serv.handlePacket(nil, nil, firstPacket, nil)
 			Expect(err).ToNot(HaveOccurred())
-			err = serv.handlePacket(nil, nil, []byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x01})
+			err = serv.handlePacket(nil, nil, []byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x01}, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(serv.sessions).To(HaveLen(1))
 			Expect(serv.sessions[connID].(*mockSession).connectionID).To(Equal(connID))
@@ -194,7 +305,7 @@ var _ = Describe("Server", func() {
 		It("closes and deletes sessions", func() {
 			serv.deleteClosedSessionsAfter = time.Second // make sure that the nil value for the closed session doesn't get deleted in this test
 			nullAEAD := crypto.NewNullAEAD(protocol.PerspectiveServer, protocol.VersionWhatever)
-			err := serv.handlePacket(nil, nil, append(firstPacket, nullAEAD.Seal(nil, nil, 0, firstPacket)...))
+			err := serv.handlePacket(&mockConnection{}, nil, append(firstPacket, nullAEAD.Seal(nil, nil, 0, firstPacket)...), nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(serv.sessions).To(HaveLen(1))
 			Expect(serv.sessions[connID]).ToNot(BeNil())
@@ -208,7 +319,7 @@ var _ = Describe("Server", func() {
 		It("deletes nil session entries after a wait time", func() {
 			serv.deleteClosedSessionsAfter = 25 * time.Millisecond
 			nullAEAD := crypto.NewNullAEAD(protocol.PerspectiveServer, protocol.VersionWhatever)
-			err := serv.handlePacket(nil, nil, append(firstPacket, nullAEAD.Seal(nil, nil, 0, firstPacket)...))
+			err := serv.handlePacket(&mockConnection{}, nil, append(firstPacket, nullAEAD.Seal(nil, nil, 0, firstPacket)...), nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(serv.sessions).To(HaveLen(1))
 			Expect(serv.sessions).To(HaveKey(connID))
@@ -222,8 +333,33 @@ var _ = Describe("Server", func() {
 			}).Should(BeFalse())
 		})
 
+		It("keeps sessions that sent a PLUS stop packet, such that they can repeat it", func() {
+			serv.deleteClosedSessionsAfter = 100 * time.Millisecond
+			nullAEAD := crypto.NewNullAEAD(protocol.PerspectiveServer, protocol.VersionWhatever)
+			err := serv.handlePacket(&mockConnection{}, nil, append(firstPacket, nullAEAD.Seal(nil, nil, 0, firstPacket)...), nil)
+			Expect(err).ToNot(HaveOccurred())
+			sess := serv.sessions[connID].(*mockSession)
+			sess.sentStop = true
+			// make session.run() return
+			sess.stopRunLoop <- struct{}{}
+			Consistently(func() packetHandler {
+				serv.sessionsMutex.Lock()
+				defer serv.sessionsMutex.Unlock()
+				return serv.sessions[connID]
+			}, 50*time.Millisecond).Should(Equal(sess))
+			err = serv.handlePacket(nil, nil, []byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x01}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.packetCount).To(Equal(2))
+			Eventually(func() bool {
+				serv.sessionsMutex.Lock()
+				defer serv.sessionsMutex.Unlock()
+				_, ok := serv.sessions[connID]
+				return ok
+			}).Should(BeFalse())
+		})
+
 		It("closes sessions and the connection when Close is called", func() {
-			session, _, _ := newMockSession(nil, 0, 0, nil, nil, nil)
+			session, _, _ := newMockSession(nil, 0, 0, nil, nil)
 			serv.sessions[1] = session
 			err := serv.Close()
 			Expect(err).NotTo(HaveOccurred())
@@ -233,10 +369,34 @@ var _ = Describe("Server", func() {
 
 		It("ignores packets for closed sessions", func() {
 			serv.sessions[connID] = nil
-			err := serv.handlePacket(nil, nil, []byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x01})
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, nil, []byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x01}, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(serv.sessions).To(HaveLen(1))
 			Expect(serv.sessions[connID]).To(BeNil())
+			// the connection is closed when the session is deleted
+			Expect(mconn.closed).To(BeFalse())
+		})
+
+		It("closes the connection when deleting a closed session", func() {
+			serv.deleteClosedSessionsAfter = 25 * time.Millisecond
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, nil, firstPacket, nil)
+			Expect(err).ToNot(HaveOccurred())
+			// make session.run() return
+			serv.sessions[connID].(*mockSession).stopRunLoop <- struct{}{}
+			Eventually(func() bool {
+				serv.sessionsMutex.Lock()
+				defer serv.sessionsMutex.Unlock()
+				return serv.sessions[connID] == nil
+			}).Should(BeTrue())
+			Eventually(func() bool {
+				serv.sessionsMutex.Lock()
+				defer serv.sessionsMutex.Unlock()
+				_, ok := serv.sessions[connID]
+				return ok
+			}).Should(BeFalse())
+			Eventually(func() bool { return mconn.closed }).Should(BeTrue())
 		})
 
 		It("closes properly", func() {
@@ -266,7 +426,7 @@ var _ = Describe("Server", func() {
 		}, 0.5)
 
 		It("closes all sessions when encountering a connection error", func() {
-			session, _, _ := newMockSession(nil, 0, 0, nil, nil, nil)
+			session, _, _ := newMockSession(nil, 0, 0, nil, nil)
 			serv.sessions[0x12345] = session
 			Expect(serv.sessions[0x12345].(*mockSession).closed).To(BeFalse())
 			testErr := errors.New("connection error")
@@ -278,7 +438,7 @@ var _ = Describe("Server", func() {
 		})
 
 		It("ignores delayed packets with mismatching versions", func() {
-			err := serv.handlePacket(nil, nil, firstPacket)
+			err := serv.handlePacket(nil, nil, firstPacket, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
 			b := &bytes.Buffer{}
@@ -286,7 +446,7 @@ var _ = Describe("Server", func() {
 			utils.WriteUint32(b, protocol.VersionNumberToTag(protocol.SupportedVersions[0]+1))
 			data := []byte{0x09, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c}
 			data = append(append(data, b.Bytes()...), 0x01)
-			err = serv.handlePacket(nil, nil, data)
+			err = serv.handlePacket(nil, nil, data, nil)
 			Expect(err).ToNot(HaveOccurred())
 			// if we didn't ignore the packet, the server would try to send a version negotation packet, which would make the test panic because it doesn't have a udpConn
 			Expect(conn.dataWritten.Bytes()).To(BeEmpty())
@@ -295,32 +455,56 @@ var _ = Describe("Server", func() {
 		})
 
 		It("errors on invalid public header", func() {
-			err := serv.handlePacket(nil, nil, nil)
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, nil, nil, nil)
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.InvalidPacketHeader))
+			Expect(mconn.closed).To(BeTrue())
+		})
+
+		It("doesn't close the connection of a session on invalid public header", func() {
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, nil, firstPacket, nil)
+			Expect(err).ToNot(HaveOccurred())
+			err = serv.handlePacket(mconn, nil, nil, nil)
 			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.InvalidPacketHeader))
+			Expect(mconn.closed).To(BeFalse())
+		})
+
+		It("doesn't close the connection of a session on public resets for unknown connections", func() {
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, nil, firstPacket, nil)
+			Expect(err).ToNot(HaveOccurred())
+			err = serv.handlePacket(mconn, nil, writePublicReset(999, 1, 1337), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.closed).To(BeFalse())
 		})
 
 		It("ignores public resets for unknown connections", func() {
-			err := serv.handlePacket(nil, nil, writePublicReset(999, 1, 1337))
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, nil, writePublicReset(999, 1, 1337), nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(serv.sessions).To(BeEmpty())
+			Expect(mconn.closed).To(BeTrue())
 		})
 
 		It("ignores public resets for known connections", func() {
-			err := serv.handlePacket(nil, nil, firstPacket)
+			err := serv.handlePacket(nil, nil, firstPacket, nil)
 			Expect(serv.sessions).To(HaveLen(1))
 			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
-			err = serv.handlePacket(nil, nil, writePublicReset(connID, 1, 1337))
+			mconn := &mockConnection{}
+			err = serv.handlePacket(mconn, nil, writePublicReset(connID, 1, 1337), nil)
 			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.closed).To(BeFalse())
 			Expect(serv.sessions).To(HaveLen(1))
 			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
 		})
 
 		It("ignores invalid public resets for known connections", func() {
-			err := serv.handlePacket(nil, nil, firstPacket)
+			err := serv.handlePacket(nil, nil, firstPacket, nil)
 			Expect(serv.sessions).To(HaveLen(1))
 			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
 			data := writePublicReset(connID, 1, 1337)
-			err = serv.handlePacket(nil, nil, data[:len(data)-2])
+			err = serv.handlePacket(nil, nil, data[:len(data)-2], nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(serv.sessions).To(HaveLen(1))
 			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
@@ -336,9 +520,47 @@ var _ = Describe("Server", func() {
 			}
 			hdr.Write(b, 13 /* not a valid QUIC version */, protocol.PerspectiveClient)
 			b.Write(bytes.Repeat([]byte{0}, protocol.ClientHelloMinimumSize-1)) // this packet is 1 byte too small
-			err := serv.handlePacket(conn, udpAddr, b.Bytes())
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, udpAddr, b.Bytes(), nil)
 			Expect(err).To(MatchError("dropping small packet with unknown version"))
-			Expect(conn.dataWritten.Len()).Should(BeZero())
+			Expect(mconn.written).To(BeEmpty())
+			Expect(mconn.closed).To(BeTrue())
+		})
+
+		It("sends a version negotiation packet through the connection and closes it", func() {
+			b := &bytes.Buffer{}
+			hdr := PublicHeader{
+				VersionFlag:     true,
+				ConnectionID:    0x1337,
+				PacketNumber:    1,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}
+			hdr.Write(b, 13 /* not a valid QUIC version */, protocol.PerspectiveClient)
+			b.Write(bytes.Repeat([]byte{0}, protocol.ClientHelloMinimumSize))
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, udpAddr, b.Bytes(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(Equal([][]byte{composeVersionNegotiation(0x1337, serv.config.Versions, utils.DefaultLogger)}))
+			Expect(mconn.closed).To(BeTrue())
+			Expect(serv.sessions).To(BeEmpty())
+		})
+
+		It("sends a public reset through the connection and closes it", func() {
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, udpAddr, []byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x01}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(mconn.written[0][0] & 0x02).ToNot(BeZero()) // check that the ResetFlag is set
+			Expect(mconn.closed).To(BeTrue())
+			Expect(serv.sessions).To(BeEmpty())
+		})
+
+		It("doesn't close the connection of new sessions", func() {
+			mconn := &mockConnection{}
+			err := serv.handlePacket(mconn, udpAddr, firstPacket, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serv.sessions).To(HaveLen(1))
+			Expect(mconn.closed).To(BeFalse())
 		})
 	})
 
@@ -365,6 +587,57 @@ var _ = Describe("Server", func() {
 		Expect(server.config.Versions).To(Equal(protocol.SupportedVersions))
 	})
 
+	It("uses the UDP transport by default", func() {
+		Expect(populateServerConfig(&Config{}).Transport).To(Equal(UDPTransport))
+	})
+
+	It("uses the PLUS transport if UsePLUS is set", func() {
+		Expect(populateServerConfig(&Config{UsePLUS: true}).Transport).To(Equal(PLUSTransport))
+	})
+
+	It("uses the transport set in the Config", func() {
+		Expect(populateServerConfig(&Config{UsePLUS: true, Transport: UDPTransport}).Transport).To(Equal(UDPTransport))
+	})
+
+	It("copies the transport parameters from the Config", func() {
+		c := populateServerConfig(&Config{
+			IdleTimeout:                           42 * time.Second,
+			ReceiveStreamFlowControlWindow:        1 << 16,
+			ReceiveConnectionFlowControlWindow:    1 << 17,
+			MaxReceiveStreamFlowControlWindow:     1 << 20,
+			MaxReceiveConnectionFlowControlWindow: 1 << 21,
+			MaxIncomingStreams:                    1000,
+			StreamScheduling:                      StreamSchedulingWeightedFair,
+		})
+		Expect(c.IdleTimeout).To(Equal(42 * time.Second))
+		Expect(c.ReceiveStreamFlowControlWindow).To(Equal(protocol.ByteCount(1 << 16)))
+		Expect(c.ReceiveConnectionFlowControlWindow).To(Equal(protocol.ByteCount(1 << 17)))
+		Expect(c.MaxReceiveStreamFlowControlWindow).To(Equal(protocol.ByteCount(1 << 20)))
+		Expect(c.MaxReceiveConnectionFlowControlWindow).To(Equal(protocol.ByteCount(1 << 21)))
+		Expect(c.MaxIncomingStreams).To(Equal(uint32(1000)))
+		Expect(c.StreamScheduling).To(Equal(StreamSchedulingWeightedFair))
+	})
+
+	It("uses the default handshake timeout", func() {
+		Expect(populateServerConfig(&Config{}).HandshakeTimeout).To(Equal(protocol.MaxTimeForCryptoHandshake))
+		Expect(populateServerConfig(&Config{HandshakeTimeout: time.Second}).HandshakeTimeout).To(Equal(time.Second))
+	})
+
+	It("uses Cubic if no congestion controller is set", func() {
+		rttStats := congestion.NewRTTStats()
+		sender := populateServerConfig(&Config{}).CongestionControl(congestion.DefaultClock{}, rttStats)
+		Expect(sender.(congestion.SendAlgorithmWithDebugInfo).RenoBeta()).To(BeNumerically("~", 0.85, 0.001))
+		c := populateServerConfig(&Config{CongestionControl: congestion.EmulatedCubicFactory(1)})
+		sender = c.CongestionControl(congestion.DefaultClock{}, rttStats)
+		Expect(sender.(congestion.SendAlgorithmWithDebugInfo).RenoBeta()).To(BeNumerically("~", 0.7, 0.001))
+	})
+
+	It("rejects invalid configs", func() {
+		config.IdleTimeout = time.Millisecond
+		_, err := Listen(&mockPacketConn{}, config)
+		Expect(err).To(MatchError("IdleTimeout too small: 1ms (minimum 1s)"))
+	})
+
 	It("listens on a given address", func() {
 		addr := "127.0.0.1:13579"
 		ln, err := ListenAddr(addr, config)
@@ -419,6 +692,37 @@ var _ = Describe("Server", func() {
 		Consistently(func() bool { return returned }).Should(BeFalse())
 	})
 
+	It("responds with version negotiation through PLUS, if UsePLUS is set", func() {
+		originalPLUSTransport := PLUSTransport
+		defer func() { PLUSTransport = originalPLUSTransport }()
+		fakeTransport, rec := newFakePLUSTransport()
+		PLUSTransport = fakeTransport
+		serverConn, clientConn := newMemPacketConnPair()
+		config.UsePLUS = true
+		config.Versions = []protocol.VersionNumber{99}
+		ln, err := Listen(serverConn, config)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		_, cconn, err := fakeTransport.Dial(clientConn, serverConn.LocalAddr(), 0x1337)
+		Expect(err).ToNot(HaveOccurred())
+		b := &bytes.Buffer{}
+		hdr := PublicHeader{
+			VersionFlag:     true,
+			ConnectionID:    0x1337,
+			PacketNumber:    1,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}
+		hdr.Write(b, 13 /* not a valid QUIC version */, protocol.PerspectiveClient)
+		b.Write(bytes.Repeat([]byte{0}, protocol.ClientHelloMinimumSize)) // add a fake CHLO
+		err = cconn.Write(b.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func() []*fakePLUSPacket { return rec.sentPackets() }).Should(HaveLen(2))
+		p := rec.sentPackets()[1]
+		Expect(p.cat).To(Equal(uint64(0x1337)))
+		Expect(p.payload).To(Equal(composeVersionNegotiation(0x1337, config.Versions, utils.DefaultLogger)))
+		Eventually(func() []uint64 { return rec.closedConnections() }).Should(ContainElement(uint64(0x1337)))
+	})
+
 	It("sends a PublicReset for new connections that don't have the VersionFlag set", func() {
 		conn.dataReadFrom = udpAddr
 		conn.dataToRead = []byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x01}
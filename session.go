@@ -15,7 +15,6 @@ import (
 	"github.com/lucas-clemente/quic-go/protocol"
 	"github.com/lucas-clemente/quic-go/qerr"
 	"github.com/lucas-clemente/quic-go/utils"
-   "github.com/mami-project/plus-lib"
 )
 
 type unpacker interface {
@@ -30,12 +29,31 @@ type receivedPacket struct {
 	feedbackData []byte //in case of PLUS, otherwise it's nil
 }
 
+// size is the size of the QUIC packet, including the public header
+func (p *receivedPacket) size() protocol.ByteCount {
+	return protocol.ByteCount(len(p.publicHeader.Raw) + len(p.data))
+}
+
 var (
 	errRstStreamOnInvalidStream   = errors.New("RST_STREAM received for unknown stream")
 	errWindowUpdateOnClosedStream = errors.New("WINDOW_UPDATE received for an already closed stream")
 	errSessionAlreadyClosed       = errors.New("cannot close session; it was already closed before")
+	errGracefulCloseTimeout       = qerr.Error(qerr.PeerGoingAway, "timeout while waiting for streams to finish")
 )
 
+// A GoawayError is returned when opening or accepting a stream after a GOAWAY frame was sent or received
+type GoawayError struct {
+	// Remote is set if the GOAWAY frame was sent by the peer
+	Remote bool
+}
+
+func (e *GoawayError) Error() string {
+	if e.Remote {
+		return "peer is going away, no new streams can be opened"
+	}
+	return "going away, no new streams can be opened"
+}
+
 var (
 	newCryptoSetup       = handshake.NewCryptoSetup
 	newCryptoSetupClient = handshake.NewCryptoSetupClient
@@ -58,8 +76,7 @@ type session struct {
 	version      protocol.VersionNumber
 	config       *Config
 
-	conn connection
-    plusConnection *PLUS.Connection
+	conn TransportConnection
 
 	streamsMap *streamsMap
 
@@ -76,6 +93,15 @@ type session struct {
 
 	cryptoSetup handshake.CryptoSetup
 
+	// PLUS feedback that doesn't fit into a single PLUSFeedbackFrame is fragmented
+	nextPLUSFeedbackID    uint64
+	plusFeedbackAssembler *plusFeedbackAssembler
+	// number of PLUSFeedbackFrames sent and received, accessed atomically
+	plusFeedbackFramesSent     uint64
+	plusFeedbackFramesReceived uint64
+	pcfRequestScheduler        *pcfRequestScheduler
+	pcfFeedbackChan            chan []byte
+
 	receivedPackets  chan *receivedPacket
 	sendingScheduled chan struct{}
 	// closeChan is used to notify the run loop that it should terminate.
@@ -83,6 +109,32 @@ type session struct {
 	runClosed chan struct{}
 	closed    uint32 // atomic bool
 
+	// stopPacket is the last packet, if it was sent with the PLUS stop flag. It is only read after runClosed is closed.
+	stopPacket []byte
+	// the number of packets received after the session was closed
+	packetsReceivedAfterClose uint32
+
+	// the run loop answers requests for a ConnectionStats snapshot on this channel
+	connectionStatsRequests chan chan ConnectionStats
+	// finalConnectionStats is the snapshot taken when the run loop returns, only read after runClosed is closed
+	finalConnectionStats ConnectionStats
+
+	// CloseGracefully passes the timeout to the run loop
+	closeGracefullyRequests chan time.Duration
+	goawaySent              bool
+	// set when a GOAWAY frame was sent or received. The connection is closed once all streams have finished.
+	draining bool
+	// the connection is closed when the deadline is reached, even if there are still open streams. It is zero if there's no deadline.
+	drainDeadline time.Time
+	// drainErr is set if the streams didn't finish before the drainDeadline, only read after runClosed is closed
+	drainErr error
+
+	logger utils.Logger
+	// tracer is nil if the connection is not traced
+	tracer ConnectionTracer
+	// the congestion state last passed to the tracer
+	tracedCongestionState CongestionState
+
 	// when we receive too many undecryptable packets during the handshake, we send a Public reset
 	// but only after a time of protocol.PublicResetTimeout has passed
 	undecryptablePackets                   []*receivedPacket
@@ -101,6 +153,8 @@ type session struct {
 	handshakeChan chan<- handshakeEvent
 
 	nextAckScheduledTime time.Time
+	// pacingDeadline is the time when the pacer allows sending the next packet, if sending was delayed by the pacer
+	pacingDeadline time.Time
 
 	connectionParameters handshake.ConnectionParametersManager
 
@@ -119,48 +173,32 @@ type session struct {
 
 var _ Session = &session{}
 
-
 // newSession makes a new session
 func newSession(
-	conn connection,
+	conn TransportConnection,
 	v protocol.VersionNumber,
 	connectionID protocol.ConnectionID,
 	sCfg *handshake.ServerConfig,
 	config *Config,
-    plusConnection *PLUS.Connection,
 ) (packetHandler, <-chan handshakeEvent, error) {
 	s := &session{
 		conn:         conn,
 		connectionID: connectionID,
-        plusConnection: plusConnection,
 		perspective:  protocol.PerspectiveServer,
 		version:      v,
 		config:       config,
 
-		connectionParameters: handshake.NewConnectionParamatersManager(protocol.PerspectiveServer, v),
+		connectionParameters: handshake.NewConnectionParamatersManager(protocol.PerspectiveServer, v, transportParameters(config)),
 	}
 
 	s.setup()
 	cryptoStream, _ := s.GetOrOpenStream(1)
 	_, _ = s.AcceptStream() // don't expose the crypto stream
 	var sourceAddr []byte
-
-    var udpAddr *net.UDPAddr
-    var ok bool
-    var _remoteAddr net.Addr
-
-    if !s.config.UsePLUS {
-        _remoteAddr = conn.RemoteAddr()
-    } else {
-        _remoteAddr = plusConnection.RemoteAddr()
-    }
-
-    udpAddr, ok = _remoteAddr.(*net.UDPAddr)
-
-	if ok {
+	if udpAddr, ok := conn.RemoteAddr().(*net.UDPAddr); ok {
 		sourceAddr = udpAddr.IP
 	} else {
-		sourceAddr = []byte(_remoteAddr.String())
+		sourceAddr = []byte(conn.RemoteAddr().String())
 	}
 	aeadChanged := make(chan protocol.EncryptionLevel, 2)
 	s.aeadChanged = aeadChanged
@@ -176,6 +214,8 @@ func newSession(
 		s.connectionParameters,
 		config.Versions,
 		aeadChanged,
+		config.KeyLogWriter,
+		s.logger,
 	)
 	if err != nil {
 		return nil, nil, err
@@ -189,23 +229,22 @@ func newSession(
 
 // declare this as a variable, such that we can it mock it in the tests
 var newClientSession = func(
-	conn connection,
+	conn TransportConnection,
 	hostname string,
 	v protocol.VersionNumber,
 	connectionID protocol.ConnectionID,
 	config *Config,
 	negotiatedVersions []protocol.VersionNumber,
-    plusConnection *PLUS.Connection,
 ) (packetHandler, <-chan handshakeEvent, error) {
+	params := transportParameters(config)
 	s := &session{
 		conn:         conn,
-        plusConnection: plusConnection,
 		connectionID: connectionID,
 		perspective:  protocol.PerspectiveClient,
 		version:      v,
 		config:       config,
 
-		connectionParameters: handshake.NewConnectionParamatersManager(protocol.PerspectiveClient, v),
+		connectionParameters: handshake.NewConnectionParamatersManager(protocol.PerspectiveClient, v, params),
 	}
 
 	s.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(s.ackAlarmChanged)
@@ -225,8 +264,10 @@ var newClientSession = func(
 		config.TLSConfig,
 		s.connectionParameters,
 		aeadChanged,
-		&handshake.TransportParameters{RequestConnectionIDTruncation: config.RequestConnectionIDTruncation},
+		params,
 		negotiatedVersions,
+		config.KeyLogWriter,
+		s.logger,
 	)
 	if err != nil {
 		return nil, nil, err
@@ -240,16 +281,26 @@ var newClientSession = func(
 
 // setup is called from newSession and newClientSession and initializes values that are independent of the perspective
 func (s *session) setup() {
+	s.logger = s.config.Logger.WithPrefix(fmt.Sprintf("%s %x", s.perspective, s.connectionID))
+	if s.config.Tracer != nil {
+		s.tracer = s.config.Tracer.TracerForConnection(s.perspective, s.connectionID)
+	}
+
 	s.rttStats = &congestion.RTTStats{}
-	flowControlManager := flowcontrol.NewFlowControlManager(s.connectionParameters, s.rttStats)
+	s.rttStats.SetLogger(s.logger)
+	flowControlManager := flowcontrol.NewFlowControlManager(s.connectionParameters, s.rttStats, s.logger)
 
-	sentPacketHandler := ackhandler.NewSentPacketHandler(s.rttStats)
+	sender := congestion.NewPacingSender(s.config.CongestionControl(congestion.DefaultClock{}, s.rttStats), s.rttStats)
+	sentPacketHandler := ackhandler.NewSentPacketHandler(s.rttStats, sender, s.onPacketLost, s.logger)
 
 	now := time.Now()
 
 	s.sentPacketHandler = sentPacketHandler
 	s.flowControlManager = flowControlManager
 	s.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(s.ackAlarmChanged)
+	s.plusFeedbackAssembler = newPLUSFeedbackAssembler()
+	s.pcfRequestScheduler = newPCFRequestScheduler()
+	s.pcfFeedbackChan = make(chan []byte, protocol.MaxQueuedPCFFeedbacks)
 
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
@@ -257,6 +308,8 @@ func (s *session) setup() {
 	s.undecryptablePackets = make([]*receivedPacket, 0, protocol.MaxUndecryptablePackets)
 	s.aeadChanged = make(chan protocol.EncryptionLevel, 2)
 	s.runClosed = make(chan struct{})
+	s.connectionStatsRequests = make(chan chan ConnectionStats)
+	s.closeGracefullyRequests = make(chan time.Duration)
 	s.handshakeCompleteChan = make(chan error, 1)
 
 	s.timer = time.NewTimer(0)
@@ -264,7 +317,7 @@ func (s *session) setup() {
 	s.sessionCreationTime = now
 
 	s.streamsMap = newStreamsMap(s.newStream, s.perspective, s.connectionParameters)
-	s.streamFramer = newStreamFramer(s.streamsMap, s.flowControlManager)
+	s.streamFramer = newStreamFramer(s.streamsMap, s.flowControlManager, s.config.StreamScheduling)
 }
 
 // run the session main loop
@@ -300,6 +353,11 @@ runLoop:
 		case <-s.sendingScheduled:
 			// We do all the interesting stuff after the switch statement, so
 			// nothing to see here.
+		case c := <-s.connectionStatsRequests:
+			c <- s.connectionStats()
+			continue
+		case timeout := <-s.closeGracefullyRequests:
+			s.sendGoaway(timeout)
 		case p := <-s.receivedPackets:
 			err := s.handlePacketImpl(p)
 			if err != nil {
@@ -312,9 +370,7 @@ runLoop:
 			}
 			// This is a bit unclean, but works properly, since the packet always
 			// begins with the public header and we never copy it.
-			//if !s.config.UsePLUS {
-         putPacketBuffer(p.publicHeader.Raw)
-         //}
+			putPacketBuffer(p.publicHeader.Raw)
 		case l, ok := <-aeadChanged:
 			if !ok { // the aeadChanged chan was closed. This means that the handshake is completed.
 				s.handshakeComplete = true
@@ -325,6 +381,9 @@ runLoop:
 				if l == protocol.EncryptionForwardSecure {
 					s.packer.SetForwardSecure()
 				}
+				if s.tracer != nil {
+					s.tracer.ChangedEncryptionLevel(l)
+				}
 				s.tryDecryptingQueuedPackets()
 				s.handshakeChan <- handshakeEvent{encLevel: l}
 			}
@@ -340,16 +399,22 @@ runLoop:
 		if err := s.sendPacket(); err != nil {
 			s.close(err)
 		}
+		if s.tracer != nil {
+			s.traceCongestionState()
+		}
 		if !s.receivedTooManyUndecrytablePacketsTime.IsZero() && s.receivedTooManyUndecrytablePacketsTime.Add(protocol.PublicResetTimeout).Before(now) && len(s.undecryptablePackets) != 0 {
 			s.close(qerr.Error(qerr.DecryptionFailure, "too many undecryptable packets received"))
 		}
 		if now.Sub(s.lastNetworkActivityTime) >= s.idleTimeout() {
 			s.close(qerr.Error(qerr.NetworkIdleTimeout, "No recent network activity."))
 		}
-		if !s.handshakeComplete && now.Sub(s.sessionCreationTime) >= protocol.MaxTimeForCryptoHandshake {
+		if !s.handshakeComplete && now.Sub(s.sessionCreationTime) >= s.config.HandshakeTimeout {
 			s.close(qerr.Error(qerr.NetworkIdleTimeout, "Crypto handshake did not complete in time."))
 		}
 		s.garbageCollectStreams()
+		if s.draining {
+			s.maybeFinishDraining(now)
+		}
 	}
 
 	// only send the error the handshakeChan when the handshake is not completed yet
@@ -359,6 +424,12 @@ runLoop:
 		s.handshakeChan <- handshakeEvent{err: closeErr.err}
 	}
 	s.handleCloseError(closeErr)
+	s.finalConnectionStats = s.connectionStats()
+	if s.tracer != nil {
+		s.tracer.Closed(closeErr.err)
+	}
+	// PCF feedback is only sent on the channel by the run loop
+	close(s.pcfFeedbackChan)
 	close(s.runClosed)
 	return closeErr.err
 }
@@ -372,13 +443,19 @@ func (s *session) maybeResetTimer() {
 	if lossTime := s.sentPacketHandler.GetAlarmTimeout(); !lossTime.IsZero() {
 		nextDeadline = utils.MinTime(nextDeadline, lossTime)
 	}
+	if !s.pacingDeadline.IsZero() {
+		nextDeadline = utils.MinTime(nextDeadline, s.pacingDeadline)
+	}
 	if !s.handshakeComplete {
-		handshakeDeadline := s.sessionCreationTime.Add(protocol.MaxTimeForCryptoHandshake)
+		handshakeDeadline := s.sessionCreationTime.Add(s.config.HandshakeTimeout)
 		nextDeadline = utils.MinTime(nextDeadline, handshakeDeadline)
 	}
 	if !s.receivedTooManyUndecrytablePacketsTime.IsZero() {
 		nextDeadline = utils.MinTime(nextDeadline, s.receivedTooManyUndecrytablePacketsTime.Add(protocol.PublicResetTimeout))
 	}
+	if !s.drainDeadline.IsZero() {
+		nextDeadline = utils.MinTime(nextDeadline, s.drainDeadline)
+	}
 
 	if nextDeadline.Equal(s.currentDeadline) {
 		// No need to reset the timer
@@ -428,11 +505,11 @@ func (s *session) handlePacketImpl(p *receivedPacket) error {
 	)
 
 	packet, err := s.unpacker.Unpack(hdr.Raw, hdr, data)
-	if utils.Debug() {
+	if s.logger.Debug() {
 		if err != nil {
-			utils.Debugf("<- Reading packet 0x%x (%d bytes) for connection %x @ %s", hdr.PacketNumber, len(data)+len(hdr.Raw), hdr.ConnectionID, time.Now().Format("15:04:05.000"))
+			s.logger.Debugf("<- Reading packet 0x%x (%d bytes) for connection %x @ %s", hdr.PacketNumber, len(data)+len(hdr.Raw), hdr.ConnectionID, time.Now().Format("15:04:05.000"))
 		} else {
-			utils.Debugf("<- Reading packet 0x%x (%d bytes) for connection %x, %s @ %s", hdr.PacketNumber, len(data)+len(hdr.Raw), hdr.ConnectionID, packet.encryptionLevel, time.Now().Format("15:04:05.000"))
+			s.logger.Debugf("<- Reading packet 0x%x (%d bytes) for connection %x, %s @ %s", hdr.PacketNumber, len(data)+len(hdr.Raw), hdr.ConnectionID, packet.encryptionLevel, time.Now().Format("15:04:05.000"))
 		}
 	}
 	// if the decryption failed, this might be a packet sent by an attacker
@@ -442,11 +519,7 @@ func (s *session) handlePacketImpl(p *receivedPacket) error {
 	}
 	if s.perspective == protocol.PerspectiveServer {
 		// update the remote address, even if unpacking failed for any other reason than a decryption error
-		if !s.config.UsePLUS {
-            s.conn.SetCurrentRemoteAddr(p.remoteAddr)
-        } else {
-            s.plusConnection.SetRemoteAddr(p.remoteAddr)
-        }
+		s.conn.SetCurrentRemoteAddr(p.remoteAddr)
 	}
 	if err != nil {
 		return err
@@ -459,12 +532,18 @@ func (s *session) handlePacketImpl(p *receivedPacket) error {
 	err = s.receivedPacketHandler.ReceivedPacket(hdr.PacketNumber, packet.IsRetransmittable())
 	// ignore duplicate packets
 	if err == ackhandler.ErrDuplicatePacket {
-		utils.Infof("Ignoring packet 0x%x due to ErrDuplicatePacket", hdr.PacketNumber)
+		s.logger.Infof("Ignoring packet 0x%x due to ErrDuplicatePacket", hdr.PacketNumber)
+		if s.tracer != nil {
+			s.tracer.DroppedPacket(p.size(), PacketDropDuplicate)
+		}
 		return nil
 	}
 	// ignore packets with packet numbers smaller than the LeastUnacked of a StopWaiting
 	if err == ackhandler.ErrPacketSmallerThanLastStopWaiting {
-		utils.Infof("Ignoring packet 0x%x due to ErrPacketSmallerThanLastStopWaiting", hdr.PacketNumber)
+		s.logger.Infof("Ignoring packet 0x%x due to ErrPacketSmallerThanLastStopWaiting", hdr.PacketNumber)
+		if s.tracer != nil {
+			s.tracer.DroppedPacket(p.size(), PacketDropBelowStopWaiting)
+		}
 		return nil
 	}
 
@@ -472,10 +551,12 @@ func (s *session) handlePacketImpl(p *receivedPacket) error {
 		return err
 	}
 
+	if s.tracer != nil {
+		s.tracer.ReceivedPacket(hdr.PacketNumber, p.size(), packet.encryptionLevel, packet.frames)
+	}
+
 	if p.feedbackData != nil {
-		if(s.config.UsePLUS) {
-			s.queuePLUSFeedbackFrame(p.feedbackData)
-		}
+		s.queuePLUSFeedbackFrame(p.feedbackData)
 	}
 
 	return s.handleFrames(packet.frames)
@@ -484,7 +565,7 @@ func (s *session) handlePacketImpl(p *receivedPacket) error {
 func (s *session) handleFrames(fs []frames.Frame) error {
 	for _, ff := range fs {
 		var err error
-		frames.LogFrame(ff, false)
+		frames.LogFrame(s.logger, ff, false)
 		switch frame := ff.(type) {
 		case *frames.StreamFrame:
 			err = s.handleStreamFrame(frame)
@@ -493,7 +574,7 @@ func (s *session) handleFrames(fs []frames.Frame) error {
 		case *frames.ConnectionCloseFrame:
 			s.registerClose(qerr.Error(frame.ErrorCode, frame.ReasonPhrase), true)
 		case *frames.GoawayFrame:
-			err = errors.New("unimplemented: handling GOAWAY frames")
+			s.handleGoawayFrame(frame)
 		case *frames.StopWaitingFrame:
 			err = s.receivedPacketHandler.ReceivedStopWaiting(frame)
 		case *frames.RstStreamFrame:
@@ -503,9 +584,25 @@ func (s *session) handleFrames(fs []frames.Frame) error {
 		case *frames.BlockedFrame:
 		case *frames.PingFrame:
 		case *frames.PLUSFeedbackFrame:
-			utils.Debugf("Received a PLUSFeedbackFrame with feedback: %x", frame.Data)
-			if s.config.UsePLUS {
-				s.plusConnection.AddPCFFeedback(frame.Data)
+			atomic.AddUint64(&s.plusFeedbackFramesReceived, 1)
+			var data []byte
+			data, err = s.plusFeedbackAssembler.Push(frame)
+			if err == nil && data != nil {
+				s.logger.Debugf("Received PLUS feedback: %x", data)
+				if s.tracer != nil {
+					s.tracer.ReceivedPLUSFeedback(data)
+				}
+				if err := s.conn.AddFeedback(data); err != nil {
+					s.logger.Errorf("Ignoring error adding PLUS feedback: %s", err.Error())
+				}
+				// the application gets its own copy, since the transport might hold on to the data
+				feedback := make([]byte, len(data))
+				copy(feedback, data)
+				select {
+				case s.pcfFeedbackChan <- feedback:
+				default:
+					s.logger.Infof("Dropping PCF feedback, since the application doesn't read it")
+				}
 			}
 		default:
 			return errors.New("Session BUG: unexpected frame type")
@@ -517,7 +614,7 @@ func (s *session) handleFrames(fs []frames.Frame) error {
 				// Can happen e.g. when packets thought missing arrive late
 			case errRstStreamOnInvalidStream:
 				// Can happen when RST_STREAMs arrive early or late (?)
-				utils.Errorf("Ignoring error in session: %s", err.Error())
+				s.logger.Errorf("Ignoring error in session: %s", err.Error())
 			case errWindowUpdateOnClosedStream:
 				// Can happen when we already sent the last StreamFrame with the FinBit, but the client already sent a WindowUpdate for this Stream
 			default:
@@ -530,11 +627,46 @@ func (s *session) handleFrames(fs []frames.Frame) error {
 
 // handlePacket is called by the server with a new packet
 func (s *session) handlePacket(p *receivedPacket) {
+	select {
+	case <-s.runClosed:
+		s.handlePacketAfterClose()
+		return
+	default:
+	}
 	// Discard packets once the amount of queued packets is larger than
 	// the channel size, protocol.MaxSessionUnprocessedPackets
 	select {
 	case s.receivedPackets <- p:
 	default:
+		if s.tracer != nil {
+			s.tracer.DroppedPacket(p.size(), PacketDropQueueFull)
+		}
+	}
+}
+
+// handlePacketAfterClose repeats the PLUS stop packet, since the peer (or devices on the path) might not have received it.
+// It is repeated with an exponential backoff, i.e. for the 1st, 2nd, 4th, 8th, ... packet received.
+func (s *session) handlePacketAfterClose() {
+	if s.stopPacket == nil {
+		return
+	}
+	n := atomic.AddUint32(&s.packetsReceivedAfterClose, 1)
+	if n&(n-1) != 0 {
+		return
+	}
+	if err := s.conn.(flowStopper).writeStop(s.stopPacket); err != nil {
+		s.logger.Debugf("Error repeating the stop packet: %s", err.Error())
+	}
+}
+
+// sentStopPacket says if the session was closed with a PLUS stop packet.
+// Such a session keeps handling packets after it was closed, in order to repeat the stop packet.
+func (s *session) sentStopPacket() bool {
+	select {
+	case <-s.runClosed:
+		return s.stopPacket != nil
+	default:
+		return false
 	}
 }
 
@@ -561,6 +693,9 @@ func (s *session) handleWindowUpdateFrame(frame *frames.WindowUpdateFrame) error
 			return errWindowUpdateOnClosedStream
 		}
 	}
+	if s.tracer != nil {
+		s.tracer.ReceivedWindowUpdate(frame.StreamID, frame.ByteOffset)
+	}
 	_, err := s.flowControlManager.UpdateWindow(frame.StreamID, frame.ByteOffset)
 	return err
 }
@@ -578,6 +713,20 @@ func (s *session) handleRstStreamFrame(frame *frames.RstStreamFrame) error {
 	return s.flowControlManager.ResetStream(frame.StreamID, frame.ByteOffset)
 }
 
+func (s *session) handleGoawayFrame(frame *frames.GoawayFrame) {
+	s.logger.Infof("Peer is going away (%s), last good stream: %d", frame.ErrorCode, frame.LastGoodStream)
+	goawayErr := &GoawayError{Remote: true}
+	s.streamsMap.GoAway(goawayErr)
+	// the peer didn't process the streams we opened after the last good stream
+	s.streamsMap.Iterate(func(str *stream) (bool, error) {
+		if id := str.StreamID(); id > frame.LastGoodStream && s.isOwnStream(id) {
+			str.Cancel(goawayErr)
+		}
+		return true, nil
+	})
+	s.startDraining(time.Time{})
+}
+
 func (s *session) handleAckFrame(frame *frames.AckFrame) error {
 	return s.sentPacketHandler.ReceivedAck(frame, s.lastRcvdPacketNumber, s.lastNetworkActivityTime)
 }
@@ -614,6 +763,62 @@ func (s *session) Close(e error) error {
 	return err
 }
 
+// CloseGracefully sends a GOAWAY frame and refuses new streams.
+// The connection is closed once all existing streams have finished, or when the timeout expires, whichever happens first.
+// It waits until the run loop has stopped before returning. If the timeout expired, an error is returned.
+func (s *session) CloseGracefully(timeout time.Duration) error {
+	select {
+	case s.closeGracefullyRequests <- timeout:
+	case <-s.runClosed:
+		return nil
+	}
+	<-s.runClosed
+	return s.drainErr
+}
+
+// sendGoaway must only be called from the run loop
+func (s *session) sendGoaway(timeout time.Duration) {
+	s.startDraining(time.Now().Add(timeout))
+	if s.goawaySent {
+		return
+	}
+	s.goawaySent = true
+	lastGoodStream := s.streamsMap.GoAway(&GoawayError{})
+	s.packer.QueueControlFrameForNextPacket(&frames.GoawayFrame{
+		ErrorCode:      qerr.PeerGoingAway,
+		LastGoodStream: lastGoodStream,
+	})
+}
+
+// startDraining must only be called from the run loop
+// If there are multiple deadlines, the earliest one is used.
+func (s *session) startDraining(deadline time.Time) {
+	s.draining = true
+	if !deadline.IsZero() && (s.drainDeadline.IsZero() || deadline.Before(s.drainDeadline)) {
+		s.drainDeadline = deadline
+	}
+}
+
+// maybeFinishDraining closes the connection once all streams have finished, or the drainDeadline was reached
+func (s *session) maybeFinishDraining(now time.Time) {
+	if !s.streamsMap.HasActiveStreams() {
+		s.close(nil)
+		return
+	}
+	if !s.drainDeadline.IsZero() && !now.Before(s.drainDeadline) {
+		s.drainErr = errGracefulCloseTimeout
+		s.close(errGracefulCloseTimeout)
+	}
+}
+
+// isOwnStream says if a stream was opened by us
+func (s *session) isOwnStream(id protocol.StreamID) bool {
+	if s.perspective == protocol.PerspectiveServer {
+		return id%2 == 0
+	}
+	return id%2 == 1
+}
+
 // close the connection. Use this when called from the run loop
 func (s *session) close(e error) error {
 	err := s.registerClose(e, false)
@@ -631,9 +836,9 @@ func (s *session) handleCloseError(closeErr closeError) error {
 	}
 	// Don't log 'normal' reasons
 	if quicErr.ErrorCode == qerr.PeerGoingAway || quicErr.ErrorCode == qerr.NetworkIdleTimeout {
-		utils.Infof("Closing connection %x", s.connectionID)
+		s.logger.Infof("Closing connection %x", s.connectionID)
 	} else {
-		utils.Errorf("Closing session with error: %s", closeErr.err.Error())
+		s.logger.Errorf("Closing session with error: %s", closeErr.err.Error())
 	}
 
 	if closeErr.err == errCloseSessionForNewVersion {
@@ -643,8 +848,12 @@ func (s *session) handleCloseError(closeErr closeError) error {
 	s.streamsMap.CloseWithError(quicErr)
 	s.closeStreamsWithError(quicErr)
 
-	// If this is a remote close we're done here
 	if closeErr.remote {
+		// PLUS needs both sides to send a stop packet, so confirm the peer's CONNECTION_CLOSE
+		if _, ok := s.conn.(flowStopper); ok {
+			return s.sendConnectionClose(qerr.Error(qerr.PeerGoingAway, ""))
+		}
+		// If this is a remote close we're done here
 		return nil
 	}
 
@@ -662,11 +871,19 @@ func (s *session) closeStreamsWithError(err error) {
 }
 
 func (s *session) sendPacket() error {
+	s.pacingDeadline = time.Time{}
 	// Repeatedly try sending until we don't have any more data, or run out of the congestion window
 	for {
 		if !s.sentPacketHandler.SendingAllowed() {
 			return nil
 		}
+		// wake up for the next pacing slot, instead of sending a burst of packets
+		// The pacer only delays stream data and retransmissions, ACKs and control frames are sent right away.
+		var paced bool
+		if pacingDeadline := s.sentPacketHandler.TimeUntilSend(); pacingDeadline.After(time.Now()) {
+			s.pacingDeadline = pacingDeadline
+			paced = true
+		}
 
 		var controlFrames []frames.Frame
 
@@ -675,18 +892,24 @@ func (s *session) sendPacket() error {
 		windowUpdateFrames := s.getWindowUpdateFrames()
 		for _, wuf := range windowUpdateFrames {
 			controlFrames = append(controlFrames, wuf)
+			if s.tracer != nil {
+				s.tracer.SentWindowUpdate(wuf.StreamID, wuf.ByteOffset)
+			}
 		}
 
 		// check for retransmissions first
-		for {
+		for !paced {
 			retransmitPacket := s.sentPacketHandler.DequeuePacketForRetransmission()
 			if retransmitPacket == nil {
 				break
 			}
-			utils.Debugf("\tDequeueing retransmission for packet 0x%x", retransmitPacket.PacketNumber)
+			s.logger.Debugf("\tDequeueing retransmission for packet 0x%x", retransmitPacket.PacketNumber)
+			if s.tracer != nil {
+				s.tracer.RetransmittedPacket(retransmitPacket.PacketNumber)
+			}
 
 			if retransmitPacket.EncryptionLevel != protocol.EncryptionForwardSecure {
-				utils.Debugf("\tDequeueing handshake retransmission for packet 0x%x", retransmitPacket.PacketNumber)
+				s.logger.Debugf("\tDequeueing handshake retransmission for packet 0x%x", retransmitPacket.PacketNumber)
 				stopWaitingFrame := s.sentPacketHandler.GetStopWaitingFrame(true)
 				var packet *packedPacket
 				packet, err := s.packer.RetransmitNonForwardSecurePacket(stopWaitingFrame, retransmitPacket)
@@ -726,12 +949,18 @@ func (s *session) sendPacket() error {
 		if ack != nil {
 			controlFrames = append(controlFrames, ack)
 		}
-		hasRetransmission := s.streamFramer.HasFramesForRetransmission()
+		hasRetransmission := !paced && s.streamFramer.HasFramesForRetransmission()
 		var stopWaitingFrame *frames.StopWaitingFrame
 		if ack != nil || hasRetransmission {
 			stopWaitingFrame = s.sentPacketHandler.GetStopWaitingFrame(hasRetransmission)
 		}
-		packet, err := s.packer.PackPacket(stopWaitingFrame, controlFrames, s.sentPacketHandler.GetLeastUnacked())
+		var packet *packedPacket
+		var err error
+		if paced {
+			packet, err = s.packer.PackControlPacket(stopWaitingFrame, controlFrames, s.sentPacketHandler.GetLeastUnacked())
+		} else {
+			packet, err = s.packer.PackPacket(stopWaitingFrame, controlFrames, s.sentPacketHandler.GetLeastUnacked())
+		}
 		if err != nil {
 			return err
 		}
@@ -748,18 +977,12 @@ func (s *session) sendPacket() error {
 			return err
 		}
 		s.nextAckScheduledTime = time.Time{}
+		if paced {
+			return nil
+		}
 	}
 }
 
-func (s *session) write(data []byte) error {
-    if(!s.config.UsePLUS) {
-        return s.conn.Write(data)
-    } else {
-			  _, err := s.plusConnection.Write(data)
-        return err
-    }
-}
-
 func (s *session) sendPackedPacket(packet *packedPacket) error {
 	err := s.sentPacketHandler.SentPacket(&ackhandler.Packet{
 		PacketNumber:    packet.number,
@@ -771,9 +994,23 @@ func (s *session) sendPackedPacket(packet *packedPacket) error {
 		return err
 	}
 
+	for _, f := range packet.frames {
+		if _, ok := f.(*frames.PLUSFeedbackFrame); ok {
+			atomic.AddUint64(&s.plusFeedbackFramesSent, 1)
+		}
+	}
+	for _, r := range s.pcfRequestScheduler.SentPacket() {
+		if err := s.queuePCFRequest(r); err != nil {
+			s.logger.Errorf("Error repeating PCF request: %s", err.Error())
+		}
+	}
+
 	s.logPacket(packet)
+	if s.tracer != nil {
+		s.tracer.SentPacket(packet.number, protocol.ByteCount(len(packet.raw)), packet.encryptionLevel, packet.frames)
+	}
 
-	err = s.write(packet.raw)
+	err = s.conn.Write(packet.raw)
 	putPacketBuffer(packet.raw)
 	return err
 }
@@ -787,18 +1024,21 @@ func (s *session) sendConnectionClose(quicErr *qerr.QuicError) error {
 		return errors.New("Session BUG: expected packet not to be nil")
 	}
 	s.logPacket(packet)
-	return s.write(packet.raw)
+	if s.tracer != nil {
+		s.tracer.SentPacket(packet.number, protocol.ByteCount(len(packet.raw)), packet.encryptionLevel, packet.frames)
+	}
+	return s.writeLastPacket(packet.raw)
 }
 
 func (s *session) logPacket(packet *packedPacket) {
-	if !utils.Debug() {
+	if !s.logger.Debug() {
 		// We don't need to allocate the slices for calling the format functions
 		return
 	}
-	if utils.Debug() {
-		utils.Debugf("-> Sending packet 0x%x (%d bytes), %s, @ %s", packet.number, len(packet.raw), packet.encryptionLevel, time.Now().Format("15:04:05.000"))
+	if s.logger.Debug() {
+		s.logger.Debugf("-> Sending packet 0x%x (%d bytes), %s, @ %s", packet.number, len(packet.raw), packet.encryptionLevel, time.Now().Format("15:04:05.000"))
 		for _, frame := range packet.frames {
-			frames.LogFrame(frame, true)
+			frames.LogFrame(s.logger, frame, true)
 		}
 	}
 }
@@ -814,6 +1054,21 @@ func (s *session) GetOrOpenStream(id protocol.StreamID) (Stream, error) {
 	return nil, err
 }
 
+// GetStream returns an open stream, or nil if the stream was not opened yet or is already closed
+func (s *session) GetStream(id protocol.StreamID) Stream {
+	if str := s.streamsMap.GetStream(id); str != nil {
+		return str
+	}
+	// make sure to return an actual nil value here, not an Stream with value nil
+	return nil
+}
+
+// AddStaticStream marks a stream as static, e.g. the h2quic header stream. Static streams live as long as the session.
+// CloseGracefully doesn't wait for them to finish, and neither does the draining after a GOAWAY frame was received.
+func (s *session) AddStaticStream(id protocol.StreamID) {
+	s.streamsMap.AddStaticStream(id)
+}
+
 // AcceptStream returns the next stream openend by the peer
 func (s *session) AcceptStream() (Stream, error) {
 	return s.streamsMap.AcceptStream()
@@ -833,10 +1088,17 @@ func (s *session) WaitUntilHandshakeComplete() error {
 }
 
 func (s *session) queuePLUSFeedbackFrame(data []byte) {
-	utils.Debugf("Queue PLUSFeedbackFrame: %d", data)
-	s.packer.QueueControlFrameForNextPacket(&frames.PLUSFeedbackFrame{
-		Data: data,
-	})
+	s.logger.Debugf("Queue PLUS feedback: %x", data)
+	if s.tracer != nil {
+		s.tracer.SentPLUSFeedback(data)
+	}
+	fs := fragmentPLUSFeedback(data, s.nextPLUSFeedbackID, protocol.MaxPLUSFeedbackFragmentSize)
+	if len(fs) > 1 {
+		s.nextPLUSFeedbackID++
+	}
+	for _, f := range fs {
+		s.packer.QueueControlFrameForNextPacket(f)
+	}
 	s.scheduleSending()
 }
 
@@ -881,8 +1143,20 @@ func (s *session) garbageCollectStreams() {
 }
 
 func (s *session) sendPublicReset(rejectedPacketNumber protocol.PacketNumber) error {
-	utils.Infof("Sending public reset for connection %x, packet number %d", s.connectionID, rejectedPacketNumber)
-	return s.write(writePublicReset(s.connectionID, rejectedPacketNumber, 0))
+	s.logger.Infof("Sending public reset for connection %x, packet number %d", s.connectionID, rejectedPacketNumber)
+	return s.writeLastPacket(writePublicReset(s.connectionID, rejectedPacketNumber, 0))
+}
+
+// writeLastPacket writes the packet that closes the connection
+// If the transport supports it (e.g. PLUS), devices on the path are told that the connection ends.
+func (s *session) writeLastPacket(p []byte) error {
+	if c, ok := s.conn.(flowStopper); ok {
+		// keep a copy, the stop packet is repeated if packets arrive after closing
+		s.stopPacket = make([]byte, len(p))
+		copy(s.stopPacket, p)
+		return c.writeStop(p)
+	}
+	return s.conn.Write(p)
 }
 
 // scheduleSending signals that we have data for sending
@@ -895,6 +1169,9 @@ func (s *session) scheduleSending() {
 
 func (s *session) tryQueueingUndecryptablePacket(p *receivedPacket) {
 	if s.handshakeComplete {
+		if s.tracer != nil {
+			s.tracer.DroppedPacket(p.size(), PacketDropUndecryptable)
+		}
 		return
 	}
 	if len(s.undecryptablePackets)+1 > protocol.MaxUndecryptablePackets {
@@ -903,13 +1180,42 @@ func (s *session) tryQueueingUndecryptablePacket(p *receivedPacket) {
 			s.receivedTooManyUndecrytablePacketsTime = time.Now()
 			s.maybeResetTimer()
 		}
-		utils.Infof("Dropping undecrytable packet 0x%x (undecryptable packet queue full)", p.publicHeader.PacketNumber)
+		s.logger.Infof("Dropping undecrytable packet 0x%x (undecryptable packet queue full)", p.publicHeader.PacketNumber)
+		if s.tracer != nil {
+			s.tracer.DroppedPacket(p.size(), PacketDropUndecryptable)
+		}
 		return
 	}
-	utils.Infof("Queueing packet 0x%x for later decryption", p.publicHeader.PacketNumber)
+	s.logger.Infof("Queueing packet 0x%x for later decryption", p.publicHeader.PacketNumber)
+	if s.tracer != nil {
+		s.tracer.BufferedUndecryptablePacket(p.size())
+	}
 	s.undecryptablePackets = append(s.undecryptablePackets, p)
 }
 
+// onPacketLost is called by the sentPacketHandler
+func (s *session) onPacketLost(p *ackhandler.Packet) {
+	if s.tracer != nil {
+		s.tracer.LostPacket(p.PacketNumber, p.Length)
+	}
+}
+
+// traceCongestionState passes the congestion state to the tracer, if the congestion window or the slow start threshold changed
+func (s *session) traceCongestionState() {
+	stats := s.sentPacketHandler.GetStatistics()
+	if stats.CongestionWindow == s.tracedCongestionState.CongestionWindow && stats.SlowStartThreshold == s.tracedCongestionState.SlowStartThreshold {
+		return
+	}
+	s.tracedCongestionState = CongestionState{
+		CongestionWindow:   stats.CongestionWindow,
+		SlowStartThreshold: stats.SlowStartThreshold,
+		BytesInFlight:      stats.BytesInFlight,
+		SmoothedRTT:        s.rttStats.SmoothedRTT(),
+		MinRTT:             s.rttStats.MinRTT(),
+	}
+	s.tracer.UpdatedCongestionState(s.tracedCongestionState)
+}
+
 func (s *session) tryDecryptingQueuedPackets() {
 	for _, p := range s.undecryptablePackets {
 		s.handlePacket(p)
@@ -932,18 +1238,102 @@ func (s *session) ackAlarmChanged(t time.Time) {
 }
 
 func (s *session) LocalAddr() net.Addr {
-    if !s.config.UsePLUS {
-        return s.conn.LocalAddr()
-    } else {
-        return s.plusConnection.LocalAddr()
-    }
+	return s.conn.LocalAddr()
 }
 
 // RemoteAddr returns the net.Addr of the client
 func (s *session) RemoteAddr() net.Addr {
-    if !s.config.UsePLUS {
-        return s.conn.RemoteAddr()
-    } else {
-        return s.plusConnection.RemoteAddr()
-    }
+	return s.conn.RemoteAddr()
+}
+
+// RequestPCF sends a PCF request, and repeats it every r.Every packets
+func (s *session) RequestPCF(r *PCFRequest) error {
+	if r.Integrity > maxPCFIntegrity {
+		return errInvalidPCFIntegrity
+	}
+	value := make([]byte, len(r.Value))
+	copy(value, r.Value)
+	req := &PCFRequest{Type: r.Type, Integrity: r.Integrity, Value: value, Every: r.Every}
+
+	if err := s.queuePCFRequest(req); err != nil {
+		return err
+	}
+	s.pcfRequestScheduler.Add(r, req)
+	return nil
+}
+
+// CancelPCFRequest stops repeating a PCF request that was passed to RequestPCF
+func (s *session) CancelPCFRequest(r *PCFRequest) {
+	s.pcfRequestScheduler.Remove(r)
+}
+
+// SetLogLevel sets the log level of the session's logger, which is passed to all parts of the connection
+func (s *session) SetLogLevel(level utils.LogLevel) {
+	s.logger.SetLogLevel(level)
+}
+
+func (s *session) queuePCFRequest(r *PCFRequest) error {
+	c, ok := s.conn.(pcfRequester)
+	if !ok {
+		return errPCFRequestsUnsupported
+	}
+	return c.queuePCFRequest(r.Type, r.Integrity, r.Value)
+}
+
+// PCFFeedback returns a channel that receives the PCF feedback from the peer
+func (s *session) PCFFeedback() <-chan []byte {
+	return s.pcfFeedbackChan
+}
+
+// ConnectionStats returns a snapshot of the connection statistics
+// The snapshot is taken by the run loop, since the sentPacketHandler and the receivedPacketHandler are not safe for concurrent use.
+func (s *session) ConnectionStats() ConnectionStats {
+	c := make(chan ConnectionStats, 1)
+	select {
+	case s.connectionStatsRequests <- c:
+		return <-c
+	case <-s.runClosed:
+		return s.finalConnectionStats
+	}
+}
+
+// connectionStats must only be called from the run loop
+func (s *session) connectionStats() ConnectionStats {
+	sent := s.sentPacketHandler.GetStatistics()
+	conn, streams := s.flowControlManager.GetStatistics()
+	stats := ConnectionStats{
+		SmoothedRTT:            s.rttStats.SmoothedRTT(),
+		MinRTT:                 s.rttStats.MinRTT(),
+		LatestRTT:              s.rttStats.LatestRTT(),
+		CongestionWindow:       sent.CongestionWindow,
+		SlowStartThreshold:     sent.SlowStartThreshold,
+		BytesInFlight:          sent.BytesInFlight,
+		PacketsSent:            sent.PacketsSent,
+		PacketsReceived:        s.receivedPacketHandler.GetStatistics().PacketsReceived,
+		PacketsLost:            sent.PacketsLost,
+		PacketsRetransmitted:   sent.PacketsRetransmitted,
+		BytesSent:              conn.BytesSent,
+		BytesReceived:          conn.BytesReceived,
+		FlowControlBlockedTime: conn.BlockedTime,
+		Streams:                make(map[protocol.StreamID]StreamStats, len(streams)),
+	}
+	for id, st := range streams {
+		stats.Streams[id] = StreamStats{
+			BytesSent:              st.BytesSent,
+			BytesReceived:          st.BytesReceived,
+			FlowControlBlockedTime: st.BlockedTime,
+		}
+	}
+	return stats
+}
+
+// PLUSInfo returns a snapshot of the PLUS statistics
+func (s *session) PLUSInfo() PLUSInfo {
+	var info PLUSInfo
+	if c, ok := s.conn.(plusInfoProvider); ok {
+		info = c.plusInfo()
+	}
+	info.FeedbackFramesSent = atomic.LoadUint64(&s.plusFeedbackFramesSent)
+	info.FeedbackFramesReceived = atomic.LoadUint64(&s.plusFeedbackFramesReceived)
+	return info
 }
@@ -15,18 +15,22 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/lucas-clemente/quic-go/ackhandler"
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/crypto"
 	"github.com/lucas-clemente/quic-go/frames"
 	"github.com/lucas-clemente/quic-go/handshake"
 	"github.com/lucas-clemente/quic-go/protocol"
 	"github.com/lucas-clemente/quic-go/qerr"
 	"github.com/lucas-clemente/quic-go/testdata"
+	"github.com/lucas-clemente/quic-go/utils"
 )
 
 type mockConnection struct {
 	remoteAddr net.Addr
 	localAddr  net.Addr
 	written    [][]byte
+	feedback   [][]byte
+	closed     bool
 }
 
 func (m *mockConnection) Write(p []byte) error {
@@ -35,14 +39,51 @@ func (m *mockConnection) Write(p []byte) error {
 	m.written = append(m.written, b)
 	return nil
 }
-func (m *mockConnection) Read([]byte) (int, net.Addr, error) { panic("not implemented") }
+func (m *mockConnection) AddFeedback(p []byte) error {
+	m.feedback = append(m.feedback, p)
+	return nil
+}
+
+func (m *mockConnection) Close() error {
+	m.closed = true
+	return nil
+}
 
 func (m *mockConnection) SetCurrentRemoteAddr(addr net.Addr) {
 	m.remoteAddr = addr
 }
 func (m *mockConnection) LocalAddr() net.Addr  { return m.localAddr }
 func (m *mockConnection) RemoteAddr() net.Addr { return m.remoteAddr }
-func (*mockConnection) Close() error           { panic("not implemented") }
+
+var _ TransportConnection = &mockConnection{}
+
+type mockPCFRequest struct {
+	pcfType   uint16
+	integrity uint16
+	value     []byte
+}
+
+// a mockConnection that can send PCF requests, like a PLUS connection
+type mockPLUSConnection struct {
+	mockConnection
+	pcfRequests []mockPCFRequest
+	stopped     [][]byte
+}
+
+func (m *mockPLUSConnection) writeStop(p []byte) error {
+	b := make([]byte, len(p))
+	copy(b, p)
+	m.stopped = append(m.stopped, b)
+	return nil
+}
+
+func (m *mockPLUSConnection) queuePCFRequest(pcfType uint16, integrity uint16, value []byte) error {
+	m.pcfRequests = append(m.pcfRequests, mockPCFRequest{pcfType: pcfType, integrity: integrity, value: value})
+	return nil
+}
+
+var _ pcfRequester = &mockPLUSConnection{}
+var _ flowStopper = &mockPLUSConnection{}
 
 type mockUnpacker struct {
 	unpackErr error
@@ -61,6 +102,7 @@ type mockSentPacketHandler struct {
 	retransmissionQueue  []*ackhandler.Packet
 	sentPackets          []*ackhandler.Packet
 	congestionLimited    bool
+	pacingDeadline       time.Time
 	requestedStopWaiting bool
 }
 
@@ -74,9 +116,14 @@ func (h *mockSentPacketHandler) ReceivedAck(ackFrame *frames.AckFrame, withPacke
 }
 
 func (h *mockSentPacketHandler) GetLeastUnacked() protocol.PacketNumber { return 1 }
-func (h *mockSentPacketHandler) GetAlarmTimeout() time.Time             { panic("not implemented") }
-func (h *mockSentPacketHandler) OnAlarm()                               { panic("not implemented") }
+func (h *mockSentPacketHandler) GetAlarmTimeout() time.Time             { return time.Time{} }
+func (h *mockSentPacketHandler) OnAlarm()                               {}
 func (h *mockSentPacketHandler) SendingAllowed() bool                   { return !h.congestionLimited }
+func (h *mockSentPacketHandler) TimeUntilSend() time.Time               { return h.pacingDeadline }
+
+func (h *mockSentPacketHandler) GetStatistics() ackhandler.SentPacketStatistics {
+	return ackhandler.SentPacketStatistics{PacketsSent: uint64(len(h.sentPackets))}
+}
 
 func (h *mockSentPacketHandler) GetStopWaitingFrame(force bool) *frames.StopWaitingFrame {
 	h.requestedStopWaiting = true
@@ -113,9 +160,82 @@ func (m *mockReceivedPacketHandler) ReceivedPacket(packetNumber protocol.PacketN
 func (m *mockReceivedPacketHandler) ReceivedStopWaiting(*frames.StopWaitingFrame) error {
 	panic("not implemented")
 }
+func (m *mockReceivedPacketHandler) GetStatistics() ackhandler.ReceivedPacketStatistics {
+	return ackhandler.ReceivedPacketStatistics{}
+}
 
 var _ ackhandler.ReceivedPacketHandler = &mockReceivedPacketHandler{}
 
+type mockTracer struct {
+	perspective  protocol.Perspective
+	connectionID protocol.ConnectionID
+	connTracer   *mockConnectionTracer
+}
+
+func (t *mockTracer) TracerForConnection(p protocol.Perspective, connectionID protocol.ConnectionID) ConnectionTracer {
+	t.perspective = p
+	t.connectionID = connectionID
+	return t.connTracer
+}
+
+type mockConnectionTracer struct {
+	sentPackets           []protocol.PacketNumber
+	receivedPackets       []protocol.PacketNumber
+	droppedPackets        []PacketDropReason
+	bufferedPackets       int
+	lostPackets           []protocol.PacketNumber
+	retransmittedPackets  []protocol.PacketNumber
+	congestionStates      []CongestionState
+	encLevels             []protocol.EncryptionLevel
+	sentWindowUpdates     []frames.WindowUpdateFrame
+	receivedWindowUpdates []frames.WindowUpdateFrame
+	sentFeedback          [][]byte
+	receivedFeedback      [][]byte
+	closed                bool
+	closeErr              error
+}
+
+func (t *mockConnectionTracer) SentPacket(pn protocol.PacketNumber, _ protocol.ByteCount, _ protocol.EncryptionLevel, _ []frames.Frame) {
+	t.sentPackets = append(t.sentPackets, pn)
+}
+func (t *mockConnectionTracer) ReceivedPacket(pn protocol.PacketNumber, _ protocol.ByteCount, _ protocol.EncryptionLevel, _ []frames.Frame) {
+	t.receivedPackets = append(t.receivedPackets, pn)
+}
+func (t *mockConnectionTracer) DroppedPacket(_ protocol.ByteCount, reason PacketDropReason) {
+	t.droppedPackets = append(t.droppedPackets, reason)
+}
+func (t *mockConnectionTracer) BufferedUndecryptablePacket(protocol.ByteCount) { t.bufferedPackets++ }
+func (t *mockConnectionTracer) LostPacket(pn protocol.PacketNumber, _ protocol.ByteCount) {
+	t.lostPackets = append(t.lostPackets, pn)
+}
+func (t *mockConnectionTracer) RetransmittedPacket(pn protocol.PacketNumber) {
+	t.retransmittedPackets = append(t.retransmittedPackets, pn)
+}
+func (t *mockConnectionTracer) UpdatedCongestionState(s CongestionState) {
+	t.congestionStates = append(t.congestionStates, s)
+}
+func (t *mockConnectionTracer) ChangedEncryptionLevel(l protocol.EncryptionLevel) {
+	t.encLevels = append(t.encLevels, l)
+}
+func (t *mockConnectionTracer) SentWindowUpdate(id protocol.StreamID, offset protocol.ByteCount) {
+	t.sentWindowUpdates = append(t.sentWindowUpdates, frames.WindowUpdateFrame{StreamID: id, ByteOffset: offset})
+}
+func (t *mockConnectionTracer) ReceivedWindowUpdate(id protocol.StreamID, offset protocol.ByteCount) {
+	t.receivedWindowUpdates = append(t.receivedWindowUpdates, frames.WindowUpdateFrame{StreamID: id, ByteOffset: offset})
+}
+func (t *mockConnectionTracer) SentPLUSFeedback(data []byte) {
+	t.sentFeedback = append(t.sentFeedback, data)
+}
+func (t *mockConnectionTracer) ReceivedPLUSFeedback(data []byte) {
+	t.receivedFeedback = append(t.receivedFeedback, data)
+}
+func (t *mockConnectionTracer) Closed(err error) {
+	t.closed = true
+	t.closeErr = err
+}
+
+var _ ConnectionTracer = &mockConnectionTracer{}
+
 func areSessionsRunning() bool {
 	var b bytes.Buffer
 	pprof.Lookup("goroutine").WriteTo(&b, 1)
@@ -133,6 +253,7 @@ var _ = Describe("Session", func() {
 		aeadChanged   chan<- protocol.EncryptionLevel
 
 		cryptoSetupSourceAddr []byte
+		cryptoSetupLogger     utils.Logger
 	)
 
 	BeforeEach(func() {
@@ -148,8 +269,11 @@ var _ = Describe("Session", func() {
 			_ handshake.ConnectionParametersManager,
 			_ []protocol.VersionNumber,
 			aeadChangedP chan<- protocol.EncryptionLevel,
+			_ io.Writer,
+			logger utils.Logger,
 		) (handshake.CryptoSetup, error) {
 			cryptoSetupSourceAddr = sourceAddr
+			cryptoSetupLogger = logger
 			aeadChanged = aeadChangedP
 			return cryptoSetup, nil
 		}
@@ -168,7 +292,7 @@ var _ = Describe("Session", func() {
 			protocol.Version35,
 			0,
 			scfg,
-			populateServerConfig(&Config{}), nil,
+			populateServerConfig(&Config{}),
 		)
 		Expect(err).NotTo(HaveOccurred())
 		sess = pSess.(*session)
@@ -191,7 +315,7 @@ var _ = Describe("Session", func() {
 				protocol.VersionWhatever,
 				0,
 				scfg,
-				populateServerConfig(&Config{}), nil,
+				populateServerConfig(&Config{}),
 			)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(cryptoSetupSourceAddr).To(Equal([]byte{192, 168, 100, 200}))
@@ -206,13 +330,27 @@ var _ = Describe("Session", func() {
 				protocol.VersionWhatever,
 				0,
 				scfg,
-				populateServerConfig(&Config{}), nil,
+				populateServerConfig(&Config{}),
 			)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(cryptoSetupSourceAddr).To(Equal([]byte("192.168.100.200:1337")))
 		})
 	})
 
+	It("uses the congestion controller from the config", func() {
+		var rttStats *congestion.RTTStats
+		config := populateServerConfig(&Config{
+			CongestionControl: func(clock congestion.Clock, r *congestion.RTTStats) congestion.SendAlgorithm {
+				rttStats = r
+				return congestion.RenoFactory(clock, r)
+			},
+		})
+		pSess, _, err := newSession(mconn, protocol.Version35, 0, scfg, config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rttStats).ToNot(BeNil())
+		Expect(rttStats).To(BeIdenticalTo(pSess.(*session).rttStats))
+	})
+
 	Context("when handling stream frames", func() {
 		It("makes new streams", func() {
 			sess.handleStreamFrame(&frames.StreamFrame{
@@ -574,9 +712,430 @@ var _ = Describe("Session", func() {
 		Expect(err).NotTo(HaveOccurred())
 	})
 
-	It("errors on GOAWAY frames", func() {
-		err := sess.handleFrames([]frames.Frame{&frames.GoawayFrame{}})
-		Expect(err).To(MatchError("unimplemented: handling GOAWAY frames"))
+	It("passes PLUSFeedbackFrames to the connection", func() {
+		err := sess.handleFrames([]frames.Frame{&frames.PLUSFeedbackFrame{Data: []byte("feedback")}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mconn.feedback).To(Equal([][]byte{[]byte("feedback")}))
+	})
+
+	It("reassembles fragmented PLUS feedback before passing it to the connection", func() {
+		err := sess.handleFrames([]frames.Frame{&frames.PLUSFeedbackFrame{FeedbackID: 3, Offset: 3, Data: []byte("dback")}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mconn.feedback).To(BeEmpty())
+		err = sess.handleFrames([]frames.Frame{&frames.PLUSFeedbackFrame{FeedbackID: 3, MoreFragments: true, Data: []byte("fee")}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mconn.feedback).To(Equal([][]byte{[]byte("feedback")}))
+	})
+
+	It("errors on inconsistent PLUS feedback fragments", func() {
+		err := sess.handleFrames([]frames.Frame{&frames.PLUSFeedbackFrame{FeedbackID: 3, Offset: 3, Data: []byte("dback")}})
+		Expect(err).NotTo(HaveOccurred())
+		err = sess.handleFrames([]frames.Frame{&frames.PLUSFeedbackFrame{FeedbackID: 3, MoreFragments: true, Data: []byte("feed")}})
+		Expect(err).To(MatchError(errInconsistentPLUSFeedbackFragments))
+		Expect(mconn.feedback).To(BeEmpty())
+	})
+
+	Context("PCF requests", func() {
+		var pconn *mockPLUSConnection
+
+		BeforeEach(func() {
+			pconn = &mockPLUSConnection{}
+			sess.conn = pconn
+		})
+
+		It("errors if the connection doesn't use PLUS", func() {
+			sess.conn = mconn
+			err := sess.RequestPCF(&PCFRequest{Type: 1})
+			Expect(err).To(MatchError(errPCFRequestsUnsupported))
+		})
+
+		It("sends a PCF request once", func() {
+			err := sess.RequestPCF(&PCFRequest{Type: 1, Integrity: 2, Value: []byte("foobar")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pconn.pcfRequests).To(Equal([]mockPCFRequest{{pcfType: 1, integrity: 2, value: []byte("foobar")}}))
+			sess.queueResetStreamFrame(5, 0)
+			err = sess.sendPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pconn.written).To(HaveLen(1))
+			Expect(pconn.pcfRequests).To(HaveLen(1))
+		})
+
+		It("repeats a PCF request every N packets", func() {
+			err := sess.RequestPCF(&PCFRequest{Type: 1, Value: []byte("foobar"), Every: 2})
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 4; i++ {
+				sess.queueResetStreamFrame(5, 0)
+				err = sess.sendPacket()
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(pconn.written).To(HaveLen(4))
+			Expect(pconn.pcfRequests).To(HaveLen(3))
+		})
+
+		It("errors on invalid integrity levels", func() {
+			err := sess.RequestPCF(&PCFRequest{Type: 1, Integrity: 4})
+			Expect(err).To(MatchError(errInvalidPCFIntegrity))
+			Expect(pconn.pcfRequests).To(BeEmpty())
+		})
+
+		It("stops repeating a cancelled PCF request", func() {
+			r := &PCFRequest{Type: 1, Value: []byte("foobar"), Every: 1}
+			err := sess.RequestPCF(r)
+			Expect(err).ToNot(HaveOccurred())
+			sess.queueResetStreamFrame(5, 0)
+			Expect(sess.sendPacket()).To(Succeed())
+			Expect(pconn.pcfRequests).To(HaveLen(2))
+			sess.CancelPCFRequest(r)
+			sess.queueResetStreamFrame(5, 0)
+			Expect(sess.sendPacket()).To(Succeed())
+			Expect(pconn.written).To(HaveLen(2))
+			Expect(pconn.pcfRequests).To(HaveLen(2))
+		})
+
+		It("copies the value of the request", func() {
+			value := []byte("foobar")
+			err := sess.RequestPCF(&PCFRequest{Type: 1, Value: value, Every: 1})
+			Expect(err).ToNot(HaveOccurred())
+			value[0] = 'x'
+			sess.queueResetStreamFrame(5, 0)
+			err = sess.sendPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pconn.pcfRequests[1].value).To(Equal([]byte("foobar")))
+		})
+
+		It("delivers PCF feedback to the application", func() {
+			err := sess.handleFrames([]frames.Frame{&frames.PLUSFeedbackFrame{Data: []byte("feedback")}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pconn.feedback).To(Equal([][]byte{[]byte("feedback")}))
+			Expect(sess.PCFFeedback()).To(Receive(Equal([]byte("feedback"))))
+		})
+
+		It("passes a copy of the PCF feedback to the application", func() {
+			err := sess.handleFrames([]frames.Frame{&frames.PLUSFeedbackFrame{Data: []byte("feedback")}})
+			Expect(err).ToNot(HaveOccurred())
+			pconn.feedback[0][0] = 'x'
+			Expect(sess.PCFFeedback()).To(Receive(Equal([]byte("feedback"))))
+		})
+
+		It("closes the PCF feedback channel when the session is closed", func() {
+			go sess.run()
+			Expect(sess.Close(nil)).To(Succeed())
+			Eventually(sess.PCFFeedback()).Should(BeClosed())
+		})
+
+		It("drops PCF feedback if the application doesn't read it", func() {
+			for i := 0; i < protocol.MaxQueuedPCFFeedbacks+1; i++ {
+				err := sess.handleFrames([]frames.Frame{&frames.PLUSFeedbackFrame{Data: []byte{byte(i)}}})
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(pconn.feedback).To(HaveLen(protocol.MaxQueuedPCFFeedbacks + 1))
+			Expect(sess.PCFFeedback()).To(HaveLen(protocol.MaxQueuedPCFFeedbacks))
+		})
+	})
+
+	Context("PLUS statistics", func() {
+		It("counts received PLUSFeedbackFrames", func() {
+			err := sess.handleFrames([]frames.Frame{
+				&frames.PLUSFeedbackFrame{Data: []byte("foo")},
+				&frames.PLUSFeedbackFrame{Data: []byte("bar")},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sess.PLUSInfo().FeedbackFramesReceived).To(Equal(uint64(2)))
+		})
+
+		It("counts sent PLUSFeedbackFrames", func() {
+			sess.queuePLUSFeedbackFrame([]byte("feedback"))
+			err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(sess.PLUSInfo().FeedbackFramesSent).To(Equal(uint64(1)))
+		})
+
+		It("doesn't report PLUS statistics if the connection doesn't use PLUS", func() {
+			Expect(sess.PLUSInfo()).To(Equal(PLUSInfo{}))
+		})
+
+		It("gets the PLUS statistics from the connection", func() {
+			stats := &plusStats{}
+			stats.receivedPacket(7, 1337, 42, true)
+			sess.conn = &plusConn{stats: stats}
+			sess.plusFeedbackFramesReceived = 3
+			Expect(sess.PLUSInfo()).To(Equal(PLUSInfo{
+				UsesPLUS:               true,
+				PacketsReceived:        1,
+				PSN:                    1337,
+				PSE:                    42,
+				FeedbackReceived:       1,
+				FeedbackFramesReceived: 3,
+			}))
+		})
+	})
+
+	Context("tracing", func() {
+		var tracer *mockConnectionTracer
+
+		BeforeEach(func() {
+			tracer = &mockConnectionTracer{}
+			sess.tracer = tracer
+		})
+
+		It("creates a tracer for the connection", func() {
+			t := &mockTracer{connTracer: tracer}
+			s, _, err := newSession(
+				mconn,
+				protocol.Version35,
+				0x1337,
+				scfg,
+				populateServerConfig(&Config{Tracer: t}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.(*session).tracer).To(Equal(tracer))
+			Expect(t.perspective).To(Equal(protocol.PerspectiveServer))
+			Expect(t.connectionID).To(Equal(protocol.ConnectionID(0x1337)))
+		})
+
+		It("doesn't trace if no tracer is configured", func() {
+			s, _, err := newSession(
+				mconn,
+				protocol.Version35,
+				0x1337,
+				scfg,
+				populateServerConfig(&Config{}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.(*session).tracer).To(BeNil())
+		})
+
+		It("traces sent packets and PLUS feedback", func() {
+			sess.queuePLUSFeedbackFrame([]byte("feedback"))
+			err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tracer.sentFeedback).To(Equal([][]byte{[]byte("feedback")}))
+			Expect(tracer.sentPackets).To(Equal([]protocol.PacketNumber{1}))
+		})
+
+		It("traces received PLUS feedback", func() {
+			err := sess.handleFrames([]frames.Frame{&frames.PLUSFeedbackFrame{Data: []byte("foo")}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tracer.receivedFeedback).To(Equal([][]byte{[]byte("foo")}))
+		})
+
+		Context("received packets", func() {
+			var hdr *PublicHeader
+
+			BeforeEach(func() {
+				sess.unpacker = &mockUnpacker{}
+				hdr = &PublicHeader{PacketNumberLen: protocol.PacketNumberLen6, PacketNumber: 5}
+			})
+
+			It("traces received and duplicate packets", func() {
+				err := sess.handlePacketImpl(&receivedPacket{publicHeader: hdr})
+				Expect(err).ToNot(HaveOccurred())
+				err = sess.handlePacketImpl(&receivedPacket{publicHeader: hdr})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tracer.receivedPackets).To(Equal([]protocol.PacketNumber{5}))
+				Expect(tracer.droppedPackets).To(Equal([]PacketDropReason{PacketDropDuplicate}))
+			})
+
+			It("traces packets below the LeastUnacked of a StopWaiting", func() {
+				err := sess.receivedPacketHandler.ReceivedStopWaiting(&frames.StopWaitingFrame{LeastUnacked: 10})
+				Expect(err).ToNot(HaveOccurred())
+				err = sess.handlePacketImpl(&receivedPacket{publicHeader: hdr})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tracer.receivedPackets).To(BeEmpty())
+				Expect(tracer.droppedPackets).To(Equal([]PacketDropReason{PacketDropBelowStopWaiting}))
+			})
+
+			It("traces packets dropped because the queue is full", func() {
+				for i := 0; i < protocol.MaxSessionUnprocessedPackets; i++ {
+					sess.handlePacket(&receivedPacket{publicHeader: hdr})
+				}
+				Expect(tracer.droppedPackets).To(BeEmpty())
+				sess.handlePacket(&receivedPacket{publicHeader: hdr})
+				Expect(tracer.droppedPackets).To(Equal([]PacketDropReason{PacketDropQueueFull}))
+			})
+
+			It("traces undecryptable packets", func() {
+				sess.tryQueueingUndecryptablePacket(&receivedPacket{publicHeader: hdr})
+				Expect(tracer.bufferedPackets).To(Equal(1))
+				sess.handshakeComplete = true
+				sess.tryQueueingUndecryptablePacket(&receivedPacket{publicHeader: hdr})
+				Expect(tracer.droppedPackets).To(Equal([]PacketDropReason{PacketDropUndecryptable}))
+			})
+		})
+
+		It("traces received window updates", func() {
+			err := sess.handleWindowUpdateFrame(&frames.WindowUpdateFrame{StreamID: 0, ByteOffset: 1000})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tracer.receivedWindowUpdates).To(Equal([]frames.WindowUpdateFrame{{StreamID: 0, ByteOffset: 1000}}))
+		})
+
+		It("traces lost and retransmitted packets, and congestion state changes", func() {
+			sess.traceCongestionState()
+			Expect(tracer.congestionStates).To(HaveLen(1))
+			sess.traceCongestionState()
+			Expect(tracer.congestionStates).To(HaveLen(1))
+			for i := 0; i < 2; i++ {
+				sess.queuePLUSFeedbackFrame([]byte("feedback"))
+				err := sess.sendPacket()
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(tracer.sentPackets).To(Equal([]protocol.PacketNumber{1, 2}))
+			// RTO
+			sess.sentPacketHandler.OnAlarm()
+			Expect(tracer.lostPackets).To(Equal([]protocol.PacketNumber{1, 2}))
+			sess.traceCongestionState()
+			Expect(tracer.congestionStates).To(HaveLen(2))
+			Expect(tracer.congestionStates[1].CongestionWindow).To(BeNumerically("<", tracer.congestionStates[0].CongestionWindow))
+			err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tracer.retransmittedPackets).To(ConsistOf(protocol.PacketNumber(1), protocol.PacketNumber(2)))
+		})
+
+		It("traces when the connection is closed", func() {
+			go sess.run()
+			sess.Close(nil)
+			Expect(tracer.closed).To(BeTrue())
+			Expect(tracer.closeErr).To(Equal(qerr.PeerGoingAway))
+		})
+	})
+
+	Context("connection statistics", func() {
+		BeforeEach(func() {
+			sess.rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+			_, err := sess.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(areSessionsRunning).Should(BeFalse())
+			go sess.run()
+		})
+
+		It("gets a snapshot of the statistics from the run loop", func() {
+			stats := sess.ConnectionStats()
+			Expect(stats.SmoothedRTT).To(Equal(50 * time.Millisecond))
+			Expect(stats.MinRTT).To(Equal(50 * time.Millisecond))
+			Expect(stats.LatestRTT).To(Equal(50 * time.Millisecond))
+			Expect(stats.CongestionWindow).ToNot(BeZero())
+			Expect(stats.SlowStartThreshold).ToNot(BeZero())
+			Expect(stats.Streams).To(HaveKey(protocol.StreamID(5)))
+			sess.Close(nil)
+			Eventually(areSessionsRunning).Should(BeFalse())
+		})
+
+		It("returns the statistics at the time the connection was closed", func() {
+			sess.Close(nil)
+			Eventually(sess.runClosed).Should(BeClosed())
+			stats := sess.ConnectionStats()
+			Expect(stats.SmoothedRTT).To(Equal(50 * time.Millisecond))
+			Expect(stats.Streams).To(HaveKey(protocol.StreamID(5)))
+		})
+	})
+
+	Context("handling GOAWAY frames", func() {
+		It("refuses new streams", func() {
+			err := sess.handleFrames([]frames.Frame{&frames.GoawayFrame{LastGoodStream: 2}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.draining).To(BeTrue())
+			Expect(sess.drainDeadline).To(BeZero())
+			_, err = sess.OpenStream()
+			Expect(err).To(Equal(&GoawayError{Remote: true}))
+			_, err = sess.AcceptStream()
+			Expect(err).To(Equal(&GoawayError{Remote: true}))
+		})
+
+		It("cancels the streams that the peer didn't process", func() {
+			str2, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			str4, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			err = sess.handleFrames([]frames.Frame{&frames.GoawayFrame{LastGoodStream: 2}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str2.(*stream).cancelled.Get()).To(BeFalse())
+			_, err = str4.Write([]byte("foobar"))
+			Expect(err).To(MatchError(&GoawayError{Remote: true}))
+		})
+
+		It("closes the connection once all streams have finished", func() {
+			str, err := sess.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			err = sess.handleFrames([]frames.Frame{&frames.GoawayFrame{LastGoodStream: 3}})
+			Expect(err).ToNot(HaveOccurred())
+			go sess.run()
+			Eventually(areSessionsRunning).Should(BeTrue())
+			Consistently(areSessionsRunning).Should(BeTrue())
+			str.(*stream).Cancel(errors.New("done"))
+			sess.scheduleSending()
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(mconn.written[0]).To(ContainSubstring(string([]byte{0x02, byte(qerr.PeerGoingAway), 0, 0, 0, 0, 0})))
+		})
+	})
+
+	Context("closing gracefully", func() {
+		BeforeEach(func() {
+			Eventually(areSessionsRunning).Should(BeFalse())
+			go sess.run()
+			Eventually(areSessionsRunning).Should(BeTrue())
+		})
+
+		It("sends a GOAWAY frame and closes the connection", func() {
+			err := sess.CloseGracefully(time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.runClosed).To(BeClosed())
+			Expect(mconn.written).To(HaveLen(2))
+			// GOAWAY frame, with the crypto stream as the last good stream
+			Expect(mconn.written[0]).To(ContainSubstring(string([]byte{0x03, byte(qerr.PeerGoingAway), 0, 0, 0, 1, 0, 0, 0, 0, 0})))
+			Expect(mconn.written[1]).To(ContainSubstring(string([]byte{0x02, byte(qerr.PeerGoingAway), 0, 0, 0, 0, 0})))
+		})
+
+		It("refuses new streams", func() {
+			str, err := sess.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			go sess.CloseGracefully(time.Hour)
+			Eventually(func() error {
+				sess.streamsMap.mutex.RLock()
+				defer sess.streamsMap.mutex.RUnlock()
+				return sess.streamsMap.goawayErr
+			}).ShouldNot(BeNil())
+			_, err = sess.OpenStream()
+			Expect(err).To(Equal(&GoawayError{}))
+			// streams opened by the peer after the GOAWAY frame are ignored
+			str5, err := sess.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str5).To(BeNil())
+			str.(*stream).Cancel(errors.New("done"))
+			sess.scheduleSending()
+			Eventually(areSessionsRunning).Should(BeFalse())
+		})
+
+		It("waits for the streams to finish", func() {
+			str, err := sess.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				err := sess.CloseGracefully(time.Hour)
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			str.(*stream).Cancel(errors.New("done"))
+			sess.scheduleSending()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the connection when the timeout expires", func() {
+			str, err := sess.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			err = sess.CloseGracefully(50 * time.Millisecond)
+			Expect(err).To(MatchError(errGracefulCloseTimeout))
+			_, err = str.Read([]byte{0})
+			Expect(err).To(MatchError(errGracefulCloseTimeout))
+		})
+
+		It("returns immediately if the connection is already closed", func() {
+			sess.Close(nil)
+			Expect(sess.CloseGracefully(time.Hour)).To(Succeed())
+		})
 	})
 
 	It("handles STOP_WAITING frames", func() {
@@ -746,6 +1305,140 @@ var _ = Describe("Session", func() {
 		})
 	})
 
+	Context("signaling the end of the flow", func() {
+		var pconn *mockPLUSConnection
+
+		BeforeEach(func() {
+			pconn = &mockPLUSConnection{}
+			sess.conn = pconn
+		})
+
+		It("sends the CONNECTION_CLOSE as the last packet", func() {
+			go sess.run()
+			sess.Close(nil)
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(pconn.written).To(BeEmpty())
+			Expect(pconn.stopped).To(HaveLen(1))
+			Expect(pconn.stopped[0]).To(ContainSubstring(string([]byte{0x02, byte(qerr.PeerGoingAway), 0, 0, 0, 0, 0})))
+		})
+
+		It("sends the Public Reset as the last packet", func() {
+			go sess.run()
+			sess.Close(handshake.ErrHOLExperiment)
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(pconn.written).To(BeEmpty())
+			Expect(pconn.stopped).To(HaveLen(1))
+			Expect(pconn.stopped[0][0] & 0x02).ToNot(BeZero()) // Public Reset
+		})
+
+		It("signals the end of the flow on idle timeouts", func(done Done) {
+			sess.lastNetworkActivityTime = time.Now().Add(-time.Hour)
+			sess.run() // Would normally not return
+			Expect(pconn.stopped).To(HaveLen(1))
+			Expect(pconn.stopped[0]).To(ContainSubstring("No recent network activity."))
+			close(done)
+		})
+
+		It("confirms the stop when the peer closed the connection", func() {
+			go sess.run()
+			sess.registerClose(qerr.Error(qerr.InternalError, "foobar"), true)
+			Eventually(sess.runClosed).Should(BeClosed())
+			Expect(pconn.written).To(BeEmpty())
+			Expect(pconn.stopped).To(HaveLen(1))
+			Expect(pconn.stopped[0]).To(ContainSubstring(string([]byte{0x02, byte(qerr.PeerGoingAway), 0, 0, 0, 0, 0})))
+		})
+
+		It("doesn't send anything when the peer closed a non-PLUS connection", func() {
+			sess.conn = mconn
+			go sess.run()
+			sess.registerClose(qerr.Error(qerr.PeerGoingAway, ""), true)
+			Eventually(sess.runClosed).Should(BeClosed())
+			Expect(mconn.written).To(BeEmpty())
+		})
+
+		It("repeats the stop packet when packets arrive after closing", func() {
+			go sess.run()
+			Expect(sess.sentStopPacket()).To(BeFalse())
+			sess.Close(nil)
+			Expect(sess.sentStopPacket()).To(BeTrue())
+			Expect(pconn.stopped).To(HaveLen(1))
+			for i := 0; i < 8; i++ {
+				sess.handlePacket(&receivedPacket{})
+			}
+			// repeated for the 1st, 2nd, 4th and 8th packet
+			Expect(pconn.stopped).To(HaveLen(5))
+			for _, p := range pconn.stopped[1:] {
+				Expect(p).To(Equal(pconn.stopped[0]))
+			}
+			Expect(pconn.written).To(BeEmpty())
+		})
+
+		It("doesn't repeat anything for non-PLUS connections", func() {
+			sess.conn = mconn
+			go sess.run()
+			sess.Close(nil)
+			Expect(sess.sentStopPacket()).To(BeFalse())
+			Expect(mconn.written).To(HaveLen(1))
+			sess.handlePacket(&receivedPacket{})
+			Expect(mconn.written).To(HaveLen(1))
+		})
+	})
+
+	Context("using the fake PLUS layer", func() {
+		var (
+			rec        *fakePLUSRecorder
+			clientConn *memPacketConn
+			serverConn *memPacketConn
+		)
+
+		BeforeEach(func() {
+			var fakeTransport *plusTransport
+			fakeTransport, rec = newFakePLUSTransport()
+			clientConn, serverConn = newMemPacketConnPair()
+			_, conn, err := fakeTransport.Dial(clientConn, serverConn.LocalAddr(), sess.connectionID)
+			Expect(err).ToNot(HaveOccurred())
+			sess.conn = conn
+		})
+
+		AfterEach(func() {
+			clientConn.Close()
+			serverConn.Close()
+		})
+
+		It("sends packets in PLUS packets", func() {
+			sess.queueResetStreamFrame(5, 0)
+			Expect(sess.sendPacket()).To(Succeed())
+			sent := rec.sentPackets()
+			Expect(sent).To(HaveLen(1))
+			Expect(sent[0].cat).To(Equal(uint64(sess.connectionID)))
+			Expect(sent[0].stop).To(BeFalse())
+			Expect(sent[0].pcfRequest).To(BeNil())
+		})
+
+		It("sends PCF requests in the PLUS header", func() {
+			err := sess.RequestPCF(&PCFRequest{Type: 1, Integrity: 2, Value: []byte("foobar")})
+			Expect(err).ToNot(HaveOccurred())
+			sess.queueResetStreamFrame(5, 0)
+			Expect(sess.sendPacket()).To(Succeed())
+			sent := rec.sentPackets()
+			Expect(sent).To(HaveLen(1))
+			Expect(sent[0].pcfRequest).To(Equal(&fakePCFRequest{pcfType: 1, integrity: 2, value: []byte("foobar")}))
+		})
+
+		It("sets the stop flag on the CONNECTION_CLOSE", func() {
+			sess.queueResetStreamFrame(5, 0)
+			Expect(sess.sendPacket()).To(Succeed())
+			go sess.run()
+			sess.Close(nil)
+			Eventually(sess.runClosed).Should(BeClosed())
+			sent := rec.sentPackets()
+			Expect(sent).To(HaveLen(2))
+			Expect(sent[0].stop).To(BeFalse())
+			Expect(sent[1].stop).To(BeTrue())
+			Expect(sent[1].payload).To(ContainSubstring(string([]byte{0x02, byte(qerr.PeerGoingAway), 0, 0, 0, 0, 0})))
+		})
+	})
+
 	Context("receiving packets", func() {
 		var hdr *PublicHeader
 
@@ -805,6 +1498,46 @@ var _ = Describe("Session", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("echoes PLUS feedback in a PLUSFeedbackFrame", func() {
+			hdr.PacketNumber = 5
+			err := sess.handlePacketImpl(&receivedPacket{publicHeader: hdr, feedbackData: []byte("feedback")})
+			Expect(err).ToNot(HaveOccurred())
+			err = sess.sendPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(mconn.written[0]).To(ContainSubstring(string(append([]byte{0x08, 0x01, 0x00, 0x08}, []byte("feedback")...))))
+		})
+
+		It("fragments PLUS feedback that doesn't fit into a single packet", func() {
+			feedback := bytes.Repeat([]byte{'f'}, int(2*protocol.MaxPLUSFeedbackFragmentSize)+1)
+			hdr.PacketNumber = 5
+			err := sess.handlePacketImpl(&receivedPacket{publicHeader: hdr, feedbackData: feedback})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.nextPLUSFeedbackID).To(Equal(uint64(1)))
+			for i := 0; i < 3; i++ {
+				err = sess.sendPacket()
+				Expect(err).ToNot(HaveOccurred())
+			}
+			var written []byte
+			for _, p := range mconn.written {
+				Expect(len(p)).To(BeNumerically("<=", protocol.MaxPacketSize))
+				written = append(written, p...)
+			}
+			Expect(written).To(ContainSubstring(string([]byte{0x08, 0x01, 0x03, 0x00, 0x00, 0xe8, 0x07})))
+			Expect(written).To(ContainSubstring(string([]byte{0x08, 0x01, 0x03, 0x00, 0xe8, 0x07, 0xe8, 0x07})))
+			Expect(written).To(ContainSubstring(string([]byte{0x08, 0x01, 0x01, 0x00, 0xd0, 0x0f, 0x01, 'f'})))
+		})
+
+		It("doesn't echo PLUS feedback of packets that can't be unpacked", func() {
+			hdr.PacketNumber = 5
+			sess.unpacker.(*mockUnpacker).unpackErr = errors.New("unpack error")
+			err := sess.handlePacketImpl(&receivedPacket{publicHeader: hdr, feedbackData: []byte("feedback")})
+			Expect(err).To(HaveOccurred())
+			err = sess.sendPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(BeEmpty())
+		})
+
 		Context("updating the remote address", func() {
 			It("sets the remote address", func() {
 				remoteIP := &net.IPAddr{IP: net.IPv4(192, 168, 0, 100)}
@@ -904,6 +1637,67 @@ var _ = Describe("Session", func() {
 			Expect(sentPackets[0].EncryptionLevel).To(Equal(protocol.EncryptionSecure))
 			Expect(sentPackets[0].Length).To(BeEquivalentTo(len(mconn.written[0])))
 		})
+
+		It("doesn't send packets before the pacer allows it", func() {
+			sph := newMockSentPacketHandler().(*mockSentPacketHandler)
+			sph.pacingDeadline = time.Now().Add(time.Hour)
+			sess.sentPacketHandler = sph
+			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
+			str, err := sess.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			str.(*stream).dataForWriting = []byte("foobar")
+			err = sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mconn.written).To(BeEmpty())
+			Expect(sess.pacingDeadline).To(Equal(sph.pacingDeadline))
+			sph.pacingDeadline = time.Now().Add(-time.Millisecond)
+			err = sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(sess.pacingDeadline).To(BeZero())
+		})
+
+		It("sends ACKs and control frames, but no stream data, while the pacer delays sending", func() {
+			sph := newMockSentPacketHandler().(*mockSentPacketHandler)
+			sph.pacingDeadline = time.Now().Add(time.Hour)
+			sess.sentPacketHandler = sph
+			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
+			str, err := sess.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			str.(*stream).dataForWriting = []byte("foobar")
+			sess.receivedPacketHandler.ReceivedPacket(0x035E, true)
+			// a StopWaitingFrame is added, so make sure the packet number is higher than its LeastUnacked
+			sess.packer.packetNumberGenerator.next = 0x1337 + 10
+			sess.packer.QueueControlFrameForNextPacket(&frames.GoawayFrame{ErrorCode: qerr.PeerGoingAway, LastGoodStream: 5})
+			err = sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(sess.pacingDeadline).To(Equal(sph.pacingDeadline))
+			Expect(sph.sentPackets).To(HaveLen(1))
+			Expect(sph.sentPackets[0].Frames).To(ContainElement(BeAssignableToTypeOf(&frames.AckFrame{})))
+			Expect(sph.sentPackets[0].Frames).To(ContainElement(BeAssignableToTypeOf(&frames.GoawayFrame{})))
+			Expect(sph.sentPackets[0].Frames).ToNot(ContainElement(BeAssignableToTypeOf(&frames.StreamFrame{})))
+			Expect(mconn.written[0]).ToNot(ContainSubstring("foobar"))
+			// the stream data is sent when the pacer allows it
+			sph.pacingDeadline = time.Now().Add(-time.Millisecond)
+			err = sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(2))
+			Expect(mconn.written[1]).To(ContainSubstring("foobar"))
+		})
+
+		It("sends WindowUpdates while the pacer delays sending", func() {
+			sph := newMockSentPacketHandler().(*mockSentPacketHandler)
+			sph.pacingDeadline = time.Now().Add(time.Hour)
+			sess.sentPacketHandler = sph
+			_, err := sess.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			sess.flowControlManager.AddBytesRead(5, protocol.ReceiveStreamFlowControlWindow)
+			err = sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sph.sentPackets).To(HaveLen(1))
+			Expect(sph.sentPackets[0].Frames).To(ContainElement(BeAssignableToTypeOf(&frames.WindowUpdateFrame{})))
+		})
 	})
 
 	Context("retransmissions", func() {
@@ -1151,6 +1945,21 @@ var _ = Describe("Session", func() {
 			Expect(mconn.written[0]).To(ContainSubstring(string([]byte{0x37, 0x13})))
 		})
 
+		It("sets the timer to the next pacing slot", func() {
+			sph := newMockSentPacketHandler().(*mockSentPacketHandler)
+			sph.pacingDeadline = time.Now().Add(30 * time.Millisecond)
+			sess.sentPacketHandler = sph
+			str, err := sess.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			str.(*stream).dataForWriting = []byte("foobar")
+			sess.scheduleSending()
+			go sess.run()
+			defer sess.Close(nil)
+			Consistently(func() int { return len(mconn.written) }, 20*time.Millisecond).Should(BeZero())
+			Eventually(func() int { return len(mconn.written) }).Should(Equal(1))
+			Expect(mconn.written[0]).To(ContainSubstring("foobar"))
+		})
+
 		Context("bundling of small packets", func() {
 			It("bundles two small frames of different streams into one packet", func() {
 				s1, err := sess.GetOrOpenStream(5)
@@ -1384,6 +2193,15 @@ var _ = Describe("Session", func() {
 			close(done)
 		})
 
+		It("uses the HandshakeTimeout from the config", func(done Done) {
+			sess.config.HandshakeTimeout = 50 * time.Millisecond
+			go sess.run()
+			Consistently(sess.runClosed, 25*time.Millisecond).ShouldNot(BeClosed())
+			Eventually(sess.runClosed).Should(BeClosed())
+			Expect(mconn.written[0]).To(ContainSubstring("Crypto handshake did not complete in time."))
+			close(done)
+		})
+
 		It("does not use ICSL before handshake", func(done Done) {
 			sess.lastNetworkActivityTime = time.Now().Add(-time.Minute)
 			cpm.idleTime = 99999 * time.Second
@@ -1435,6 +2253,18 @@ var _ = Describe("Session", func() {
 			Expect(ok).To(BeFalse())
 		})
 
+		It("looks up streams without opening them", func() {
+			str := sess.GetStream(11)
+			Expect(str).To(BeNil())
+			// make sure that the returned value is a plain nil, not an Stream with value nil
+			_, ok := str.(Stream)
+			Expect(ok).To(BeFalse())
+			Expect(sess.streamsMap.streams).ToNot(HaveKey(protocol.StreamID(11)))
+			_, err := sess.GetOrOpenStream(11)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.GetStream(11).StreamID()).To(Equal(protocol.StreamID(11)))
+		})
+
 		// all relevant tests for this are in the streamsMap
 		It("opens streams synchronously", func() {
 			str, err := sess.OpenStreamSync()
@@ -1518,6 +2348,13 @@ var _ = Describe("Session", func() {
 		mconn.remoteAddr = addr
 		Expect(sess.RemoteAddr()).To(Equal(addr))
 	})
+
+	It("sets the log level of the connection", func() {
+		Expect(cryptoSetupLogger).To(BeIdenticalTo(sess.logger))
+		sess.SetLogLevel(utils.LogLevelDebug)
+		Expect(sess.logger.Debug()).To(BeTrue())
+		Expect(utils.DefaultLogger.Debug()).To(BeFalse())
+	})
 })
 
 var _ = Describe("Client Session", func() {
@@ -1543,6 +2380,8 @@ var _ = Describe("Client Session", func() {
 			aeadChangedP chan<- protocol.EncryptionLevel,
 			_ *handshake.TransportParameters,
 			_ []protocol.VersionNumber,
+			_ io.Writer,
+			_ utils.Logger,
 		) (handshake.CryptoSetup, error) {
 			aeadChanged = aeadChangedP
 			return cryptoSetup, nil
@@ -1558,7 +2397,6 @@ var _ = Describe("Client Session", func() {
 			0,
 			populateClientConfig(&Config{}),
 			nil,
-			nil,
 		)
 		sess = sessP.(*session)
 		Expect(err).ToNot(HaveOccurred())
@@ -1569,6 +2407,37 @@ var _ = Describe("Client Session", func() {
 		newCryptoSetupClient = handshake.NewCryptoSetupClient
 	})
 
+	Context("closing gracefully", func() {
+		It("waits for stream 3 to finish", func() {
+			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
+			go sess.run()
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.StreamID()).To(Equal(protocol.StreamID(3)))
+			_, err = str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(func() int { return len(mconn.written) }).ShouldNot(BeZero())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(sess.CloseGracefully(time.Hour)).To(Succeed())
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			Expect(sess.Close(nil)).To(Succeed())
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("doesn't wait for static streams", func() {
+			go sess.run()
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			sess.AddStaticStream(str.StreamID())
+			Expect(sess.CloseGracefully(time.Hour)).To(Succeed())
+			Eventually(areSessionsRunning).Should(BeFalse())
+		})
+	})
+
 	Context("receiving packets", func() {
 		var hdr *PublicHeader
 
@@ -1586,6 +2455,22 @@ var _ = Describe("Client Session", func() {
 			Eventually(func() []byte { return cryptoSetup.divNonce }).Should(Equal(hdr.DiversificationNonce))
 			Expect(sess.Close(nil)).To(Succeed())
 		})
+
+		It("echoes PLUS feedback in a PLUSFeedbackFrame", func() {
+			hdr.PacketNumber = 5
+			err := sess.handlePacketImpl(&receivedPacket{publicHeader: hdr, feedbackData: []byte("feedback")})
+			Expect(err).ToNot(HaveOccurred())
+			err = sess.sendPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(mconn.written[0]).To(ContainSubstring(string(append([]byte{0x08, 0x01, 0x00, 0x08}, []byte("feedback")...))))
+		})
+	})
+
+	It("passes PLUSFeedbackFrames to the connection", func() {
+		err := sess.handleFrames([]frames.Frame{&frames.PLUSFeedbackFrame{Data: []byte("feedback")}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mconn.feedback).To(Equal([][]byte{[]byte("feedback")}))
 	})
 
 	It("does not block if an error occurs", func(done Done) {
@@ -3,7 +3,9 @@ package quic
 import (
 	"fmt"
 	"io"
+	"net"
 	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/flowcontrol"
 	"github.com/lucas-clemente/quic-go/frames"
@@ -49,8 +51,29 @@ type stream struct {
 	doneWritingOrErrCond sync.Cond
 
 	flowControlManager flowcontrol.FlowControlManager
+
+	// the deadlines are protected by the mutex. The timers wake up blocked Read and Write calls when a deadline is reached.
+	readDeadline       time.Time
+	readDeadlineTimer  *time.Timer
+	writeDeadline      time.Time
+	writeDeadlineTimer *time.Timer
+
+	// set by SetPriority, protected by the mutex
+	priority protocol.StreamPriority
+	weight   uint16
+	// pass is the virtual time of the streamScheduler, only accessed by the streamFramer
+	pass uint64
 }
 
+// deadlineError is returned by Read and Write when the deadline is reached
+type deadlineError struct{}
+
+func (deadlineError) Error() string   { return "deadline exceeded" }
+func (deadlineError) Temporary() bool { return true }
+func (deadlineError) Timeout() bool   { return true }
+
+var errDeadline net.Error = &deadlineError{}
+
 // newStream creates a new Stream
 func newStream(StreamID protocol.StreamID, onData func(), onReset func(protocol.StreamID, protocol.ByteCount), flowControlManager flowcontrol.FlowControlManager) (*stream, error) {
 	s := &stream{
@@ -59,6 +82,8 @@ func newStream(StreamID protocol.StreamID, onData func(), onReset func(protocol.
 		streamID:           StreamID,
 		flowControlManager: flowControlManager,
 		frameQueue:         newStreamFrameSorter(),
+		priority:           protocol.DefaultStreamPriority,
+		weight:             protocol.DefaultStreamWeight,
 	}
 
 	s.newFrameOrErrCond.L = &s.mutex
@@ -96,6 +121,10 @@ func (s *stream) Read(p []byte) (int, error) {
 				err = s.err
 				break
 			}
+			if deadlineExceeded(s.readDeadline) {
+				err = errDeadline
+				break
+			}
 			if frame != nil {
 				s.readPosInFrame = int(s.readOffset - frame.Offset)
 				break
@@ -160,12 +189,22 @@ func (s *stream) Write(p []byte) (int, error) {
 		return 0, nil
 	}
 
+	if deadlineExceeded(s.writeDeadline) {
+		return 0, errDeadline
+	}
+
 	s.dataForWriting = make([]byte, len(p))
 	copy(s.dataForWriting, p)
 
 	s.onData()
 
 	for s.dataForWriting != nil && s.err == nil {
+		if deadlineExceeded(s.writeDeadline) {
+			// the data that wasn't sent yet is dropped
+			n := len(p) - len(s.dataForWriting)
+			s.dataForWriting = nil
+			return n, errDeadline
+		}
 		s.doneWritingOrErrCond.Wait()
 	}
 
@@ -176,6 +215,53 @@ func (s *stream) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
+// SetReadDeadline sets the deadline for future and currently blocked Read calls
+func (s *stream) SetReadDeadline(t time.Time) error {
+	s.mutex.Lock()
+	s.readDeadline = t
+	s.readDeadlineTimer = s.resetDeadlineTimer(s.readDeadlineTimer, t, &s.newFrameOrErrCond)
+	s.mutex.Unlock()
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for future and currently blocked Write calls
+func (s *stream) SetWriteDeadline(t time.Time) error {
+	s.mutex.Lock()
+	s.writeDeadline = t
+	s.writeDeadlineTimer = s.resetDeadlineTimer(s.writeDeadlineTimer, t, &s.doneWritingOrErrCond)
+	s.mutex.Unlock()
+	return nil
+}
+
+// SetDeadline sets the read and the write deadline
+func (s *stream) SetDeadline(t time.Time) error {
+	s.SetReadDeadline(t)
+	s.SetWriteDeadline(t)
+	return nil
+}
+
+// resetDeadlineTimer stops the timer of the previous deadline, and returns a timer that wakes up the cond when the new deadline is reached
+// It must be called with the mutex held.
+func (s *stream) resetDeadlineTimer(timer *time.Timer, deadline time.Time, cond *sync.Cond) *time.Timer {
+	if timer != nil {
+		timer.Stop()
+	}
+	// the new deadline might already have passed
+	cond.Broadcast()
+	if deadline.IsZero() {
+		return nil
+	}
+	return time.AfterFunc(deadline.Sub(time.Now()), func() {
+		s.mutex.Lock()
+		cond.Broadcast()
+		s.mutex.Unlock()
+	})
+}
+
+func deadlineExceeded(deadline time.Time) bool {
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
 func (s *stream) lenOfDataForWriting() protocol.ByteCount {
 	s.mutex.Lock()
 	var l protocol.ByteCount
@@ -326,3 +412,26 @@ func (s *stream) finished() bool {
 func (s *stream) StreamID() protocol.StreamID {
 	return s.streamID
 }
+
+// SetPriority sets the priority and the weight used by the streamScheduler
+func (s *stream) SetPriority(priority protocol.StreamPriority, weight uint16) {
+	if priority > protocol.LowestStreamPriority {
+		priority = protocol.LowestStreamPriority
+	}
+	if weight < protocol.MinStreamWeight {
+		weight = protocol.MinStreamWeight
+	}
+	if weight > protocol.MaxStreamWeight {
+		weight = protocol.MaxStreamWeight
+	}
+	s.mutex.Lock()
+	s.priority = priority
+	s.weight = weight
+	s.mutex.Unlock()
+}
+
+func (s *stream) getPriority() (protocol.StreamPriority, uint16) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.priority, s.weight
+}
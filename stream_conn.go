@@ -0,0 +1,150 @@
+package quic
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/utils"
+)
+
+var errStreamConnClosed = errors.New("use of closed network connection")
+
+type streamConn struct {
+	Stream
+	session Session
+
+	closed utils.AtomicBool
+}
+
+var _ net.Conn = &streamConn{}
+
+// StreamConn returns a net.Conn that reads from and writes to a stream of a session.
+// LocalAddr and RemoteAddr return the addresses of the session.
+// Closing the net.Conn closes the stream, but not the session.
+func StreamConn(session Session, stream Stream) net.Conn {
+	return &streamConn{Stream: stream, session: session}
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.session.LocalAddr()
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.session.RemoteAddr()
+}
+
+func (c *streamConn) Read(p []byte) (int, error) {
+	if c.closed.Get() {
+		return 0, errStreamConnClosed
+	}
+	n, err := c.Stream.Read(p)
+	if err != nil && c.closed.Get() {
+		return n, errStreamConnClosed
+	}
+	return n, err
+}
+
+func (c *streamConn) Write(p []byte) (int, error) {
+	if c.closed.Get() {
+		return 0, errStreamConnClosed
+	}
+	return c.Stream.Write(p)
+}
+
+// Close closes the stream for writing, and unblocks all Read calls
+func (c *streamConn) Close() error {
+	if c.closed.Get() {
+		return errStreamConnClosed
+	}
+	c.closed.Set(true)
+	c.Stream.SetReadDeadline(time.Now())
+	return c.Stream.Close()
+}
+
+// sessionConn is a net.Conn that owns its session
+type sessionConn struct {
+	streamConn
+
+	closeSessionOnce sync.Once
+}
+
+// Close closes the stream, and closes the session gracefully in the background, such that the remaining data is still delivered
+func (c *sessionConn) Close() error {
+	err := c.streamConn.Close()
+	c.closeSessionOnce.Do(func() {
+		go c.session.CloseGracefully(protocol.StreamConnCloseTimeout)
+	})
+	return err
+}
+
+type streamListener struct {
+	ln Listener
+
+	connChan chan net.Conn
+	// errorChan is closed when the Listener returned an error, which is then stored in err
+	errorChan chan struct{}
+	err       error
+}
+
+var _ net.Listener = &streamListener{}
+
+// StreamListener returns a net.Listener that accepts the sessions of a Listener, and returns the first stream opened by the peer of every session as a net.Conn.
+// Closing such a net.Conn closes its session, after the remaining data was delivered.
+// Sessions are accepted in the background, such that sessions that don't open a stream don't block the others.
+func StreamListener(ln Listener) net.Listener {
+	l := &streamListener{
+		ln:        ln,
+		connChan:  make(chan net.Conn),
+		errorChan: make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *streamListener) run() {
+	for {
+		sess, err := l.ln.Accept()
+		if err != nil {
+			l.err = err
+			close(l.errorChan)
+			return
+		}
+		go l.acceptStream(sess)
+	}
+}
+
+func (l *streamListener) acceptStream(sess Session) {
+	str, err := sess.AcceptStream()
+	if err != nil {
+		// the session was closed before the peer opened a stream
+		return
+	}
+	select {
+	case l.connChan <- &sessionConn{streamConn: streamConn{Stream: str, session: sess}}:
+	case <-l.errorChan:
+		sess.Close(nil)
+	}
+}
+
+// Accept waits for and returns the next connection
+func (l *streamListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.connChan:
+		return conn, nil
+	case <-l.errorChan:
+		return nil, l.err
+	}
+}
+
+// Close closes the Listener, and all its sessions
+func (l *streamListener) Close() error {
+	return l.ln.Close()
+}
+
+// Addr returns the local network addr that the Listener is listening on
+func (l *streamListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
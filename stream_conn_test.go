@@ -0,0 +1,281 @@
+package quic
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/flowcontrol"
+	"github.com/lucas-clemente/quic-go/frames"
+	"github.com/lucas-clemente/quic-go/protocol"
+	"github.com/lucas-clemente/quic-go/testdata"
+	"github.com/lucas-clemente/quic-go/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mockStreamConnSession struct {
+	Session // the methods that aren't needed by the tests panic
+
+	streamToAccept   chan Stream
+	acceptStreamErr  error
+	closed           chan error
+	closedGracefully chan time.Duration
+}
+
+func newMockStreamConnSession() *mockStreamConnSession {
+	return &mockStreamConnSession{
+		streamToAccept:   make(chan Stream, 1),
+		closed:           make(chan error, 1),
+		closedGracefully: make(chan time.Duration, 1),
+	}
+}
+
+func (s *mockStreamConnSession) AcceptStream() (Stream, error) {
+	if s.acceptStreamErr != nil {
+		return nil, s.acceptStreamErr
+	}
+	return <-s.streamToAccept, nil
+}
+func (s *mockStreamConnSession) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 443}
+}
+func (s *mockStreamConnSession) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 1234}
+}
+func (s *mockStreamConnSession) Close(e error) error {
+	s.closed <- e
+	return nil
+}
+func (s *mockStreamConnSession) CloseGracefully(timeout time.Duration) error {
+	s.closedGracefully <- timeout
+	return nil
+}
+
+type mockStreamConnListener struct {
+	sessions chan Session
+	closed   chan struct{}
+}
+
+func (l *mockStreamConnListener) Accept() (Session, error) {
+	select {
+	case sess := <-l.sessions:
+		return sess, nil
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+func (l *mockStreamConnListener) Close() error {
+	close(l.closed)
+	return nil
+}
+func (l *mockStreamConnListener) Addr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 443}
+}
+func (l *mockStreamConnListener) StopAccepting() {
+	panic("not implemented")
+}
+
+var _ = Describe("Stream Conn", func() {
+	var (
+		str  *stream
+		sess *mockStreamConnSession
+	)
+
+	BeforeEach(func() {
+		var streamID protocol.StreamID = 5
+		flowControlManager := flowcontrol.NewFlowControlManager(&mockConnectionParametersManager{}, &congestion.RTTStats{}, utils.DefaultLogger)
+		flowControlManager.NewStream(streamID, true)
+		str, _ = newStream(streamID, func() {}, func(protocol.StreamID, protocol.ByteCount) {}, flowControlManager)
+		sess = newMockStreamConnSession()
+	})
+
+	Context("StreamConn", func() {
+		It("returns the addresses of the session", func() {
+			conn := StreamConn(sess, str)
+			Expect(conn.LocalAddr()).To(Equal(sess.LocalAddr()))
+			Expect(conn.RemoteAddr()).To(Equal(sess.RemoteAddr()))
+		})
+
+		It("reads from the stream", func() {
+			conn := StreamConn(sess, str)
+			str.AddStreamFrame(&frames.StreamFrame{Data: []byte("foobar")})
+			b := make([]byte, 6)
+			n, err := conn.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(6))
+			Expect(b).To(Equal([]byte("foobar")))
+		})
+
+		It("sets the deadlines of the stream", func() {
+			conn := StreamConn(sess, str)
+			conn.SetReadDeadline(time.Now().Add(-time.Second))
+			_, err := conn.Read(make([]byte, 6))
+			Expect(err).To(HaveOccurred())
+			Expect(err.(net.Error).Timeout()).To(BeTrue())
+		})
+
+		It("closes the stream, but not the session", func() {
+			conn := StreamConn(sess, str)
+			Expect(conn.Close()).To(Succeed())
+			Expect(str.finishedWriting.Get()).To(BeTrue())
+			Consistently(sess.closedGracefully).ShouldNot(Receive())
+			Expect(sess.closed).ToNot(Receive())
+		})
+
+		It("unblocks a Read when it is closed", func() {
+			conn := StreamConn(sess, str)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := conn.Read(make([]byte, 6))
+				Expect(err).To(MatchError(errStreamConnClosed))
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			Expect(conn.Close()).To(Succeed())
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("errors when reading or writing after it was closed", func() {
+			conn := StreamConn(sess, str)
+			str.AddStreamFrame(&frames.StreamFrame{Data: []byte("foobar")})
+			Expect(conn.Close()).To(Succeed())
+			_, err := conn.Read(make([]byte, 6))
+			Expect(err).To(MatchError(errStreamConnClosed))
+			_, err = conn.Write([]byte("foobar"))
+			Expect(err).To(MatchError(errStreamConnClosed))
+			Expect(conn.Close()).To(MatchError(errStreamConnClosed))
+		})
+	})
+
+	Context("StreamListener", func() {
+		var (
+			qln *mockStreamConnListener
+			ln  net.Listener
+		)
+
+		BeforeEach(func() {
+			qln = &mockStreamConnListener{
+				sessions: make(chan Session),
+				closed:   make(chan struct{}),
+			}
+			ln = StreamListener(qln)
+		})
+
+		It("returns the address of the Listener", func() {
+			Expect(ln.Addr()).To(Equal(qln.Addr()))
+		})
+
+		It("accepts the first stream of a session", func() {
+			qln.sessions <- sess
+			sess.streamToAccept <- str
+			conn, err := ln.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.RemoteAddr()).To(Equal(sess.RemoteAddr()))
+			str.AddStreamFrame(&frames.StreamFrame{Data: []byte("foobar")})
+			b := make([]byte, 6)
+			_, err = conn.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("foobar")))
+		})
+
+		It("isn't blocked by sessions that don't open a stream", func() {
+			qln.sessions <- newMockStreamConnSession()
+			qln.sessions <- sess
+			sess.streamToAccept <- str
+			conn, err := ln.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.(*sessionConn).Stream).To(Equal(str))
+		})
+
+		It("ignores sessions that are closed before opening a stream", func() {
+			closedSess := newMockStreamConnSession()
+			closedSess.acceptStreamErr = errors.New("session closed")
+			qln.sessions <- closedSess
+			qln.sessions <- sess
+			sess.streamToAccept <- str
+			conn, err := ln.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.(*sessionConn).session).To(Equal(sess))
+		})
+
+		It("closes the session gracefully when the net.Conn is closed", func() {
+			qln.sessions <- sess
+			sess.streamToAccept <- str
+			conn, err := ln.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.Close()).To(Succeed())
+			Expect(str.finishedWriting.Get()).To(BeTrue())
+			Eventually(sess.closedGracefully).Should(Receive(Equal(protocol.StreamConnCloseTimeout)))
+		})
+
+		It("closes the session only once, if the net.Conn is closed multiple times", func() {
+			qln.sessions <- sess
+			sess.streamToAccept <- str
+			conn, err := ln.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.Close()).To(Succeed())
+			Expect(conn.Close()).To(MatchError(errStreamConnClosed))
+			Eventually(sess.closedGracefully).Should(Receive())
+			Consistently(sess.closedGracefully).ShouldNot(Receive())
+		})
+
+		It("returns the error of the Listener, once it is closed", func() {
+			Expect(ln.Close()).To(Succeed())
+			_, err := ln.Accept()
+			Expect(err).To(MatchError("listener closed"))
+		})
+
+		It("closes sessions whose stream is accepted after the Listener was closed", func() {
+			qln.sessions <- sess
+			Expect(ln.Close()).To(Succeed())
+			sess.streamToAccept <- str
+			Eventually(sess.closed).Should(Receive(BeNil()))
+		})
+	})
+
+	Context("using a real client and server", func() {
+		It("delivers all data written before the net.Conn is closed", func() {
+			data := make([]byte, 500*1000)
+			rand.Read(data)
+			serverConn, clientConn := newMemPacketConnPair()
+			ln, err := Listen(serverConn, &Config{TLSConfig: testdata.GetTLSConfig()})
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			sln := StreamListener(ln)
+			go func() {
+				defer GinkgoRecover()
+				conn, err := sln.Accept()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = io.ReadFull(conn, make([]byte, 4))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = conn.Write(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(conn.Close()).To(Succeed())
+			}()
+
+			sess, err := Dial(clientConn, serverConn.LocalAddr(), "quic.clemente.io:1337", &Config{TLSConfig: &tls.Config{InsecureSkipVerify: true}})
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStreamSync()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.StreamID()).To(Equal(protocol.StreamID(3)))
+			conn := StreamConn(sess, str)
+			// the server only accepts the stream once it received data on it
+			_, err = conn.Write([]byte("ping"))
+			Expect(err).ToNot(HaveOccurred())
+			received, err := ioutil.ReadAll(conn)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(received).To(Equal(data))
+			Expect(conn.Close()).To(Succeed())
+			// the server closes the session once the stream finished
+			Eventually(sess.(*session).runClosed, 5*time.Second).Should(BeClosed())
+		})
+	})
+})
@@ -11,15 +11,17 @@ type streamFramer struct {
 	streamsMap *streamsMap
 
 	flowControlManager flowcontrol.FlowControlManager
+	scheduler          *streamScheduler
 
 	retransmissionQueue []*frames.StreamFrame
 	blockedFrameQueue   []*frames.BlockedFrame
 }
 
-func newStreamFramer(streamsMap *streamsMap, flowControlManager flowcontrol.FlowControlManager) *streamFramer {
+func newStreamFramer(streamsMap *streamsMap, flowControlManager flowcontrol.FlowControlManager, scheduling StreamSchedulingMode) *streamFramer {
 	return &streamFramer{
 		streamsMap:         streamsMap,
 		flowControlManager: flowControlManager,
+		scheduler:          newStreamScheduler(scheduling),
 	}
 }
 
@@ -114,6 +116,7 @@ func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount) (res []
 
 		frame.Data = data
 		f.flowControlManager.AddBytesSent(s.streamID, protocol.ByteCount(len(data)))
+		f.scheduler.SentData(s, protocol.ByteCount(len(data)))
 
 		// Finally, check if we are now FC blocked and should queue a BLOCKED frame
 		if f.flowControlManager.RemainingConnectionWindowSize() == 0 {
@@ -135,7 +138,11 @@ func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount) (res []
 		return true, nil
 	}
 
-	f.streamsMap.RoundRobinIterate(fn)
+	if f.scheduler.mode == StreamSchedulingRoundRobin {
+		f.streamsMap.RoundRobinIterate(fn)
+	} else {
+		f.streamsMap.PriorityIterate(f.scheduler, fn)
+	}
 
 	return
 }
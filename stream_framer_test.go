@@ -28,8 +28,8 @@ var _ = Describe("Stream Framer", func() {
 			Data:     []byte{0xDE, 0xCA, 0xFB, 0xAD},
 		}
 
-		stream1 = &stream{streamID: 10}
-		stream2 = &stream{streamID: 11}
+		stream1 = &stream{streamID: 10, priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight}
+		stream2 = &stream{streamID: 11, priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight}
 
 		streamsMap = newStreamsMap(nil, protocol.PerspectiveServer, &mockConnectionParametersManager{})
 		streamsMap.putStream(stream1)
@@ -40,7 +40,7 @@ var _ = Describe("Stream Framer", func() {
 		fcm.sendWindowSizes[stream2.streamID] = protocol.MaxByteCount
 		fcm.sendWindowSizes[retransmittedFrame1.StreamID] = protocol.MaxByteCount
 		fcm.sendWindowSizes[retransmittedFrame2.StreamID] = protocol.MaxByteCount
-		framer = newStreamFramer(streamsMap, fcm)
+		framer = newStreamFramer(streamsMap, fcm, StreamSchedulingRoundRobin)
 	})
 
 	It("says if it has retransmissions", func() {
@@ -136,6 +136,44 @@ var _ = Describe("Stream Framer", func() {
 			Expect(fs[0].StreamID).ToNot(Equal(firstStreamID))
 		})
 
+		It("sends the data of the stream with the highest priority first, with strict priority scheduling", func() {
+			framer = newStreamFramer(streamsMap, fcm, StreamSchedulingStrictPriority)
+			stream1.SetPriority(protocol.LowestStreamPriority, protocol.DefaultStreamWeight)
+			stream2.SetPriority(protocol.HighestStreamPriority, protocol.DefaultStreamWeight)
+			stream1.dataForWriting = bytes.Repeat([]byte("f"), 100)
+			stream2.dataForWriting = bytes.Repeat([]byte("e"), 100)
+			var streamIDs []protocol.StreamID
+			for i := 0; i < 20; i++ {
+				for _, f := range framer.PopStreamFrames(30) {
+					streamIDs = append(streamIDs, f.StreamID)
+				}
+			}
+			Expect(streamIDs).To(ContainElement(stream1.streamID))
+			// stream1 only sends once stream2 has no more data to send
+			for i, id := range streamIDs {
+				if id == stream1.streamID {
+					Expect(streamIDs[i:]).ToNot(ContainElement(stream2.streamID))
+					break
+				}
+			}
+			Expect(stream2.dataForWriting).To(BeEmpty())
+		})
+
+		It("shares the bandwidth according to the weights, with weighted fair scheduling", func() {
+			framer = newStreamFramer(streamsMap, fcm, StreamSchedulingWeightedFair)
+			stream1.SetPriority(protocol.HighestStreamPriority, 30)
+			stream2.SetPriority(protocol.LowestStreamPriority, 60)
+			stream1.dataForWriting = bytes.Repeat([]byte("f"), 1000)
+			stream2.dataForWriting = bytes.Repeat([]byte("e"), 1000)
+			sent := make(map[protocol.StreamID]protocol.ByteCount)
+			for i := 0; i < 30; i++ {
+				for _, f := range framer.PopStreamFrames(20) {
+					sent[f.StreamID] += f.DataLen()
+				}
+			}
+			Expect(sent[stream2.streamID]).To(BeNumerically("~", 2*sent[stream1.streamID], 20))
+		})
+
 		Context("splitting of frames", func() {
 			It("splits off nothing", func() {
 				f := &frames.StreamFrame{
@@ -0,0 +1,69 @@
+package quic
+
+import (
+	"sort"
+
+	"github.com/lucas-clemente/quic-go/protocol"
+)
+
+// passPerByte is the pass a stream with a weight of 1 accumulates for every byte sent.
+// Streams with a larger weight accumulate less, such that they are scheduled more often.
+const passPerByte = protocol.MaxStreamWeight
+
+// The streamScheduler implements StreamSchedulingStrictPriority and StreamSchedulingWeightedFair.
+// It uses stride scheduling: Every stream has a pass, which grows with the amount of data sent on it, divided by its weight.
+// The stream with the smallest pass is scheduled first.
+type streamScheduler struct {
+	mode StreamSchedulingMode
+	// virtualTime is the pass of the stream that sent data last.
+	// Streams that were idle start at the virtualTime, so they don't get to send all the data they missed out on.
+	virtualTime uint64
+}
+
+func newStreamScheduler(mode StreamSchedulingMode) *streamScheduler {
+	return &streamScheduler{mode: mode}
+}
+
+// Sort sorts the streams in the order they should send their data
+func (s *streamScheduler) Sort(streams []*stream) {
+	sorter := &streamSorter{
+		streams:    streams,
+		priorities: make([]protocol.StreamPriority, len(streams)),
+		strict:     s.mode == StreamSchedulingStrictPriority,
+	}
+	for i, str := range streams {
+		sorter.priorities[i], _ = str.getPriority()
+		if str.pass < s.virtualTime {
+			str.pass = s.virtualTime
+		}
+	}
+	// the stable sort keeps the order of streams with the same pass, such that the scheduling is deterministic
+	sort.Stable(sorter)
+}
+
+// SentData must be called when data of a stream was sent
+func (s *streamScheduler) SentData(str *stream, n protocol.ByteCount) {
+	_, weight := str.getPriority()
+	s.virtualTime = str.pass
+	str.pass += uint64(n) * passPerByte / uint64(weight)
+}
+
+type streamSorter struct {
+	streams    []*stream
+	priorities []protocol.StreamPriority
+	strict     bool
+}
+
+func (s *streamSorter) Len() int { return len(s.streams) }
+
+func (s *streamSorter) Less(i, j int) bool {
+	if s.strict && s.priorities[i] != s.priorities[j] {
+		return s.priorities[i] < s.priorities[j]
+	}
+	return s.streams[i].pass < s.streams[j].pass
+}
+
+func (s *streamSorter) Swap(i, j int) {
+	s.streams[i], s.streams[j] = s.streams[j], s.streams[i]
+	s.priorities[i], s.priorities[j] = s.priorities[j], s.priorities[i]
+}
@@ -0,0 +1,110 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Scheduler", func() {
+	var str1, str2, str3 *stream
+
+	BeforeEach(func() {
+		str1 = &stream{streamID: 5, priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight}
+		str2 = &stream{streamID: 7, priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight}
+		str3 = &stream{streamID: 9, priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight}
+	})
+
+	getIDs := func(streams []*stream) []protocol.StreamID {
+		var ids []protocol.StreamID
+		for _, str := range streams {
+			ids = append(ids, str.StreamID())
+		}
+		return ids
+	}
+
+	Context("strict priority", func() {
+		var scheduler *streamScheduler
+
+		BeforeEach(func() {
+			scheduler = newStreamScheduler(StreamSchedulingStrictPriority)
+		})
+
+		It("sorts streams by their priority", func() {
+			str1.SetPriority(5, protocol.DefaultStreamWeight)
+			str2.SetPriority(protocol.HighestStreamPriority, protocol.DefaultStreamWeight)
+			str3.SetPriority(protocol.DefaultStreamPriority, protocol.DefaultStreamWeight)
+			streams := []*stream{str1, str2, str3}
+			scheduler.Sort(streams)
+			Expect(getIDs(streams)).To(Equal([]protocol.StreamID{7, 9, 5}))
+		})
+
+		It("schedules streams with a higher priority first, even if they sent more data", func() {
+			str1.SetPriority(protocol.HighestStreamPriority, protocol.DefaultStreamWeight)
+			str2.SetPriority(protocol.LowestStreamPriority, protocol.DefaultStreamWeight)
+			scheduler.SentData(str1, 1000000)
+			streams := []*stream{str2, str1}
+			scheduler.Sort(streams)
+			Expect(getIDs(streams)).To(Equal([]protocol.StreamID{5, 7}))
+		})
+
+		It("alternates between streams with the same priority", func() {
+			streams := []*stream{str1, str2}
+			scheduler.Sort(streams)
+			Expect(getIDs(streams)).To(Equal([]protocol.StreamID{5, 7}))
+			scheduler.SentData(streams[0], 1000)
+			scheduler.Sort(streams)
+			Expect(getIDs(streams)).To(Equal([]protocol.StreamID{7, 5}))
+		})
+	})
+
+	Context("weighted fair", func() {
+		var scheduler *streamScheduler
+
+		BeforeEach(func() {
+			scheduler = newStreamScheduler(StreamSchedulingWeightedFair)
+		})
+
+		It("ignores the priorities", func() {
+			str2.SetPriority(protocol.HighestStreamPriority, protocol.DefaultStreamWeight)
+			streams := []*stream{str1, str2}
+			scheduler.Sort(streams)
+			Expect(getIDs(streams)).To(Equal([]protocol.StreamID{5, 7}))
+		})
+
+		It("shares the bandwidth according to the weights", func() {
+			str1.SetPriority(protocol.DefaultStreamPriority, 64)
+			str2.SetPriority(protocol.DefaultStreamPriority, 32)
+			str3.SetPriority(protocol.DefaultStreamPriority, 32)
+			streams := []*stream{str1, str2, str3}
+			sent := make(map[protocol.StreamID]int)
+			for i := 0; i < 400; i++ {
+				scheduler.Sort(streams)
+				scheduler.SentData(streams[0], 1000)
+				sent[streams[0].StreamID()]++
+			}
+			Expect(sent[5]).To(Equal(200))
+			Expect(sent[7]).To(Equal(100))
+			Expect(sent[9]).To(Equal(100))
+		})
+
+		It("doesn't let streams that were idle catch up", func() {
+			streams := []*stream{str1}
+			for i := 0; i < 100; i++ {
+				scheduler.Sort(streams)
+				scheduler.SentData(str1, 1000)
+			}
+			// str2 starts sending now
+			streams = []*stream{str1, str2}
+			sent := make(map[protocol.StreamID]int)
+			for i := 0; i < 10; i++ {
+				scheduler.Sort(streams)
+				scheduler.SentData(streams[0], 1000)
+				sent[streams[0].StreamID()]++
+			}
+			Expect(sent[5]).To(BeNumerically("~", 5, 1))
+			Expect(sent[7]).To(BeNumerically("~", 5, 1))
+		})
+	})
+})
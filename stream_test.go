@@ -3,6 +3,7 @@ package quic
 import (
 	"errors"
 	"io"
+	"net"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/congestion"
@@ -112,6 +113,10 @@ func (m *mockFlowControlHandler) UpdateWindow(streamID protocol.StreamID, offset
 	panic("not implemented")
 }
 
+func (m *mockFlowControlHandler) GetStatistics() (flowcontrol.Statistics, map[protocol.StreamID]flowcontrol.Statistics) {
+	panic("not implemented")
+}
+
 var _ = Describe("Stream", func() {
 	var (
 		str          *stream
@@ -137,7 +142,7 @@ var _ = Describe("Stream", func() {
 		resetCalled = false
 		var streamID protocol.StreamID = 1337
 		cpm := &mockConnectionParametersManager{}
-		flowControlManager := flowcontrol.NewFlowControlManager(cpm, &congestion.RTTStats{})
+		flowControlManager := flowcontrol.NewFlowControlManager(cpm, &congestion.RTTStats{}, utils.DefaultLogger)
 		flowControlManager.NewStream(streamID, true)
 		str, _ = newStream(streamID, onData, onReset, flowControlManager)
 	})
@@ -146,6 +151,31 @@ var _ = Describe("Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	Context("priorities", func() {
+		It("has the default priority and weight", func() {
+			priority, weight := str.getPriority()
+			Expect(priority).To(Equal(protocol.DefaultStreamPriority))
+			Expect(weight).To(BeEquivalentTo(protocol.DefaultStreamWeight))
+		})
+
+		It("sets the priority and the weight", func() {
+			str.SetPriority(1, 200)
+			priority, weight := str.getPriority()
+			Expect(priority).To(Equal(protocol.StreamPriority(1)))
+			Expect(weight).To(BeEquivalentTo(200))
+		})
+
+		It("clamps values out of range", func() {
+			str.SetPriority(100, 0)
+			priority, weight := str.getPriority()
+			Expect(priority).To(Equal(protocol.LowestStreamPriority))
+			Expect(weight).To(BeEquivalentTo(protocol.MinStreamWeight))
+			str.SetPriority(0, 1000)
+			_, weight = str.getPriority()
+			Expect(weight).To(BeEquivalentTo(protocol.MaxStreamWeight))
+		})
+	})
+
 	Context("reading", func() {
 		It("reads a single StreamFrame", func() {
 			frame := frames.StreamFrame{
@@ -801,6 +831,100 @@ var _ = Describe("Stream", func() {
 		})
 	})
 
+	Context("deadlines", func() {
+		isTimeoutError := func(err error) bool {
+			nerr, ok := err.(net.Error)
+			return ok && nerr.Timeout()
+		}
+
+		It("times out a blocked Read", func() {
+			str.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+			start := time.Now()
+			n, err := str.Read(make([]byte, 4))
+			Expect(n).To(BeZero())
+			Expect(isTimeoutError(err)).To(BeTrue())
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+
+		It("returns immediately if the read deadline has already passed", func() {
+			str.AddStreamFrame(&frames.StreamFrame{Data: []byte("foobar")})
+			str.SetReadDeadline(time.Now().Add(-time.Second))
+			_, err := str.Read(make([]byte, 6))
+			Expect(isTimeoutError(err)).To(BeTrue())
+		})
+
+		It("unblocks a Read when the deadline is moved to the past", func() {
+			errChan := make(chan error)
+			go func() {
+				_, err := str.Read(make([]byte, 4))
+				errChan <- err
+			}()
+			Consistently(errChan).ShouldNot(Receive())
+			str.SetReadDeadline(time.Now().Add(-time.Second))
+			var err error
+			Eventually(errChan).Should(Receive(&err))
+			Expect(isTimeoutError(err)).To(BeTrue())
+		})
+
+		It("reads data that arrives before the deadline", func() {
+			str.SetReadDeadline(time.Now().Add(time.Hour))
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				str.AddStreamFrame(&frames.StreamFrame{Data: []byte("foobar")})
+			}()
+			b := make([]byte, 6)
+			n, err := str.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(6))
+			Expect(b).To(Equal([]byte("foobar")))
+		})
+
+		It("doesn't time out after the deadline was removed", func() {
+			str.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+			str.SetReadDeadline(time.Time{})
+			errChan := make(chan error)
+			go func() {
+				_, err := str.Read(make([]byte, 4))
+				errChan <- err
+			}()
+			Consistently(errChan).ShouldNot(Receive())
+			str.Cancel(errors.New("test done"))
+			Eventually(errChan).Should(Receive())
+		})
+
+		It("times out a blocked Write, and drops the data that wasn't sent", func() {
+			str.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+			var n int
+			var err error
+			done := make(chan struct{})
+			go func() {
+				n, err = str.Write([]byte("foobar"))
+				close(done)
+			}()
+			Eventually(func() protocol.ByteCount { return str.lenOfDataForWriting() }).Should(Equal(protocol.ByteCount(6)))
+			Expect(str.getDataForWriting(2)).To(Equal([]byte("fo")))
+			Eventually(done).Should(BeClosed())
+			Expect(n).To(Equal(2))
+			Expect(isTimeoutError(err)).To(BeTrue())
+			Expect(str.getDataForWriting(1000)).To(BeNil())
+		})
+
+		It("returns immediately if the write deadline has already passed", func() {
+			str.SetWriteDeadline(time.Now().Add(-time.Second))
+			n, err := str.Write([]byte("foobar"))
+			Expect(n).To(BeZero())
+			Expect(isTimeoutError(err)).To(BeTrue())
+			Expect(str.lenOfDataForWriting()).To(BeZero())
+		})
+
+		It("sets the read and the write deadline", func() {
+			deadline := time.Now().Add(time.Hour)
+			str.SetDeadline(deadline)
+			Expect(str.readDeadline).To(Equal(deadline))
+			Expect(str.writeDeadline).To(Equal(deadline))
+		})
+	})
+
 	Context("flow control, for receiving", func() {
 		BeforeEach(func() {
 			str.flowControlManager = &mockFlowControlHandler{}
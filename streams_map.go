@@ -28,6 +28,10 @@ type streamsMap struct {
 
 	closeErr           error
 	nextStreamToAccept protocol.StreamID
+	// goawayErr is set once a GOAWAY frame was sent or received. No new streams are opened after that.
+	goawayErr error
+	// static streams, e.g. the crypto stream, are not taken into account by HasActiveStreams
+	staticStreams map[protocol.StreamID]bool
 
 	newStream newStreamLambda
 
@@ -49,6 +53,7 @@ func newStreamsMap(newStream newStreamLambda, pers protocol.Perspective, connect
 		openStreams:          make([]protocol.StreamID, 0),
 		newStream:            newStream,
 		connectionParameters: connectionParameters,
+		staticStreams:        map[protocol.StreamID]bool{1: true},
 	}
 	sm.nextStreamOrErrCond.L = &sm.mutex
 	sm.openStreamOrErrCond.L = &sm.mutex
@@ -94,6 +99,11 @@ func (m *streamsMap) GetOrOpenStream(id protocol.StreamID) (*stream, error) {
 		return nil, qerr.Error(qerr.InvalidStreamID, fmt.Sprintf("attempted to open stream %d from server-side", id))
 	}
 
+	// streams opened by the peer after the GOAWAY frame are ignored
+	if m.goawayErr != nil {
+		return nil, nil
+	}
+
 	// sid is the next stream that will be opened
 	sid := m.highestStreamOpenedByPeer + 2
 	// if there is no stream opened yet, and this is the server, stream 1 should be openend
@@ -112,11 +122,21 @@ func (m *streamsMap) GetOrOpenStream(id protocol.StreamID) (*stream, error) {
 	return m.streams[id], nil
 }
 
+// GetStream returns an open stream, or nil if the stream was not opened yet or is already closed.
+// In contrast to GetOrOpenStream, it never opens a stream.
+func (m *streamsMap) GetStream(id protocol.StreamID) *stream {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.streams[id]
+}
+
 func (m *streamsMap) openRemoteStream(id protocol.StreamID) (*stream, error) {
-	if m.numIncomingStreams >= m.connectionParameters.GetMaxIncomingStreams() {
+	maxIncomingStreams := m.connectionParameters.GetMaxIncomingStreams()
+	if m.numIncomingStreams >= maxIncomingStreams {
 		return nil, qerr.TooManyOpenStreams
 	}
-	if id+protocol.MaxNewStreamIDDelta < m.highestStreamOpenedByPeer {
+	maxNewStreamIDDelta := protocol.MaxNewStreamIDDeltaMultiplier * protocol.StreamID(maxIncomingStreams)
+	if m.highestStreamOpenedByPeer > maxNewStreamIDDelta && id < m.highestStreamOpenedByPeer-maxNewStreamIDDelta {
 		return nil, qerr.Error(qerr.InvalidStreamID, fmt.Sprintf("attempted to open stream %d, which is a lot smaller than the highest opened stream, %d", id, m.highestStreamOpenedByPeer))
 	}
 
@@ -169,6 +189,9 @@ func (m *streamsMap) OpenStream() (*stream, error) {
 	if m.closeErr != nil {
 		return nil, m.closeErr
 	}
+	if m.goawayErr != nil {
+		return nil, m.goawayErr
+	}
 	return m.openStreamImpl()
 }
 
@@ -180,6 +203,9 @@ func (m *streamsMap) OpenStreamSync() (*stream, error) {
 		if m.closeErr != nil {
 			return nil, m.closeErr
 		}
+		if m.goawayErr != nil {
+			return nil, m.goawayErr
+		}
 		str, err := m.openStreamImpl()
 		if err == nil {
 			return str, err
@@ -206,6 +232,10 @@ func (m *streamsMap) AcceptStream() (*stream, error) {
 		if ok {
 			break
 		}
+		// the peer won't open any new streams after the GOAWAY frame
+		if m.goawayErr != nil && m.nextStreamToAccept > m.highestStreamOpenedByPeer {
+			return nil, m.goawayErr
+		}
 		m.nextStreamOrErrCond.Wait()
 	}
 	m.nextStreamToAccept += 2
@@ -268,6 +298,45 @@ func (m *streamsMap) RoundRobinIterate(fn streamLambda) error {
 	return nil
 }
 
+// PriorityIterate executes the streamLambda for every open stream, until the streamLambda returns false
+// It prioritizes the crypto- and the header-stream (StreamIDs 1 and 3), the other streams are sorted by the streamScheduler
+func (m *streamsMap) PriorityIterate(scheduler *streamScheduler, fn streamLambda) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, i := range []protocol.StreamID{1, 3} {
+		cont, err := m.iterateFunc(i, fn)
+		if err != nil && err != errMapAccess {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+	}
+
+	streams := make([]*stream, 0, len(m.openStreams))
+	for _, streamID := range m.openStreams {
+		if streamID == 1 || streamID == 3 {
+			continue
+		}
+		if str := m.streams[streamID]; str != nil {
+			streams = append(streams, str)
+		}
+	}
+	scheduler.Sort(streams)
+
+	for _, str := range streams {
+		cont, err := fn(str)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			break
+		}
+	}
+	return nil
+}
+
 func (m *streamsMap) iterateFunc(streamID protocol.StreamID, fn streamLambda) (bool, error) {
 	str, ok := m.streams[streamID]
 	if !ok {
@@ -317,6 +386,39 @@ func (m *streamsMap) RemoveStream(id protocol.StreamID) error {
 	return nil
 }
 
+// GoAway is called when a GOAWAY frame is sent or received
+// OpenStream, OpenStreamSync and AcceptStream then return err, and streams opened by the peer afterwards are ignored.
+// It returns the highest stream ID opened by the peer, i.e. the last good stream of the GOAWAY frame.
+func (m *streamsMap) GoAway(err error) protocol.StreamID {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.goawayErr == nil {
+		m.goawayErr = err
+	}
+	m.nextStreamOrErrCond.Broadcast()
+	m.openStreamOrErrCond.Broadcast()
+	return m.highestStreamOpenedByPeer
+}
+
+// AddStaticStream marks a stream as static. Static streams live as long as the session, so HasActiveStreams doesn't take them into account.
+func (m *streamsMap) AddStaticStream(id protocol.StreamID) {
+	m.mutex.Lock()
+	m.staticStreams[id] = true
+	m.mutex.Unlock()
+}
+
+// HasActiveStreams says if there are any open streams, other than the static streams (e.g. the crypto stream)
+func (m *streamsMap) HasActiveStreams() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for id := range m.streams {
+		if !m.staticStreams[id] {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *streamsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
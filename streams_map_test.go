@@ -60,7 +60,7 @@ var _ = Describe("Streams Map", func() {
 	setNewStreamsMap := func(p protocol.Perspective) {
 		m = newStreamsMap(nil, p, cpm)
 		m.newStream = func(id protocol.StreamID) (*stream, error) {
-			return &stream{streamID: id}, nil
+			return &stream{streamID: id, priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight}, nil
 		}
 	}
 
@@ -115,6 +115,21 @@ var _ = Describe("Streams Map", func() {
 					Expect(s).To(BeNil())
 				})
 
+				It("looks up streams without opening them", func() {
+					Expect(m.GetStream(5)).To(BeNil())
+					Expect(m.streams).To(BeEmpty())
+					_, err := m.GetOrOpenStream(5)
+					Expect(err).NotTo(HaveOccurred())
+					s := m.GetStream(5)
+					Expect(s).ToNot(BeNil())
+					Expect(s.StreamID()).To(Equal(protocol.StreamID(5)))
+					Expect(m.GetStream(7)).To(BeNil())
+					Expect(m.numIncomingStreams).To(BeEquivalentTo(3))
+					err = m.RemoveStream(5)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(m.GetStream(5)).To(BeNil())
+				})
+
 				It("opens skipped streams", func() {
 					_, err := m.GetOrOpenStream(5)
 					Expect(err).NotTo(HaveOccurred())
@@ -483,6 +498,105 @@ var _ = Describe("Streams Map", func() {
 		})
 	})
 
+	Context("going away", func() {
+		testErr := errors.New("going away")
+
+		BeforeEach(func() {
+			setNewStreamsMap(protocol.PerspectiveServer)
+		})
+
+		It("returns the highest stream opened by the peer", func() {
+			_, err := m.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.GoAway(testErr)).To(Equal(protocol.StreamID(5)))
+		})
+
+		It("refuses to open new streams", func() {
+			m.GoAway(testErr)
+			_, err := m.OpenStream()
+			Expect(err).To(MatchError(testErr))
+			_, err = m.OpenStreamSync()
+			Expect(err).To(MatchError(testErr))
+		})
+
+		It("stops OpenStreamSync from waiting", func() {
+			for i := 0; i < int(cpm.GetMaxOutgoingStreams()); i++ {
+				_, err := m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+			}
+			errChan := make(chan error, 1)
+			go func() {
+				_, err := m.OpenStreamSync()
+				errChan <- err
+			}()
+			Consistently(errChan).ShouldNot(Receive())
+			m.GoAway(testErr)
+			Eventually(errChan).Should(Receive(MatchError(testErr)))
+		})
+
+		It("ignores streams opened by the peer afterwards", func() {
+			_, err := m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			m.GoAway(testErr)
+			str, err := m.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(BeNil())
+			str, err = m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).ToNot(BeNil())
+		})
+
+		It("accepts the streams opened before, and then errors", func() {
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			m.GoAway(testErr)
+			str, err := m.AcceptStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.StreamID()).To(Equal(protocol.StreamID(1)))
+			_, err = m.AcceptStream()
+			Expect(err).To(MatchError(testErr))
+		})
+
+		It("stops AcceptStream from waiting", func() {
+			errChan := make(chan error, 1)
+			go func() {
+				_, err := m.AcceptStream()
+				errChan <- err
+			}()
+			Consistently(errChan).ShouldNot(Receive())
+			m.GoAway(testErr)
+			Eventually(errChan).Should(Receive(MatchError(testErr)))
+		})
+
+		It("keeps the first error", func() {
+			m.GoAway(testErr)
+			m.GoAway(errors.New("another error"))
+			_, err := m.OpenStream()
+			Expect(err).To(MatchError(testErr))
+		})
+
+		It("says if there are active streams", func() {
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.HasActiveStreams()).To(BeFalse())
+			_, err = m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.HasActiveStreams()).To(BeTrue())
+		})
+
+		It("doesn't take static streams into account", func() {
+			_, err := m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			m.AddStaticStream(3)
+			Expect(m.HasActiveStreams()).To(BeFalse())
+			_, err = m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.HasActiveStreams()).To(BeTrue())
+		})
+	})
+
 	Context("DoS mitigation, iterating and deleting", func() {
 		BeforeEach(func() {
 			setNewStreamsMap(protocol.PerspectiveServer)
@@ -491,7 +605,7 @@ var _ = Describe("Streams Map", func() {
 		Context("deleting streams", func() {
 			BeforeEach(func() {
 				for i := 1; i <= 5; i++ {
-					err := m.putStream(&stream{streamID: protocol.StreamID(i)})
+					err := m.putStream(&stream{streamID: protocol.StreamID(i), priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight})
 					Expect(err).ToNot(HaveOccurred())
 				}
 				Expect(m.openStreams).To(Equal([]protocol.StreamID{1, 2, 3, 4, 5}))
@@ -536,7 +650,7 @@ var _ = Describe("Streams Map", func() {
 			// create 3 streams, ids 1 to 3
 			BeforeEach(func() {
 				for i := 1; i <= 3; i++ {
-					err := m.putStream(&stream{streamID: protocol.StreamID(i)})
+					err := m.putStream(&stream{streamID: protocol.StreamID(i), priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight})
 					Expect(err).NotTo(HaveOccurred())
 				}
 			})
@@ -592,7 +706,7 @@ var _ = Describe("Streams Map", func() {
 				lambdaCalledForStream = lambdaCalledForStream[:0]
 				numIterations = 0
 				for i := 4; i <= 8; i++ {
-					err := m.putStream(&stream{streamID: protocol.StreamID(i)})
+					err := m.putStream(&stream{streamID: protocol.StreamID(i), priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight})
 					Expect(err).NotTo(HaveOccurred())
 				}
 			})
@@ -674,9 +788,9 @@ var _ = Describe("Streams Map", func() {
 
 			Context("Prioritizing crypto- and header streams", func() {
 				BeforeEach(func() {
-					err := m.putStream(&stream{streamID: 1})
+					err := m.putStream(&stream{streamID: 1, priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight})
 					Expect(err).NotTo(HaveOccurred())
-					err = m.putStream(&stream{streamID: 3})
+					err = m.putStream(&stream{streamID: 3, priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight})
 					Expect(err).NotTo(HaveOccurred())
 				})
 
@@ -697,5 +811,59 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 		})
+
+		Context("PriorityIterate", func() {
+			var (
+				lambdaCalledForStream []protocol.StreamID
+				scheduler             *streamScheduler
+			)
+
+			fn := func(str *stream) (bool, error) {
+				lambdaCalledForStream = append(lambdaCalledForStream, str.StreamID())
+				return true, nil
+			}
+
+			BeforeEach(func() {
+				lambdaCalledForStream = lambdaCalledForStream[:0]
+				scheduler = newStreamScheduler(StreamSchedulingStrictPriority)
+				for i := 4; i <= 6; i++ {
+					err := m.putStream(&stream{streamID: protocol.StreamID(i), priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight})
+					Expect(err).NotTo(HaveOccurred())
+				}
+			})
+
+			It("executes the lambda for every stream, in the order of the scheduler", func() {
+				m.streams[6].SetPriority(protocol.HighestStreamPriority, protocol.DefaultStreamWeight)
+				err := m.PriorityIterate(scheduler, fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{6, 4, 5}))
+			})
+
+			It("gets crypto- and header stream first", func() {
+				Expect(m.putStream(&stream{streamID: 1, priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight})).To(Succeed())
+				Expect(m.putStream(&stream{streamID: 3, priority: protocol.DefaultStreamPriority, weight: protocol.DefaultStreamWeight})).To(Succeed())
+				m.streams[6].SetPriority(protocol.HighestStreamPriority, protocol.DefaultStreamWeight)
+				err := m.PriorityIterate(scheduler, fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{1, 3, 6, 4, 5}))
+			})
+
+			It("stops when the lambda returns false", func() {
+				err := m.PriorityIterate(scheduler, func(str *stream) (bool, error) {
+					lambdaCalledForStream = append(lambdaCalledForStream, str.StreamID())
+					return false, nil
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4}))
+			})
+
+			It("returns the error of the lambda", func() {
+				testErr := errors.New("test error")
+				err := m.PriorityIterate(scheduler, func(str *stream) (bool, error) {
+					return true, testErr
+				})
+				Expect(err).To(MatchError(testErr))
+			})
+		})
 	})
 })
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 )
 
 // LogLevel of quic-go
@@ -31,6 +32,81 @@ var (
 	mutex sync.Mutex
 )
 
+// A Logger logs.
+// Sessions use a child logger created by WithPrefix, such that log lines of concurrent connections can be told apart.
+type Logger interface {
+	// SetLogLevel sets the log level of this logger, and of the child loggers created afterwards.
+	SetLogLevel(LogLevel)
+	// WithPrefix creates a child logger that prefixes every line with prefix, after the prefix of the parent.
+	WithPrefix(prefix string) Logger
+	// Debug returns true if the log level is LogLevelDebug
+	Debug() bool
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// DefaultLogger is used if no Logger is set in the quic.Config.
+// Until its level is set, it uses the global log level, which is read from the QUIC_GO_LOG_LEVEL environment variable, and set by SetLogLevel.
+var DefaultLogger Logger = &defaultLogger{}
+
+type defaultLogger struct {
+	prefix string
+	// level is accessed atomically. If it is 0, the global log level is used.
+	level uint32
+}
+
+var _ Logger = &defaultLogger{}
+
+func (l *defaultLogger) SetLogLevel(level LogLevel) {
+	atomic.StoreUint32(&l.level, uint32(level))
+}
+
+func (l *defaultLogger) WithPrefix(prefix string) Logger {
+	if l.prefix != "" {
+		prefix = l.prefix + " " + prefix
+	}
+	return &defaultLogger{
+		prefix: prefix,
+		level:  atomic.LoadUint32(&l.level),
+	}
+}
+
+func (l *defaultLogger) logLevel() LogLevel {
+	if level := atomic.LoadUint32(&l.level); level != 0 {
+		return LogLevel(level)
+	}
+	return logLevel
+}
+
+func (l *defaultLogger) Debug() bool {
+	return l.logLevel() == LogLevelDebug
+}
+
+func (l *defaultLogger) Debugf(format string, args ...interface{}) {
+	l.logf(LogLevelDebug, format, args...)
+}
+
+func (l *defaultLogger) Infof(format string, args ...interface{}) {
+	l.logf(LogLevelInfo, format, args...)
+}
+
+func (l *defaultLogger) Errorf(format string, args ...interface{}) {
+	l.logf(LogLevelError, format, args...)
+}
+
+func (l *defaultLogger) logf(level LogLevel, format string, args ...interface{}) {
+	if l.logLevel() > level {
+		return
+	}
+	mutex.Lock()
+	if l.prefix != "" {
+		io.WriteString(out, l.prefix+" ")
+	}
+	fmt.Fprintf(out, format+"\n", args...)
+	mutex.Unlock()
+}
+
 // SetLogWriter sets the log writer.
 func SetLogWriter(w io.Writer) {
 	out = w
@@ -43,34 +119,22 @@ func SetLogLevel(level LogLevel) {
 
 // Debugf logs something
 func Debugf(format string, args ...interface{}) {
-	if logLevel == LogLevelDebug {
-		mutex.Lock()
-		fmt.Fprintf(out, format+"\n", args...)
-		mutex.Unlock()
-	}
+	DefaultLogger.Debugf(format, args...)
 }
 
 // Infof logs something
 func Infof(format string, args ...interface{}) {
-	if logLevel <= LogLevelInfo {
-		mutex.Lock()
-		fmt.Fprintf(out, format+"\n", args...)
-		mutex.Unlock()
-	}
+	DefaultLogger.Infof(format, args...)
 }
 
 // Errorf logs something
 func Errorf(format string, args ...interface{}) {
-	if logLevel <= LogLevelError {
-		mutex.Lock()
-		fmt.Fprintf(out, format+"\n", args...)
-		mutex.Unlock()
-	}
+	DefaultLogger.Errorf(format, args...)
 }
 
 // Debug returns true if the log level is LogLevelDebug
 func Debug() bool {
-	return logLevel == LogLevelDebug
+	return DefaultLogger.Debug()
 }
 
 func init() {
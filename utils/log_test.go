@@ -77,4 +77,32 @@ var _ = Describe("Log", func() {
 		readLoggingEnv()
 		Expect(logLevel).To(Equal(LogLevelNothing))
 	})
+	Context("loggers with a prefix", func() {
+		It("prefixes every line", func() {
+			SetLogLevel(LogLevelInfo)
+			logger := DefaultLogger.WithPrefix("server 1337")
+			logger.Infof("info %d", 42)
+			logger.WithPrefix("foo").Errorf("err")
+			Expect(b.String()).To(Equal("server 1337 info 42\nserver 1337 foo err\n"))
+		})
+
+		It("uses the global log level until the level is set", func() {
+			logger := DefaultLogger.WithPrefix("server")
+			Expect(logger.Debug()).To(BeFalse())
+			SetLogLevel(LogLevelDebug)
+			Expect(logger.Debug()).To(BeTrue())
+		})
+
+		It("sets the log level per logger", func() {
+			SetLogLevel(LogLevelError)
+			logger := DefaultLogger.WithPrefix("server")
+			logger.SetLogLevel(LogLevelDebug)
+			other := DefaultLogger.WithPrefix("client")
+			logger.Debugf("debug")
+			other.Debugf("debug")
+			other.Errorf("err")
+			Expect(b.String()).To(Equal("server debug\nclient err\n"))
+			Expect(logger.WithPrefix("child").Debug()).To(BeTrue())
+		})
+	})
 })
@@ -67,6 +67,11 @@ var _ = Describe("Min / Max", func() {
 			Expect(MinInt64(5, 7)).To(Equal(int64(5)))
 		})
 
+		It("returns the maximum ByteCount", func() {
+			Expect(MaxByteCount(7, 5)).To(Equal(protocol.ByteCount(7)))
+			Expect(MaxByteCount(5, 7)).To(Equal(protocol.ByteCount(7)))
+		})
+
 		It("returns the minimum ByteCount", func() {
 			Expect(MinByteCount(7, 5)).To(Equal(protocol.ByteCount(5)))
 			Expect(MinByteCount(5, 7)).To(Equal(protocol.ByteCount(5)))
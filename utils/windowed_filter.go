@@ -0,0 +1,71 @@
+package utils
+
+// A WindowedMaxFilter tracks the maximum of the samples taken in a sliding window.
+// It uses Kathleen Nichols' algorithm, keeping the best, second best and third best sample, so it only needs constant space.
+// The time can be any monotonically increasing counter, e.g. the number of round trips.
+// Samples with a value of 0 are ignored.
+type WindowedMaxFilter struct {
+	window    uint64
+	estimates [3]windowedSample
+}
+
+type windowedSample struct {
+	value uint64
+	time  uint64
+}
+
+// NewWindowedMaxFilter creates a new WindowedMaxFilter
+func NewWindowedMaxFilter(window uint64) *WindowedMaxFilter {
+	return &WindowedMaxFilter{window: window}
+}
+
+// Best returns the maximum of the samples in the window
+func (f *WindowedMaxFilter) Best() uint64 {
+	return f.estimates[0].value
+}
+
+// Reset discards all samples, and starts a new window with the given sample
+func (f *WindowedMaxFilter) Reset(value, time uint64) {
+	s := windowedSample{value: value, time: time}
+	f.estimates = [3]windowedSample{s, s, s}
+}
+
+// Update adds a new sample
+func (f *WindowedMaxFilter) Update(value, time uint64) {
+	if value == 0 {
+		return
+	}
+	// reset all estimates if there are none yet, if the new sample is a new maximum, or if nothing has been sampled for a whole window
+	if f.estimates[0].value == 0 || value >= f.estimates[0].value || time-f.estimates[2].time > f.window {
+		f.Reset(value, time)
+		return
+	}
+
+	s := windowedSample{value: value, time: time}
+	if value >= f.estimates[1].value {
+		f.estimates[1] = s
+		f.estimates[2] = s
+	} else if value >= f.estimates[2].value {
+		f.estimates[2] = s
+	}
+
+	// expire the best estimate, and make sure that the other estimates are taken from different parts of the window
+	if time-f.estimates[0].time > f.window {
+		f.estimates[0] = f.estimates[1]
+		f.estimates[1] = f.estimates[2]
+		f.estimates[2] = s
+		if time-f.estimates[0].time > f.window {
+			f.estimates[0] = f.estimates[1]
+			f.estimates[1] = f.estimates[2]
+		}
+		return
+	}
+	if f.estimates[1].value == f.estimates[0].value && time-f.estimates[1].time > f.window/4 {
+		f.estimates[1] = s
+		f.estimates[2] = s
+		return
+	}
+	if f.estimates[2].value == f.estimates[1].value && time-f.estimates[2].time > f.window/2 {
+		f.estimates[2] = s
+	}
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Windowed max filter", func() {
+	var f *WindowedMaxFilter
+
+	BeforeEach(func() {
+		f = NewWindowedMaxFilter(10)
+	})
+
+	It("returns 0 without samples", func() {
+		Expect(f.Best()).To(BeZero())
+	})
+
+	It("ignores samples with a value of 0", func() {
+		f.Update(0, 1)
+		Expect(f.Best()).To(BeZero())
+	})
+
+	It("returns the maximum", func() {
+		f.Update(5, 1)
+		f.Update(10, 2)
+		f.Update(7, 3)
+		Expect(f.Best()).To(Equal(uint64(10)))
+	})
+
+	It("expires old samples", func() {
+		f.Update(10, 0)
+		f.Update(5, 5)
+		f.Update(3, 10)
+		Expect(f.Best()).To(Equal(uint64(10)))
+		f.Update(3, 11)
+		Expect(f.Best()).To(Equal(uint64(5)))
+		f.Update(3, 16)
+		Expect(f.Best()).To(Equal(uint64(3)))
+	})
+
+	It("resets if no samples were taken for a whole window", func() {
+		f.Update(10, 0)
+		f.Update(3, 20)
+		Expect(f.Best()).To(Equal(uint64(3)))
+	})
+
+	It("resets", func() {
+		f.Update(10, 0)
+		f.Reset(4, 1)
+		Expect(f.Best()).To(Equal(uint64(4)))
+	})
+})